@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	infoFormat string
+	infoOutput string
+)
+
+// analyzeCmd печатает результат анализа репозитория без генерации pipeline
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze",
+	Short: "Print detected project information",
+	Long:  `Analyze a local or remote repository and print the detected ProjectInfo as JSON or YAML without generating a pipeline`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var projectInfo *analyzer.ProjectInfo
+		var err error
+		if repoPath != "" {
+			projectInfo, err = analyzer.AnalyzeLocalRepo(repoPath)
+		} else if remoteRepo != "" {
+			projectInfo, err = analyzer.AnalyzeRemoteRepo(remoteRepo, branch)
+		} else {
+			fmt.Fprintln(os.Stderr, "Please specify either --repo or --remote")
+			cmd.Help()
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error analyzing repository: %v\n", err)
+			os.Exit(1)
+		}
+
+		data, err := analyzer.EncodeProjectInfo(projectInfo, infoFormat)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding project info: %v\n", err)
+			os.Exit(1)
+		}
+		if infoOutput == "" {
+			os.Stdout.Write(data)
+			return
+		}
+		if err := os.WriteFile(infoOutput, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing project info: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "✓ Project info written: %s\n", infoOutput)
+	},
+}
+
+func init() {
+	analyzeCmd.Flags().StringVarP(&repoPath, "repo", "r", "", "Path to local repository")
+	analyzeCmd.Flags().StringVarP(&remoteRepo, "remote", "R", "", "URL of remote git repository")
+	analyzeCmd.Flags().StringVarP(&branch, "branch", "b", "main", "Branch to analyze")
+	analyzeCmd.Flags().StringVarP(&infoFormat, "format", "f", "json", "Output format: json or yaml")
+	analyzeCmd.Flags().StringVarP(&infoOutput, "output", "o", "", "Write project info to a file instead of stdout")
+	rootCmd.AddCommand(analyzeCmd)
+}
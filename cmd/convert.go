@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/generator"
+	"github.com/immxrtalbeast/pipeline-gen/internal/git"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+	"github.com/spf13/cobra"
+)
+
+var (
+	convertInput string
+	convertFrom  string
+)
+
+// convertSources — пути, по которым CI-системы ищут свою конфигурацию.
+var convertSources = []string{
+	".github/workflows/*.yml", ".github/workflows/*.yaml",
+	".forgejo/workflows/*.yml", ".forgejo/workflows/*.yaml",
+	".gitea/workflows/*.yml", ".gitea/workflows/*.yaml",
+	".gitlab-ci.yml", "Jenkinsfile", ".travis.yml",
+}
+
+// convertCmd переводит существующую конфигурацию CI в другой формат
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert an existing CI config to another format",
+	Long:  `Parse an existing pipeline (GitHub Actions, Forgejo Actions or GitLab CI) from a file or a repository and write the equivalent pipeline in another format. Constructs that cannot be converted are reported`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, content, err := readConvertSource()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading CI config: %v\n", err)
+			os.Exit(1)
+		}
+		from := convertFrom
+		if from == "" {
+			detected, ok := pipeline.SourceFormat(file)
+			if !ok {
+				fmt.Fprintf(os.Stderr, "Cannot detect the format of %s, use --from\n", file)
+				os.Exit(1)
+			}
+			from = detected
+		}
+
+		p, issues, err := pipeline.Parse(content, from)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		out, err := pipeline.Render(p, format)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error rendering pipeline: %v\n", err)
+			os.Exit(1)
+		}
+		for _, issue := range issues {
+			fmt.Fprintf(os.Stderr, "⚠ %s\n", issue)
+		}
+
+		if outputFile == "-" {
+			os.Stdout.WriteString(out)
+			return
+		}
+		if !cmd.Flags().Changed("output") {
+			outputFile = "pipeline.yml"
+			if path, ok := generator.DefaultOutput(format); ok {
+				outputFile = path
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(outputFile, []byte(out), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing pipeline: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Converted %s (%s) to %s: %s\n", file, from, format, outputFile)
+		if len(issues) > 0 {
+			fmt.Printf("⚠ %d construct(s) could not be converted exactly, see the warnings above\n", len(issues))
+		}
+	},
+}
+
+// readConvertSource читает исходную конфигурацию: файл --input или
+// единственную поддерживаемую конфигурацию в репозитории. С --repo и
+// --remote путь --input указывается относительно корня репозитория.
+func readConvertSource() (string, []byte, error) {
+	var files []string
+	var read func(name string) ([]byte, error)
+	switch {
+	case remoteRepo != "":
+		repo, err := git.AnalyzeRemoteRepo(remoteRepo, branch)
+		if err != nil {
+			return "", nil, err
+		}
+		files = repo.Structure
+		read = func(name string) ([]byte, error) {
+			content, ok := repo.GetFileContent(name)
+			if !ok {
+				return nil, fmt.Errorf("file %s not found in repository", name)
+			}
+			return []byte(content), nil
+		}
+	case repoPath != "":
+		for _, pattern := range convertSources {
+			matches, _ := filepath.Glob(filepath.Join(repoPath, filepath.FromSlash(pattern)))
+			for _, match := range matches {
+				rel, _ := filepath.Rel(repoPath, match)
+				files = append(files, filepath.ToSlash(rel))
+			}
+		}
+		read = func(name string) ([]byte, error) {
+			return os.ReadFile(filepath.Join(repoPath, filepath.FromSlash(name)))
+		}
+	case convertInput != "":
+		content, err := os.ReadFile(convertInput)
+		return convertInput, content, err
+	default:
+		return "", nil, fmt.Errorf("specify --input, --repo or --remote")
+	}
+
+	if convertInput != "" {
+		content, err := read(convertInput)
+		return convertInput, content, err
+	}
+	var supported, unsupported []string
+	for _, file := range files {
+		matched := false
+		for _, pattern := range convertSources {
+			if ok, _ := path.Match(pattern, file); ok {
+				matched = true
+			}
+		}
+		from, ok := pipeline.SourceFormat(file)
+		if !matched || !ok || convertFrom != "" && from != convertFrom {
+			continue
+		}
+		if contains(pipeline.ParseFormats, from) {
+			supported = append(supported, file)
+		} else {
+			unsupported = append(unsupported, file)
+		}
+	}
+	sort.Strings(supported)
+	switch {
+	case len(supported) == 1:
+		content, err := read(supported[0])
+		return supported[0], content, err
+	case len(supported) > 1:
+		return "", nil, fmt.Errorf("several CI configs found (%s), choose one with --input", strings.Join(supported, ", "))
+	case len(unsupported) > 0:
+		return "", nil, fmt.Errorf("found %s, but only %s configs can be converted", strings.Join(unsupported, ", "), strings.Join(pipeline.ParseFormats, ", "))
+	default:
+		return "", nil, fmt.Errorf("no CI config found")
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	convertCmd.Flags().StringVarP(&convertInput, "input", "i", "", "CI config to convert (relative to the repository with --repo or --remote)")
+	convertCmd.Flags().StringVar(&convertFrom, "from", "", "Format of the source config: github, forgejo or gitlab (detected from the file path by default)")
+	convertCmd.Flags().StringVarP(&repoPath, "repo", "r", "", "Path to local repository to find the CI config in")
+	convertCmd.Flags().StringVarP(&remoteRepo, "remote", "R", "", "URL of remote git repository to find the CI config in")
+	convertCmd.Flags().StringVarP(&branch, "branch", "b", "main", "Branch of the remote repository")
+	convertCmd.Flags().StringVarP(&format, "format", "f", "github", "Target CI/CD format")
+	convertCmd.Flags().StringVarP(&outputFile, "output", "o", "pipeline.yml", "Output file, - for stdout (defaults to the conventional path of the format or pipeline.yml)")
+	rootCmd.AddCommand(convertCmd)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var forceDockerfile bool
+
+// dockerfileCmd пишет Dockerfile для проекта, в котором его нет
+var dockerfileCmd = &cobra.Command{
+	Use:   "dockerfile",
+	Short: "Generate a Dockerfile for the project",
+	Long:  `Analyze a local or remote repository and write a multi-stage Dockerfile that builds the project and runs it as a non-root user. Supported languages: Go, JavaScript, Python and Java (Maven or Gradle)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var projectInfo *analyzer.ProjectInfo
+		var err error
+		if fromInfo != "" {
+			projectInfo, err = analyzer.LoadProjectInfo(fromInfo)
+		} else if repoPath != "" {
+			projectInfo, err = analyzer.AnalyzeLocalRepo(repoPath)
+		} else if remoteRepo != "" {
+			projectInfo, err = analyzer.AnalyzeRemoteRepo(remoteRepo, branch)
+		} else {
+			fmt.Fprintln(os.Stderr, "Please specify either --repo, --remote or --from-info")
+			cmd.Help()
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error analyzing repository: %v\n", err)
+			os.Exit(1)
+		}
+
+		if outputFile == "-" {
+			content, err := generator.GenerateDockerfile(projectInfo)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error generating Dockerfile: %v\n", err)
+				os.Exit(1)
+			}
+			os.Stdout.WriteString(content)
+			return
+		}
+		if !cmd.Flags().Changed("output") {
+			outputFile = dockerfilePath()
+		}
+		if projectInfo.HasDockerfile && !forceDockerfile {
+			fmt.Fprintln(os.Stderr, "Repository already has a Dockerfile, use --force to overwrite it")
+			os.Exit(1)
+		}
+		if err := generator.WriteDockerfile(projectInfo, outputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating Dockerfile: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Dockerfile generated: %s\n", outputFile)
+	},
+}
+
+// dockerfilePath — путь Dockerfile по умолчанию: корень локального
+// репозитория или текущий каталог.
+func dockerfilePath() string {
+	if repoPath != "" {
+		return filepath.Join(repoPath, "Dockerfile")
+	}
+	return "Dockerfile"
+}
+
+func init() {
+	dockerfileCmd.Flags().StringVarP(&repoPath, "repo", "r", "", "Path to local repository")
+	dockerfileCmd.Flags().StringVarP(&remoteRepo, "remote", "R", "", "URL of remote git repository")
+	dockerfileCmd.Flags().StringVarP(&branch, "branch", "b", "main", "Branch to analyze")
+	dockerfileCmd.Flags().StringVar(&fromInfo, "from-info", "", "Generate from a saved ProjectInfo file instead of analyzing a repository")
+	dockerfileCmd.Flags().StringVarP(&outputFile, "output", "o", "Dockerfile", "Output file, - for stdout (defaults to the repository root with --repo)")
+	dockerfileCmd.Flags().BoolVar(&forceDockerfile, "force", false, "Overwrite an existing Dockerfile")
+	rootCmd.AddCommand(dockerfileCmd)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/spf13/cobra"
+)
+
+// explainCmd печатает обоснование каждого обнаруженного значения
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "Explain how project information was detected",
+	Long:  `Analyze a local or remote repository and print, for every detected field, the file, line and rule behind its value together with a confidence level`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var projectInfo *analyzer.ProjectInfo
+		var err error
+		if fromInfo != "" {
+			projectInfo, err = analyzer.LoadProjectInfo(fromInfo)
+		} else if repoPath != "" {
+			projectInfo, err = analyzer.AnalyzeLocalRepo(repoPath)
+		} else if remoteRepo != "" {
+			projectInfo, err = analyzer.AnalyzeRemoteRepo(remoteRepo, branch)
+		} else {
+			fmt.Fprintln(os.Stderr, "Please specify either --repo, --remote or --from-info")
+			cmd.Help()
+			os.Exit(1)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error analyzing repository: %v\n", err)
+			os.Exit(1)
+		}
+
+		printEvidence(projectInfo.Evidence)
+		for _, c := range projectInfo.Components {
+			if c.Root == "." && c.Name == "root" {
+				continue
+			}
+			fmt.Printf("\nComponent %s (%s):\n", c.Name, c.Root)
+			printEvidence(c.Info.Evidence)
+		}
+	},
+}
+
+func printEvidence(evidence []analyzer.Evidence) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tVALUE\tCONFIDENCE\tSOURCE\tRULE")
+	for _, e := range evidence {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.Field, e.Value, e.Confidence, e.Source(), e.Rule)
+	}
+	w.Flush()
+}
+
+func init() {
+	explainCmd.Flags().StringVarP(&repoPath, "repo", "r", "", "Path to local repository")
+	explainCmd.Flags().StringVarP(&remoteRepo, "remote", "R", "", "URL of remote git repository")
+	explainCmd.Flags().StringVarP(&branch, "branch", "b", "main", "Branch to analyze")
+	explainCmd.Flags().StringVar(&fromInfo, "from-info", "", "Explain a saved ProjectInfo file instead of analyzing a repository")
+	rootCmd.AddCommand(explainCmd)
+}
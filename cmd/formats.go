@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+// formatsCmd печатает матрицу поддерживаемых языков и форматов
+var formatsCmd = &cobra.Command{
+	Use:   "formats",
+	Short: "Show supported languages and CI/CD formats",
+	Long:  `Print the matrix of languages and CI/CD formats that have a registered pipeline generator`,
+	Run: func(cmd *cobra.Command, args []string) {
+		formats := generator.Formats()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprint(w, "LANGUAGE")
+		for _, f := range formats {
+			fmt.Fprintf(w, "\t%s", f)
+		}
+		fmt.Fprintln(w)
+
+		for _, language := range generator.Languages() {
+			fmt.Fprint(w, language)
+			for _, f := range formats {
+				mark := "-"
+				if _, ok := generator.Lookup(language, f); ok {
+					mark = "✓"
+				}
+				fmt.Fprintf(w, "\t%s", mark)
+			}
+			fmt.Fprintln(w)
+		}
+		w.Flush()
+
+		fmt.Printf("\nDeploy targets: %s\n", strings.Join(generator.DeployTargets(), ", "))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(formatsCmd)
+}
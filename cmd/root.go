@@ -4,8 +4,11 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
 	"github.com/immxrtalbeast/pipeline-gen/internal/generator"
@@ -13,13 +16,20 @@ import (
 )
 
 var (
-	repoPath      string
-	remoteRepo    string
-	branch        string
-	outputFile    string
-	format        string
-	listFile      string
-	maxConcurrent int
+	repoPath       string
+	remoteRepo     string
+	branch         string
+	outputFile     string
+	format         string
+	listFile       string
+	maxConcurrent  int
+	fromInfo       string
+	dumpInfo       string
+	mergeExisting  bool
+	assumeYes      bool
+	deployTarget   string
+	registry       string
+	withDockerfile bool
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -30,7 +40,13 @@ var rootCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		var projectInfo *analyzer.ProjectInfo
 		var err error
-		if repoPath != "" {
+		if fromInfo != "" {
+			projectInfo, err = analyzer.LoadProjectInfo(fromInfo)
+			if err != nil {
+				fmt.Printf("Error loading project info: %v\n", err)
+				os.Exit(1)
+			}
+		} else if repoPath != "" {
 			projectInfo, err = analyzer.AnalyzeLocalRepo(repoPath)
 			if err != nil {
 				fmt.Printf("Error analyzing local repository: %v\n", err)
@@ -53,11 +69,80 @@ var rootCmd = &cobra.Command{
 			fmt.Printf("✓ Pipelines generated successfully for all repositories in %s\n", listFile)
 			return
 		} else {
-			fmt.Println("Please specify either --repo, --remote or --list")
+			fmt.Println("Please specify either --repo, --remote, --list or --from-info")
 			cmd.Help()
 			os.Exit(1)
 		}
 
+		if dumpInfo != "" {
+			if err := analyzer.SaveProjectInfo(projectInfo, dumpInfo); err != nil {
+				fmt.Printf("Error writing project info: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Project info written: %s\n", dumpInfo)
+		}
+
+		if deployTarget != "" || registry != "" {
+			if projectInfo.Config == nil {
+				projectInfo.Config = &analyzer.Config{}
+			}
+		}
+		if deployTarget != "" {
+			projectInfo.Config.Deploy.Target = deployTarget
+		}
+		if registry != "" {
+			// Адрес вместо имени реестра означает собственный реестр
+			if strings.ContainsAny(registry, "./:") {
+				projectInfo.Config.Registry.Provider, projectInfo.Config.Registry.URL = "custom", registry
+			} else {
+				projectInfo.Config.Registry.Provider = registry
+			}
+		}
+
+		if withDockerfile && !projectInfo.HasDockerfile {
+			path := dockerfilePath()
+			// Без шаблона для языка pipeline генерируется как раньше, без образа
+			if err := generator.WriteDockerfile(projectInfo, path); err != nil {
+				fmt.Printf("⚠ Dockerfile not generated: %v\n", err)
+			} else {
+				fmt.Printf("✓ Dockerfile generated: %s\n", path)
+			}
+		}
+
+		if !cmd.Flags().Changed("output") {
+			if path, ok := generator.DefaultOutput(format); ok {
+				outputFile = path
+			}
+		}
+		if repoPath != "" {
+			if mergeExisting && !cmd.Flags().Changed("output") {
+				target, err := generator.MergeTarget(repoPath, format)
+				if err != nil {
+					fmt.Printf("Error choosing the config to merge with: %v\n", err)
+					os.Exit(1)
+				}
+				if target != "" {
+					outputFile = target
+				}
+			} else if existing := overwrittenConfig(repoPath, format); existing != "" && !mergeExisting {
+				fmt.Printf("⚠ Existing %s config will be overwritten: %s\n", format, existing)
+				if generator.CanMerge(format) {
+					fmt.Println("  use --merge to update the generated jobs and keep the hand-written ones")
+				}
+			}
+		}
+
+		if mergeExisting {
+			written, err := generator.MergePipeline(projectInfo, outputFile, format, confirmMerge)
+			if err != nil {
+				fmt.Printf("Error merging pipeline: %v\n", err)
+				os.Exit(1)
+			}
+			if written {
+				fmt.Printf("✓ Pipeline merged successfully: %s\n", outputFile)
+			}
+			return
+		}
 		err = generator.GeneratePipeline(projectInfo, outputFile, format)
 		if err != nil {
 			fmt.Printf("Error generating pipeline: %v\n", err)
@@ -69,6 +154,33 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// overwrittenConfig возвращает существующую конфигурацию формата, которую
+// перезапишет вывод в outputFile, или пустую строку.
+func overwrittenConfig(repoPath, format string) string {
+	output, err := filepath.Abs(outputFile)
+	if err != nil {
+		return ""
+	}
+	for _, config := range generator.ExistingConfigs(repoPath, format) {
+		if abs, err := filepath.Abs(config); err == nil && abs == output {
+			return config
+		}
+	}
+	return ""
+}
+
+// confirmMerge показывает изменения слияния и спрашивает, записывать ли их.
+func confirmMerge(diff string) bool {
+	fmt.Print(diff)
+	if assumeYes {
+		return true
+	}
+	fmt.Print("Apply these changes? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -83,7 +195,14 @@ func init() {
 	rootCmd.Flags().StringVarP(&listFile, "list", "l", "", "Path to txt file with links to repositories")
 	rootCmd.Flags().StringVarP(&remoteRepo, "remote", "R", "", "URL of remote git repository")
 	rootCmd.Flags().StringVarP(&branch, "branch", "b", "main", "Branch to analyze")
-	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "pipeline.yml", "Output pipeline file")
-	rootCmd.Flags().StringVarP(&format, "format", "f", "github", "CI/CD format (github, gitlab, jenkins)")
+	rootCmd.Flags().StringVarP(&outputFile, "output", "o", "pipeline.yml", "Output pipeline file (formats with a conventional path, e.g. forgejo or circleci, default to it)")
+	rootCmd.Flags().StringVarP(&format, "format", "f", "github", "CI/CD format (run 'pipeline-gen formats' to list supported ones)")
 	rootCmd.Flags().IntVarP(&maxConcurrent, "concurrent", "c", 10, "Max goroutines")
+	rootCmd.Flags().StringVar(&fromInfo, "from-info", "", "Generate from a saved ProjectInfo file (JSON or YAML) instead of analyzing a repository")
+	rootCmd.Flags().StringVar(&dumpInfo, "dump-info", "", "Write the detected ProjectInfo to a file before generating")
+	rootCmd.Flags().BoolVar(&mergeExisting, "merge", false, "Merge with the existing config: update generated jobs, keep hand-written ones and show a diff before writing")
+	rootCmd.Flags().StringVar(&deployTarget, "deploy", "", "Deploy target (ssh-docker, docker-compose, kubernetes, helm, static or none), overrides deploy.target from .pipeline-gen.yaml")
+	rootCmd.Flags().StringVar(&registry, "registry", "", "Container registry for the image (ghcr, gitlab, dockerhub, ecr, gcr or a registry address), overrides registry from .pipeline-gen.yaml")
+	rootCmd.Flags().BoolVar(&withDockerfile, "with-dockerfile", false, "Generate a Dockerfile when the project has none, so the pipeline builds and deploys an image")
+	rootCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Write merged changes without asking")
 }
@@ -1,283 +1,349 @@
 package analyzer
 
 import (
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/immxrtalbeast/pipeline-gen/internal/git"
 )
 
 type ProjectInfo struct {
-	Language       string   `json:"language"`
-	RepoName       string   `json:"repo_name"`
-	Version        string   `json:"version"`
-	Architecture   string   `json:"architecture"`
-	Dependencies   []string `json:"dependencies"`
-	BuildTool      string   `json:"build_tool"`
-	TestFramework  string   `json:"test_framework"`
-	HasDockerfile  bool     `json:"has_dockerfile"`
-	HasMakefile    bool     `json:"has_makefile"`
-	Modules        []string `json:"modules"`
-	RepositoryType string   `json:"repository_type"` // "local" или "remote"
-	RemoteURL      string   `json:"remote_url,omitempty"`
-	HasTests       bool     `json:"has_tests"` // ← Добавляем это поле
-	PackageManager string   `json:"package_manager"`
-	Structure      []string `json:"structure"`
-	RepositoryURL  string   `json:"repository_url"`
-	MainFilePath   string   `json:"main_file_path"`
+	Language       string      `json:"language" yaml:"language"`
+	RepoName       string      `json:"repo_name" yaml:"repo_name"`
+	Version        string      `json:"version" yaml:"version"`
+	Architecture   string      `json:"architecture" yaml:"architecture"`
+	Dependencies   []string    `json:"dependencies" yaml:"dependencies"`
+	BuildTool      string      `json:"build_tool" yaml:"build_tool"`
+	TestFramework  string      `json:"test_framework" yaml:"test_framework"`
+	HasDockerfile  bool        `json:"has_dockerfile" yaml:"has_dockerfile"`
+	Dockerfile     *Dockerfile `json:"dockerfile,omitempty" yaml:"dockerfile,omitempty"` // разобранный Dockerfile корня репозитория
+	HasMakefile    bool        `json:"has_makefile" yaml:"has_makefile"`
+	Modules        []string    `json:"modules" yaml:"modules"`
+	RepositoryType string      `json:"repository_type" yaml:"repository_type"` // "local" или "remote"
+	RemoteURL      string      `json:"remote_url,omitempty" yaml:"remote_url,omitempty"`
+	HasTests       bool        `json:"has_tests" yaml:"has_tests"` // ← Добавляем это поле
+	PackageManager string      `json:"package_manager" yaml:"package_manager"`
+	Structure      []string    `json:"structure" yaml:"structure"`
+	RepositoryURL  string      `json:"repository_url" yaml:"repository_url"`
+	MainFilePath   string      `json:"main_file_path" yaml:"main_file_path"`
+	Config         *Config     `json:"config,omitempty" yaml:"config,omitempty"` // настройки из .pipeline-gen.yaml
+	Evidence       []Evidence  `json:"evidence,omitempty" yaml:"evidence,omitempty"`
+	Components     []Component `json:"components,omitempty" yaml:"components,omitempty"` // части полиглот-репозитория
 }
 
-func AnalyzeRemoteRepo(repoURL, branch string) (*ProjectInfo, error) {
-	remoteInfo, err := git.AnalyzeRemoteRepo(repoURL, branch)
+// LanguageAnalyzer заполняет ProjectInfo для одного языка по дереву файлов.
+// Один и тот же анализатор используется для локальных и удаленных репозиториев.
+type LanguageAnalyzer interface {
+	Language() string
+	Analyze(repo *Repo, info *ProjectInfo) error
+}
+
+// AnalyzerFunc превращает функцию в LanguageAnalyzer для языка.
+func AnalyzerFunc(language string, analyze func(*Repo, *ProjectInfo) error) LanguageAnalyzer {
+	return funcAnalyzer{language: language, analyze: analyze}
+}
+
+type funcAnalyzer struct {
+	language string
+	analyze  func(*Repo, *ProjectInfo) error
+}
+
+func (a funcAnalyzer) Language() string { return a.language }
+func (a funcAnalyzer) Analyze(repo *Repo, info *ProjectInfo) error {
+	return a.analyze(repo, info)
+}
+
+var (
+	analyzersMu sync.RWMutex
+	analyzers   = map[string]LanguageAnalyzer{}
+)
+
+// RegisterAnalyzer добавляет анализатор языка. Анализатор, зарегистрированный
+// позже для того же языка, заменяет предыдущий.
+func RegisterAnalyzer(a LanguageAnalyzer) {
+	analyzersMu.Lock()
+	defer analyzersMu.Unlock()
+	analyzers[a.Language()] = a
+}
+
+func lookupAnalyzer(language string) (LanguageAnalyzer, bool) {
+	analyzersMu.RLock()
+	defer analyzersMu.RUnlock()
+	a, ok := analyzers[language]
+	return a, ok
+}
+
+func init() {
+	for language, analyze := range map[string]func(*Repo, *ProjectInfo) error{
+		"go":          analyzeGoProject,
+		"python":      analyzePythonProject,
+		"java_gradle": analyzeJavaProject,
+		"java_maven":  analyzeJavaProject,
+		"rust":        analyzeRustProject,
+		"cpp":         analyzeCppProject,
+		"javascript":  analyzeJavaScriptProject,
+		"ruby":        analyzeRubyProject,
+		"csharp":      analyzeCSharpProject,
+		"swift":       analyzeSwiftProject,
+		"php":         analyzePHPProject,
+	} {
+		RegisterAnalyzer(AnalyzerFunc(language, analyze))
+	}
+}
+
+// AnalyzeFS анализирует произвольное дерево файлов. Локальный и удаленный
+// анализ сводятся к этой функции, поэтому для одного и того же дерева
+// они дают одинаковый ProjectInfo.
+func AnalyzeFS(fsys fs.FS) (*ProjectInfo, error) {
+	repo, err := NewRepo(fsys)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error reading repository files: %v", err)
 	}
-	parts := strings.Split(repoURL, "/")
 
-	info := &ProjectInfo{
-		RepositoryType: "remote",
-		RemoteURL:      repoURL,
-		RepoName:       parts[len(parts)-1],
+	info, err := analyzeRepo(repo)
+	if err != nil {
+		return nil, err
 	}
+	components, err := detectComponents(repo, info)
+	if err != nil {
+		return nil, err
+	}
+	info.Components = components
 
-	// Определяем язык проекта
-	info.Language = detectLanguageFromMemory(remoteInfo)
+	return info, nil
+}
+
+// analyzeRepo определяет язык дерева и заполняет ProjectInfo его анализатором.
+func analyzeRepo(repo *Repo) (*ProjectInfo, error) {
+	return analyzeLanguage(repo, nil)
+}
 
-	if info.Language == "go" {
-		analyzeGoProjectFromMemory(remoteInfo, info)
-		info.Architecture = detectGoArchitectureFromMemory(remoteInfo)
+// analyzeLanguage заполняет ProjectInfo анализатором языка. Без language
+// язык берется из конфигурации или определяется по дереву. Заданный
+// language — еще один язык корня полиглот-репозитория: закрепленные
+// в конфигурации значения относятся к основному языку и к нему
+// не применяются.
+func analyzeLanguage(repo *Repo, language *Evidence) (*ProjectInfo, error) {
+	cfg, err := loadConfig(repo)
+	if err != nil {
+		return nil, err
 	}
 
-	switch info.Language {
-	case "go":
-		analyzeGoProjectFromMemory(remoteInfo, info)
-		info.Architecture = detectGoArchitectureFromMemory(remoteInfo)
-	case "python":
-		analyzePythonProjectFromMemory(remoteInfo, info)
-	case "java_gradle", "java_maven":
-		analyzeJavaProjectFromMemory(remoteInfo, info)
-	case "rust":
-		analyzeRustProjectFromMemory(remoteInfo, info)
-	case "cpp":
-		analyzeCppProjectFromMemory(remoteInfo, info)
-	case "javascript":
-		analyzeJavaScriptProjectFromMemory(remoteInfo, info)
-	case "ruby":
-		analyzeRubyProjectFromMemory(remoteInfo, info)
-	case "csharp":
-		analyzeCSharpProjectFromMemory(remoteInfo, info)
-	case "swift":
-		analyzeSwiftProjectFromMemory(remoteInfo, info)
-	case "php":
-		analyzePHPProjectFromMemory(remoteInfo, info)
+	info := &ProjectInfo{}
+	switch {
+	case language != nil:
+		info.Language = language.Value
+		info.AddEvidence(*language)
+	case cfg != nil && cfg.Language != "":
+		info.Language = cfg.Language
+	default:
+		var evidence Evidence
+		info.Language, evidence = detectLanguage(repo)
+		info.AddEvidence(evidence)
+	}
+	if a, ok := lookupAnalyzer(info.Language); ok {
+		if err := a.Analyze(repo, info); err != nil {
+			return nil, err
+		}
+	}
+	info.HasDockerfile = repo.HasFile("Dockerfile")
+	applyDockerfile(repo, info)
+	if cfg != nil && language == nil {
+		cfg.apply(info)
+	} else if cfg != nil {
+		info.Config = cfg
 	}
-	info.HasDockerfile = remoteInfo.HasFile("Dockerfile")
-	info.HasMakefile = remoteInfo.HasFile("Makefile")
+	info.HasMakefile = repo.HasFile("Makefile")
+	info.Structure = repo.Files()
+	collectEvidence(info)
 
 	return info, nil
 }
-func detectLanguageFromMemory(remoteInfo *git.RemoteRepoInfo) string {
-	// Проверяем наличие конфигурационных файлов
-	if remoteInfo.HasFile("go.mod") {
-		return "go"
+
+func AnalyzeRemoteRepo(repoURL, branch string) (*ProjectInfo, error) {
+	remoteInfo, err := git.AnalyzeRemoteRepo(repoURL, branch)
+	if err != nil {
+		return nil, err
 	}
-	if remoteInfo.HasFile("package.json") {
-		return "javascript"
+
+	info, err := AnalyzeFS(remoteInfo)
+	if err != nil {
+		return nil, err
 	}
-	if remoteInfo.HasFile("requirements.txt") || remoteInfo.HasFile("setup.py") {
-		return "python"
+	parts := strings.Split(strings.TrimSuffix(repoURL, ".git"), "/")
+	info.RepositoryType = "remote"
+	info.RemoteURL = repoURL
+	info.RepoName = parts[len(parts)-1]
+
+	return info, nil
+}
+
+func AnalyzeLocalRepo(repoPath string) (*ProjectInfo, error) {
+	info, err := AnalyzeFS(os.DirFS(repoPath))
+	if err != nil {
+		return nil, err
 	}
-	if remoteInfo.HasFile("Cargo.toml") {
-		return "rust"
+	info.RepositoryType = "local"
+	if abs, err := filepath.Abs(repoPath); err == nil {
+		info.RepoName = filepath.Base(abs)
 	}
 
-	if remoteInfo.HasFile("build.gradle") || remoteInfo.HasFile("build.gradle.kts") {
-		return "java_gradle"
+	return info, nil
+}
+
+// languageMarkers — файлы в корне репозитория, по которым однозначно
+// определяется язык. Порядок задает приоритет.
+var languageMarkers = []struct {
+	file     string
+	language string
+}{
+	{"go.mod", "go"},
+	{"package.json", "javascript"},
+	{"requirements.txt", "python"},
+	{"setup.py", "python"},
+	{"pyproject.toml", "python"},
+	{"Cargo.toml", "rust"},
+	{"build.gradle", "java_gradle"},
+	{"build.gradle.kts", "java_gradle"},
+	{"pom.xml", "java_maven"},
+	{"CMakeLists.txt", "cpp"},
+	{"Gemfile", "ruby"},
+	{"Package.swift", "swift"},
+	{"composer.json", "php"},
+	{"artisan", "php"},
+	{"Makefile", "cpp"},
+}
+
+// detectLanguage определяет язык по файлам-маркерам в корне репозитория,
+// а если их нет — по расширениям файлов. Вместе с языком возвращается
+// правило, по которому он выбран.
+func detectLanguage(repo *Repo) (string, Evidence) {
+	var found []string
+	language := ""
+	for _, marker := range languageMarkers {
+		if !repo.HasFile(marker.file) {
+			continue
+		}
+		if language == "" {
+			language = marker.language
+		}
+		found = append(found, fmt.Sprintf("%s (%s)", marker.file, marker.language))
 	}
-	if remoteInfo.HasFile("pom.xml") {
-		return "java_maven"
+	if language != "" {
+		evidence := Evidence{
+			Field:      "language",
+			Value:      language,
+			File:       strings.Fields(found[0])[0],
+			Rule:       "marker file in repository root",
+			Confidence: ConfidenceHigh,
+		}
+		if conflicting := markersOfOtherLanguages(found, language); len(conflicting) > 0 {
+			evidence.Rule += "; first by priority, also found " + strings.Join(conflicting, ", ")
+			evidence.Confidence = ConfidenceMedium
+		}
+		return language, evidence
 	}
-	if remoteInfo.HasFile("CMakeLists.txt") {
-		return "cpp"
+
+	for _, pattern := range []string{"*.sln", "*.csproj"} {
+		if matches := repo.Glob(pattern); len(matches) > 0 {
+			return "csharp", Evidence{
+				Field:      "language",
+				Value:      "csharp",
+				File:       matches[0],
+				Rule:       "marker file in repository root",
+				Confidence: ConfidenceHigh,
+			}
+		}
 	}
-	if remoteInfo.HasFile("Makefile") {
-		return "cpp"
+
+	language, count, total := detectLanguageByExtensions(repo.Files())
+	evidence := Evidence{
+		Field:      "language",
+		Value:      language,
+		Rule:       "no marker files; no source files with known extensions",
+		Confidence: ConfidenceLow,
 	}
-	if remoteInfo.HasFile("Gemfile") {
-		return "ruby"
+	if count > 0 {
+		evidence.Rule = fmt.Sprintf("no marker files; most source files by extension (%d of %d)", count, total)
+		if count*2 > total {
+			evidence.Confidence = ConfidenceMedium
+		}
 	}
-	if remoteInfo.HasFile(".csproj") || remoteInfo.HasFile(".sln") {
-		return "csharp"
+	return language, evidence
+}
+
+// markersOfOtherLanguages отбирает найденные маркеры, указывающие на другой язык.
+func markersOfOtherLanguages(found []string, language string) []string {
+	var other []string
+	for _, marker := range found {
+		if !strings.HasSuffix(marker, "("+language+")") {
+			other = append(other, marker)
+		}
 	}
-	return detectLanguageByExtensions(remoteInfo.Structure)
+	return other
 }
-func detectLanguageByExtensions(fileList []string) string {
+
+// detectLanguageByExtensions выбирает язык с наибольшим числом исходных
+// файлов. Возвращает язык, число его файлов и общее число исходных файлов.
+func detectLanguageByExtensions(fileList []string) (string, int, int) {
 	extCount := make(map[string]int)
+	total := 0
 
 	for _, file := range fileList {
 		if strings.Contains(file, ".") {
 			ext := strings.ToLower(filepath.Ext(file))
+			lang := ""
 			switch ext {
 			case ".go":
-				extCount["go"]++
+				lang = "go"
 			case ".js", ".ts", ".jsx", ".tsx":
-				extCount["javascript"]++
+				lang = "javascript"
 			case ".py":
-				extCount["python"]++
+				lang = "python"
 			case ".rs":
-				extCount["rust"]++
+				lang = "rust"
 			case ".java":
-				extCount["java"]++
+				lang = "java"
 			case ".cpp", ".c", ".h", ".hpp":
-				extCount["cpp"]++
+				lang = "cpp"
 			case ".rb", ".rake", ".gemspec":
-				extCount["ruby"]++
+				lang = "ruby"
 			case ".csproj", ".sln":
-				extCount["csharp"]++
+				lang = "csharp"
 			case ".swift":
-				extCount["swift"]++
+				lang = "swift"
 			case ".php":
-				extCount["php"]++
+				lang = "php"
+			}
+			if lang != "" {
+				extCount[lang]++
+				total++
 			}
 		}
 	}
 
-	// Возвращаем язык с наибольшим количеством файлов
+	// Возвращаем язык с наибольшим количеством файлов, при равенстве — первый по алфавиту
+	langs := make([]string, 0, len(extCount))
+	for lang := range extCount {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
 	var maxLang string
 	maxCount := 0
-	for lang, count := range extCount {
-		if count > maxCount {
-			maxCount = count
+	for _, lang := range langs {
+		if extCount[lang] > maxCount {
+			maxCount = extCount[lang]
 			maxLang = lang
 		}
 	}
 
 	if maxLang == "" {
-		return "unknown"
-	}
-	return maxLang
-}
-
-func AnalyzeLocalRepo(repoPath string) (*ProjectInfo, error) {
-	info := &ProjectInfo{}
-
-	// Определяем язык проекта
-	lang, err := detectLanguage(repoPath)
-	if err != nil {
-		return nil, err
-	}
-	info.Language = lang
-
-	switch lang {
-	case "python":
-		err = analyzePythonProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "go":
-		err = analyzeGoProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "rust":
-		err = analyzeRustProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "cpp":
-		err = analyzeCppProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "javascript":
-		err = analyzeJavaScriptProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "ruby":
-		err = analyzeRubyProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "csharp":
-		err = analyzeCSharpProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "swift":
-		err = analyzeSwiftProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
-	case "php":
-		err = analyzePHPProject(repoPath, info)
-		if err != nil {
-			return nil, err
-		}
+		return "unknown", 0, total
 	}
-
-	info.Architecture = detectArchitecture(repoPath)
-
-	return info, nil
-}
-
-func detectLanguage(repoPath string) (string, error) {
-	files := []string{
-		"go.mod",
-		"package.json",
-		"requirements.txt",
-		"setup.py",
-		"pyproject.toml",
-		"build.gradle",
-		"build.gradle.kts",
-		"pom.xml",
-		"Cargo.toml",
-		"CMakeLists.txt",
-		"Makefile",
-		"Gemfile",
-		".csproj",
-		".sln",
-		"Package.swift",
-		"composer.json",
-		"artisan",
-		"symfony",
-		"index.php",
-	}
-
-	for _, file := range files {
-		if exists(filepath.Join(repoPath, file)) {
-			switch file {
-			case "go.mod":
-				return "go", nil
-			case "package.json":
-				return "javascript", nil
-			case "requirements.txt":
-				return "python", nil
-			case "Cargo.toml":
-				return "rust", nil
-			case "pom.xml":
-				return "java_maven", nil
-			case "build.gradle", "build.gradle.kts":
-				return "java_gradle", nil
-			case "CMakeLists.txt", "Makefile":
-				return "cpp", nil
-			case "Gemfile":
-				return "ruby", nil
-			case ".csproj", ".sln":
-				return "csharp", nil
-			case "Package.swift":
-				return "swift", nil
-			case "composer.json", "arisan", "symfony", "index.php":
-				return "php", nil
-			}
-		}
-	}
-
-	return "unknown", nil
-}
-
-func exists(path string) bool {
-	_, err := os.Stat(path)
-	return !os.IsNotExist(err)
+	return maxLang, maxCount, total
 }
@@ -0,0 +1,190 @@
+package analyzer
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// Component — самостоятельная часть репозитория со своим языком,
+// например фронтенд на JavaScript рядом с бэкендом на Go.
+type Component struct {
+	Name string       `json:"name" yaml:"name"`
+	Root string       `json:"root" yaml:"root"` // каталог относительно корня репозитория, "." для корня
+	Info *ProjectInfo `json:"info" yaml:"info"`
+}
+
+// componentDepth ограничивает глубину поиска компонентов: apps/web, но не глубже.
+const componentDepth = 2
+
+// componentSkipDirs не содержат компонентов: там лежат чужой код и примеры.
+var componentSkipDirs = map[string]bool{
+	"vendor":      true,
+	"third_party": true,
+	"testdata":    true,
+	"examples":    true,
+	"docs":        true,
+}
+
+// detectComponents ищет каталоги с файлами-маркерами других языков. Вложенный
+// маркер того же языка, что и у родительского компонента, считается модулем
+// этого компонента, а не отдельной частью. Маркеры других языков в самом
+// корне дают компоненты с каталогом ".", например package.json рядом
+// с go.mod. Если в репозитории один язык, возвращается nil.
+func detectComponents(repo *Repo, root *ProjectInfo) ([]Component, error) {
+	roots := map[string]string{}
+	if root.Language != "unknown" {
+		roots["."] = root.Language
+	}
+	extra := extraRootLanguages(repo, root.Language)
+	rootLanguages := map[string]bool{languageFamily(root.Language): true}
+	for _, evidence := range extra {
+		rootLanguages[languageFamily(evidence.Value)] = true
+	}
+	for _, file := range repo.Files() {
+		dir := path.Dir(file)
+		if dir == "." || strings.Count(dir, "/") >= componentDepth || inSkippedDir(dir) {
+			continue
+		}
+		if _, seen := roots[dir]; seen || !isMarker(path.Base(file)) {
+			continue
+		}
+		roots[dir] = markerLanguage(repo, dir)
+	}
+
+	dirs := make([]string, 0, len(roots))
+	for dir := range roots {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+
+	var components []Component
+	languages := map[string]bool{}
+	for _, dir := range dirs {
+		parent := parentComponent(dir, roots)
+		if parent != "" && roots[parent] == roots[dir] {
+			continue
+		}
+		// Модуль одного из дополнительных языков корня, например web/package.json
+		// при package.json в корне
+		if parent == "." && rootLanguages[languageFamily(roots[dir])] {
+			continue
+		}
+		// Корневой компонент — копия результата анализа без списка компонентов
+		rootInfo := *root
+		info := &rootInfo
+		if dir != "." {
+			sub, err := repo.Sub(dir)
+			if err != nil {
+				return nil, err
+			}
+			if info, err = analyzeRepo(sub); err != nil {
+				return nil, err
+			}
+		}
+		name := path.Base(dir)
+		if dir == "." {
+			name = "root"
+		}
+		components = append(components, Component{Name: name, Root: dir, Info: info})
+		languages[info.Language] = true
+
+		if dir != "." {
+			continue
+		}
+		for _, evidence := range extra {
+			info, err := analyzeLanguage(repo, &evidence)
+			if err != nil {
+				return nil, err
+			}
+			components = append(components, Component{Name: evidence.Value, Root: ".", Info: info})
+			languages[info.Language] = true
+		}
+	}
+
+	if len(languages) < 2 {
+		return nil, nil
+	}
+	return components, nil
+}
+
+// extraRootLanguages возвращает языки маркеров в корне, кроме основного
+// языка репозитория, — по одному правилу на язык.
+func extraRootLanguages(repo *Repo, language string) []Evidence {
+	seen := map[string]bool{languageFamily(language): true}
+	var extra []Evidence
+	add := func(file, language string) {
+		if seen[languageFamily(language)] {
+			return
+		}
+		seen[languageFamily(language)] = true
+		extra = append(extra, Evidence{
+			Field:      "language",
+			Value:      language,
+			File:       file,
+			Rule:       "marker file in repository root; additional language of the root",
+			Confidence: ConfidenceHigh,
+		})
+	}
+	for _, marker := range languageMarkers {
+		if isMarker(marker.file) && repo.HasFile(marker.file) {
+			add(marker.file, marker.language)
+		}
+	}
+	for _, pattern := range []string{"*.sln", "*.csproj"} {
+		if matches := repo.Glob(pattern); len(matches) > 0 {
+			add(matches[0], "csharp")
+		}
+	}
+	return extra
+}
+
+// languageFamily сводит варианты языка к одному: java_gradle и java_maven — java.
+func languageFamily(language string) string {
+	family, _, _ := strings.Cut(language, "_")
+	return family
+}
+
+// isMarker сообщает, что файл указывает на язык. Makefile не учитывается:
+// он встречается в каталогах любых проектов.
+func isMarker(name string) bool {
+	if name == "Makefile" {
+		return false
+	}
+	for _, marker := range languageMarkers {
+		if marker.file == name {
+			return true
+		}
+	}
+	return strings.HasSuffix(name, ".csproj") || strings.HasSuffix(name, ".sln")
+}
+
+// markerLanguage выбирает язык каталога по маркеру с наивысшим приоритетом.
+func markerLanguage(repo *Repo, dir string) string {
+	for _, marker := range languageMarkers {
+		if isMarker(marker.file) && repo.HasFile(dir+"/"+marker.file) {
+			return marker.language
+		}
+	}
+	return "csharp"
+}
+
+// parentComponent возвращает ближайший каталог-предок, являющийся компонентом.
+func parentComponent(dir string, roots map[string]string) string {
+	for dir != "." {
+		dir = path.Dir(dir)
+		if _, ok := roots[dir]; ok {
+			return dir
+		}
+	}
+	return ""
+}
+
+func inSkippedDir(dir string) bool {
+	for _, part := range strings.Split(dir, "/") {
+		if componentSkipDirs[part] || strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
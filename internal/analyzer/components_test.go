@@ -0,0 +1,38 @@
+package analyzer
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestDetectComponentsRootLanguages(t *testing.T) {
+	files := fstest.MapFS{
+		"go.mod":       {Data: []byte("module example.com/app\n\ngo 1.22\n")},
+		"main.go":      {Data: []byte("package main\n\nfunc main() {}\n")},
+		"package.json": {Data: []byte(`{"name": "app", "scripts": {"test": "jest"}}`)},
+		"web/index.js": {Data: []byte("console.log('app')\n")},
+	}
+	info, err := AnalyzeFS(files)
+	if err != nil {
+		t.Fatalf("AnalyzeFS: %v", err)
+	}
+	if info.Language != "go" {
+		t.Errorf("language = %q, want go", info.Language)
+	}
+	want := []struct{ name, language string }{
+		{"root", "go"},
+		{"javascript", "javascript"},
+	}
+	if len(info.Components) != len(want) {
+		t.Fatalf("components = %+v, want %d", info.Components, len(want))
+	}
+	for i, w := range want {
+		c := info.Components[i]
+		if c.Name != w.name || c.Root != "." || c.Info.Language != w.language {
+			t.Errorf("component %d = %s (%s, %s), want %s (., %s)", i, c.Name, c.Root, c.Info.Language, w.name, w.language)
+		}
+	}
+	if evidence := info.Components[1].Info.EvidenceFor("language"); len(evidence) != 1 || evidence[0].File != "package.json" {
+		t.Errorf("language evidence = %+v, want package.json", evidence)
+	}
+}
@@ -0,0 +1,141 @@
+package analyzer
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFiles — имена файла конфигурации в корне анализируемого репозитория.
+var ConfigFiles = []string{".pipeline-gen.yaml", ".pipeline-gen.yml"}
+
+// Config — настройки проекта из .pipeline-gen.yaml. Заданные в нем значения
+// имеют приоритет над результатами анализаторов.
+type Config struct {
+	Language      string            `json:"language,omitempty" yaml:"language,omitempty"`
+	Version       string            `json:"version,omitempty" yaml:"version,omitempty"`
+	BuildTool     string            `json:"build_tool,omitempty" yaml:"build_tool,omitempty"`
+	TestFramework string            `json:"test_framework,omitempty" yaml:"test_framework,omitempty"`
+	Branches      []string          `json:"branches,omitempty" yaml:"branches,omitempty"`
+	Images        map[string]string `json:"images,omitempty" yaml:"images,omitempty"` // ID джоба или "default" -> образ
+	Stages        StageFilter       `json:"stages,omitempty" yaml:"stages,omitempty"`
+	Services      map[string]string `json:"services,omitempty" yaml:"services,omitempty"` // имя хоста -> образ сервиса для тестов
+	Registry      RegistryConfig    `json:"registry,omitempty" yaml:"registry,omitempty"`
+	Deploy        DeployConfig      `json:"deploy,omitempty" yaml:"deploy,omitempty"`
+
+	source  string // имя прочитанного файла конфигурации
+	content string
+}
+
+// StageFilter выбирает стадии pipeline. Если Include не пуст, остаются
+// только перечисленные стадии; стадии из Skip удаляются всегда.
+type StageFilter struct {
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	Skip    []string `json:"skip,omitempty" yaml:"skip,omitempty"`
+}
+
+// RegistryConfig задает реестр, в который публикуется образ проекта, и
+// параметры его сборки.
+type RegistryConfig struct {
+	Provider string `json:"provider,omitempty" yaml:"provider,omitempty"` // ghcr, gitlab, dockerhub, ecr, gcr или custom
+	URL      string `json:"url,omitempty" yaml:"url,omitempty"`           // custom: адрес реестра с пространством имен, например registry.example.com/team
+	Image    string `json:"image,omitempty" yaml:"image,omitempty"`       // имя образа, по умолчанию имя репозитория
+	Target   string `json:"target,omitempty" yaml:"target,omitempty"`     // стадия Dockerfile для --target
+	// BuildArgs — значения аргументов сборки. Аргументы Dockerfile без
+	// значения по умолчанию, не заданные здесь, берутся из секретов.
+	BuildArgs map[string]string `json:"build_args,omitempty" yaml:"build_args,omitempty"`
+}
+
+// DeployConfig выбирает способ деплоя и его параметры. Пустые значения
+// заменяются значениями по умолчанию цели деплоя.
+type DeployConfig struct {
+	Target      string `json:"target,omitempty" yaml:"target,omitempty"`           // ssh-docker, docker-compose, kubernetes, helm, static или none
+	Environment string `json:"environment,omitempty" yaml:"environment,omitempty"` // окружение CI, production по умолчанию
+	Manual      bool   `json:"manual,omitempty" yaml:"manual,omitempty"`           // запуск деплоя вручную
+	Port        int    `json:"port,omitempty" yaml:"port,omitempty"`               // порт контейнера, по умолчанию из EXPOSE
+	ComposeFile string `json:"compose_file,omitempty" yaml:"compose_file,omitempty"`
+	Manifests   string `json:"manifests,omitempty" yaml:"manifests,omitempty"` // kubernetes: каталог манифестов
+	Chart       string `json:"chart,omitempty" yaml:"chart,omitempty"`         // helm: путь к чарту
+	Namespace   string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Path        string `json:"path,omitempty" yaml:"path,omitempty"` // docker-compose и static: каталог на сервере
+	Dir         string `json:"dir,omitempty" yaml:"dir,omitempty"`   // static: каталог собранного сайта
+}
+
+// StageEnabled сообщает, попадает ли стадия в pipeline с учетом фильтра.
+func (c *Config) StageEnabled(stage string) bool {
+	if c == nil {
+		return true
+	}
+	for _, s := range c.Stages.Skip {
+		if s == stage {
+			return false
+		}
+	}
+	if len(c.Stages.Include) == 0 {
+		return true
+	}
+	for _, s := range c.Stages.Include {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// loadConfig читает конфигурацию из корня репозитория. Отсутствие файла
+// не считается ошибкой.
+func loadConfig(repo *Repo) (*Config, error) {
+	for _, name := range ConfigFiles {
+		content, ok := repo.ReadFile(name)
+		if !ok {
+			continue
+		}
+		cfg := &Config{source: name, content: content}
+		if err := yaml.Unmarshal([]byte(content), cfg); err != nil {
+			return nil, fmt.Errorf("error parsing %s: %w", name, err)
+		}
+		return cfg, nil
+	}
+	return nil, nil
+}
+
+// apply переносит закрепленные в конфигурации значения в ProjectInfo.
+func (c *Config) apply(info *ProjectInfo) {
+	pins := []struct {
+		field  string
+		value  string
+		target *string
+	}{
+		{"language", c.Language, &info.Language},
+		{"version", c.Version, &info.Version},
+		{"build_tool", c.BuildTool, &info.BuildTool},
+		{"test_framework", c.TestFramework, &info.TestFramework},
+	}
+	for _, pin := range pins {
+		if pin.value == "" {
+			continue
+		}
+		*pin.target = pin.value
+		info.dropEvidence(pin.field)
+		info.AddEvidence(Evidence{
+			Field:      pin.field,
+			Value:      pin.value,
+			File:       c.source,
+			Line:       c.keyLine(pin.field),
+			Rule:       "pinned in configuration",
+			Confidence: ConfidenceHigh,
+		})
+	}
+	info.Config = c
+}
+
+// keyLine возвращает номер строки верхнеуровневого ключа в файле конфигурации.
+func (c *Config) keyLine(key string) int {
+	for i, line := range strings.Split(c.content, "\n") {
+		if strings.HasPrefix(line, key+":") {
+			return i + 1
+		}
+	}
+	return 0
+}
@@ -1,370 +1,190 @@
 package analyzer
 
 import (
-	"os"
-	"path/filepath"
+	"path"
 	"regexp"
 	"strings"
-
-	"github.com/immxrtalbeast/pipeline-gen/internal/git"
 )
 
-func analyzeCppProjectFromMemory(remoteInfo *git.RemoteRepoInfo, info *ProjectInfo) {
-	info.BuildTool = detectCppBuildTool(remoteInfo)
-	info.TestFramework = detectCppTestFramework(remoteInfo)
-	info.Version = detectCppVersion(remoteInfo)
-	info.Dependencies = detectCppDependencies(remoteInfo)
-	info.HasTests = detectCppTestsFromMemory(remoteInfo)
-	info.Modules = detectCppModulesFromMemory(remoteInfo)
-}
-
-func analyzeCppProject(repoPath string, info *ProjectInfo) error {
-	info.BuildTool = detectCppBuildToolLocal(repoPath)
-	info.TestFramework = detectCppTestFrameworkLocal(repoPath)
-	info.Version = detectCppVersionLocal(repoPath)
-	info.Dependencies = detectCppDependenciesLocal(repoPath)
-	info.HasTests = detectCppTestsLocal(repoPath)
-	info.Modules = detectCppModulesLocal(repoPath)
+func analyzeCppProject(repo *Repo, info *ProjectInfo) error {
+	info.setWithEvidence(detectCppBuildTool(repo))
+	info.setWithEvidence(detectCppTestFramework(repo))
+	info.setWithEvidence(detectCppVersion(repo))
+	info.setDependencies(detectCppDependencies(repo))
+	info.setWithEvidence(detectCppTests(repo))
+	info.Modules = detectCppModules(repo)
 	return nil
 }
 
 // Вспомогательные функции для анализа C++
-func detectCppBuildTool(remoteInfo *git.RemoteRepoInfo) string {
+func detectCppBuildTool(repo *Repo) Evidence {
 	// Проверяем различные системы сборки C++
-	if remoteInfo.HasFile("CMakeLists.txt") {
-		return "cmake"
-	}
-	if remoteInfo.HasFile("Makefile") || remoteInfo.HasFile("makefile") {
-		return "make"
-	}
-	if remoteInfo.HasFile("configure") || remoteInfo.HasFile("configure.ac") {
-		return "autotools"
-	}
-	if remoteInfo.HasFile("meson.build") {
-		return "meson"
-	}
-	if remoteInfo.HasFile("bazel.BUILD") || remoteInfo.HasFile("BUILD") {
-		return "bazel"
-	}
-	if remoteInfo.HasFile("conanfile.txt") || remoteInfo.HasFile("conanfile.py") {
-		return "conan"
-	}
-	if remoteInfo.HasFile("premake5.lua") {
-		return "premake"
+	for _, manifest := range []struct{ file, tool string }{
+		{"CMakeLists.txt", "cmake"},
+		{"Makefile", "make"},
+		{"makefile", "make"},
+		{"configure", "autotools"},
+		{"configure.ac", "autotools"},
+		{"meson.build", "meson"},
+		{"bazel.BUILD", "bazel"},
+		{"BUILD", "bazel"},
+		{"conanfile.txt", "conan"},
+		{"conanfile.py", "conan"},
+		{"premake5.lua", "premake"},
+	} {
+		if repo.HasFile(manifest.file) {
+			return fileEvidence("build_tool", manifest.tool, manifest.file)
+		}
 	}
 	
 	// Проверяем наличие файлов проектов IDE
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, ".sln") || strings.HasSuffix(file, ".vcxproj") {
-			return "msbuild"
+			return fileEvidence("build_tool", "msbuild", file)
 		}
 		if strings.HasSuffix(file, ".pro") {
-			return "qmake"
+			return fileEvidence("build_tool", "qmake", file)
 		}
 	}
 	
-	return "make" // по умолчанию
-}
-
-func detectCppBuildToolLocal(repoPath string) string {
-	// Проверяем локальные файлы
-	if exists(filepath.Join(repoPath, "CMakeLists.txt")) {
-		return "cmake"
-	}
-	if exists(filepath.Join(repoPath, "Makefile")) || exists(filepath.Join(repoPath, "makefile")) {
-		return "make"
-	}
-	if exists(filepath.Join(repoPath, "configure")) || exists(filepath.Join(repoPath, "configure.ac")) {
-		return "autotools"
-	}
-	if exists(filepath.Join(repoPath, "meson.build")) {
-		return "meson"
-	}
-	
-	// Ищем файлы проектов
-	matches, _ := filepath.Glob(filepath.Join(repoPath, "*.sln"))
-	if len(matches) > 0 {
-		return "msbuild"
-	}
-	
-	matches, _ = filepath.Glob(filepath.Join(repoPath, "*.pro"))
-	if len(matches) > 0 {
-		return "qmake"
-	}
-	
-	return "make"
+	return defaultEvidence("build_tool", "make")
 }
 
-func detectCppTestFramework(remoteInfo *git.RemoteRepoInfo) string {
+func detectCppTestFramework(repo *Repo) Evidence {
 	// Анализируем исходные файлы на наличие тестовых фреймворков
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, ".cpp") || strings.HasSuffix(file, ".h") || strings.HasSuffix(file, ".hpp") {
-			if content, exists := remoteInfo.GetFileContent(file); exists {
-				if strings.Contains(content, "#include <gtest/gtest.h>") || 
-				   strings.Contains(content, "#include \"gtest/gtest.h\"") {
-					return "gtest"
-				}
-				if strings.Contains(content, "#include <catch2/catch.hpp>") || 
-				   strings.Contains(content, "#include \"catch2/catch.hpp\"") {
-					return "catch2"
-				}
-				if strings.Contains(content, "#include <boost/test/unit_test.hpp>") {
-					return "boost-test"
-				}
-				if strings.Contains(content, "#include <doctest/doctest.h>") {
-					return "doctest"
+			if content, exists := repo.ReadFile(file); exists {
+				found := matchTokens("test_framework", file, content, []tokenRule{
+					{"gtest", []string{"#include <gtest/gtest.h>", "#include \"gtest/gtest.h\""}},
+					{"catch2", []string{"#include <catch2/catch.hpp>", "#include \"catch2/catch.hpp\""}},
+					{"boost-test", []string{"#include <boost/test/unit_test.hpp>"}},
+					{"doctest", []string{"#include <doctest/doctest.h>"}},
+				})
+				if len(found) > 0 {
+					return found[0]
 				}
 			}
 		}
 	}
 	
 	// Проверяем файлы конфигурации
-	if content, exists := remoteInfo.GetFileContent("CMakeLists.txt"); exists {
-		if strings.Contains(content, "find_package(GTest") || strings.Contains(content, "gtest") {
-			return "gtest"
-		}
-		if strings.Contains(content, "Catch2") {
-			return "catch2"
-		}
-	}
-	
-	return "custom" // пользовательская система тестов
-}
-
-func detectCppTestFrameworkLocal(repoPath string) string {
-	// Упрощенная проверка - сканируем файлы по шаблону
-	patterns := []string{
-		filepath.Join(repoPath, "**/*.cpp"),
-		filepath.Join(repoPath, "**/*.h"),
-		filepath.Join(repoPath, "**/*.hpp"),
-	}
-	
-	for _, pattern := range patterns {
-		matches, _ := filepath.Glob(pattern)
-		for _, match := range matches {
-			content, err := os.ReadFile(match)
-			if err == nil {
-				text := string(content)
-				if strings.Contains(text, "gtest/gtest.h") {
-					return "gtest"
-				}
-				if strings.Contains(text, "catch2/catch.hpp") {
-					return "catch2"
-				}
-				if strings.Contains(text, "boost/test/unit_test.hpp") {
-					return "boost-test"
-				}
-				if strings.Contains(text, "doctest/doctest.h") {
-					return "doctest"
-				}
-			}
+	if content, exists := repo.ReadFile("CMakeLists.txt"); exists {
+		found := matchTokens("test_framework", "CMakeLists.txt", content, []tokenRule{
+			{"gtest", []string{"find_package(GTest", "gtest"}},
+			{"catch2", []string{"Catch2"}},
+		})
+		if len(found) > 0 {
+			return found[0]
 		}
 	}
 	
-	return "custom"
+	return defaultEvidence("test_framework", "custom") // пользовательская система тестов
 }
 
-func detectCppVersion(remoteInfo *git.RemoteRepoInfo) string {
+func detectCppVersion(repo *Repo) Evidence {
 	// Пытаемся определить стандарт C++ из файлов
 	stdRegex := regexp.MustCompile(`-std=c\+\+(\d+)(\w*)`)
 	
 	// Проверяем CMakeLists.txt
-	if content, exists := remoteInfo.GetFileContent("CMakeLists.txt"); exists {
+	if content, exists := repo.ReadFile("CMakeLists.txt"); exists {
 		lines := strings.Split(content, "\n")
-		for _, line := range lines {
+		for i, line := range lines {
 			if strings.Contains(line, "CXX_STANDARD") {
 				// set(CMAKE_CXX_STANDARD 17)
 				re := regexp.MustCompile(`CXX_STANDARD\s+(\d+)`)
 				matches := re.FindStringSubmatch(line)
 				if len(matches) > 1 {
-					return matches[1]
+					return lineEvidence("version", matches[1], "CMakeLists.txt", i+1, "CXX_STANDARD in CMakeLists.txt")
 				}
 			}
 			if strings.Contains(line, "-std=c++") {
 				matches := stdRegex.FindStringSubmatch(line)
 				if len(matches) > 1 {
-					return matches[1]
+					return lineEvidence("version", matches[1], "CMakeLists.txt", i+1, "-std flag in CMakeLists.txt")
 				}
 			}
 		}
 	}
 	
 	// Проверяем Makefile
-	if content, exists := remoteInfo.GetFileContent("Makefile"); exists {
+	if content, exists := repo.ReadFile("Makefile"); exists {
 		lines := strings.Split(content, "\n")
-		for _, line := range lines {
+		for i, line := range lines {
 			if strings.Contains(line, "-std=c++") {
 				matches := stdRegex.FindStringSubmatch(line)
 				if len(matches) > 1 {
-					return matches[1]
+					return lineEvidence("version", matches[1], "Makefile", i+1, "-std flag in Makefile")
 				}
 			}
 		}
 	}
 	
 	// Проверяем исходные файлы на наличие макросов
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, ".cpp") || strings.HasSuffix(file, ".h") {
-			if content, exists := remoteInfo.GetFileContent(file); exists {
+			if content, exists := repo.ReadFile(file); exists {
 				if strings.Contains(content, "__cplusplus") {
 					// Можно попытаться определить по значению макроса
-					if strings.Contains(content, "199711L") {
-						return "98"
-					}
-					if strings.Contains(content, "201103L") {
-						return "11"
-					}
-					if strings.Contains(content, "201402L") {
-						return "14"
-					}
-					if strings.Contains(content, "201703L") {
-						return "17"
-					}
-					if strings.Contains(content, "202002L") {
-						return "20"
-					}
-				}
-			}
-		}
-	}
-	
-	return "17" // по умолчанию C++17
-}
-
-func detectCppVersionLocal(repoPath string) string {
-	stdRegex := regexp.MustCompile(`-std=c\+\+(\d+)(\w*)`)
-	
-	// Проверяем CMakeLists.txt
-	cmakePath := filepath.Join(repoPath, "CMakeLists.txt")
-	if exists(cmakePath) {
-		content, err := os.ReadFile(cmakePath)
-		if err == nil {
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, "CXX_STANDARD") {
-					re := regexp.MustCompile(`CXX_STANDARD\s+(\d+)`)
-					matches := re.FindStringSubmatch(line)
-					if len(matches) > 1 {
-						return matches[1]
-					}
-				}
-			}
-		}
-	}
-	
-	// Проверяем Makefile
-	makefilePath := filepath.Join(repoPath, "Makefile")
-	if exists(makefilePath) {
-		content, err := os.ReadFile(makefilePath)
-		if err == nil {
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				if strings.Contains(line, "-std=c++") {
-					matches := stdRegex.FindStringSubmatch(line)
-					if len(matches) > 1 {
-						return matches[1]
+					found := matchTokens("version", file, content, []tokenRule{
+						{"98", []string{"199711L"}},
+						{"11", []string{"201103L"}},
+						{"14", []string{"201402L"}},
+						{"17", []string{"201703L"}},
+						{"20", []string{"202002L"}},
+					})
+					if len(found) > 0 {
+						return found[0]
 					}
 				}
 			}
 		}
 	}
 	
-	return "17"
+	return defaultEvidence("version", "17") // по умолчанию C++17
 }
 
-func detectCppDependencies(remoteInfo *git.RemoteRepoInfo) []string {
-	deps := []string{}
+func detectCppDependencies(repo *Repo) []Evidence {
+	var deps []Evidence
 
 	// Анализируем CMakeLists.txt на зависимости
-	if content, exists := remoteInfo.GetFileContent("CMakeLists.txt"); exists {
-		if strings.Contains(content, "find_package(OpenGL") || strings.Contains(content, "OpenGL::") {
-			deps = append(deps, "graphics:opengl")
-		}
-		if strings.Contains(content, "find_package(Qt") || strings.Contains(content, "Qt5") || strings.Contains(content, "Qt6") {
-			deps = append(deps, "gui:qt")
-		}
-		if strings.Contains(content, "Boost") || strings.Contains(content, "find_package(Boost") {
-			deps = append(deps, "framework:boost")
-		}
-		if strings.Contains(content, "OpenCV") || strings.Contains(content, "find_package(OpenCV") {
-			deps = append(deps, "vision:opencv")
-		}
-		if strings.Contains(content, "Threads") {
-			deps = append(deps, "threading")
-		}
-		if strings.Contains(content, "OpenMP") {
-			deps = append(deps, "parallel:openmp")
-		}
-		if strings.Contains(content, "MPI") {
-			deps = append(deps, "parallel:mpi")
-		}
-		if strings.Contains(content, "CUDA") {
-			deps = append(deps, "gpu:cuda")
-		}
-		if strings.Contains(content, "OpenCL") {
-			deps = append(deps, "gpu:opencl")
-		}
-		if strings.Contains(content, "SFML") {
-			deps = append(deps, "multimedia:sfml")
-		}
-		if strings.Contains(content, "SDL2") {
-			deps = append(deps, "multimedia:sdl2")
-		}
+	if content, exists := repo.ReadFile("CMakeLists.txt"); exists {
+		deps = matchTokens("dependencies", "CMakeLists.txt", content, []tokenRule{
+			{"graphics:opengl", []string{"find_package(OpenGL", "OpenGL::"}},
+			{"gui:qt", []string{"find_package(Qt", "Qt5", "Qt6"}},
+			{"framework:boost", []string{"Boost"}},
+			{"vision:opencv", []string{"OpenCV"}},
+			{"threading", []string{"Threads"}},
+			{"parallel:openmp", []string{"OpenMP"}},
+			{"parallel:mpi", []string{"MPI"}},
+			{"gpu:cuda", []string{"CUDA"}},
+			{"gpu:opencl", []string{"OpenCL"}},
+			{"multimedia:sfml", []string{"SFML"}},
+			{"multimedia:sdl2", []string{"SDL2"}},
+		})
 	}
 
 	// Анализируем conanfile.txt/py
-	if content, exists := remoteInfo.GetFileContent("conanfile.txt"); exists {
+	if content, exists := repo.ReadFile("conanfile.txt"); exists {
 		lines := strings.Split(content, "\n")
-		for _, line := range lines {
+		for i, line := range lines {
 			line = strings.TrimSpace(line)
 			if !strings.HasPrefix(line, "#") && strings.Contains(line, "/") {
-				deps = append(deps, "conan:"+line)
+				deps = append(deps, lineEvidence("dependencies", "conan:"+line, "conanfile.txt", i+1, "requirement in conanfile.txt"))
 			}
 		}
 	}
 
 	// Анализируем исходные файлы на включения
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, ".cpp") || strings.HasSuffix(file, ".h") {
-			if content, exists := remoteInfo.GetFileContent(file); exists {
-				if strings.Contains(content, "#include <mysql.h>") || strings.Contains(content, "#include <pqxx/pqxx>") {
-					deps = append(deps, "database")
-				}
-				if strings.Contains(content, "#include <curl/curl.h>") {
-					deps = append(deps, "network:curl")
-				}
-				if strings.Contains(content, "#include <openssl/") {
-					deps = append(deps, "crypto:openssl")
-				}
-				if strings.Contains(content, "#include <json/json.h>") || strings.Contains(content, "#include <nlohmann/json.hpp>") {
-					deps = append(deps, "json")
-				}
-			}
-		}
-	}
-
-	return deps
-}
-
-func detectCppDependenciesLocal(repoPath string) []string {
-	deps := []string{}
-
-	cmakePath := filepath.Join(repoPath, "CMakeLists.txt")
-	if exists(cmakePath) {
-		content, err := os.ReadFile(cmakePath)
-		if err == nil {
-			text := string(content)
-			if strings.Contains(text, "Qt5") || strings.Contains(text, "Qt6") {
-				deps = append(deps, "gui:qt")
-			}
-			if strings.Contains(text, "Boost") {
-				deps = append(deps, "framework:boost")
-			}
-			if strings.Contains(text, "OpenCV") {
-				deps = append(deps, "vision:opencv")
-			}
-			if strings.Contains(text, "CUDA") {
-				deps = append(deps, "gpu:cuda")
+			if content, exists := repo.ReadFile(file); exists {
+				deps = append(deps, matchTokens("dependencies", file, content, []tokenRule{
+					{"database", []string{"#include <mysql.h>", "#include <pqxx/pqxx>"}},
+					{"network:curl", []string{"#include <curl/curl.h>"}},
+					{"crypto:openssl", []string{"#include <openssl/"}},
+					{"json", []string{"#include <json/json.h>", "#include <nlohmann/json.hpp>"}},
+				})...)
 			}
 		}
 	}
@@ -372,68 +192,40 @@ func detectCppDependenciesLocal(repoPath string) []string {
 	return deps
 }
 
-func detectCppTestsFromMemory(remoteInfo *git.RemoteRepoInfo) bool {
+func detectCppTests(repo *Repo) Evidence {
 	// Ищем тестовые файлы
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.Contains(strings.ToLower(file), "test") && 
 		   (strings.HasSuffix(file, ".cpp") || strings.HasSuffix(file, ".h")) {
-			return true
+			return testFileEvidence(file)
 		}
 		if strings.Contains(file, "/test/") || strings.Contains(file, "/tests/") {
-			return true
+			return testFileEvidence(file)
 		}
 	}
 	
 	// Проверяем конфигурационные файлы тестов
-	if remoteInfo.HasFile("CTestTestfile.cmake") {
-		return true
+	if repo.HasFile("CTestTestfile.cmake") {
+		return Evidence{Field: "has_tests", Value: "true", File: "CTestTestfile.cmake", Rule: "test configuration", Confidence: ConfidenceMedium}
 	}
-	if content, exists := remoteInfo.GetFileContent("CMakeLists.txt"); exists {
-		if strings.Contains(content, "enable_testing()") || strings.Contains(content, "add_test") {
-			return true
+	if content, exists := repo.ReadFile("CMakeLists.txt"); exists {
+		found := matchTokens("has_tests", "CMakeLists.txt", content, []tokenRule{
+			{"true", []string{"enable_testing()", "add_test"}},
+		})
+		if len(found) > 0 {
+			return found[0]
 		}
 	}
 	
-	return false
-}
-
-func detectCppTestsLocal(repoPath string) bool {
-	// Ищем тестовые директории и файлы
-	patterns := []string{
-		filepath.Join(repoPath, "test"),
-		filepath.Join(repoPath, "tests"),
-		filepath.Join(repoPath, "**/test/**/*.cpp"),
-		filepath.Join(repoPath, "**/*test*.cpp"),
-		filepath.Join(repoPath, "CTestTestfile.cmake"),
-	}
-
-	for _, pattern := range patterns {
-		matches, _ := filepath.Glob(pattern)
-		if len(matches) > 0 {
-			return true
-		}
-	}
-
-	// Проверяем CMakeLists.txt на наличие тестов
-	cmakePath := filepath.Join(repoPath, "CMakeLists.txt")
-	if exists(cmakePath) {
-		content, err := os.ReadFile(cmakePath)
-		if err == nil {
-			if strings.Contains(string(content), "enable_testing()") {
-				return true
-			}
-		}
-	}
-
-	return false
+	return noTestsEvidence()
 }
 
 // Функции для обнаружения модулей/подпроектов
-func detectCppModulesFromMemory(remoteInfo *git.RemoteRepoInfo) []string {
+func detectCppModules(repo *Repo) []string {
 	modules := []string{}
 
 	// Для CMake ищем add_subdirectory
-	if content, exists := remoteInfo.GetFileContent("CMakeLists.txt"); exists {
+	if content, exists := repo.ReadFile("CMakeLists.txt"); exists {
 		lines := strings.Split(content, "\n")
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
@@ -449,49 +241,12 @@ func detectCppModulesFromMemory(remoteInfo *git.RemoteRepoInfo) []string {
 	}
 
 	// Ищем поддиректории с собственными CMakeLists.txt
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.Contains(file, "/CMakeLists.txt") && file != "CMakeLists.txt" {
-			dir := filepath.Dir(file)
+			dir := path.Dir(file)
 			modules = append(modules, dir)
 		}
 	}
 
 	return modules
 }
-
-func detectCppModulesLocal(repoPath string) []string {
-	modules := []string{}
-
-	// Анализируем главный CMakeLists.txt
-	cmakePath := filepath.Join(repoPath, "CMakeLists.txt")
-	if exists(cmakePath) {
-		content, err := os.ReadFile(cmakePath)
-		if err == nil {
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.HasPrefix(line, "add_subdirectory(") {
-					parts := strings.Split(line, "(")
-					if len(parts) > 1 {
-						module := strings.TrimRight(parts[1], " )")
-						modules = append(modules, module)
-					}
-				}
-			}
-		}
-	}
-
-	// Ищем поддиректории с CMakeLists.txt
-	filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if info.Name() == "CMakeLists.txt" && path != cmakePath {
-			relPath, _ := filepath.Rel(repoPath, filepath.Dir(path))
-			modules = append(modules, relPath)
-		}
-		return nil
-	})
-
-	return modules
-}
\ No newline at end of file
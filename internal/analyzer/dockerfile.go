@@ -0,0 +1,329 @@
+package analyzer
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Dockerfile — параметры сборки и запуска образа из Dockerfile.
+type Dockerfile struct {
+	BaseImage       string        `json:"base_image" yaml:"base_image"` // образ финальной стадии
+	Language        string        `json:"language,omitempty" yaml:"language,omitempty"`
+	LanguageVersion string        `json:"language_version,omitempty" yaml:"language_version,omitempty"`
+	Stages          []DockerStage `json:"stages" yaml:"stages"`
+	Ports           []int         `json:"ports,omitempty" yaml:"ports,omitempty"`
+	Args            []DockerArg   `json:"args,omitempty" yaml:"args,omitempty"`
+	HealthCheck     *HealthCheck  `json:"healthcheck,omitempty" yaml:"healthcheck,omitempty"`
+}
+
+// DockerStage — стадия многоэтапной сборки. Name задан только у стадий
+// с FROM ... AS name, их можно собирать через --target.
+type DockerStage struct {
+	Name  string `json:"name,omitempty" yaml:"name,omitempty"`
+	Image string `json:"image" yaml:"image"`
+}
+
+// DockerArg — аргумент сборки. Аргументы без значения по умолчанию
+// нужно передавать через --build-arg.
+type DockerArg struct {
+	Name       string `json:"name" yaml:"name"`
+	Default    string `json:"default,omitempty" yaml:"default,omitempty"`
+	HasDefault bool   `json:"has_default,omitempty" yaml:"has_default,omitempty"`
+}
+
+// HealthCheck — проверка состояния контейнера из HEALTHCHECK. Port и Path
+// заполняются, если команда обращается к HTTP-адресу.
+type HealthCheck struct {
+	Command string `json:"command" yaml:"command"`
+	Port    int    `json:"port,omitempty" yaml:"port,omitempty"`
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// Targets возвращает имена стадий, которые можно собрать через --target.
+func (d *Dockerfile) Targets() []string {
+	var targets []string
+	for _, stage := range d.Stages {
+		if stage.Name != "" {
+			targets = append(targets, stage.Name)
+		}
+	}
+	return targets
+}
+
+// HasArg сообщает, объявлен ли в Dockerfile аргумент сборки name.
+func (d *Dockerfile) HasArg(name string) bool {
+	if d == nil {
+		return false
+	}
+	for _, arg := range d.Args {
+		if arg.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Port возвращает первый порт из EXPOSE или 0.
+func (d *Dockerfile) Port() int {
+	if d == nil || len(d.Ports) == 0 {
+		return 0
+	}
+	return d.Ports[0]
+}
+
+// baseImageLanguages сопоставляет официальные образы языкам. Версия языка
+// берется из начала тега образа.
+var baseImageLanguages = map[string]string{
+	"golang":          "go",
+	"python":          "python",
+	"node":            "javascript",
+	"openjdk":         "java",
+	"eclipse-temurin": "java",
+	"amazoncorretto":  "java",
+	"maven":           "java",
+	"gradle":          "java",
+	"rust":            "rust",
+	"ruby":            "ruby",
+	"php":             "php",
+	"swift":           "swift",
+	"dotnet/sdk":      "csharp",
+	"dotnet/aspnet":   "csharp",
+	"dotnet/runtime":  "csharp",
+}
+
+var (
+	imageVersionPattern = regexp.MustCompile(`^v?(\d+(\.\d+)*)`)
+	jdkVersionPattern   = regexp.MustCompile(`(?:jdk|temurin|corretto|java)-?(\d+)`)
+	healthURLPattern    = regexp.MustCompile(`https?://[^\s"'\\]+`)
+	dockerVarPattern    = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}?`)
+)
+
+// ParseDockerfile разбирает Dockerfile. Значения аргументов и переменных
+// окружения по умолчанию подставляются в FROM, EXPOSE и HEALTHCHECK.
+func ParseDockerfile(content string) *Dockerfile {
+	d := &Dockerfile{}
+	vars := map[string]string{}
+	// Порты и проверка состояния завершенных стадий
+	var ports [][]int
+	var healthChecks []*HealthCheck
+	for _, line := range dockerInstructions(content) {
+		instruction, rest, _ := strings.Cut(line, " ")
+		rest = strings.TrimSpace(rest)
+		switch strings.ToUpper(instruction) {
+		case "FROM":
+			fields := strings.Fields(rest)
+			// Флаги вроде --platform=... идут перед образом
+			for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+				fields = fields[1:]
+			}
+			if len(fields) == 0 {
+				continue
+			}
+			stage := DockerStage{Image: expandDockerVars(fields[0], vars)}
+			if len(fields) >= 3 && strings.EqualFold(fields[1], "as") {
+				stage.Name = fields[2]
+			}
+			if len(d.Stages) > 0 {
+				ports = append(ports, d.Ports)
+				healthChecks = append(healthChecks, d.HealthCheck)
+			}
+			// Порты и проверка состояния наследуются только внутри стадии
+			// и стадиями, собранными на ее основе
+			d.Ports, d.HealthCheck = nil, nil
+			if j := d.stageIndex(stage.Image, len(d.Stages)); j >= 0 {
+				d.Ports = append([]int(nil), ports[j]...)
+				d.HealthCheck = healthChecks[j]
+			}
+			d.Stages = append(d.Stages, stage)
+		case "ARG":
+			for _, field := range strings.Fields(rest) {
+				name, value, ok := strings.Cut(field, "=")
+				value = strings.Trim(value, `"'`)
+				if !d.HasArg(name) {
+					d.Args = append(d.Args, DockerArg{Name: name, Default: value, HasDefault: ok})
+				}
+				if ok {
+					vars[name] = value
+				}
+			}
+		case "ENV":
+			for name, value := range parseDockerEnv(rest) {
+				vars[name] = value
+			}
+		case "EXPOSE":
+			for _, field := range strings.Fields(expandDockerVars(rest, vars)) {
+				port, _, _ := strings.Cut(field, "/")
+				if n, err := strconv.Atoi(port); err == nil {
+					d.Ports = append(d.Ports, n)
+				}
+			}
+		case "HEALTHCHECK":
+			d.HealthCheck = parseHealthCheck(expandDockerVars(rest, vars))
+		}
+	}
+
+	if len(d.Stages) > 0 {
+		d.BaseImage = d.stageImage(len(d.Stages) - 1)
+	}
+	// Язык определяется по последней стадии на образе языка: финальная
+	// стадия часто собрана на distroless или alpine
+	for i := len(d.Stages) - 1; i >= 0 && d.Language == ""; i-- {
+		d.Language, d.LanguageVersion = imageLanguage(d.stageImage(i))
+	}
+	return d
+}
+
+// dockerInstructions склеивает продолженные строки и отбрасывает
+// комментарии и пустые строки.
+func dockerInstructions(content string) []string {
+	var instructions []string
+	var current strings.Builder
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") || (line == "" && current.Len() == 0) {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\") + " ")
+			continue
+		}
+		current.WriteString(line)
+		if s := strings.TrimSpace(current.String()); s != "" {
+			instructions = append(instructions, s)
+		}
+		current.Reset()
+	}
+	if s := strings.TrimSpace(current.String()); s != "" {
+		instructions = append(instructions, s)
+	}
+	return instructions
+}
+
+// parseDockerEnv разбирает ENV в формах "KEY=value ..." и "KEY value".
+func parseDockerEnv(rest string) map[string]string {
+	env := map[string]string{}
+	if first, _, _ := strings.Cut(rest, " "); !strings.Contains(first, "=") {
+		name, value, _ := strings.Cut(rest, " ")
+		env[name] = strings.TrimSpace(value)
+		return env
+	}
+	for _, field := range strings.Fields(rest) {
+		if name, value, ok := strings.Cut(field, "="); ok {
+			env[name] = strings.Trim(value, `"'`)
+		}
+	}
+	return env
+}
+
+// parseHealthCheck извлекает команду HEALTHCHECK и HTTP-адрес, к которому
+// она обращается. HEALTHCHECK NONE отключает проверку.
+func parseHealthCheck(rest string) *HealthCheck {
+	fields := strings.Fields(rest)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 || strings.EqualFold(fields[0], "NONE") || !strings.EqualFold(fields[0], "CMD") {
+		return nil
+	}
+	command := strings.TrimSpace(strings.Join(fields[1:], " "))
+	// Exec-форма ["curl", "-f", "http://..."]
+	var args []string
+	if err := json.Unmarshal([]byte(command), &args); err == nil {
+		command = strings.Join(args, " ")
+	}
+	check := &HealthCheck{Command: command}
+	if u, err := url.Parse(healthURLPattern.FindString(command)); err == nil && u.Host != "" {
+		check.Path = u.RequestURI()
+		if port, err := strconv.Atoi(u.Port()); err == nil {
+			check.Port = port
+		} else if u.Scheme == "https" {
+			check.Port = 443
+		} else {
+			check.Port = 80
+		}
+	}
+	return check
+}
+
+// expandDockerVars подставляет известные значения ${NAME} и ${NAME:-default}.
+func expandDockerVars(s string, vars map[string]string) string {
+	return dockerVarPattern.ReplaceAllStringFunc(s, func(ref string) string {
+		m := dockerVarPattern.FindStringSubmatch(ref)
+		if value, ok := vars[m[1]]; ok && value != "" {
+			return value
+		}
+		if m[2] != "" {
+			return m[3]
+		}
+		return ref
+	})
+}
+
+// imageLanguage определяет язык и его версию по образу вида repo:tag.
+func imageLanguage(image string) (string, string) {
+	ref, tag, _ := strings.Cut(strings.Split(image, "@")[0], ":")
+	for repo, language := range baseImageLanguages {
+		if ref != repo && !strings.HasSuffix(ref, "/"+repo) {
+			continue
+		}
+		if language == "java" && (repo == "maven" || repo == "gradle") {
+			// Версия у образов сборщиков — версия самого сборщика
+			if m := jdkVersionPattern.FindStringSubmatch(tag); m != nil {
+				return language, m[1]
+			}
+			return language, ""
+		}
+		if m := imageVersionPattern.FindStringSubmatch(tag); m != nil {
+			return language, m[1]
+		}
+		return language, ""
+	}
+	return "", ""
+}
+
+// stageImage возвращает образ стадии. FROM может ссылаться на одну из
+// предыдущих стадий, тогда берется ее образ.
+func (d *Dockerfile) stageImage(i int) string {
+	if j := d.stageIndex(d.Stages[i].Image, i); j >= 0 {
+		return d.stageImage(j)
+	}
+	return d.Stages[i].Image
+}
+
+// stageIndex возвращает номер ближайшей стадии перед before с именем
+// name или -1, если FROM ссылается не на стадию, а на образ.
+func (d *Dockerfile) stageIndex(name string, before int) int {
+	for j := before - 1; j >= 0; j-- {
+		if d.Stages[j].Name != "" && strings.EqualFold(d.Stages[j].Name, name) {
+			return j
+		}
+	}
+	return -1
+}
+
+// applyDockerfile разбирает Dockerfile корня репозитория. Версия языка из
+// базового образа заменяет версию анализатора, если тот не нашел ее
+// в файлах проекта и вернул значение по умолчанию.
+func applyDockerfile(repo *Repo, info *ProjectInfo) {
+	content, ok := repo.ReadFile("Dockerfile")
+	if !ok {
+		return
+	}
+	d := ParseDockerfile(content)
+	info.Dockerfile = d
+	if info.detected("version") || d.LanguageVersion == "" || d.Language == "" || !strings.HasPrefix(info.Language, d.Language) {
+		return
+	}
+	e := Evidence{Field: "version", Value: d.LanguageVersion, File: "Dockerfile",
+		Rule: "base image tag in Dockerfile", Confidence: ConfidenceMedium}
+	// Версия могла прийти из ARG, тогда ссылкой служит строка с ним
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, d.LanguageVersion) && (e.Line == 0 || strings.HasPrefix(strings.ToUpper(strings.TrimSpace(line)), "FROM")) {
+			e.Line = i + 1
+		}
+	}
+	info.setWithEvidence(e)
+}
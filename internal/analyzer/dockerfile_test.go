@@ -0,0 +1,267 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseDockerfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *Dockerfile
+	}{
+		{
+			name: "multi-stage with named stages",
+			content: `FROM golang:1.22-alpine AS build
+WORKDIR /src
+RUN go build -o /out/app .
+
+FROM gcr.io/distroless/static-debian12:nonroot
+COPY --from=build /out/app /app
+`,
+			want: &Dockerfile{
+				BaseImage:       "gcr.io/distroless/static-debian12:nonroot",
+				Language:        "go",
+				LanguageVersion: "1.22",
+				Stages: []DockerStage{
+					{Name: "build", Image: "golang:1.22-alpine"},
+					{Image: "gcr.io/distroless/static-debian12:nonroot"},
+				},
+			},
+		},
+		{
+			name: "platform flag and stage reference",
+			content: `FROM --platform=$BUILDPLATFORM node:20-alpine as deps
+RUN npm ci
+FROM deps
+`,
+			want: &Dockerfile{
+				BaseImage:       "node:20-alpine",
+				Language:        "javascript",
+				LanguageVersion: "20",
+				Stages: []DockerStage{
+					{Name: "deps", Image: "node:20-alpine"},
+					{Image: "deps"},
+				},
+			},
+		},
+		{
+			name: "arg defaults expanded in FROM",
+			content: `ARG PYTHON_VERSION=3.12
+ARG PIP_TOKEN
+FROM python:${PYTHON_VERSION}-slim
+`,
+			want: &Dockerfile{
+				BaseImage:       "python:3.12-slim",
+				Language:        "python",
+				LanguageVersion: "3.12",
+				Stages:          []DockerStage{{Image: "python:3.12-slim"}},
+				Args: []DockerArg{
+					{Name: "PYTHON_VERSION", Default: "3.12", HasDefault: true},
+					{Name: "PIP_TOKEN"},
+				},
+			},
+		},
+		{
+			name: "env expanded in EXPOSE",
+			content: `FROM node:20
+ENV PORT=3000 NODE_ENV=production
+EXPOSE ${PORT}/tcp \
+    9229/udp
+`,
+			want: &Dockerfile{
+				BaseImage:       "node:20",
+				Language:        "javascript",
+				LanguageVersion: "20",
+				Stages:          []DockerStage{{Image: "node:20"}},
+				Ports:           []int{3000, 9229},
+			},
+		},
+		{
+			name: "healthcheck exec form",
+			content: `FROM python:3.12
+ENV APP_PORT 8000
+HEALTHCHECK --interval=30s CMD ["curl", "-f", "http://localhost:${APP_PORT}/health"]
+`,
+			want: &Dockerfile{
+				BaseImage:       "python:3.12",
+				Language:        "python",
+				LanguageVersion: "3.12",
+				Stages:          []DockerStage{{Image: "python:3.12"}},
+				HealthCheck:     &HealthCheck{Command: "curl -f http://localhost:8000/health", Port: 8000, Path: "/health"},
+			},
+		},
+		{
+			name: "healthcheck shell form",
+			content: `FROM ruby:3.3
+HEALTHCHECK CMD wget -qO- https://localhost/ready?full=1 || exit 1
+`,
+			want: &Dockerfile{
+				BaseImage:       "ruby:3.3",
+				Language:        "ruby",
+				LanguageVersion: "3.3",
+				Stages:          []DockerStage{{Image: "ruby:3.3"}},
+				HealthCheck:     &HealthCheck{Command: "wget -qO- https://localhost/ready?full=1 || exit 1", Port: 443, Path: "/ready?full=1"},
+			},
+		},
+		{
+			name: "healthcheck none",
+			content: `FROM php:8.3-fpm
+HEALTHCHECK NONE
+`,
+			want: &Dockerfile{
+				BaseImage:       "php:8.3-fpm",
+				Language:        "php",
+				LanguageVersion: "8.3",
+				Stages:          []DockerStage{{Image: "php:8.3-fpm"}},
+			},
+		},
+		{
+			name: "ports and healthcheck do not leak into the next stage",
+			content: `FROM node:20 AS build
+EXPOSE 3000
+HEALTHCHECK CMD curl -f http://localhost:3000/
+FROM nginx:1.27
+EXPOSE 80
+`,
+			want: &Dockerfile{
+				BaseImage:       "nginx:1.27",
+				Language:        "javascript",
+				LanguageVersion: "20",
+				Stages: []DockerStage{
+					{Name: "build", Image: "node:20"},
+					{Image: "nginx:1.27"},
+				},
+				Ports: []int{80},
+			},
+		},
+		{
+			name: "stage built from an earlier stage keeps its ports and healthcheck",
+			content: `FROM node:20 AS base
+EXPOSE 3000
+HEALTHCHECK CMD curl -f http://localhost:3000/health
+FROM node:20 AS build
+EXPOSE 9229
+FROM base
+EXPOSE 9090
+`,
+			want: &Dockerfile{
+				BaseImage:       "node:20",
+				Language:        "javascript",
+				LanguageVersion: "20",
+				Stages: []DockerStage{
+					{Name: "base", Image: "node:20"},
+					{Name: "build", Image: "node:20"},
+					{Image: "base"},
+				},
+				Ports:       []int{3000, 9090},
+				HealthCheck: &HealthCheck{Command: "curl -f http://localhost:3000/health", Port: 3000, Path: "/health"},
+			},
+		},
+		{
+			name: "maven builder reports jdk version",
+			content: `FROM maven:3.9-eclipse-temurin-21 AS build
+FROM gcr.io/distroless/java21
+`,
+			want: &Dockerfile{
+				BaseImage:       "gcr.io/distroless/java21",
+				Language:        "java",
+				LanguageVersion: "21",
+				Stages: []DockerStage{
+					{Name: "build", Image: "maven:3.9-eclipse-temurin-21"},
+					{Image: "gcr.io/distroless/java21"},
+				},
+			},
+		},
+		{
+			name: "language from the last language stage",
+			content: `FROM gradle:8.5-jdk17 AS build
+FROM eclipse-temurin:21-jre
+`,
+			want: &Dockerfile{
+				BaseImage:       "eclipse-temurin:21-jre",
+				Language:        "java",
+				LanguageVersion: "21",
+				Stages: []DockerStage{
+					{Name: "build", Image: "gradle:8.5-jdk17"},
+					{Image: "eclipse-temurin:21-jre"},
+				},
+			},
+		},
+		{
+			name: "gradle builder without jdk in tag",
+			content: `FROM gradle:8.5 AS build
+FROM alpine:3.20
+`,
+			want: &Dockerfile{
+				BaseImage: "alpine:3.20",
+				Language:  "java",
+				Stages: []DockerStage{
+					{Name: "build", Image: "gradle:8.5"},
+					{Image: "alpine:3.20"},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseDockerfile(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDockerfile() =\n%+v\nwant\n%+v", got, tt.want)
+				if !reflect.DeepEqual(got.HealthCheck, tt.want.HealthCheck) {
+					t.Errorf("healthcheck = %+v, want %+v", got.HealthCheck, tt.want.HealthCheck)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyDockerfileVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name: "default version replaced by base image",
+			files: fstest.MapFS{
+				"requirements.txt": {Data: []byte("flask\n")},
+				"Dockerfile":       {Data: []byte("FROM python:3.12-slim\n")},
+			},
+			want: "3.12",
+		},
+		{
+			name: "detected version kept",
+			files: fstest.MapFS{
+				"requirements.txt": {Data: []byte("flask\n")},
+				".python-version":  {Data: []byte("3.10\n")},
+				"Dockerfile":       {Data: []byte("FROM python:3.12-slim\n")},
+			},
+			want: "3.10",
+		},
+		{
+			name: "base image of another language ignored",
+			files: fstest.MapFS{
+				"requirements.txt": {Data: []byte("flask\n")},
+				"Dockerfile":       {Data: []byte("FROM node:20\n")},
+			},
+			want: "3.9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := AnalyzeFS(tt.files)
+			if err != nil {
+				t.Fatalf("AnalyzeFS: %v", err)
+			}
+			if info.Version != tt.want {
+				t.Errorf("version = %q, want %q", info.Version, tt.want)
+			}
+			if evidence := info.EvidenceFor("version"); len(evidence) != 1 || evidence[0].Value != tt.want {
+				t.Errorf("version evidence = %+v, want one for %q", evidence, tt.want)
+			}
+		})
+	}
+}
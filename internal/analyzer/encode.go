@@ -0,0 +1,76 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EncodeProjectInfo сериализует результат анализа в JSON или YAML.
+func EncodeProjectInfo(info *ProjectInfo, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case "yaml", "yml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(info); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	default:
+		return nil, fmt.Errorf("unsupported info format: %s (supported: json, yaml)", format)
+	}
+}
+
+// infoFormatForPath выбирает формат файла с ProjectInfo по расширению.
+func infoFormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "json"
+	}
+}
+
+// SaveProjectInfo записывает результат анализа в файл. Формат выбирается
+// по расширению: .yaml/.yml — YAML, остальные — JSON.
+func SaveProjectInfo(info *ProjectInfo, path string) error {
+	data, err := EncodeProjectInfo(info, infoFormatForPath(path))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadProjectInfo читает ProjectInfo, сохраненный командой analyze или
+// исправленный вручную, вместо анализа репозитория.
+func LoadProjectInfo(path string) (*ProjectInfo, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading project info: %w", err)
+	}
+	info := &ProjectInfo{}
+	if infoFormatForPath(path) == "yaml" {
+		err = yaml.Unmarshal(data, info)
+	} else {
+		err = json.Unmarshal(data, info)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing project info %s: %w", path, err)
+	}
+	if info.Language == "" {
+		return nil, fmt.Errorf("project info %s does not specify a language", path)
+	}
+	return info, nil
+}
@@ -0,0 +1,241 @@
+package analyzer
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Confidence — насколько можно доверять значению поля ProjectInfo.
+type Confidence string
+
+const (
+	ConfidenceHigh   Confidence = "high"   // явное указание: маркер, манифест, конфигурация
+	ConfidenceMedium Confidence = "medium" // косвенный признак
+	ConfidenceLow    Confidence = "low"    // значение по умолчанию или догадка
+)
+
+// Evidence объясняет, почему поле ProjectInfo получило свое значение.
+type Evidence struct {
+	Field      string     `json:"field" yaml:"field"`
+	Value      string     `json:"value" yaml:"value"`
+	File       string     `json:"file,omitempty" yaml:"file,omitempty"`
+	Line       int        `json:"line,omitempty" yaml:"line,omitempty"`
+	Rule       string     `json:"rule" yaml:"rule"`
+	Confidence Confidence `json:"confidence" yaml:"confidence"`
+
+	defaulted bool // значение взято анализатором по умолчанию
+}
+
+// Source возвращает место, откуда взято значение, в виде file:line.
+func (e Evidence) Source() string {
+	if e.File == "" {
+		return "-"
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d", e.File, e.Line)
+	}
+	return e.File
+}
+
+// AddEvidence добавляет обоснование значения поля.
+func (info *ProjectInfo) AddEvidence(e Evidence) {
+	info.Evidence = append(info.Evidence, e)
+}
+
+// setWithEvidence присваивает полю значение, выбранное анализатором, и
+// записывает правило, по которому оно выбрано. Прежние обоснования поля
+// заменяются.
+func (info *ProjectInfo) setWithEvidence(e Evidence) {
+	switch e.Field {
+	case "version":
+		info.Version = e.Value
+	case "build_tool":
+		info.BuildTool = e.Value
+	case "test_framework":
+		info.TestFramework = e.Value
+	case "package_manager":
+		info.PackageManager = e.Value
+	case "architecture":
+		info.Architecture = e.Value
+	case "main_file_path":
+		info.MainFilePath = e.Value
+	case "has_tests":
+		info.HasTests = e.Value == "true"
+	}
+	info.dropEvidence(e.Field)
+	info.AddEvidence(e)
+}
+
+// setDependencies заменяет зависимости найденными анализатором, каждую
+// со своим обоснованием.
+func (info *ProjectInfo) setDependencies(found []Evidence) {
+	info.Dependencies = []string{}
+	info.dropEvidence("dependencies")
+	for _, e := range found {
+		info.Dependencies = append(info.Dependencies, e.Value)
+		info.AddEvidence(e)
+	}
+}
+
+// EvidenceFor возвращает обоснования поля.
+func (info *ProjectInfo) EvidenceFor(field string) []Evidence {
+	var result []Evidence
+	for _, e := range info.Evidence {
+		if e.Field == field {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// dropEvidence удаляет обоснования поля, значение которого переопределено.
+func (info *ProjectInfo) dropEvidence(field string) {
+	kept := info.Evidence[:0]
+	for _, e := range info.Evidence {
+		if e.Field != field {
+			kept = append(kept, e)
+		}
+	}
+	info.Evidence = kept
+}
+
+func (info *ProjectInfo) hasEvidence(field, value string) bool {
+	for _, e := range info.Evidence {
+		if e.Field == field && e.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+// evidenceFields задает порядок полей в отчете.
+var evidenceFields = []string{
+	"language", "version", "build_tool", "test_framework", "package_manager",
+	"architecture", "main_file_path", "has_tests", "has_dockerfile", "has_makefile", "dependencies",
+}
+
+// collectEvidence записывает обоснования для файлов корня репозитория и
+// для значений, которые анализатор выставил, не сообщив правила.
+func collectEvidence(info *ProjectInfo) {
+	scalars := []struct{ field, value string }{
+		{"version", info.Version},
+		{"build_tool", info.BuildTool},
+		{"test_framework", info.TestFramework},
+		{"package_manager", info.PackageManager},
+		{"architecture", info.Architecture},
+		{"main_file_path", info.MainFilePath},
+		{"has_tests", fmt.Sprint(info.HasTests)},
+	}
+	for _, f := range scalars {
+		if f.value != "" && !info.hasEvidence(f.field, f.value) {
+			info.AddEvidence(defaultEvidence(f.field, f.value))
+		}
+	}
+	for _, f := range []struct {
+		field string
+		file  string
+		value bool
+	}{{"has_dockerfile", "Dockerfile", info.HasDockerfile}, {"has_makefile", "Makefile", info.HasMakefile}} {
+		e := Evidence{Field: f.field, Value: fmt.Sprint(f.value), Rule: f.file + " not found in repository root", Confidence: ConfidenceHigh}
+		if f.value {
+			e.File, e.Rule = f.file, "file exists in repository root"
+		}
+		info.AddEvidence(e)
+	}
+	for _, dep := range info.Dependencies {
+		if !info.hasEvidence("dependencies", dep) {
+			info.AddEvidence(defaultEvidence("dependencies", dep))
+		}
+	}
+
+	order := map[string]int{}
+	for i, field := range evidenceFields {
+		order[field] = i
+	}
+	sort.SliceStable(info.Evidence, func(i, j int) bool {
+		return order[info.Evidence[i].Field] < order[info.Evidence[j].Field]
+	})
+}
+
+// fileEvidence — значение выбрано по наличию файла.
+func fileEvidence(field, value, file string) Evidence {
+	return Evidence{Field: field, Value: value, File: file, Rule: file + " exists", Confidence: ConfidenceHigh}
+}
+
+// lineEvidence — значение прочитано из строки файла, где оно указано явно.
+func lineEvidence(field, value, file string, line int, rule string) Evidence {
+	return Evidence{Field: field, Value: value, File: file, Line: line, Rule: rule, Confidence: ConfidenceHigh}
+}
+
+// matchEvidence — значение выбрано по вхождению token в содержимое файла.
+// Ссылкой служит первая строка с этим вхождением.
+func matchEvidence(field, value, file, content, token string) Evidence {
+	quoted := token
+	if !strings.Contains(token, `"`) {
+		quoted = strconv.Quote(token)
+	}
+	return Evidence{Field: field, Value: value, File: file, Line: lineOf(content, token),
+		Rule: quoted + " found in " + path.Base(file), Confidence: ConfidenceMedium}
+}
+
+// defaultEvidence — анализатор не нашел признаков и взял значение
+// по умолчанию.
+func defaultEvidence(field, value string) Evidence {
+	return Evidence{Field: field, Value: value, Rule: "analyzer default", Confidence: ConfidenceLow, defaulted: true}
+}
+
+// testFileEvidence — тесты найдены по файлу.
+func testFileEvidence(file string) Evidence {
+	return Evidence{Field: "has_tests", Value: "true", File: file, Rule: "test file", Confidence: ConfidenceHigh}
+}
+
+// noTestsEvidence — ни файлов, ни настроек тестов не найдено.
+func noTestsEvidence() Evidence {
+	return Evidence{Field: "has_tests", Value: "false", Rule: "no test files found", Confidence: ConfidenceMedium}
+}
+
+// tokenRule — значение поля, на которое указывает вхождение в файл
+// одной из строк.
+type tokenRule struct {
+	value  string
+	tokens []string
+}
+
+// matchTokens проверяет правила по порядку и возвращает обоснования всех
+// сработавших.
+func matchTokens(field, file, content string, rules []tokenRule) []Evidence {
+	var found []Evidence
+	for _, rule := range rules {
+		for _, token := range rule.tokens {
+			if strings.Contains(content, token) {
+				found = append(found, matchEvidence(field, rule.value, file, content, token))
+				break
+			}
+		}
+	}
+	return found
+}
+
+// lineOf возвращает номер первой строки с token или 0.
+func lineOf(content, token string) int {
+	for i, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, token) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// detected сообщает, что значение поля нашел детектор, а не взял
+// по умолчанию.
+func (info *ProjectInfo) detected(field string) bool {
+	for _, e := range info.EvidenceFor(field) {
+		if !e.defaulted {
+			return true
+		}
+	}
+	return false
+}
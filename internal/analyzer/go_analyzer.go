@@ -1,310 +1,157 @@
 package analyzer
 
 import (
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
-
-	"github.com/immxrtalbeast/pipeline-gen/internal/git"
 )
 
-func analyzeGoProjectFromMemory(remoteInfo *git.RemoteRepoInfo, info *ProjectInfo) {
-	info.BuildTool = "go"
-	info.TestFramework = "testing"
+func analyzeGoProject(repo *Repo, info *ProjectInfo) error {
+	info.setWithEvidence(Evidence{Field: "build_tool", Value: "go", Rule: "standard Go toolchain", Confidence: ConfidenceHigh})
+	info.setWithEvidence(Evidence{Field: "test_framework", Value: "testing", Rule: "standard Go toolchain", Confidence: ConfidenceHigh})
 
 	// Читаем go.mod для получения версии
-	if content, exists := remoteInfo.GetFileContent("go.mod"); exists {
+	if content, exists := repo.ReadFile("go.mod"); exists {
 		lines := strings.Split(content, "\n")
-		for _, line := range lines {
+		for i, line := range lines {
 			if strings.HasPrefix(line, "go ") {
-				info.Version = strings.TrimSpace(strings.TrimPrefix(line, "go "))
+				info.setWithEvidence(lineEvidence("version", strings.TrimSpace(strings.TrimPrefix(line, "go ")), "go.mod", i+1, "go directive"))
 				break
 			}
 		}
 	}
 
 	// Анализируем зависимости
-	info.Dependencies = detectGoDependenciesFromMemory(remoteInfo)
-	info.Modules = detectGoModulesFromMemory(remoteInfo)
+	info.setDependencies(detectGoDependencies(repo))
+	info.Modules = detectGoModules(repo)
 
-	for _, file := range remoteInfo.Structure {
+	info.setWithEvidence(noTestsEvidence())
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, "_test.go") {
-			info.HasTests = true
-
+			info.setWithEvidence(testFileEvidence(file))
+			break
 		}
 	}
-	info.MainFilePath = findMainFilePathFromMemory(remoteInfo)
-
+	if e, ok := findMainFilePath(repo); ok {
+		info.setWithEvidence(e)
+	}
+	info.setWithEvidence(detectGoArchitecture(repo))
+	return nil
 }
 
-func findMainFilePathFromMemory(remoteInfo *git.RemoteRepoInfo) string {
-
-	for _, file := range remoteInfo.Structure {
-
-		if strings.HasSuffix(file, "main.go") {
-
-			return file
+// findMainFilePath ищет файл с точкой входа: main.go в корне, затем
+// в подкаталогах, затем любой файл с func main.
+func findMainFilePath(repo *Repo) (Evidence, bool) {
+	if repo.HasFile("main.go") {
+		return Evidence{Field: "main_file_path", Value: "main.go", File: "main.go",
+			Rule: "main.go in repository root", Confidence: ConfidenceHigh}, true
+	}
 
+	for _, file := range repo.Files() {
+		if path.Base(file) == "main.go" {
+			return Evidence{Field: "main_file_path", Value: file, File: file,
+				Rule: "main.go", Confidence: ConfidenceHigh}, true
 		}
-
 	}
 
-	for _, file := range remoteInfo.Structure {
-
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, ".go") && !strings.HasSuffix(file, "_test.go") {
-
-			if content, exists := remoteInfo.GetFileContent(file); exists {
-
-				if containsMainFunction(content) {
-
-					return file
-
+			if content, exists := repo.ReadFile(file); exists {
+				if line := mainFunctionLine(content); line > 0 {
+					return lineEvidence("main_file_path", file, file, line, "declares func main"), true
 				}
-
 			}
-
 		}
-
 	}
 
-	return ""
-
+	return Evidence{}, false
 }
 
-func containsMainFunction(content string) bool {
-
+// mainFunctionLine возвращает номер строки с func main вне комментариев
+// или 0.
+func mainFunctionLine(content string) int {
 	lines := strings.Split(content, "\n")
-
 	inBlockComment := false
-
-	for _, line := range lines {
-
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
-
 		if strings.Contains(line, "/*") {
-
 			inBlockComment = true
-
 		}
-
 		if strings.Contains(line, "*/") {
-
 			inBlockComment = false
-
 			continue
-
 		}
-
 		if inBlockComment {
-
 			continue
-
 		}
-
 		if strings.HasPrefix(line, "//") {
-
 			continue
-
 		}
-
 		if strings.Contains(line, "func main()") {
-
-			return true
-
+			return i + 1
 		}
-
 	}
-
-	return false
-
+	return 0
 }
 
-func detectGoDependenciesFromMemory(remoteInfo *git.RemoteRepoInfo) []string {
-	deps := []string{}
-
+func detectGoDependencies(repo *Repo) []Evidence {
 	// Анализируем go.mod на наличие популярных зависимостей
-	if content, exists := remoteInfo.GetFileContent("go.mod"); exists {
-		if strings.Contains(content, "github.com/gin-gonic/gin") {
-			deps = append(deps, "web-framework:gin")
-		}
-		if strings.Contains(content, "github.com/gorilla/mux") {
-			deps = append(deps, "web-framework:gorilla")
-		}
-		if strings.Contains(content, "database/sql") || strings.Contains(content, "gorm.io/gorm") {
-			deps = append(deps, "database")
-		}
+	content, exists := repo.ReadFile("go.mod")
+	if !exists {
+		return nil
 	}
-
-	return deps
+	return matchTokens("dependencies", "go.mod", content, []tokenRule{
+		{"web-framework:gin", []string{"github.com/gin-gonic/gin"}},
+		{"web-framework:gorilla", []string{"github.com/gorilla/mux"}},
+		{"database", []string{"database/sql", "gorm.io/gorm"}},
+	})
 }
 
-func detectGoModulesFromMemory(remoteInfo *git.RemoteRepoInfo) []string {
+func detectGoModules(repo *Repo) []string {
 	modules := []string{}
 
 	// Ищем вложенные go.mod файлы
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, "go.mod") && file != "go.mod" {
-			dir := filepath.Dir(file)
+			dir := path.Dir(file)
 			modules = append(modules, dir)
 		}
 	}
 
 	return modules
 }
-func detectGoArchitectureFromMemory(remoteInfo *git.RemoteRepoInfo) string {
+
+func detectGoArchitecture(repo *Repo) Evidence {
+	e := Evidence{Field: "architecture", Confidence: ConfidenceMedium}
 	// Определяем архитектуру по структуре каталогов
-	hasCmd := remoteInfo.HasDirectory("cmd")
-	hasPkg := remoteInfo.HasDirectory("pkg")
-	hasInternal := remoteInfo.HasDirectory("internal")
+	hasCmd := repo.HasDirectory("cmd")
+	hasPkg := repo.HasDirectory("pkg")
+	hasInternal := repo.HasDirectory("internal")
 
 	if hasCmd && hasPkg {
-		return "standard-go-layout"
+		e.Value, e.Rule = "standard-go-layout", "cmd and pkg directories in repository root"
+		return e
 	}
 	if hasInternal {
-		return "with-internal-packages"
+		e.Value, e.Rule = "with-internal-packages", "internal directory in repository root"
+		return e
 	}
 
 	// Проверяем наличие типичных структур
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.Contains(file, "/cmd/") || strings.Contains(file, "/pkg/") {
-			return "standard-go-layout"
-		}
-	}
-
-	return "simple"
-}
-
-//local
-
-func analyzeGoProject(repoPath string, info *ProjectInfo) error {
-	// Читаем go.mod для получения версии и зависимостей
-	goModPath := filepath.Join(repoPath, "go.mod")
-	if exists(goModPath) {
-		content, err := os.ReadFile(goModPath)
-		if err != nil {
-			return err
-		}
-
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, "go ") {
-				info.Version = strings.TrimSpace(strings.TrimPrefix(line, "go "))
-			}
-		}
-	}
-
-	info.BuildTool = "go"
-	info.TestFramework = "testing"
-
-	// Простой анализ зависимостей
-	info.Dependencies = detectGoDependencies(repoPath)
-	info.MainFilePath = findMainFilePath(repoPath)
-	return nil
-}
-func findMainFilePath(repoPath string) string {
-
-	mainGoPath := filepath.Join(repoPath, "main.go")
-
-	if exists(mainGoPath) {
-
-		return "main.go"
-
-	}
-
-	var mainFilePath string
-
-	filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
-
-		if err != nil {
-
-			return nil
-
-		}
-
-		if mainFilePath != "" {
-
-			return filepath.SkipAll
-
-		}
-
-		if info.IsDir() || strings.HasSuffix(path, "_test.go") {
-
-			return nil
-
-		}
-
-		if strings.HasSuffix(path, ".go") {
-
-			if strings.HasSuffix(path, "main.go") {
-
-				relPath, _ := filepath.Rel(repoPath, path)
-
-				mainFilePath = relPath
-
-				return filepath.SkipAll
-
-			}
-
-			content, err := os.ReadFile(path)
-
-			if err != nil {
-
-				return nil
-
-			}
-
-			if containsMainFunction(string(content)) {
-
-				relPath, _ := filepath.Rel(repoPath, path)
-
-				mainFilePath = relPath
-
-			}
-
-		}
-
-		return nil
-
-	})
-
-	return mainFilePath
-
-}
-
-func detectGoDependencies(repoPath string) []string {
-	deps := []string{}
-
-	// Проверяем наличие common зависимостей через анализ импортов
-	goFiles, _ := filepath.Glob(filepath.Join(repoPath, "*.go"))
-	for _, file := range goFiles {
-		content, err := os.ReadFile(file)
-		if err != nil {
-			continue
-		}
-
-		lines := strings.Split(string(content), "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, "import") {
-				if strings.Contains(line, "github.com/gin-gonic/gin") {
-					deps = append(deps, "web-framework:gin")
-				}
-				if strings.Contains(line, "database/sql") {
-					deps = append(deps, "database:sql")
-				}
-			}
+			e.Value, e.File, e.Rule = "standard-go-layout", file, "nested cmd or pkg directory"
+			return e
 		}
 	}
 
-	return deps
-}
-
-func detectArchitecture(repoPath string) string {
-	// Упрощенное определение архитектуры
-	if exists(filepath.Join(repoPath, "cmd")) && exists(filepath.Join(repoPath, "pkg")) {
-		return "standard-go-layout"
-	}
-	if exists(filepath.Join(repoPath, "internal")) {
-		return "with-internal-packages"
+	e.Value = "simple"
+	switch {
+	case hasCmd:
+		e.Rule = "cmd directory without pkg or internal"
+	case hasPkg:
+		e.Rule = "pkg directory without cmd or internal"
+	default:
+		e.Rule = "no cmd, pkg or internal directories"
 	}
-	return "simple"
+	return e
 }
@@ -1,177 +1,87 @@
 package analyzer
 
 import (
-    "os"
-    "path/filepath"
     "strings"
     "regexp"
-
-    "github.com/immxrtalbeast/pipeline-gen/internal/git"
 )
 
-func analyzePHPProjectFromMemory(remoteInfo *git.RemoteRepoInfo, info *ProjectInfo) {
-    info.BuildTool = detectPHPBuildTool(remoteInfo)
-    info.TestFramework = detectPHPTestFramework(remoteInfo)
-    info.Version = detectPHPVersion(remoteInfo)
-    info.Dependencies = detectPHPDependencies(remoteInfo)
-    info.HasTests = detectPHPTestsFromMemory(remoteInfo)
-    info.PackageManager = detectPHPPackageManager(remoteInfo)
-}
-
-func analyzePHPProject(repoPath string, info *ProjectInfo) error {
-    info.BuildTool = detectPHPBuildToolLocal(repoPath)
-    info.TestFramework = detectPHPTestFrameworkLocal(repoPath)
-    info.Version = detectPHPVersionLocal(repoPath)
-    info.Dependencies = detectPHPDependenciesLocal(repoPath)
-    info.HasTests = detectPHPTestsLocal(repoPath)
-    info.PackageManager = detectPHPPackageManagerLocal(repoPath)
+func analyzePHPProject(repo *Repo, info *ProjectInfo) error {
+    info.setWithEvidence(detectPHPBuildTool(repo))
+    info.setWithEvidence(detectPHPTestFramework(repo))
+    info.setWithEvidence(detectPHPVersion(repo))
+    info.setDependencies(detectPHPDependencies(repo))
+    info.setWithEvidence(detectPHPTests(repo))
+    info.setWithEvidence(detectPHPPackageManager(repo))
     return nil
 }
 
 // Вспомогательные функции для анализа PHP
-func detectPHPBuildTool(remoteInfo *git.RemoteRepoInfo) string {
-    if remoteInfo.HasFile("composer.json") {
-        return "composer"
-    }
-    if remoteInfo.HasFile("package.json") && remoteInfo.HasFile("webpack.mix.js") {
-        return "laravel-mix"
-    }
-    if remoteInfo.HasFile("artisan") {
-        return "laravel"
-    }
-    if remoteInfo.HasFile("symfony") {
-        return "symfony"
+func detectPHPBuildTool(repo *Repo) Evidence {
+    if repo.HasFile("composer.json") {
+        return fileEvidence("build_tool", "composer", "composer.json")
     }
-    return "php" // простой PHP проект
-}
-
-func detectPHPBuildToolLocal(repoPath string) string {
-    if exists(filepath.Join(repoPath, "composer.json")) {
-        return "composer"
-    }
-    if exists(filepath.Join(repoPath, "artisan")) {
-        return "laravel"
+    if repo.HasFile("package.json") && repo.HasFile("webpack.mix.js") {
+        return fileEvidence("build_tool", "laravel-mix", "webpack.mix.js")
     }
-    if exists(filepath.Join(repoPath, "symfony")) {
-        return "symfony"
+    if repo.HasFile("artisan") {
+        return fileEvidence("build_tool", "laravel", "artisan")
     }
-    if exists(filepath.Join(repoPath, "package.json")) && exists(filepath.Join(repoPath, "webpack.mix.js")) {
-        return "laravel-mix"
-    }
-    return "php"
-}
-
-func detectPHPPackageManager(remoteInfo *git.RemoteRepoInfo) string {
-    if remoteInfo.HasFile("composer.json") {
-        return "composer"
+    if repo.HasFile("symfony") {
+        return fileEvidence("build_tool", "symfony", "symfony")
     }
-    return "none"
+    return defaultEvidence("build_tool", "php") // простой PHP проект
 }
 
-func detectPHPPackageManagerLocal(repoPath string) string {
-    if exists(filepath.Join(repoPath, "composer.json")) {
-        return "composer"
+func detectPHPPackageManager(repo *Repo) Evidence {
+    if repo.HasFile("composer.json") {
+        return fileEvidence("package_manager", "composer", "composer.json")
     }
-    return "none"
+    return defaultEvidence("package_manager", "none")
 }
 
-func detectPHPTestFramework(remoteInfo *git.RemoteRepoInfo) string {
+func detectPHPTestFramework(repo *Repo) Evidence {
     // Проверяем composer.json на наличие тестовых фреймворков
-    if content, exists := remoteInfo.GetFileContent("composer.json"); exists {
-        text := strings.ToLower(content)
-        if strings.Contains(text, "phpunit/phpunit") {
-            return "phpunit"
-        }
-        if strings.Contains(text, "codeception/codeception") {
-            return "codeception"
-        }
-        if strings.Contains(text, "behat/behat") {
-            return "behat"
-        }
-        if strings.Contains(text, "phpstan/phpstan") {
-            return "phpstan" // статический анализ
-        }
-        if strings.Contains(text, "pestphp/pest") {
-            return "pest"
+    if content, exists := repo.ReadFile("composer.json"); exists {
+        found := matchTokens("test_framework", "composer.json", strings.ToLower(content), []tokenRule{
+            {"phpunit", []string{"phpunit/phpunit"}},
+            {"codeception", []string{"codeception/codeception"}},
+            {"behat", []string{"behat/behat"}},
+            {"phpstan", []string{"phpstan/phpstan"}}, // статический анализ
+            {"pest", []string{"pestphp/pest"}},
+        })
+        if len(found) > 0 {
+            return found[0]
         }
     }
 
     // Проверяем наличие конфигурационных файлов тестов
-    if remoteInfo.HasFile("phpunit.xml") || remoteInfo.HasFile("phpunit.xml.dist") {
-        return "phpunit"
-    }
-    if remoteInfo.HasFile("codeception.yml") {
-        return "codeception"
-    }
-    if remoteInfo.HasFile("behat.yml") {
-        return "behat"
-    }
-    if remoteInfo.HasFile("pest.yml") {
-        return "pest"
+    for _, config := range []struct{ file, framework string }{
+        {"phpunit.xml", "phpunit"},
+        {"phpunit.xml.dist", "phpunit"},
+        {"codeception.yml", "codeception"},
+        {"behat.yml", "behat"},
+        {"pest.yml", "pest"},
+    } {
+        if repo.HasFile(config.file) {
+            return fileEvidence("test_framework", config.framework, config.file)
+        }
     }
 
     // Ищем тестовые файлы
-    for _, file := range remoteInfo.Structure {
+    for _, file := range repo.Files() {
         if strings.Contains(file, "Test.php") && 
            (strings.Contains(file, "/tests/") || strings.Contains(file, "/Tests/")) {
-            return "phpunit" // предположительно
-        }
-    }
-
-    return "phpunit" // по умолчанию
-}
-
-func detectPHPTestFrameworkLocal(repoPath string) string {
-    // Проверяем composer.json
-    composerPath := filepath.Join(repoPath, "composer.json")
-    if exists(composerPath) {
-        content, err := os.ReadFile(composerPath)
-        if err == nil {
-            text := strings.ToLower(string(content))
-            if strings.Contains(text, "phpunit/phpunit") {
-                return "phpunit"
-            }
-            if strings.Contains(text, "codeception/codeception") {
-                return "codeception"
-            }
-            if strings.Contains(text, "pestphp/pest") {
-                return "pest"
-            }
+            return Evidence{Field: "test_framework", Value: "phpunit", File: file,
+                Rule: "PHPUnit-style test file", Confidence: ConfidenceMedium} // предположительно
         }
     }
 
-    // Проверяем конфигурационные файлы
-    if exists(filepath.Join(repoPath, "phpunit.xml")) || 
-       exists(filepath.Join(repoPath, "phpunit.xml.dist")) {
-        return "phpunit"
-    }
-    if exists(filepath.Join(repoPath, "codeception.yml")) {
-        return "codeception"
-    }
-    if exists(filepath.Join(repoPath, "pest.yml")) {
-        return "pest"
-    }
-
-    // Ищем тестовые файлы
-    patterns := []string{
-        filepath.Join(repoPath, "**/tests/**/*Test.php"),
-        filepath.Join(repoPath, "**/Tests/**/*Test.php"),
-        filepath.Join(repoPath, "**/*Test.php"),
-    }
-
-    for _, pattern := range patterns {
-        matches, _ := filepath.Glob(pattern)
-        if len(matches) > 0 {
-            return "phpunit"
-        }
-    }
-
-    return "phpunit"
+    return defaultEvidence("test_framework", "phpunit")
 }
 
-func detectPHPVersion(remoteInfo *git.RemoteRepoInfo) string {
+func detectPHPVersion(repo *Repo) Evidence {
     // Анализируем версию PHP из composer.json
-    if content, exists := remoteInfo.GetFileContent("composer.json"); exists {
+    if content, exists := repo.ReadFile("composer.json"); exists {
         // Ищем "php": "^7.4|^8.0" и т.д.
         re := regexp.MustCompile(`"php"\s*:\s*"([^"]+)"`)
         matches := re.FindStringSubmatch(content)
@@ -181,238 +91,110 @@ func detectPHPVersion(remoteInfo *git.RemoteRepoInfo) string {
             if strings.Contains(versionConstraint, "^") {
                 parts := strings.Split(versionConstraint, "^")
                 if len(parts) > 1 {
-                    return strings.Split(parts[1], "|")[0] // берем первую версию
+                    version := strings.Split(parts[1], "|")[0] // берем первую версию
+                    return lineEvidence("version", version, "composer.json", lineOf(content, matches[0]), "php constraint in composer.json")
                 }
             }
-            return "8.1" // fallback
+            return defaultEvidence("version", "8.1") // fallback
         }
     }
 
     // Проверяем наличие файла .php-version
-    if content, exists := remoteInfo.GetFileContent(".php-version"); exists {
-        return strings.TrimSpace(content)
+    if content, exists := repo.ReadFile(".php-version"); exists {
+        return lineEvidence("version", strings.TrimSpace(content), ".php-version", 1, "version file")
     }
 
     // Проверяем наличие .tool-versions (asdf)
-    if content, exists := remoteInfo.GetFileContent(".tool-versions"); exists {
+    if content, exists := repo.ReadFile(".tool-versions"); exists {
         lines := strings.Split(content, "\n")
-        for _, line := range lines {
+        for i, line := range lines {
             if strings.Contains(line, "php") {
                 parts := strings.Fields(line)
                 if len(parts) > 1 {
-                    return parts[1]
+                    return lineEvidence("version", parts[1], ".tool-versions", i+1, "php in .tool-versions")
                 }
             }
         }
     }
 
-    return "8.1" // версия по умолчанию
+    return defaultEvidence("version", "8.1")
 }
 
-func detectPHPVersionLocal(repoPath string) string {
-    // Проверяем composer.json
-    composerPath := filepath.Join(repoPath, "composer.json")
-    if exists(composerPath) {
-        content, err := os.ReadFile(composerPath)
-        if err == nil {
-            re := regexp.MustCompile(`"php"\s*:\s*"([^"]+)"`)
-            matches := re.FindStringSubmatch(string(content))
-            if len(matches) > 1 {
-                versionConstraint := matches[1]
-                if strings.Contains(versionConstraint, "^") {
-                    parts := strings.Split(versionConstraint, "^")
-                    if len(parts) > 1 {
-                        return strings.Split(parts[1], "|")[0]
-                    }
-                }
-            }
-        }
-    }
-
-    // Проверяем .php-version
-    phpVersionPath := filepath.Join(repoPath, ".php-version")
-    if exists(phpVersionPath) {
-        content, err := os.ReadFile(phpVersionPath)
-        if err == nil {
-            return strings.TrimSpace(string(content))
-        }
-    }
-
-    // Проверяем .tool-versions
-    toolVersionsPath := filepath.Join(repoPath, ".tool-versions")
-    if exists(toolVersionsPath) {
-        content, err := os.ReadFile(toolVersionsPath)
-        if err == nil {
-            lines := strings.Split(string(content), "\n")
-            for _, line := range lines {
-                if strings.Contains(line, "php") {
-                    parts := strings.Fields(line)
-                    if len(parts) > 1 {
-                        return parts[1]
-                    }
-                }
-            }
-        }
-    }
-
-    return "8.1"
-}
-
-func detectPHPDependencies(remoteInfo *git.RemoteRepoInfo) []string {
-    deps := []string{}
+func detectPHPDependencies(repo *Repo) []Evidence {
+    var deps []Evidence
 
     // Анализируем composer.json на наличие популярных фреймворков и библиотек
-    if content, exists := remoteInfo.GetFileContent("composer.json"); exists {
+    if content, exists := repo.ReadFile("composer.json"); exists {
         text := strings.ToLower(content)
 
-        // Фреймворки
-        if strings.Contains(text, `"laravel/framework"`) || strings.Contains(text, `"illuminate/`) {
-            deps = append(deps, "framework:laravel")
-        }
-        if strings.Contains(text, `"symfony/`) {
-            deps = append(deps, "framework:symfony")
-        }
-        if strings.Contains(text, `"codeigniter4/framework"`) {
-            deps = append(deps, "framework:codeigniter")
-        }
+        deps = matchTokens("dependencies", "composer.json", text, []tokenRule{
+            // Фреймворки
+            {"framework:laravel", []string{`"laravel/framework"`, `"illuminate/`}},
+            {"framework:symfony", []string{`"symfony/`}},
+            {"framework:codeigniter", []string{`"codeigniter4/framework"`}},
 
-        // Базы данных
-        if strings.Contains(text, `"doctrine/orm"`) || strings.Contains(text, `"illuminate/database"`) {
-            deps = append(deps, "database:orm")
-        }
-        if strings.Contains(text, `"mongodb/mongodb"`) {
-            deps = append(deps, "database:mongodb")
-        }
+            // Базы данных
+            {"database:orm", []string{`"doctrine/orm"`, `"illuminate/database"`}},
+            {"database:mongodb", []string{`"mongodb/mongodb"`}},
 
-        // API
-        if strings.Contains(text, `"laravel/sanctum"`) || strings.Contains(text, `"tymon/jwt-auth"`) {
-            deps = append(deps, "api:authentication")
-        }
+            // API
+            {"api:authentication", []string{`"laravel/sanctum"`, `"tymon/jwt-auth"`}},
+        })
 
         // Frontend
-        if strings.Contains(text, `"laravel/ui"`) || remoteInfo.HasFile("webpack.mix.js") {
-            deps = append(deps, "frontend:build-tools")
+        if strings.Contains(text, `"laravel/ui"`) {
+            deps = append(deps, matchEvidence("dependencies", "frontend:build-tools", "composer.json", text, `"laravel/ui"`))
+        } else if repo.HasFile("webpack.mix.js") {
+            deps = append(deps, fileEvidence("dependencies", "frontend:build-tools", "webpack.mix.js"))
         }
 
-        // Тестирование
-        if strings.Contains(text, `"phpunit/phpunit"`) {
-            deps = append(deps, "testing:phpunit")
-        }
-        if strings.Contains(text, `"mockery/mockery"`) {
-            deps = append(deps, "testing:mockery")
-        }
+        deps = append(deps, matchTokens("dependencies", "composer.json", text, []tokenRule{
+            // Тестирование
+            {"testing:phpunit", []string{`"phpunit/phpunit"`}},
+            {"testing:mockery", []string{`"mockery/mockery"`}},
 
-        // Анализ кода
-        if strings.Contains(text, `"phpstan/phpstan"`) {
-            deps = append(deps, "quality:static-analysis")
-        }
-        if strings.Contains(text, `"squizlabs/php_codesniffer"`) {
-            deps = append(deps, "quality:code-style")
-        }
+            // Анализ кода
+            {"quality:static-analysis", []string{`"phpstan/phpstan"`}},
+            {"quality:code-style", []string{`"squizlabs/php_codesniffer"`}},
+        })...)
     }
 
     // Проверяем наличие специфичных файлов фреймворков
-    if remoteInfo.HasFile("artisan") {
-        deps = append(deps, "framework:laravel")
+    if repo.HasFile("artisan") {
+        deps = append(deps, fileEvidence("dependencies", "framework:laravel", "artisan"))
     }
-    if remoteInfo.HasFile("symfony") {
-        deps = append(deps, "framework:symfony")
+    if repo.HasFile("symfony") {
+        deps = append(deps, fileEvidence("dependencies", "framework:symfony", "symfony"))
     }
 
     return deps
 }
 
-func detectPHPDependenciesLocal(repoPath string) []string {
-    deps := []string{}
-
-    composerPath := filepath.Join(repoPath, "composer.json")
-    if exists(composerPath) {
-        content, err := os.ReadFile(composerPath)
-        if err == nil {
-            text := strings.ToLower(string(content))
-
-            if strings.Contains(text, `"laravel/framework"`) {
-                deps = append(deps, "framework:laravel")
-            }
-            if strings.Contains(text, `"symfony/`) {
-                deps = append(deps, "framework:symfony")
-            }
-            if strings.Contains(text, `"doctrine/orm"`) {
-                deps = append(deps, "database:orm")
-            }
-            if strings.Contains(text, `"phpunit/phpunit"`) {
-                deps = append(deps, "testing:phpunit")
-            }
-        }
-    }
-
-    // Проверяем файлы фреймворков
-    if exists(filepath.Join(repoPath, "artisan")) {
-        deps = append(deps, "framework:laravel")
-    }
-    if exists(filepath.Join(repoPath, "symfony")) {
-        deps = append(deps, "framework:symfony")
-    }
-
-    return deps
-}
-
-func detectPHPTestsFromMemory(remoteInfo *git.RemoteRepoInfo) bool {
+func detectPHPTests(repo *Repo) Evidence {
     // Ищем тестовые файлы в PHP проектах
-    for _, file := range remoteInfo.Structure {
-        if (strings.HasSuffix(file, "Test.php") || strings.HasSuffix(file, "Test.php")) &&
+    for _, file := range repo.Files() {
+        if strings.HasSuffix(file, "Test.php") &&
            (strings.Contains(file, "/tests/") || strings.Contains(file, "/Tests/")) {
-            return true
+            return testFileEvidence(file)
         }
     }
 
     // Проверяем наличие конфигурационных файлов тестов
-    if remoteInfo.HasFile("phpunit.xml") || remoteInfo.HasFile("phpunit.xml.dist") ||
-       remoteInfo.HasFile("codeception.yml") || remoteInfo.HasFile("behat.yml") ||
-       remoteInfo.HasFile("pest.yml") {
-        return true
+    for _, file := range []string{"phpunit.xml", "phpunit.xml.dist", "codeception.yml", "behat.yml", "pest.yml"} {
+        if repo.HasFile(file) {
+            return Evidence{Field: "has_tests", Value: "true", File: file, Rule: "test configuration", Confidence: ConfidenceMedium}
+        }
     }
 
     // Проверяем composer.json на наличие тестовых зависимостей
-    if content, exists := remoteInfo.GetFileContent("composer.json"); exists {
-        if strings.Contains(content, "phpunit") || strings.Contains(content, "codeception") ||
-           strings.Contains(content, "behat") || strings.Contains(content, "pest") {
-            return true
+    if content, exists := repo.ReadFile("composer.json"); exists {
+        found := matchTokens("has_tests", "composer.json", content, []tokenRule{
+            {"true", []string{"phpunit", "codeception", "behat", "pest"}},
+        })
+        if len(found) > 0 {
+            return found[0]
         }
     }
 
-    return false
+    return noTestsEvidence()
 }
-
-func detectPHPTestsLocal(repoPath string) bool {
-    // Ищем тесты в локальной директории
-    patterns := []string{
-        filepath.Join(repoPath, "**/tests/**/*Test.php"),
-        filepath.Join(repoPath, "**/Tests/**/*Test.php"),
-        filepath.Join(repoPath, "**/*Test.php"),
-    }
-
-    for _, pattern := range patterns {
-        matches, _ := filepath.Glob(pattern)
-        if len(matches) > 0 {
-            return true
-        }
-    }
-
-    // Проверяем конфигурационные файлы
-    testConfigs := []string{
-        "phpunit.xml",
-        "phpunit.xml.dist", 
-        "codeception.yml",
-        "behat.yml",
-        "pest.yml",
-    }
-
-    for _, config := range testConfigs {
-        if exists(filepath.Join(repoPath, config)) {
-            return true
-        }
-    }
-
-    return false
-}
\ No newline at end of file
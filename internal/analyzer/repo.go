@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// skippedDirs не попадают в список файлов: это служебные каталоги и
+// зависимости, которых нет в удаленном репозитории.
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+}
+
+// Repo — дерево файлов репозитория, одинаковое для локального каталога
+// и клона в памяти. Пути в нем всегда относительные и разделены "/".
+type Repo struct {
+	fsys  fs.FS
+	files []string
+	cache map[string]string
+}
+
+// NewRepo обходит файловую систему и запоминает список файлов.
+func NewRepo(fsys fs.FS) (*Repo, error) {
+	repo := &Repo{fsys: fsys, cache: map[string]string{}}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name != "." && skippedDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		repo.files = append(repo.files, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(repo.files)
+	return repo, nil
+}
+
+// Files возвращает отсортированный список файлов репозитория.
+func (r *Repo) Files() []string {
+	return r.files
+}
+
+// ReadFile возвращает содержимое файла. Прочитанные файлы кешируются:
+// анализаторы обращаются к одним и тем же манифестам по нескольку раз.
+func (r *Repo) ReadFile(name string) (string, bool) {
+	if content, ok := r.cache[name]; ok {
+		return content, true
+	}
+	data, err := fs.ReadFile(r.fsys, name)
+	if err != nil {
+		return "", false
+	}
+	r.cache[name] = string(data)
+	return string(data), true
+}
+
+// HasFile проверяет наличие файла в репозитории.
+func (r *Repo) HasFile(name string) bool {
+	i := sort.SearchStrings(r.files, name)
+	return i < len(r.files) && r.files[i] == name
+}
+
+// HasDirectory проверяет наличие непустой директории.
+func (r *Repo) HasDirectory(dir string) bool {
+	prefix := dir + "/"
+	i := sort.SearchStrings(r.files, prefix)
+	return i < len(r.files) && strings.HasPrefix(r.files[i], prefix)
+}
+
+// HasFileWithExtension проверяет наличие файлов с определенным расширением.
+func (r *Repo) HasFileWithExtension(ext string) bool {
+	for _, file := range r.files {
+		if strings.HasSuffix(file, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// Glob возвращает файлы, подходящие под шаблон path.Match.
+func (r *Repo) Glob(pattern string) []string {
+	var matches []string
+	for _, file := range r.files {
+		if ok, _ := path.Match(pattern, file); ok {
+			matches = append(matches, file)
+		}
+	}
+	return matches
+}
+
+// Sub возвращает поддерево каталога dir как отдельный репозиторий.
+func (r *Repo) Sub(dir string) (*Repo, error) {
+	fsys, err := fs.Sub(r.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	sub := &Repo{fsys: fsys, cache: map[string]string{}}
+	prefix := dir + "/"
+	for _, file := range r.files {
+		if strings.HasPrefix(file, prefix) {
+			sub.files = append(sub.files, strings.TrimPrefix(file, prefix))
+		}
+	}
+	return sub, nil
+}
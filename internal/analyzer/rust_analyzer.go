@@ -1,85 +1,47 @@
 package analyzer
 
 import (
-	"os"
-	"path/filepath"
 	"strings"
-
-	"github.com/immxrtalbeast/pipeline-gen/internal/git"
 )
 
-func analyzeRustProjectFromMemory(remoteInfo *git.RemoteRepoInfo, info *ProjectInfo) {
-	info.BuildTool = detectRustBuildTool(remoteInfo)
-	info.TestFramework = detectRustTestFramework(remoteInfo)
-	info.Version = detectRustVersion(remoteInfo)
-	info.Dependencies = detectRustDependencies(remoteInfo)
-	info.HasTests = detectRustTestsFromMemory(remoteInfo)
-	info.Modules = detectRustCratesFromMemory(remoteInfo)
-}
-
-func analyzeRustProject(repoPath string, info *ProjectInfo) error {
-	info.BuildTool = detectRustBuildToolLocal(repoPath)
-	info.TestFramework = detectRustTestFrameworkLocal(repoPath)
-	info.Version = detectRustVersionLocal(repoPath)
-	info.Dependencies = detectRustDependenciesLocal(repoPath)
-	info.HasTests = detectRustTestsLocal(repoPath)
-	info.Modules = detectRustCratesLocal(repoPath)
+func analyzeRustProject(repo *Repo, info *ProjectInfo) error {
+	info.setWithEvidence(detectRustBuildTool(repo))
+	info.setWithEvidence(detectRustTestFramework(repo))
+	info.setWithEvidence(detectRustVersion(repo))
+	info.setDependencies(detectRustDependencies(repo))
+	info.setWithEvidence(detectRustTests(repo))
+	info.Modules = detectRustCrates(repo)
 	return nil
 }
 
 // Вспомогательные функции для анализа Rust/Cargo
-func detectRustBuildTool(remoteInfo *git.RemoteRepoInfo) string {
-	if remoteInfo.HasFile("Cargo.toml") {
-		return "cargo"
-	}
-	return "unknown"
-}
-
-func detectRustBuildToolLocal(repoPath string) string {
-	if exists(filepath.Join(repoPath, "Cargo.toml")) {
-		return "cargo"
+func detectRustBuildTool(repo *Repo) Evidence {
+	if repo.HasFile("Cargo.toml") {
+		return fileEvidence("build_tool", "cargo", "Cargo.toml")
 	}
-	return "unknown"
+	return defaultEvidence("build_tool", "unknown")
 }
 
-func detectRustTestFramework(remoteInfo *git.RemoteRepoInfo) string {
+func detectRustTestFramework(repo *Repo) Evidence {
 	// Rust использует встроенную систему тестирования, но могут быть дополнительные фреймворки
-	if content, exists := remoteInfo.GetFileContent("Cargo.toml"); exists {
-		if strings.Contains(content, "proptest") {
-			return "proptest"
-		}
-		if strings.Contains(content, "rstest") {
-			return "rstest"
-		}
-		if strings.Contains(content, "cucumber") {
-			return "cucumber"
+	if content, exists := repo.ReadFile("Cargo.toml"); exists {
+		found := matchTokens("test_framework", "Cargo.toml", content, []tokenRule{
+			{"proptest", []string{"proptest"}},
+			{"rstest", []string{"rstest"}},
+			{"cucumber", []string{"cucumber"}},
+		})
+		if len(found) > 0 {
+			return found[0]
 		}
 	}
-	return "builtin" // встроенная система тестов Rust
+	return defaultEvidence("test_framework", "builtin") // встроенная система тестов Rust
 }
 
-func detectRustTestFrameworkLocal(repoPath string) string {
-	cargoPath := filepath.Join(repoPath, "Cargo.toml")
-	if exists(cargoPath) {
-		content, err := os.ReadFile(cargoPath)
-		if err == nil {
-			text := string(content)
-			if strings.Contains(text, "proptest") {
-				return "proptest"
-			}
-			if strings.Contains(text, "rstest") {
-				return "rstest"
-			}
-		}
-	}
-	return "builtin"
-}
-
-func detectRustVersion(remoteInfo *git.RemoteRepoInfo) string {
+func detectRustVersion(repo *Repo) Evidence {
 	// Проверяем rust-toolchain.toml или rust-toolchain
-	if content, exists := remoteInfo.GetFileContent("rust-toolchain.toml"); exists {
+	if content, exists := repo.ReadFile("rust-toolchain.toml"); exists {
 		lines := strings.Split(content, "\n")
-		for _, line := range lines {
+		for i, line := range lines {
 			line = strings.TrimSpace(line)
 			if strings.Contains(line, "channel") {
 				// channel = "stable"
@@ -88,7 +50,7 @@ func detectRustVersion(remoteInfo *git.RemoteRepoInfo) string {
 					parts := strings.Split(line, "=")
 					if len(parts) > 1 {
 						version := strings.Trim(parts[1], " \"'")
-						return version
+						return lineEvidence("version", version, "rust-toolchain.toml", i+1, "channel in rust-toolchain.toml")
 					}
 				}
 			}
@@ -96,20 +58,20 @@ func detectRustVersion(remoteInfo *git.RemoteRepoInfo) string {
 	}
 
 	// Проверяем файл rust-toolchain (без расширения)
-	if content, exists := remoteInfo.GetFileContent("rust-toolchain"); exists {
+	if content, exists := repo.ReadFile("rust-toolchain"); exists {
 		lines := strings.Split(content, "\n")
-		for _, line := range lines {
+		for i, line := range lines {
 			line = strings.TrimSpace(line)
 			if line != "" && !strings.HasPrefix(line, "#") {
-				return line
+				return lineEvidence("version", line, "rust-toolchain", i+1, "toolchain in rust-toolchain")
 			}
 		}
 	}
 
 	// Проверяем Cargo.toml на наличие ограничений версии
-	if content, exists := remoteInfo.GetFileContent("Cargo.toml"); exists {
+	if content, exists := repo.ReadFile("Cargo.toml"); exists {
 		lines := strings.Split(content, "\n")
-		for _, line := range lines {
+		for i, line := range lines {
 			line = strings.TrimSpace(line)
 			if strings.Contains(line, "rust-version") {
 				// rust-version = "1.60"
@@ -117,200 +79,76 @@ func detectRustVersion(remoteInfo *git.RemoteRepoInfo) string {
 					parts := strings.Split(line, "=")
 					if len(parts) > 1 {
 						version := strings.Trim(parts[1], " \"'")
-						return version
+						return lineEvidence("version", version, "Cargo.toml", i+1, "rust-version in Cargo.toml")
 					}
 				}
 			}
 		}
 	}
 
-	return "stable" // версия по умолчанию
-}
-
-func detectRustVersionLocal(repoPath string) string {
-	// Проверяем rust-toolchain.toml
-	toolchainPath := filepath.Join(repoPath, "rust-toolchain.toml")
-	if exists(toolchainPath) {
-		content, err := os.ReadFile(toolchainPath)
-		if err == nil {
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if strings.Contains(line, "channel") {
-					if strings.Contains(line, "=") {
-						parts := strings.Split(line, "=")
-						if len(parts) > 1 {
-							version := strings.Trim(parts[1], " \"'")
-							return version
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Проверяем rust-toolchain
-	toolchainPath = filepath.Join(repoPath, "rust-toolchain")
-	if exists(toolchainPath) {
-		content, err := os.ReadFile(toolchainPath)
-		if err == nil {
-			lines := strings.Split(string(content), "\n")
-			for _, line := range lines {
-				line = strings.TrimSpace(line)
-				if line != "" && !strings.HasPrefix(line, "#") {
-					return line
-				}
-			}
-		}
-	}
-
-	return "stable"
-}
-
-func detectRustDependencies(remoteInfo *git.RemoteRepoInfo) []string {
-	deps := []string{}
-
-	if content, exists := remoteInfo.GetFileContent("Cargo.toml"); exists {
-		// Определяем тип проекта
-		if strings.Contains(content, "[lib]") {
-			deps = append(deps, "type:library")
-		} else {
-			deps = append(deps, "type:binary")
-		}
-
-		// Популярные крейты и фреймворки
-		if strings.Contains(content, "tokio") {
-			deps = append(deps, "runtime:tokio")
-		}
-		if strings.Contains(content, "async-std") {
-			deps = append(deps, "runtime:async-std")
-		}
-		if strings.Contains(content, "serde") {
-			deps = append(deps, "serialization:serde")
-		}
-		if strings.Contains(content, "actix-web") {
-			deps = append(deps, "framework:actix-web")
-		}
-		if strings.Contains(content, "rocket") {
-			deps = append(deps, "framework:rocket")
-		}
-		if strings.Contains(content, "warp") {
-			deps = append(deps, "framework:warp")
-		}
-		if strings.Contains(content, "diesel") {
-			deps = append(deps, "orm:diesel")
-		}
-		if strings.Contains(content, "sqlx") {
-			deps = append(deps, "database:sqlx")
-		}
-		if strings.Contains(content, "clap") || strings.Contains(content, "structopt") {
-			deps = append(deps, "cli")
-		}
-		if strings.Contains(content, "reqwest") {
-			deps = append(deps, "http-client")
-		}
-		if strings.Contains(content, "hyper") {
-			deps = append(deps, "http")
-		}
-	}
-
-	return deps
+	return defaultEvidence("version", "stable")
 }
 
-func detectRustDependenciesLocal(repoPath string) []string {
-	deps := []string{}
-
-	cargoPath := filepath.Join(repoPath, "Cargo.toml")
-	if exists(cargoPath) {
-		content, err := os.ReadFile(cargoPath)
-		if err == nil {
-			text := string(content)
-			if strings.Contains(text, "[lib]") {
-				deps = append(deps, "type:library")
-			} else {
-				deps = append(deps, "type:binary")
-			}
-
-			if strings.Contains(text, "tokio") {
-				deps = append(deps, "runtime:tokio")
-			}
-			if strings.Contains(text, "actix-web") {
-				deps = append(deps, "framework:actix-web")
-			}
-			if strings.Contains(text, "diesel") {
-				deps = append(deps, "orm:diesel")
-			}
-		}
-	}
-
-	return deps
+func detectRustDependencies(repo *Repo) []Evidence {
+	content, exists := repo.ReadFile("Cargo.toml")
+	if !exists {
+		return nil
+	}
+
+	// Определяем тип проекта
+	deps := []Evidence{{Field: "dependencies", Value: "type:binary", File: "Cargo.toml",
+		Rule: "no [lib] section in Cargo.toml", Confidence: ConfidenceMedium}}
+	if strings.Contains(content, "[lib]") {
+		deps[0] = matchEvidence("dependencies", "type:library", "Cargo.toml", content, "[lib]")
+	}
+
+	// Популярные крейты и фреймворки
+	return append(deps, matchTokens("dependencies", "Cargo.toml", content, []tokenRule{
+		{"runtime:tokio", []string{"tokio"}},
+		{"runtime:async-std", []string{"async-std"}},
+		{"serialization:serde", []string{"serde"}},
+		{"framework:actix-web", []string{"actix-web"}},
+		{"framework:rocket", []string{"rocket"}},
+		{"framework:warp", []string{"warp"}},
+		{"orm:diesel", []string{"diesel"}},
+		{"database:sqlx", []string{"sqlx"}},
+		{"cli", []string{"clap", "structopt"}},
+		{"http-client", []string{"reqwest"}},
+		{"http", []string{"hyper"}},
+	})...)
 }
 
-func detectRustTestsFromMemory(remoteInfo *git.RemoteRepoInfo) bool {
+func detectRustTests(repo *Repo) Evidence {
 	// Ищем тестовые файлы в Rust проектах
-	for _, file := range remoteInfo.Structure {
+	for _, file := range repo.Files() {
 		if strings.HasSuffix(file, ".rs") {
 			// Проверяем наличие тестов в исходных файлах
-			if content, exists := remoteInfo.GetFileContent(file); exists {
-				if strings.Contains(content, "#[test]") ||
-					strings.Contains(content, "#[cfg(test)]") ||
-					strings.Contains(content, "#[tokio::test]") {
-					return true
+			if content, exists := repo.ReadFile(file); exists {
+				for _, attr := range []string{"#[test]", "#[cfg(test)]", "#[tokio::test]"} {
+					if strings.Contains(content, attr) {
+						return lineEvidence("has_tests", "true", file, lineOf(content, attr), attr+" attribute")
+					}
 				}
 			}
 		}
 	}
 
 	// Проверяем наличие тестов в Cargo.toml
-	if content, exists := remoteInfo.GetFileContent("Cargo.toml"); exists {
+	if content, exists := repo.ReadFile("Cargo.toml"); exists {
 		if strings.Contains(content, "dev-dependencies") {
-			return true
+			return matchEvidence("has_tests", "true", "Cargo.toml", content, "dev-dependencies")
 		}
 	}
 
-	return false
-}
-
-func detectRustTestsLocal(repoPath string) bool {
-	// Ищем тесты в исходных файлах
-	patterns := []string{
-		filepath.Join(repoPath, "**/*.rs"),
-	}
-
-	for _, pattern := range patterns {
-		matches, _ := filepath.Glob(pattern)
-		for _, match := range matches {
-			content, err := os.ReadFile(match)
-			if err == nil {
-				text := string(content)
-				if strings.Contains(text, "#[test]") ||
-					strings.Contains(text, "#[cfg(test)]") {
-					return true
-				}
-			}
-		}
-	}
-
-	// Проверяем dev-dependencies в Cargo.toml
-	cargoPath := filepath.Join(repoPath, "Cargo.toml")
-	if exists(cargoPath) {
-		content, err := os.ReadFile(cargoPath)
-		if err == nil {
-			if strings.Contains(string(content), "dev-dependencies") {
-				return true
-			}
-		}
-	}
-
-	return false
+	return noTestsEvidence()
 }
 
 // Функции для обнаружения крейтов (workspace members)
-func detectRustCratesFromMemory(remoteInfo *git.RemoteRepoInfo) []string {
+func detectRustCrates(repo *Repo) []string {
 	crates := []string{}
 
 	// Проверяем Cargo.toml на наличие workspace
-	if content, exists := remoteInfo.GetFileContent("Cargo.toml"); exists {
+	if content, exists := repo.ReadFile("Cargo.toml"); exists {
 		if strings.Contains(content, "[workspace]") {
 			lines := strings.Split(content, "\n")
 			inMembersSection := false
@@ -339,51 +177,9 @@ func detectRustCratesFromMemory(remoteInfo *git.RemoteRepoInfo) []string {
 	}
 
 	// Если это не workspace, добавляем корневой крейт
-	if len(crates) == 0 && remoteInfo.HasFile("Cargo.toml") {
+	if len(crates) == 0 && repo.HasFile("Cargo.toml") {
 		crates = append(crates, ".")
 	}
 
 	return crates
 }
-
-func detectRustCratesLocal(repoPath string) []string {
-	crates := []string{}
-
-	cargoPath := filepath.Join(repoPath, "Cargo.toml")
-	if exists(cargoPath) {
-		content, err := os.ReadFile(cargoPath)
-		if err == nil {
-			text := string(content)
-			if strings.Contains(text, "[workspace]") {
-				lines := strings.Split(text, "\n")
-				inMembersSection := false
-				for _, line := range lines {
-					line = strings.TrimSpace(line)
-					if strings.Contains(line, "members") {
-						inMembersSection = true
-						continue
-					}
-					if inMembersSection {
-						if strings.Contains(line, "[") {
-							break
-						}
-						if strings.Contains(line, "\"") {
-							parts := strings.Split(line, "\"")
-							for i := 1; i < len(parts); i += 2 {
-								if i < len(parts) && parts[i] != "" {
-									crates = append(crates, parts[i])
-								}
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	if len(crates) == 0 && exists(cargoPath) {
-		crates = append(crates, ".")
-	}
-
-	return crates
-}
\ No newline at end of file
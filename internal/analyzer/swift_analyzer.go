@@ -1,117 +1,60 @@
 package analyzer
 
 import (
-    "os"
-    "path/filepath"
     "strings"
-
-    "github.com/immxrtalbeast/pipeline-gen/internal/git"
 )
 
-func analyzeSwiftProjectFromMemory(remoteInfo *git.RemoteRepoInfo, info *ProjectInfo) {
-    info.BuildTool = detectSwiftBuildTool(remoteInfo)
-    info.TestFramework = detectSwiftTestFramework(remoteInfo)
-    info.Version = detectSwiftVersion(remoteInfo)
-    info.Dependencies = detectSwiftDependencies(remoteInfo)
-    info.HasTests = detectSwiftTestsFromMemory(remoteInfo)
-    info.PackageManager = "spm" // Swift Package Manager по умолчанию
-}
-
-func analyzeSwiftProject(repoPath string, info *ProjectInfo) error {
-    info.BuildTool = detectSwiftBuildToolLocal(repoPath)
-    info.TestFramework = detectSwiftTestFrameworkLocal(repoPath)
-    info.Version = detectSwiftVersionLocal(repoPath)
-    info.Dependencies = detectSwiftDependenciesLocal(repoPath)
-    info.HasTests = detectSwiftTestsLocal(repoPath)
-    info.PackageManager = "spm"
+func analyzeSwiftProject(repo *Repo, info *ProjectInfo) error {
+    info.setWithEvidence(detectSwiftBuildTool(repo))
+    info.setWithEvidence(detectSwiftTestFramework(repo))
+    info.setWithEvidence(detectSwiftVersion(repo))
+    info.setDependencies(detectSwiftDependencies(repo))
+    info.setWithEvidence(detectSwiftTests(repo))
+    info.setWithEvidence(defaultEvidence("package_manager", "spm")) // Swift Package Manager по умолчанию
     return nil
 }
 
 // Вспомогательные функции для анализа Swift
-func detectSwiftBuildTool(remoteInfo *git.RemoteRepoInfo) string {
-    if remoteInfo.HasFile("Package.swift") {
-        return "swift-package-manager"
-    }
-    if remoteInfo.HasFile(".xcodeproj") || remoteInfo.HasFile(".xcworkspace") {
-        return "xcodebuild"
-    }
-    return "unknown"
-}
-
-func detectSwiftBuildToolLocal(repoPath string) string {
-    if exists(filepath.Join(repoPath, "Package.swift")) {
-        return "swift-package-manager"
-    }
-    
-    // Проверяем наличие Xcode проектов
-    matches, _ := filepath.Glob(filepath.Join(repoPath, "*.xcodeproj"))
-    if len(matches) > 0 {
-        return "xcodebuild"
+func detectSwiftBuildTool(repo *Repo) Evidence {
+    if repo.HasFile("Package.swift") {
+        return fileEvidence("build_tool", "swift-package-manager", "Package.swift")
     }
-    
-    matches, _ = filepath.Glob(filepath.Join(repoPath, "*.xcworkspace"))
-    if len(matches) > 0 {
-        return "xcodebuild"
+    for _, pattern := range []string{"*.xcodeproj/*", "*.xcworkspace/*"} {
+        if matches := repo.Glob(pattern); len(matches) > 0 {
+            return fileEvidence("build_tool", "xcodebuild", matches[0])
+        }
     }
-    
-    return "unknown"
+    return defaultEvidence("build_tool", "unknown")
 }
 
-func detectSwiftTestFramework(remoteInfo *git.RemoteRepoInfo) string {
+func detectSwiftTestFramework(repo *Repo) Evidence {
     // Swift использует XCTest по умолчанию
-    if content, exists := remoteInfo.GetFileContent("Package.swift"); exists {
-        if strings.Contains(content, "XCTest") || strings.Contains(content, "testTarget") {
-            return "xctest"
+    if content, exists := repo.ReadFile("Package.swift"); exists {
+        found := matchTokens("test_framework", "Package.swift", content, []tokenRule{
+            {"xctest", []string{"XCTest", "testTarget"}},
+        })
+        if len(found) > 0 {
+            return found[0]
         }
     }
     
     // Проверяем наличие тестовых файлов
-    for _, file := range remoteInfo.Structure {
+    for _, file := range repo.Files() {
         if strings.Contains(file, "Test.swift") || 
            strings.Contains(file, "Tests.swift") || 
            strings.Contains(file, "/Tests/") {
-            return "xctest"
-        }
-    }
-    
-    return "xctest" // по умолчанию
-}
-
-func detectSwiftTestFrameworkLocal(repoPath string) string {
-    // Проверяем Package.swift
-    packagePath := filepath.Join(repoPath, "Package.swift")
-    if exists(packagePath) {
-        content, err := os.ReadFile(packagePath)
-        if err == nil {
-            text := string(content)
-            if strings.Contains(text, "XCTest") || strings.Contains(text, "testTarget") {
-                return "xctest"
-            }
-        }
-    }
-    
-    // Ищем тестовые файлы
-    patterns := []string{
-        filepath.Join(repoPath, "**/*Test.swift"),
-        filepath.Join(repoPath, "**/*Tests.swift"),
-        filepath.Join(repoPath, "**/Tests/**/*.swift"),
-    }
-    
-    for _, pattern := range patterns {
-        matches, _ := filepath.Glob(pattern)
-        if len(matches) > 0 {
-            return "xctest"
+            return Evidence{Field: "test_framework", Value: "xctest", File: file, Rule: "test file", Confidence: ConfidenceMedium}
         }
     }
     
-    return "xctest"
+    return defaultEvidence("test_framework", "xctest")
 }
 
-func detectSwiftVersion(remoteInfo *git.RemoteRepoInfo) string {
+func detectSwiftVersion(repo *Repo) Evidence {
     // Анализируем версию Swift из Package.swift
-    if content, exists := remoteInfo.GetFileContent("Package.swift"); exists {
+    if content, exists := repo.ReadFile("Package.swift"); exists {
         lines := strings.Split(content, "\n")
-        for _, line := range lines {
+        for i, line := range lines {
             line = strings.TrimSpace(line)
             if strings.Contains(line, "swift-tools-version:") {
                 // Пример: // swift-tools-version:5.7
@@ -122,153 +65,58 @@ func detectSwiftVersion(remoteInfo *git.RemoteRepoInfo) string {
                     if commentIndex := strings.Index(version, "//"); commentIndex != -1 {
                         version = strings.TrimSpace(version[:commentIndex])
                     }
-                    return version
-                }
-            }
-        }
-    }
-    
-    return "5.7" // версия по умолчанию
-}
-
-func detectSwiftVersionLocal(repoPath string) string {
-    packagePath := filepath.Join(repoPath, "Package.swift")
-    if exists(packagePath) {
-        content, err := os.ReadFile(packagePath)
-        if err == nil {
-            lines := strings.Split(string(content), "\n")
-            for _, line := range lines {
-                line = strings.TrimSpace(line)
-                if strings.Contains(line, "swift-tools-version:") {
-                    parts := strings.Split(line, "swift-tools-version:")
-                    if len(parts) > 1 {
-                        version := strings.TrimSpace(parts[1])
-                        if commentIndex := strings.Index(version, "//"); commentIndex != -1 {
-                            version = strings.TrimSpace(version[:commentIndex])
-                        }
-                        return version
-                    }
+                    return lineEvidence("version", version, "Package.swift", i+1, "swift-tools-version in Package.swift")
                 }
             }
         }
     }
     
-    return "5.7"
+    return defaultEvidence("version", "5.7")
 }
 
-func detectSwiftDependencies(remoteInfo *git.RemoteRepoInfo) []string {
-    deps := []string{}
-    
+func detectSwiftDependencies(repo *Repo) []Evidence {
     // Анализируем Package.swift на наличие популярных зависимостей
-    if content, exists := remoteInfo.GetFileContent("Package.swift"); exists {
-        text := strings.ToLower(content)
-        
+    content, exists := repo.ReadFile("Package.swift")
+    if !exists {
+        return nil
+    }
+    return matchTokens("dependencies", "Package.swift", strings.ToLower(content), []tokenRule{
         // Фреймворки
-        if strings.Contains(text, "vapor") {
-            deps = append(deps, "framework:vapor")
-        }
-        if strings.Contains(text, "perfect") {
-            deps = append(deps, "framework:perfect")
-        }
-        if strings.Contains(text, "kitura") {
-            deps = append(deps, "framework:kitura")
-        }
-        
+        {"framework:vapor", []string{"vapor"}},
+        {"framework:perfect", []string{"perfect"}},
+        {"framework:kitura", []string{"kitura"}},
+
         // Базы данных
-        if strings.Contains(text, "fluent") || strings.Contains(text, "sqlite") || 
-           strings.Contains(text, "postgres") || strings.Contains(text, "mongodb") {
-            deps = append(deps, "database")
-        }
-        
+        {"database", []string{"fluent", "sqlite", "postgres", "mongodb"}},
+
         // UI фреймворки (для iOS/macOS)
-        if strings.Contains(text, "swiftui") || strings.Contains(text, "uikit") || 
-           strings.Contains(text, "appkit") {
-            deps = append(deps, "ui-framework")
-        }
-        
-        // Сетевые библиотеки
-        if strings.Contains(text, "alamofire") || strings.Contains(text, "urlsession") {
-            deps = append(deps, "networking")
-        }
-    }
-    
-    return deps
-}
+        {"ui-framework", []string{"swiftui", "uikit", "appkit"}},
 
-func detectSwiftDependenciesLocal(repoPath string) []string {
-    deps := []string{}
-    
-    packagePath := filepath.Join(repoPath, "Package.swift")
-    if exists(packagePath) {
-        content, err := os.ReadFile(packagePath)
-        if err == nil {
-            text := strings.ToLower(string(content))
-            
-            if strings.Contains(text, "vapor") {
-                deps = append(deps, "framework:vapor")
-            }
-            if strings.Contains(text, "perfect") {
-                deps = append(deps, "framework:perfect")
-            }
-            if strings.Contains(text, "fluent") || strings.Contains(text, "sqlite") {
-                deps = append(deps, "database")
-            }
-            if strings.Contains(text, "swiftui") || strings.Contains(text, "uikit") {
-                deps = append(deps, "ui-framework")
-            }
-        }
-    }
-    
-    return deps
+        // Сетевые библиотеки
+        {"networking", []string{"alamofire", "urlsession"}},
+    })
 }
 
-func detectSwiftTestsFromMemory(remoteInfo *git.RemoteRepoInfo) bool {
+func detectSwiftTests(repo *Repo) Evidence {
     // Ищем тестовые файлы в Swift проектах
-    for _, file := range remoteInfo.Structure {
+    for _, file := range repo.Files() {
         if strings.HasSuffix(file, "Test.swift") ||
            strings.HasSuffix(file, "Tests.swift") ||
            strings.Contains(file, "/Tests/") ||
            strings.Contains(file, "/test/") && strings.HasSuffix(file, ".swift") {
-            return true
+            return testFileEvidence(file)
         }
     }
     
     // Проверяем наличие тестовой цели в Package.swift
-    if content, exists := remoteInfo.GetFileContent("Package.swift"); exists {
-        if strings.Contains(content, "testTarget") || strings.Contains(content, ".testTarget") {
-            return true
+    if content, exists := repo.ReadFile("Package.swift"); exists {
+        found := matchTokens("has_tests", "Package.swift", content, []tokenRule{
+            {"true", []string{"testTarget"}},
+        })
+        if len(found) > 0 {
+            return found[0]
         }
     }
     
-    return false
+    return noTestsEvidence()
 }
-
-func detectSwiftTestsLocal(repoPath string) bool {
-    // Ищем тесты в локальной директории
-    patterns := []string{
-        filepath.Join(repoPath, "**/*Test.swift"),
-        filepath.Join(repoPath, "**/*Tests.swift"),
-        filepath.Join(repoPath, "**/Tests/**/*.swift"),
-        filepath.Join(repoPath, "**/test/**/*.swift"),
-    }
-    
-    for _, pattern := range patterns {
-        matches, _ := filepath.Glob(pattern)
-        if len(matches) > 0 {
-            return true
-        }
-    }
-    
-    // Проверяем Package.swift на наличие тестовой цели
-    packagePath := filepath.Join(repoPath, "Package.swift")
-    if exists(packagePath) {
-        content, err := os.ReadFile(packagePath)
-        if err == nil {
-            if strings.Contains(string(content), "testTarget") {
-                return true
-            }
-        }
-    }
-    
-    return false
-}
\ No newline at end of file
@@ -0,0 +1,119 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// buildComponents строит общий pipeline полиглот-репозитория: по группе
+// джобов на каждый компонент.
+func buildComponents(info *analyzer.ProjectInfo, format string) (*pipeline.Pipeline, error) {
+	var merged *pipeline.Pipeline
+	for _, c := range info.Components {
+		g, err := lookupGenerator(c.Info.Language, format)
+		if err != nil {
+			return nil, fmt.Errorf("component %s: %w", c.Root, err)
+		}
+		builder, ok := g.(PipelineBuilder)
+		if !ok {
+			return nil, fmt.Errorf("component %s: %s generator for %s cannot be combined with other components", c.Root, format, c.Info.Language)
+		}
+		p := builder.Build(c.Info)
+		if merged == nil {
+			merged = &pipeline.Pipeline{Name: "CI/CD Pipeline", Branches: p.Branches}
+		}
+		addComponent(merged, p, c)
+	}
+	if merged == nil {
+		return nil, fmt.Errorf("no components to generate")
+	}
+	return merged, nil
+}
+
+// addComponent переносит джобы pipeline компонента в общий pipeline. Джобы
+// компонентов из подкаталогов получают префикс и рабочий каталог, а пути
+// артефактов и кеша пересчитываются от корня репозитория. Дополнительные
+// языки корня получают префикс по имени языка.
+func addComponent(dst, src *pipeline.Pipeline, c analyzer.Component) {
+	dst.Stages = mergeStages(dst.Stages, src.Stages)
+
+	dir, prefix := "", ""
+	switch {
+	case c.Root != ".":
+		dir = c.Root
+		prefix = strings.ReplaceAll(c.Root, "/", "-") + "-"
+	case c.Name != "root":
+		prefix = c.Name + "-"
+	}
+	for _, job := range src.Jobs {
+		if prefix != "" {
+			job.Name = c.Name + ": " + job.Title()
+			job.ID = prefix + job.ID
+			for i, need := range job.Needs {
+				job.Needs[i] = prefix + need
+			}
+		}
+		job.Dir = dir
+		// Переменные и кеш уровня pipeline у каждого компонента свои
+		job.Env = append(append([]pipeline.Var{}, src.Env...), job.Env...)
+		if job.Cache == nil && src.Cache != nil {
+			cache := *src.Cache
+			job.Cache = &cache
+		}
+		if job.Cache != nil {
+			job.Cache.Paths = joinPaths(dir, job.Cache.Paths)
+			job.Cache.KeyFiles = joinPaths(dir, job.Cache.KeyFiles)
+		}
+		for i, step := range job.Steps {
+			if step.Kind == pipeline.KindUpload || step.Kind == pipeline.KindCoverage {
+				job.Steps[i].Paths = joinPaths(dir, step.Paths)
+				if step.Artifact != "" {
+					job.Steps[i].Artifact = prefix + step.Artifact
+				}
+			}
+		}
+		dst.AddJob(job)
+	}
+}
+
+// mergeStages добавляет новые стадии перед первой общей стадией, которая
+// идет за ними, чтобы сохранить порядок обоих списков.
+func mergeStages(dst, src []string) []string {
+	for i, stage := range src {
+		if indexOf(dst, stage) >= 0 {
+			continue
+		}
+		at := len(dst)
+		for _, next := range src[i+1:] {
+			if j := indexOf(dst, next); j >= 0 {
+				at = j
+				break
+			}
+		}
+		dst = append(dst[:at], append([]string{stage}, dst[at:]...)...)
+	}
+	return dst
+}
+
+func indexOf(values []string, value string) int {
+	for i, v := range values {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func joinPaths(dir string, paths []string) []string {
+	if dir == "" {
+		return paths
+	}
+	joined := make([]string, len(paths))
+	for i, p := range paths {
+		joined[i] = dir + "/" + p
+	}
+	return joined
+}
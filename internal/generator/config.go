@@ -0,0 +1,37 @@
+package generator
+
+import (
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// applyConfig применяет к построенному pipeline настройки из .pipeline-gen.yaml:
+// ветки, образы джобов и набор стадий.
+func applyConfig(p *pipeline.Pipeline, cfg *analyzer.Config) {
+	if cfg == nil {
+		return
+	}
+	if len(cfg.Branches) > 0 {
+		p.Branches = cfg.Branches
+	}
+
+	var stages []string
+	for _, stage := range p.Stages {
+		if cfg.StageEnabled(stage) {
+			stages = append(stages, stage)
+		}
+	}
+	p.Stages = stages
+	p.RemoveJobs(func(job *pipeline.Job) bool {
+		return !cfg.StageEnabled(job.Stage)
+	})
+
+	// Образ "default" не трогает матричные джобы: их образ зависит от версии в матрице.
+	for _, job := range p.Jobs {
+		if image, ok := cfg.Images[job.ID]; ok {
+			job.Image = image
+		} else if image, ok := cfg.Images["default"]; ok && len(job.Matrix) == 0 {
+			job.Image = image
+		}
+	}
+}
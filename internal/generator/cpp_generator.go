@@ -5,276 +5,143 @@ import (
 	"strings"
 
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateCppPipeline(info *analyzer.ProjectInfo) string {
-	var pipeline strings.Builder
-
+func buildCppPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
 	buildTool := info.BuildTool
-	if buildTool == "unknown" {
+	if buildTool == "unknown" || buildTool == "" {
 		buildTool = "make" // значение по умолчанию
 	}
+	const image = "ubuntu:latest"
+	toolchain := []string{"build-essential", "cmake", "git"}
+	if buildTool == "meson" {
+		toolchain = append(toolchain, "meson", "ninja-build")
+	}
+	if buildTool == "autotools" {
+		toolchain = append(toolchain, "autoconf", "automake", "libtool")
+	}
 
-	pipeline.WriteString(fmt.Sprintf(`name: C++ CI/CD Pipeline (%s)
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
-
-jobs:
-`, strings.Title(buildTool)))
-
-	// Job для установки зависимостей и конфигурации
-	pipeline.WriteString(`  setup:
-    runs-on: ${{ matrix.os }}
-    strategy:
-      matrix:
-        os: [ubuntu-latest, windows-latest, macos-latest]
-        build_type: [Debug, Release]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install dependencies
-      run: |
-        sudo apt-get update
-        sudo apt-get install -y cmake g++ make
-      if: matrix.os == 'ubuntu-latest'
-`)
-
-	// Добавляем специфичные для build tool шаги
-	switch buildTool {
-	case "cmake":
-		pipeline.WriteString(`    - name: Configure CMake
-      run: cmake -B ${{github.workspace}}/build -DCMAKE_BUILD_TYPE=${{ matrix.build_type }}
-
-    - name: Build
-      run: cmake --build ${{github.workspace}}/build --config ${{ matrix.build_type }}
-`)
-	case "make":
-		pipeline.WriteString(`    - name: Configure
-      run: ./configure
-      if: matrix.os == 'ubuntu-latest'
-
-    - name: Build
-      run: make -j$(nproc)
-`)
-	case "autotools":
-		pipeline.WriteString(`    - name: Autogen
-      run: ./autogen.sh
-      if: exists('autogen.sh')
-
-    - name: Configure
-      run: ./configure
-
-    - name: Build
-      run: make -j$(nproc)
-`)
-	case "meson":
-		pipeline.WriteString(`    - name: Setup Meson
-      run: pip install meson ninja
-
-    - name: Configure
-      run: meson setup build
-
-    - name: Build
-      run: meson compile -C build
-`)
+	p := &pipeline.Pipeline{
+		Name:     fmt.Sprintf("C++ CI/CD Pipeline (%s)", strings.Title(buildTool)),
+		Branches: []string{"main", "master", "develop"},
+		Stages:   []string{"build", "test", "analysis", "release", "deploy"},
+		Env: []pipeline.Var{
+			{Name: "CPP_STANDARD", Value: getCppStandard(info.Version)},
+			{Name: "BUILD_TOOL", Value: buildTool},
+		},
 	}
 
+	// Job для конфигурации и сборки
+	build := p.AddJob(&pipeline.Job{ID: "build", Stage: "build", Image: image})
+	build.Steps = append(build.Steps, pipeline.Checkout(), pipeline.Packages(toolchain...))
+	build.Steps = append(build.Steps, cppBuildSteps(buildTool, "Debug")...)
+
 	// Job для тестирования
+	previousJob := "build"
 	if info.HasTests {
-		pipeline.WriteString(fmt.Sprintf(`  test:
-    runs-on: ${{ matrix.os }}
-    needs: setup
-    strategy:
-      matrix:
-        os: [ubuntu-latest, windows-latest]
-        build_type: [Debug]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install dependencies
-      run: |
-        sudo apt-get update
-        sudo apt-get install -y cmake g++ make %s
-      if: matrix.os == 'ubuntu-latest'
-`, getTestDependencies(info.TestFramework)))
-
-		// Конфигурация для тестов
+		previousJob = "test"
+		packages := append(append([]string{}, toolchain...), strings.Fields(getTestDependencies(info.TestFramework))...)
+		test := p.AddJob(&pipeline.Job{ID: "test", Stage: "test", Image: image, Needs: []string{"build"}})
+		test.Steps = append(test.Steps, pipeline.Checkout(), pipeline.Packages(packages...))
 		switch buildTool {
 		case "cmake":
-			pipeline.WriteString(`    - name: Configure with tests
-      run: cmake -B build -DCMAKE_BUILD_TYPE=${{ matrix.build_type }} -DBUILD_TESTING=ON
-
-    - name: Build tests
-      run: cmake --build build --target all test
-
-    - name: Run tests
-      run: ctest --test-dir build --output-on-failure
-`)
+			test.Steps = append(test.Steps,
+				pipeline.Run("Configure with tests", "cmake -B build -DCMAKE_BUILD_TYPE=Debug -DBUILD_TESTING=ON"),
+				pipeline.Run("Build tests", "cmake --build build"),
+				pipeline.Run("Run tests", "ctest --test-dir build --output-on-failure"),
+			)
+		case "meson":
+			test.Steps = append(test.Steps,
+				pipeline.Run("Configure", "meson setup build"),
+				pipeline.Run("Run tests", "meson test -C build"),
+			)
 		default:
-			pipeline.WriteString(`    - name: Build and run tests
-      run: |
-        make -j$(nproc)
-        make test
-`)
-		}
-
-		// Для конкретных фреймворков тестирования
-		switch info.TestFramework {
-		case "gtest":
-			pipeline.WriteString(`    - name: Install Google Test
-      run: sudo apt-get install -y libgtest-dev libgmock-dev
-      if: matrix.os == 'ubuntu-latest'
-`)
-		case "catch2":
-			pipeline.WriteString(`    - name: Install Catch2
-      run: sudo apt-get install -y catch2
-      if: matrix.os == 'ubuntu-latest'
-`)
+			test.Steps = append(test.Steps, pipeline.Run("Build and run tests", "make -j$(nproc)\nmake test"))
 		}
 	}
 
 	// Job для статического анализа
-	pipeline.WriteString(`  static-analysis:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install analysis tools
-      run: |
-        sudo apt-get update
-        sudo apt-get install -y clang-tidy cppcheck
-    - name: Run cppcheck
-      run: cppcheck --enable=all --inconclusive --std=c++${{ env.CPP_STANDARD }} src/ include/
-    - name: Run clang-tidy
-      run: |
-        find src/ include/ -name '*.cpp' -o -name '*.h' | xargs clang-tidy -p build/
-      if: env.BUILD_TOOL == 'cmake'
-`)
+	analysis := p.AddJob(&pipeline.Job{ID: "static-analysis", Stage: "analysis", Image: image, AllowFailure: true})
+	analysis.Steps = append(analysis.Steps,
+		pipeline.Checkout(),
+		pipeline.Packages("cppcheck", "clang-tidy"),
+		pipeline.Run("Run cppcheck", "cppcheck --enable=all --inconclusive --std=c++$CPP_STANDARD src/ include/"),
+	)
 
-	// Job для сборки релиза
-	previousJob := "setup"
-	if info.HasTests {
-		previousJob = "test"
-	}
-
-	pipeline.WriteString(fmt.Sprintf(`  build-release:
-    runs-on: ${{ matrix.os }}
-    needs: %s
-    strategy:
-      matrix:
-        os: [ubuntu-latest, windows-latest, macos-latest]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install compiler
-      run: |
-        sudo apt-get update
-        sudo apt-get install -y cmake g++-11
-      if: matrix.os == 'ubuntu-latest'
-`, previousJob))
-
-	// Сборка релиза в зависимости от build tool
-	switch buildTool {
-	case "cmake":
-		pipeline.WriteString(`    - name: Configure Release
-      run: cmake -B build -DCMAKE_BUILD_TYPE=Release -DCMAKE_CXX_COMPILER=g++-11
-
-    - name: Build Release
-      run: cmake --build build --config Release --parallel
+	// Job для проверки формата кода
+	format := p.AddJob(&pipeline.Job{ID: "format", Stage: "analysis", Image: image, AllowFailure: true})
+	format.Steps = append(format.Steps,
+		pipeline.Checkout(),
+		pipeline.Packages("clang-format"),
+		pipeline.Run("Check code format", `find src/ include/ -name '*.cpp' -o -name '*.h' | xargs clang-format --dry-run -Werror`),
+	)
 
-    - name: Run benchmarks
-      run: ./build/benchmarks/benchmark_runner
-      if: exists('benchmarks')
-`)
-	case "make":
-		pipeline.WriteString(`    - name: Build Release
-      run: make -j$(nproc) CXX=g++-11 CXXFLAGS="-O3 -DNDEBUG"
-`)
+	// Job для проверки покрытия (если включено)
+	if info.HasTests && buildTool == "cmake" {
+		coverage := p.AddJob(&pipeline.Job{ID: "coverage", Stage: "analysis", Image: image, Needs: []string{"test"}})
+		coverage.Steps = append(coverage.Steps,
+			pipeline.Checkout(),
+			pipeline.Packages(append(append([]string{}, toolchain...), "gcovr")...),
+			pipeline.Run("Build with coverage", `cmake -B build -DCMAKE_BUILD_TYPE=Debug -DBUILD_TESTING=ON -DCMAKE_CXX_FLAGS="--coverage"
+cmake --build build`),
+			pipeline.Run("Run tests with coverage", "ctest --test-dir build\ngcovr --root . --xml-pretty --output coverage.xml"),
+			pipeline.CoverageReport("cobertura", "coverage.xml"),
+		)
 	}
 
-	pipeline.WriteString(`    - name: Upload artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: cpp-${{ matrix.os }}-release
-        path: |
-          build/**
-          *.exe
-          *.dll
-          *.so
-          *.dylib
-`)
-
-	// Job для проверки формата кода
-	pipeline.WriteString(`  format:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install clang-format
-      run: sudo apt-get install -y clang-format
-    - name: Check code format
-      run: |
-        find src/ include/ -name '*.cpp' -o -name '*.h' | xargs clang-format -i --dry-run -Werror
-`)
+	// Job для сборки релиза
+	release := p.AddJob(&pipeline.Job{ID: "build-release", Stage: "release", Image: image, Needs: []string{previousJob}})
+	release.Steps = append(release.Steps, pipeline.Checkout(), pipeline.Packages(toolchain...))
+	release.Steps = append(release.Steps, cppBuildSteps(buildTool, "Release")...)
+	release.Steps = append(release.Steps, pipeline.Upload("cpp-release", "build/"))
 
 	// Job для сборки документации (Doxygen)
-	pipeline.WriteString(`  docs:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Doxygen
-      run: sudo apt-get install -y doxygen graphviz
-    - name: Generate documentation
-      run: doxygen Doxyfile
-      if: exists('Doxyfile')
-    - name: Upload documentation
-      uses: actions/upload-artifact@v3
-      with:
-        name: cpp-docs
-        path: docs/
-`)
-
-	// Job для проверки покрытия (если включено)
-	if containsDependency(info.Dependencies, "coverage") || info.HasTests {
-		pipeline.WriteString(`  coverage:
-    runs-on: ubuntu-latest
-    needs: test
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install coverage tools
-      run: sudo apt-get install -y gcovr lcov
-    - name: Build with coverage
-      run: |
-        cmake -B build -DCMAKE_BUILD_TYPE=Debug -DCMAKE_CXX_FLAGS="--coverage"
-        cmake --build build
-    - name: Run tests with coverage
-      run: |
-        ./build/tests/unit_tests
-        gcovr --root . --xml-pretty --output coverage.xml
-    - name: Upload coverage
-      uses: codecov/codecov-action@v3
-      with:
-        file: coverage.xml
-`)
-	}
+	docs := p.AddJob(&pipeline.Job{ID: "docs", Stage: "release", Image: image, AllowFailure: true})
+	docs.Steps = append(docs.Steps,
+		pipeline.Checkout(),
+		pipeline.Packages("doxygen", "graphviz"),
+		pipeline.Run("Generate documentation", "if [ -f Doxyfile ]; then doxygen Doxyfile; fi"),
+		pipeline.Upload("cpp-docs", "docs/"),
+	)
 
 	// Job для кросс-компиляции (если нужно)
 	if containsDependency(info.Dependencies, "embedded") || containsDependency(info.Dependencies, "cross-platform") {
-		pipeline.WriteString(`  cross-compile:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install cross-compiler
-      run: |
-        sudo apt-get update
-        sudo apt-get install -y gcc-arm-linux-gnueabihf g++-arm-linux-gnueabihf
-    - name: Cross-compile for ARM
-      run: |
-        cmake -B build-arm -DCMAKE_TOOLCHAIN_FILE=toolchain-arm.cmake
-        cmake --build build-arm
-`)
+		cross := p.AddJob(&pipeline.Job{ID: "cross-compile", Stage: "release", Image: image, Needs: []string{previousJob}})
+		cross.Steps = append(cross.Steps,
+			pipeline.Checkout(),
+			pipeline.Packages("cmake", "gcc-arm-linux-gnueabihf", "g++-arm-linux-gnueabihf"),
+			pipeline.Run("Cross-compile for ARM", "cmake -B build-arm -DCMAKE_TOOLCHAIN_FILE=toolchain-arm.cmake\ncmake --build build-arm"),
+		)
 	}
 
-	return pipeline.String()
+	return p
+}
+
+// cppBuildSteps возвращает шаги конфигурации и сборки для билд-тула.
+func cppBuildSteps(buildTool, buildType string) []pipeline.Step {
+	switch buildTool {
+	case "cmake":
+		return []pipeline.Step{
+			pipeline.Run("Configure CMake", "cmake -B build -DCMAKE_BUILD_TYPE="+buildType),
+			pipeline.Run("Build", "cmake --build build --config "+buildType+" --parallel"),
+		}
+	case "meson":
+		return []pipeline.Step{
+			pipeline.Run("Configure", "meson setup build --buildtype="+strings.ToLower(buildType)),
+			pipeline.Run("Build", "meson compile -C build"),
+		}
+	case "autotools":
+		return []pipeline.Step{
+			pipeline.Run("Configure", "if [ -f autogen.sh ]; then ./autogen.sh; fi\n./configure"),
+			pipeline.Run("Build", "make -j$(nproc)"),
+		}
+	default:
+		return []pipeline.Step{
+			pipeline.Run("Configure", "if [ -f configure ]; then ./configure; fi"),
+			pipeline.Run("Build", "make -j$(nproc)"),
+		}
+	}
 }
 
 // Вспомогательная функция для получения зависимостей тестов
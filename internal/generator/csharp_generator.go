@@ -1,167 +1,84 @@
 package generator
 
 import (
-  "fmt"
   "strings"
 
   "github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+  "github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateCSharpPipeline(info *analyzer.ProjectInfo) string {
-  var pipeline strings.Builder
-
-  pipeline.WriteString(`name: .NET CI/CD Pipeline
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
-
-jobs:
-`)
-
-  pipeline.WriteString(`  setup:
-    runs-on: windows-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup .NET
-      uses: actions/setup-dotnet@v4
-      with:
-        dotnet-version: '`)
-  if info.Version != "" {
-    pipeline.WriteString(info.Version)
-  } else {
-    pipeline.WriteString("8.0.x")
+func buildCSharpPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+  version := strings.TrimSuffix(info.Version, ".x")
+  if version == "" {
+    version = "8.0"
   }
-  pipeline.WriteString(`'
-`)
+  image := "mcr.microsoft.com/dotnet/sdk:" + version
 
-  pipeline.WriteString(`  restore:
-    runs-on: windows-latest
-    needs: setup
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup .NET
-      uses: actions/setup-dotnet@v4
-      with:
-        dotnet-version: '`)
-  if info.Version != "" {
-    pipeline.WriteString(info.Version)
-  } else {
-    pipeline.WriteString("8.0.x")
+  p := &pipeline.Pipeline{
+    Name:     ".NET CI/CD Pipeline",
+    Branches: []string{"main", "master", "develop"},
+    Stages:   []string{"test", "build", "deploy"},
+    Env:      []pipeline.Var{{Name: "NUGET_PACKAGES", Value: ".nuget/packages"}},
+    Cache:    &pipeline.Cache{KeyFiles: []string{"packages.lock.json"}, Paths: []string{".nuget/packages/"}},
   }
-  pipeline.WriteString(`'
-    - name: Restore dependencies
-      run: dotnet restore
-`)
 
+  prev := "verify"
   if info.HasTests {
-    pipeline.WriteString(`  test:
-    runs-on: windows-latest
-    needs: restore
-    strategy:
-      matrix:
-        dotnet: [`)
-    if info.Version != "" && info.Version != "8.0" {
-      pipeline.WriteString(fmt.Sprintf(" '%s.x', '8.0.x' ", info.Version))
-    } else {
-      pipeline.WriteString(" '7.0.x', '8.0.x' ")
+    prev = "test"
+
+    versions := []string{"7.0", "8.0"}
+    if version != "8.0" {
+      versions = []string{version, "8.0"}
     }
-    pipeline.WriteString(`]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup .NET ${{ matrix.dotnet }}
-      uses: actions/setup-dotnet@v4
-      with:
-        dotnet-version: ${{ matrix.dotnet }}
-    - name: Restore
-      run: dotnet restore
-    - name: Build
-      run: dotnet build --no-restore --configuration Release
-    - name: Test
-      run: `)
+    test := p.AddJob(&pipeline.Job{
+      ID:     "test",
+      Stage:  "test",
+      Image:  "mcr.microsoft.com/dotnet/sdk:" + pipeline.MatrixRef("dotnet"),
+      Matrix: []pipeline.Axis{{Name: "dotnet", Values: versions}},
+    })
+    test.Steps = append(test.Steps, dotnetSetupSteps(pipeline.MatrixRef("dotnet")+".x")...)
+
+    command := "dotnet test --no-build --configuration Release"
     switch info.TestFramework {
-    case "mstest":
-      pipeline.WriteString("dotnet test --no-build --configuration Release --logger trx")
-    case "nunit":
-      pipeline.WriteString("dotnet test --no-build --configuration Release --logger trx")
-    case "xunit":
-      pipeline.WriteString("dotnet test --no-build --configuration Release --logger trx")
-    default:
-      pipeline.WriteString("dotnet test --no-build --configuration Release")
+    case "mstest", "nunit", "xunit":
+      command += " --logger trx"
     }
-    pipeline.WriteString(`
-`)
+    test.Steps = append(test.Steps, pipeline.Run("Test", command))
   } else {
-    pipeline.WriteString(`  verify:
-    runs-on: windows-latest
-    needs: restore
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup .NET
-      uses: actions/setup-dotnet@v4
-      with:
-        dotnet-version: '`)
-    if info.Version != "" {
-      pipeline.WriteString(info.Version)
-    } else {
-      pipeline.WriteString("8.0.x")
-    }
-    pipeline.WriteString(`'
-    - name: Restore
-      run: dotnet restore
-    - name: Build
-      run: dotnet build --no-restore --configuration Release
-`)
+    verify := p.AddJob(&pipeline.Job{ID: "verify", Stage: "test", Image: image})
+    verify.Steps = append(verify.Steps, dotnetSetupSteps(version+".x")...)
   }
 
-  prev := "test"
-  if !info.HasTests {
-    prev = "verify"
-  }
-
-  pipeline.WriteString(fmt.Sprintf(`  publish:
-    runs-on: windows-latest
-    needs: %s
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup .NET
-      uses: actions/setup-dotnet@v4
-      with:
-        dotnet-version: '`, prev))
-  if info.Version != "" {
-    pipeline.WriteString(info.Version)
-  } else {
-    pipeline.WriteString("8.0.x")
-  }
-  pipeline.WriteString(`'
-    - name: Restore
-      run: dotnet restore
-    - name: Build
-      run: dotnet build --no-restore --configuration Release
-`)
+  publish := p.AddJob(&pipeline.Job{ID: "build", Name: "Publish", Stage: "build", Image: image, Needs: []string{prev}})
+  publish.Steps = append(publish.Steps, dotnetSetupSteps(version+".x")...)
 
   // Publish for ASP.NET Core if detected
   if containsDependency(info.Dependencies, "web-framework:aspnetcore") {
-    pipeline.WriteString(`    - name: Publish
-      run: dotnet publish --no-build --configuration Release -o out
-    - name: Upload artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: publish
-        path: out/
-`)
+    publish.Steps = append(publish.Steps,
+      pipeline.Run("Publish", "dotnet publish --no-build --configuration Release -o out"),
+      pipeline.Upload("publish", "out/"),
+    )
   } else {
-    pipeline.WriteString(`    - name: Pack (NuGet)
-      run: dotnet pack --no-build --configuration Release -o packages
-    - name: Upload artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: packages
-        path: packages/
-`)
+    publish.Steps = append(publish.Steps,
+      pipeline.Run("Pack (NuGet)", "dotnet pack --no-build --configuration Release -o packages"),
+      pipeline.Upload("packages", "packages/"),
+    )
   }
 
-  return pipeline.String()
+  // Проекты решения собираются отдельно при изменении их каталога
+  addModuleJobs(p, info.Modules, image, []pipeline.Step{pipeline.Checkout(), pipeline.Setup("dotnet", version)}, func(dir string) moduleCommands {
+    return moduleCommands{test: "dotnet test " + dir, build: "dotnet build " + dir + " --configuration Release"}
+  })
+
+  return p
+}
+
+// dotnetSetupSteps — исходники, SDK, восстановление пакетов и сборка.
+func dotnetSetupSteps(version string) []pipeline.Step {
+  return []pipeline.Step{
+    pipeline.Checkout(),
+    pipeline.Setup("dotnet", version),
+    pipeline.Run("Restore", "dotnet restore"),
+    pipeline.Run("Build", "dotnet build --no-restore --configuration Release"),
+  }
 }
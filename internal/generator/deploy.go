@@ -0,0 +1,450 @@
+package generator
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// DeployTarget строит джоб деплоя. Джоб добавляется в модель pipeline и
+// сериализуется рендером формата, поэтому цель работает во всех форматах.
+// Команды шагов ссылаются на опубликованный образ через $IMAGE_REF.
+type DeployTarget interface {
+	Name() string
+	Job(info *analyzer.ProjectInfo, p *pipeline.Pipeline) *pipeline.Job
+}
+
+// DeployTargetFunc превращает функцию в DeployTarget.
+func DeployTargetFunc(name string, job func(*analyzer.ProjectInfo, *pipeline.Pipeline) *pipeline.Job) DeployTarget {
+	return funcDeployTarget{name: name, job: job}
+}
+
+type funcDeployTarget struct {
+	name string
+	job  func(*analyzer.ProjectInfo, *pipeline.Pipeline) *pipeline.Job
+}
+
+func (t funcDeployTarget) Name() string { return t.name }
+func (t funcDeployTarget) Job(info *analyzer.ProjectInfo, p *pipeline.Pipeline) *pipeline.Job {
+	return t.job(info, p)
+}
+
+var (
+	deployTargetsMu sync.RWMutex
+	deployTargets   = map[string]DeployTarget{}
+)
+
+// RegisterDeployTarget добавляет цель деплоя. Цель, зарегистрированная
+// позже с тем же именем, заменяет предыдущую.
+func RegisterDeployTarget(t DeployTarget) {
+	deployTargetsMu.Lock()
+	defer deployTargetsMu.Unlock()
+	deployTargets[t.Name()] = t
+}
+
+// LookupDeployTarget ищет цель деплоя по имени.
+func LookupDeployTarget(name string) (DeployTarget, bool) {
+	deployTargetsMu.RLock()
+	defer deployTargetsMu.RUnlock()
+	t, ok := deployTargets[name]
+	return t, ok
+}
+
+// DeployTargets возвращает отсортированные имена целей деплоя.
+func DeployTargets() []string {
+	deployTargetsMu.RLock()
+	defer deployTargetsMu.RUnlock()
+	var names []string
+	for name := range deployTargets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// deployJob строит джоб деплоя выбранной цели или возвращает nil, если
+// деплой не нужен. Без выбранной цели проекты с Dockerfile деплоятся
+// контейнером по SSH.
+func deployJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline, format string) (*pipeline.Job, error) {
+	if !info.Config.StageEnabled("deploy") {
+		return nil, nil
+	}
+	name := deployConfig(info).Target
+	if name == "" && info.HasDockerfile {
+		name = "ssh-docker"
+	}
+	if name == "" || name == "none" {
+		return nil, nil
+	}
+	target, ok := LookupDeployTarget(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown deploy target %s (supported: %s, none)", name, strings.Join(DeployTargets(), ", "))
+	}
+	job := target.Job(info, p)
+	if job == nil {
+		return nil, nil
+	}
+	if job.ID == "" {
+		job.ID = "deploy"
+	}
+	if job.Stage == "" {
+		job.Stage = "deploy"
+	}
+	if job.Needs == nil {
+		job.Needs = deployNeedsJobs(p)
+	}
+	if err := resolveImageRef(job, info, p, format); err != nil {
+		return nil, fmt.Errorf("deploy target %s: %w", name, err)
+	}
+	return job, nil
+}
+
+// addDeployJob добавляет в pipeline джоб деплоя. Существующий джоб с тем же
+// ID заменяется на месте, так что ссылки needs на него остаются верными.
+func addDeployJob(p *pipeline.Pipeline, info *analyzer.ProjectInfo, format string) error {
+	job, err := deployJob(info, p, format)
+	if err != nil || job == nil {
+		return err
+	}
+	if indexOf(p.Stages, job.Stage) < 0 {
+		p.Stages = append(p.Stages, job.Stage)
+	}
+	for i, existing := range p.Jobs {
+		if existing.ID == job.ID {
+			p.Jobs[i] = job
+			return nil
+		}
+	}
+	p.Jobs = append(p.Jobs, job)
+	return nil
+}
+
+// deployNeedsJobs возвращает джобы последней стадии перед деплоем. Джобы
+// с фильтром по изменениям пропускаются: в GitHub Actions пропущенный
+// джоб отменил бы и деплой.
+func deployNeedsJobs(p *pipeline.Pipeline) []string {
+	var needs []string
+	for _, stage := range p.Stages {
+		if stage == "deploy" {
+			break
+		}
+		var ids []string
+		for _, job := range p.Jobs {
+			if job.Stage == stage && len(job.When.Changes) == 0 && job.ID != "deploy" {
+				ids = append(ids, job.ID)
+			}
+		}
+		if len(ids) > 0 {
+			needs = ids
+		}
+	}
+	return needs
+}
+
+// deployConfig возвращает настройки деплоя проекта.
+func deployConfig(info *analyzer.ProjectInfo) analyzer.DeployConfig {
+	if info.Config == nil {
+		return analyzer.DeployConfig{}
+	}
+	return info.Config.Deploy
+}
+
+// newDeployJob создает джоб деплоя с условиями запуска из настроек:
+// основные ветки pipeline и окружение production.
+func newDeployJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline, image string) *pipeline.Job {
+	cfg := deployConfig(info)
+	environment := cfg.Environment
+	if environment == "" {
+		environment = "production"
+	}
+	branches := p.Branches
+	if len(branches) == 0 {
+		branches = []string{"main"}
+	}
+	return &pipeline.Job{
+		ID:          "deploy",
+		Stage:       "deploy",
+		Image:       image,
+		Environment: environment,
+		When:        pipeline.Condition{Branches: branches, Manual: cfg.Manual},
+	}
+}
+
+// secretEnv передает джобу секреты с теми же именами.
+func secretEnv(names ...string) []pipeline.Var {
+	var env []pipeline.Var
+	for _, name := range names {
+		env = append(env, pipeline.Var{Name: name, Value: pipeline.SecretRef(name)})
+	}
+	return env
+}
+
+// sshOptions — ключ и проверка хоста по known_hosts из секрета
+// DEPLOY_KNOWN_HOSTS вместо StrictHostKeyChecking=no.
+const sshOptions = "-i ~/.ssh/deploy_key -o StrictHostKeyChecking=yes"
+
+// sshSetupSteps ставят клиент SSH (в образах alpine его нет) и записывают
+// ключ и known_hosts.
+func sshSetupSteps() []pipeline.Step {
+	return []pipeline.Step{
+		pipeline.Run("Install SSH client", "command -v ssh >/dev/null || apk add --no-cache openssh-client"),
+		pipeline.Run("Configure SSH", `mkdir -p ~/.ssh && chmod 700 ~/.ssh
+echo "$DEPLOY_SSH_KEY" > ~/.ssh/deploy_key && chmod 600 ~/.ssh/deploy_key
+echo "$DEPLOY_KNOWN_HOSTS" > ~/.ssh/known_hosts`),
+	}
+}
+
+var sshSecrets = []string{"DEPLOY_HOST", "DEPLOY_USER", "DEPLOY_SSH_KEY", "DEPLOY_KNOWN_HOSTS"}
+
+// containerPort возвращает порт приложения: из настроек, из EXPOSE или
+// HEALTHCHECK Dockerfile, иначе 8080.
+func containerPort(info *analyzer.ProjectInfo) int {
+	if port := deployConfig(info).Port; port != 0 {
+		return port
+	}
+	if port := info.Dockerfile.Port(); port != 0 {
+		return port
+	}
+	if info.Dockerfile != nil && info.Dockerfile.HealthCheck != nil && info.Dockerfile.HealthCheck.Port != 0 {
+		return info.Dockerfile.HealthCheck.Port
+	}
+	return 8080
+}
+
+// healthEndpoint возвращает порт контейнера и путь HTTP-проверки из
+// HEALTHCHECK Dockerfile. Путь пустой, если проверка не обращается
+// к HTTP-адресу.
+func healthEndpoint(info *analyzer.ProjectInfo) (int, string) {
+	if info.Dockerfile == nil || info.Dockerfile.HealthCheck == nil {
+		return 0, ""
+	}
+	return info.Dockerfile.HealthCheck.Port, info.Dockerfile.HealthCheck.Path
+}
+
+// healthCheckCommand ждет, пока приложение начнет отвечать по адресу
+// проверки состояния.
+func healthCheckCommand(port int, path string) string {
+	return fmt.Sprintf("curl -fsS -o /dev/null --retry 10 --retry-delay 3 --retry-connrefused http://localhost:%d%s", port, path)
+}
+
+// sshDockerJob перезапускает контейнер образа проекта на сервере по SSH.
+// Если в Dockerfile объявлен HEALTHCHECK по HTTP, после запуска адрес
+// проверки опрашивается на сервере.
+func sshDockerJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline) *pipeline.Job {
+	port := containerPort(info)
+	name := imageName(info)
+	ports := fmt.Sprintf("-p %d:%d", port, port)
+	healthPort, healthPath := healthEndpoint(info)
+	if healthPath != "" && healthPort != port {
+		ports += fmt.Sprintf(" -p %d:%d", healthPort, healthPort)
+	}
+
+	job := newDeployJob(info, p, "alpine:3.20")
+	job.Env = secretEnv(sshSecrets...)
+	job.Steps = append(sshSetupSteps(), pipeline.Run("Deploy container", fmt.Sprintf(
+		`ssh %s "$DEPLOY_USER@$DEPLOY_HOST" "docker pull $IMAGE_REF && (docker rm -f %s || true) && docker run -d --name %s --restart unless-stopped %s $IMAGE_REF"`,
+		sshOptions, name, name, ports)))
+	if healthPath != "" {
+		job.Steps = append(job.Steps, pipeline.Run("Health check", fmt.Sprintf(
+			`ssh %s "$DEPLOY_USER@$DEPLOY_HOST" "%s"`, sshOptions, healthCheckCommand(healthPort, healthPath))))
+	}
+	return job
+}
+
+// dockerComposeJob копирует compose-файл на сервер и обновляет сервисы.
+// Опубликованный образ передается в переменной IMAGE: compose-файл
+// ссылается на него как image: ${IMAGE}. Если в Dockerfile есть
+// HEALTHCHECK, compose ждет, пока контейнеры станут healthy.
+func dockerComposeJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline) *pipeline.Job {
+	cfg := deployConfig(info)
+	file := cfg.ComposeFile
+	if file == "" {
+		file = "docker-compose.yml"
+	}
+	dir := cfg.Path
+	if dir == "" {
+		dir = "/opt/" + imageName(info)
+	}
+
+	up := "docker compose up -d --remove-orphans"
+	if info.Dockerfile != nil && info.Dockerfile.HealthCheck != nil {
+		up += " --wait"
+	}
+
+	job := newDeployJob(info, p, "alpine:3.20")
+	job.Env = append(secretEnv(sshSecrets...), pipeline.Var{Name: "DEPLOY_PATH", Value: dir})
+	job.Steps = append([]pipeline.Step{pipeline.Checkout()}, sshSetupSteps()...)
+	job.Steps = append(job.Steps, pipeline.Run("Deploy with Docker Compose", fmt.Sprintf(
+		`ssh %[1]s "$DEPLOY_USER@$DEPLOY_HOST" "mkdir -p $DEPLOY_PATH"
+scp %[1]s %[2]s "$DEPLOY_USER@$DEPLOY_HOST:$DEPLOY_PATH/docker-compose.yml"
+ssh %[1]s "$DEPLOY_USER@$DEPLOY_HOST" "cd $DEPLOY_PATH && export IMAGE=$IMAGE_REF && docker compose pull && %[3]s"`,
+		sshOptions, file, up)))
+	return job
+}
+
+// kubeImage содержит kubectl и helm и запускает shell без entrypoint.
+const kubeImage = "alpine/k8s:1.30.2"
+
+// kubeconfigStep записывает kubeconfig из секрета KUBE_CONFIG.
+func kubeconfigStep() pipeline.Step {
+	return pipeline.Run("Configure kubectl", `mkdir -p ~/.kube
+echo "$KUBE_CONFIG" > ~/.kube/config && chmod 600 ~/.kube/config`)
+}
+
+// portForwardCheck пробрасывает порт приложения из Deployment и опрашивает
+// адрес проверки состояния. deployment — имя ресурса или переменная
+// shell с ним.
+func portForwardCheck(deployment, namespace string, port int, path string) string {
+	return fmt.Sprintf(`kubectl port-forward %s %d:%d%s >/dev/null &
+FORWARD_PID=$!
+STATUS=0
+%s || STATUS=$?
+kill $FORWARD_PID
+[ "$STATUS" -eq 0 ]`, deployment, port, port, namespace, healthCheckCommand(port, path))
+}
+
+// kubernetesJob применяет манифесты из каталога репозитория и переводит
+// Deployment с именем образа проекта на опубликованный образ. Если
+// в Dockerfile объявлен HEALTHCHECK по HTTP, после выката адрес проверки
+// опрашивается через kubectl port-forward.
+func kubernetesJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline) *pipeline.Job {
+	cfg := deployConfig(info)
+	manifests := cfg.Manifests
+	if manifests == "" {
+		manifests = "k8s"
+	}
+	namespace := ""
+	if cfg.Namespace != "" {
+		namespace = " --namespace " + cfg.Namespace
+	}
+	name := imageName(info)
+	command := fmt.Sprintf(`kubectl apply --recursive -f %[1]s%[2]s
+kubectl set image deployment/%[3]s %[3]s="$IMAGE_REF"%[2]s
+kubectl rollout status deployment/%[3]s%[2]s`, manifests, namespace, name)
+
+	job := newDeployJob(info, p, kubeImage)
+	job.Env = secretEnv("KUBE_CONFIG")
+	job.Steps = []pipeline.Step{pipeline.Checkout(), kubeconfigStep(), pipeline.Run("Apply manifests", command)}
+	if port, path := healthEndpoint(info); path != "" {
+		job.Steps = append(job.Steps, pipeline.Run("Health check", portForwardCheck("deployment/"+name, namespace, port, path)))
+	}
+	return job
+}
+
+// helmJob устанавливает или обновляет релиз чарта с образом проекта.
+// Проверка состояния после выката находит Deployment релиза по
+// стандартной метке app.kubernetes.io/instance.
+func helmJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline) *pipeline.Job {
+	cfg := deployConfig(info)
+	chart := cfg.Chart
+	if chart == "" {
+		chart = "./chart"
+	}
+	name := imageName(info)
+	// Чарты собирают образ как repository:tag, поэтому digest передается
+	// в теге вида latest@sha256:...
+	command := fmt.Sprintf(`case "$IMAGE_REF" in
+  *@*) REPOSITORY="${IMAGE_REF%%@*}" TAG="latest@${IMAGE_REF#*@}" ;;
+  *) REPOSITORY="${IMAGE_REF%%:*}" TAG="${IMAGE_REF##*:}" ;;
+esac
+helm upgrade --install %s %s --wait \
+  --set image.repository="$REPOSITORY" --set image.tag="$TAG"`, name, chart)
+	if cfg.Namespace != "" {
+		command += fmt.Sprintf(" \\\n  --namespace %s --create-namespace", cfg.Namespace)
+	}
+
+	job := newDeployJob(info, p, kubeImage)
+	job.Env = secretEnv("KUBE_CONFIG")
+	job.Steps = []pipeline.Step{pipeline.Checkout(), kubeconfigStep(), pipeline.Run("Upgrade Helm release", command)}
+	if port, path := healthEndpoint(info); path != "" {
+		namespace := ""
+		if cfg.Namespace != "" {
+			namespace = " --namespace " + cfg.Namespace
+		}
+		check := fmt.Sprintf("DEPLOYMENT=$(kubectl get deployment -l app.kubernetes.io/instance=%s -o name%s | head -n 1)\n", name, namespace) +
+			portForwardCheck(`"$DEPLOYMENT"`, namespace, port, path)
+		job.Steps = append(job.Steps, pipeline.Run("Health check", check))
+	}
+	return job
+}
+
+// staticJob собирает сайт шагами джоба build и загружает его на сервер
+// через rsync. Сборка повторяется, потому что не во всех форматах джобы
+// получают артефакты предыдущих.
+func staticJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline) *pipeline.Job {
+	cfg := deployConfig(info)
+	remote := cfg.Path
+	if remote == "" {
+		remote = "/var/www/" + imageName(info)
+	}
+
+	job := newDeployJob(info, p, "debian:bookworm-slim")
+	build := staticBuildJob(p)
+	dir := cfg.Dir
+	if build != nil {
+		job.Image, job.Dir = build.Image, build.Dir
+		job.Env = append(job.Env, build.Env...)
+		for _, step := range build.Steps {
+			switch step.Kind {
+			case pipeline.KindUpload, pipeline.KindCoverage:
+				if dir == "" && step.Kind == pipeline.KindUpload && len(step.Paths) > 0 {
+					dir = strings.TrimPrefix(step.Paths[0], build.Dir+"/")
+				}
+			default:
+				job.Steps = append(job.Steps, step)
+			}
+		}
+		// Матрица не копируется: используются первые значения осей
+		for _, axis := range build.Matrix {
+			job.Image = strings.ReplaceAll(job.Image, pipeline.MatrixRef(axis.Name), axis.Values[0])
+			for i := range job.Steps {
+				job.Steps[i].Version = strings.ReplaceAll(job.Steps[i].Version, pipeline.MatrixRef(axis.Name), axis.Values[0])
+				job.Steps[i].Command = strings.ReplaceAll(job.Steps[i].Command, pipeline.MatrixRef(axis.Name), axis.Values[0])
+			}
+		}
+	} else {
+		job.Steps = []pipeline.Step{pipeline.Checkout()}
+	}
+	if dir == "" {
+		dir = "public"
+	}
+	dir = strings.TrimSuffix(strings.TrimSuffix(dir, "**"), "/")
+
+	// Пакеты ставятся сразу после получения исходников
+	at := 0
+	if len(job.Steps) > 0 && job.Steps[0].Kind == pipeline.KindCheckout {
+		at = 1
+	}
+	job.Steps = append(job.Steps[:at], append([]pipeline.Step{pipeline.Packages("rsync", "openssh-client")}, job.Steps[at:]...)...)
+	job.Env = append(job.Env, secretEnv(sshSecrets...)...)
+	job.Env = append(job.Env, pipeline.Var{Name: "DEPLOY_PATH", Value: remote})
+	job.Steps = append(job.Steps, sshSetupSteps()[1], pipeline.Run("Upload site", fmt.Sprintf(
+		`rsync -az --delete -e "ssh %s" %s/ "$DEPLOY_USER@$DEPLOY_HOST:$DEPLOY_PATH/"`, sshOptions, path.Clean(dir))))
+	return job
+}
+
+// staticBuildJob находит джоб сборки сайта: build или первый джоб стадии build.
+func staticBuildJob(p *pipeline.Pipeline) *pipeline.Job {
+	if job := p.Job("build"); job != nil {
+		return job
+	}
+	for _, job := range p.Jobs {
+		if job.Stage == "build" {
+			return job
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterDeployTarget(DeployTargetFunc("ssh-docker", sshDockerJob))
+	RegisterDeployTarget(DeployTargetFunc("docker-compose", dockerComposeJob))
+	RegisterDeployTarget(DeployTargetFunc("kubernetes", kubernetesJob))
+	RegisterDeployTarget(DeployTargetFunc("helm", helmJob))
+	RegisterDeployTarget(DeployTargetFunc("static", staticJob))
+}
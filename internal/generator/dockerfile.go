@@ -0,0 +1,258 @@
+package generator
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+)
+
+// dockerfileTemplates строят Dockerfile проекта по языку. Образы собираются
+// в несколько стадий: зависимости и сборка отдельно от финального образа,
+// который запускает приложение не от root.
+var dockerfileTemplates = map[string]func(info *analyzer.ProjectInfo, port int) string{
+	"go":          goDockerfile,
+	"javascript":  nodeDockerfile,
+	"python":      pythonDockerfile,
+	"java_maven":  javaDockerfile,
+	"java_gradle": javaDockerfile,
+}
+
+// defaultPorts — порт приложения по умолчанию для EXPOSE, если он не задан
+// в настройках деплоя.
+var defaultPorts = map[string]int{
+	"javascript": 3000,
+	"python":     8000,
+}
+
+var versionPrefixPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// DockerfileLanguages возвращает отсортированные языки, для которых
+// можно сгенерировать Dockerfile.
+func DockerfileLanguages() []string {
+	var languages []string
+	for language := range dockerfileTemplates {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// GenerateDockerfile строит многоэтапный Dockerfile для проекта.
+func GenerateDockerfile(info *analyzer.ProjectInfo) (string, error) {
+	template, ok := dockerfileTemplates[info.Language]
+	if !ok {
+		return "", fmt.Errorf("no Dockerfile template for %s (supported: %s)", info.Language, strings.Join(DockerfileLanguages(), ", "))
+	}
+	port := deployConfig(info).Port
+	if port == 0 {
+		port = defaultPorts[info.Language]
+	}
+	if port == 0 {
+		port = 8080
+	}
+	return "# syntax=docker/dockerfile:1\n" + template(info, port), nil
+}
+
+// WriteDockerfile записывает сгенерированный Dockerfile и отмечает его
+// в ProjectInfo, чтобы pipeline собирал и деплоил образ.
+func WriteDockerfile(info *analyzer.ProjectInfo, outputFile string) error {
+	content, err := GenerateDockerfile(info)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write Dockerfile: %w", err)
+	}
+	info.HasDockerfile = true
+	info.Dockerfile = analyzer.ParseDockerfile(content)
+	return nil
+}
+
+// imageVersion оставляет от версии языка major.minor: ограничения вроде
+// ">=3.10" и патч-версии не подходят для тегов образов.
+func imageVersion(version, fallback string) string {
+	if v := versionPrefixPattern.FindString(version); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func hasStructureFile(info *analyzer.ProjectInfo, name string) bool {
+	for _, file := range info.Structure {
+		if file == name {
+			return true
+		}
+	}
+	return false
+}
+
+// goDockerfile собирает статический бинарник пакета с main и запускает его
+// в distroless-образе.
+func goDockerfile(info *analyzer.ProjectInfo, port int) string {
+	pkg := "."
+	if dir := path.Dir(info.MainFilePath); info.MainFilePath != "" && dir != "." {
+		pkg = "./" + dir
+	}
+	modules := "go.mod"
+	if hasStructureFile(info, "go.sum") {
+		modules += " go.sum"
+	}
+	return fmt.Sprintf(`FROM golang:%s-alpine AS build
+WORKDIR /src
+COPY %s ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -trimpath -ldflags="-s -w" -o /out/app %s
+
+FROM gcr.io/distroless/static-debian12:nonroot
+COPY --from=build /out/app /app
+USER nonroot:nonroot
+EXPOSE %d
+ENTRYPOINT ["/app"]
+`, imageVersion(info.Version, "1.22"), modules, pkg, port)
+}
+
+// nodeDockerfile ставит зависимости найденным менеджером пакетов, собирает
+// проект скриптом build и оставляет в финальном образе только
+// production-зависимости.
+func nodeDockerfile(info *analyzer.ProjectInfo, port int) string {
+	version := imageVersion(info.Version, "20")
+	tool := jsPackageManager(info.BuildTool)
+	lockFile := jsLockFile(tool)
+	var install, build, prune string
+	switch tool {
+	case "yarn":
+		install = "corepack enable && yarn install --frozen-lockfile"
+		build = "if grep -q '\"build\"' package.json; then yarn run build; fi"
+		prune = "yarn install --frozen-lockfile --production"
+	case "pnpm":
+		install = "corepack enable && pnpm install --frozen-lockfile"
+		build = "pnpm run --if-present build"
+		prune = "pnpm prune --prod"
+	default:
+		install = "npm ci"
+		build = "npm run build --if-present"
+		prune = "npm prune --omit=dev"
+	}
+	files := "package.json"
+	if hasStructureFile(info, lockFile) {
+		files += " " + lockFile
+	} else if tool == "npm" {
+		install = "npm install"
+	}
+	return fmt.Sprintf(`FROM node:%[1]s-alpine AS build
+WORKDIR /app
+COPY %[2]s ./
+RUN %[3]s
+COPY . .
+RUN %[4]s && %[5]s
+
+FROM node:%[1]s-alpine
+ENV NODE_ENV=production
+WORKDIR /app
+COPY --from=build --chown=node:node /app ./
+USER node
+EXPOSE %[6]d
+CMD ["npm", "start"]
+`, version, files, install, build, prune, port)
+}
+
+// pythonDockerfile ставит зависимости poetry или pip в виртуальное
+// окружение и копирует его в финальный образ. Команда запуска зависит
+// от веб-фреймворка.
+func pythonDockerfile(info *analyzer.ProjectInfo, port int) string {
+	version := imageVersion(info.Version, "3.12")
+	install := `COPY requirements.txt ./
+RUN pip install -r requirements.txt`
+	if !hasStructureFile(info, "requirements.txt") {
+		install = "RUN pip install --upgrade pip"
+	}
+	if info.BuildTool == "poetry" {
+		install = `RUN pip install poetry poetry-plugin-export
+COPY pyproject.toml poetry.lock* ./
+RUN poetry export --format requirements.txt --without-hashes --output requirements.txt \
+    && pip install -r requirements.txt`
+	}
+	return fmt.Sprintf(`FROM python:%[1]s-slim AS build
+ENV PIP_NO_CACHE_DIR=1 PIP_DISABLE_PIP_VERSION_CHECK=1
+RUN python -m venv /opt/venv
+ENV PATH=/opt/venv/bin:$PATH
+WORKDIR /app
+%[2]s
+
+FROM python:%[1]s-slim
+ENV PATH=/opt/venv/bin:$PATH PYTHONUNBUFFERED=1 PYTHONDONTWRITEBYTECODE=1
+RUN useradd --create-home --uid 10001 app
+WORKDIR /app
+COPY --from=build /opt/venv /opt/venv
+COPY --chown=app:app . .
+USER app
+EXPOSE %[3]d
+CMD %[4]s
+`, version, install, port, pythonCommand(info, port))
+}
+
+// pythonCommand выбирает команду запуска: сервер фреймворка или главный
+// модуль проекта.
+func pythonCommand(info *analyzer.ProjectInfo, port int) string {
+	module := "main"
+	for _, name := range []string{"main.py", "app.py", "wsgi.py", "server.py"} {
+		if hasStructureFile(info, name) {
+			module = strings.TrimSuffix(name, ".py")
+			break
+		}
+	}
+	switch {
+	case containsDependency(info.Dependencies, "web-framework:django"):
+		return fmt.Sprintf(`["python", "manage.py", "runserver", "0.0.0.0:%d"]`, port)
+	case containsDependency(info.Dependencies, "web-framework:fastapi"):
+		return fmt.Sprintf(`["uvicorn", "%s:app", "--host", "0.0.0.0", "--port", "%d"]`, module, port)
+	case containsDependency(info.Dependencies, "web-framework:flask"):
+		return fmt.Sprintf(`["flask", "--app", "%s", "run", "--host", "0.0.0.0", "--port", "%d"]`, module, port)
+	default:
+		return fmt.Sprintf(`["python", "%s.py"]`, module)
+	}
+}
+
+// javaDockerfile собирает jar Maven или Gradle (через wrapper, если он
+// есть) и запускает его на JRE.
+func javaDockerfile(info *analyzer.ProjectInfo, port int) string {
+	tool := detectBuildTool(info)
+	version := strings.SplitN(imageVersion(cleanJavaVersion(getDefaultJavaVersion(info)), "17"), ".", 2)[0]
+	var build string
+	if tool == "gradle" {
+		build = fmt.Sprintf(`COPY . .
+RUN %s \
+    && find build/libs -name '*.jar' ! -name '*-plain.jar' | head -n 1 | xargs -I{} cp {} /app.jar`, gradleBuildCommand(info))
+	} else {
+		build = `COPY pom.xml ./
+RUN mvn -B -q dependency:go-offline
+COPY . .
+RUN mvn -B -q package -DskipTests \
+    && find target -maxdepth 1 -name '*.jar' ! -name '*-sources.jar' ! -name '*-javadoc.jar' | head -n 1 | xargs -I{} cp {} /app.jar`
+	}
+	return fmt.Sprintf(`FROM %s AS build
+WORKDIR /src
+%s
+
+FROM eclipse-temurin:%s-jre
+RUN useradd --system --uid 10001 app
+WORKDIR /app
+COPY --from=build /app.jar app.jar
+USER app
+EXPOSE %d
+ENTRYPOINT ["java", "-jar", "app.jar"]
+`, javaImage(tool, version), build, version, port)
+}
+
+func gradleBuildCommand(info *analyzer.ProjectInfo) string {
+	if hasStructureFile(info, "gradlew") {
+		return "chmod +x gradlew && ./gradlew build -x test --no-daemon"
+	}
+	return "gradle build -x test --no-daemon"
+}
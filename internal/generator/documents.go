@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+	"gopkg.in/yaml.v3"
+)
+
+// splitDocuments разбирает файл из нескольких YAML-документов, разделенных "---".
+func splitDocuments(content string) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	dec := yaml.NewDecoder(bytes.NewBufferString(content))
+	for {
+		doc := &yaml.Node{}
+		err := dec.Decode(doc)
+		if errors.Is(err, io.EOF) {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML document: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// joinDocuments сериализует документы обратно в один файл.
+func joinDocuments(docs []*yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(doc); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// separateKeys отделяет пустой строкой ключи верхнего уровня и джобы
+// секции jobsKey, как это делают рендеры pipeline: разбор YAML пустые
+// строки не сохраняет.
+func separateKeys(content, jobsKey string) string {
+	var out []string
+	section, prev := "", ""
+	for i, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		topLevel := keyAtIndent(line, 0)
+		if topLevel {
+			section = strings.SplitN(line, ":", 2)[0]
+		}
+		job := jobsKey != "" && section == jobsKey && keyAtIndent(line, 2) && prev != jobsKey+":"
+		if i > 0 && (topLevel || job) {
+			out = append(out, "")
+		}
+		out = append(out, line)
+		prev = line
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// keyAtIndent сообщает, начинается ли в строке ключ с отступом indent.
+func keyAtIndent(line string, indent int) bool {
+	if len(line) <= indent || strings.TrimLeft(line[:indent], " ") != "" {
+		return false
+	}
+	c := line[indent]
+	return c != ' ' && c != '-' && c != '#'
+}
+
+// parseNode разбирает фрагмент YAML в узел, пригодный для вставки в документ.
+func parseNode(snippet string) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(snippet), &doc); err != nil {
+		return nil, err
+	}
+	return doc.Content[0], nil
+}
+
+// setMappingValue заменяет значение ключа на месте или добавляет ключ в конец.
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// setMappingValues задает значения ключей keys. Новые ключи встают перед
+// следующим из keys, поэтому их порядок сохраняется, а существующие
+// заменяются на месте.
+func setMappingValues(m *yaml.Node, keys []string, values []*yaml.Node) {
+	for i := len(keys) - 1; i >= 0; i-- {
+		if pipeline.MappingValue(m, keys[i]) != nil || i == len(keys)-1 {
+			setMappingValue(m, keys[i], values[i])
+			continue
+		}
+		for j := 0; j+1 < len(m.Content); j += 2 {
+			if m.Content[j].Value == keys[i+1] {
+				m.Content = append(m.Content[:j], append([]*yaml.Node{{Kind: yaml.ScalarNode, Value: keys[i]}, values[i]}, m.Content[j:]...)...)
+				break
+			}
+		}
+	}
+}
+
+// deleteMappingKey удаляет ключ из mapping-узла.
+func deleteMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -4,244 +4,559 @@ import (
 	"bufio"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+	"gopkg.in/yaml.v3"
 )
 
+// defaultOutputs — пути, по которым CI-системы ищут свою конфигурацию.
+var defaultOutputs = map[string]string{
+	"forgejo":    ".forgejo/workflows/ci.yml",
+	"circleci":   ".circleci/config.yml",
+	"bitbucket":  "bitbucket-pipelines.yml",
+	"woodpecker": ".woodpecker.yml",
+	"drone":      ".drone.yml",
+	"codebuild":  "buildspec.yml",
+	"cloudbuild": "cloudbuild.yaml",
+}
+
+// DefaultOutput возвращает путь файла, в котором CI-система формата ищет
+// pipeline, если он отличается от общего pipeline.yml.
+func DefaultOutput(format string) (string, bool) {
+	path, ok := defaultOutputs[format]
+	return path, ok
+}
+
+// GeneratePipeline находит генератор для языка проекта и формата в реестре
+// и записывает pipeline в outputFile. Если outputFile — каталог (оканчивается
+// на "/"), файл получает имя, принятое для формата.
 func GeneratePipeline(info *analyzer.ProjectInfo, outputFile string, format string) error {
-	var pipelineContent string
+	pipelineContent, err := buildPipeline(info, format)
+	if err != nil {
+		return err
+	}
+	return writePipeline(outputPath(outputFile, format), pipelineContent)
+}
 
-	// Выбираем генератор в зависимости от формата
-	switch format {
-	case "gitlab":
-		switch info.Language {
-		case "go":
-			pipelineContent = generateGitLabGoPipeline(info)
-		case "python":
-			pipelineContent = generateGitLabPythonPipeline(info)
-		case "java_gradle", "java_maven":
-			pipelineContent = generateGitLabJavaPipeline(info)
-		case "javascript":
-			pipelineContent = generateGitLabJavaScriptPipeline(info)
-		case "csharp":
-			pipelineContent = generateGitLabCSharpPipeline(info)
-		case "ruby":
-			pipelineContent = generateGitLabRubyPipeline(info)
-		case "rust":
-			pipelineContent = generateGitLabRustPipeline(info)
-		case "cpp":
-			pipelineContent = generateGitLabCppPipeline(info)
-		default:
-			return fmt.Errorf("unsupported language: %s", info.Language)
+// buildPipeline строит pipeline вместе с деплоем и помечает джобы,
+// которыми владеет генератор.
+func buildPipeline(info *analyzer.ProjectInfo, format string) (string, error) {
+	pipelineContent, err := generateContent(info, format)
+	if err != nil {
+		return "", err
+	}
+	return markGeneratedJobs(pipelineContent, format)
+}
+
+// outputPath раскрывает каталог в outputFile в путь файла с именем,
+// принятым для формата.
+func outputPath(outputFile, format string) string {
+	if !strings.HasSuffix(outputFile, "/") {
+		return outputFile
+	}
+	name := "pipeline.yml"
+	if path, ok := DefaultOutput(format); ok {
+		name = filepath.Base(path)
+	}
+	return filepath.Join(outputFile, name)
+}
+
+func writePipeline(outputFile, content string) error {
+	// Некоторые форматы ожидают файл в подкаталоге, например .circleci/config.yml
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return os.WriteFile(outputFile, []byte(content), 0644)
+}
+
+// generateContent строит pipeline одним генератором или, для полиглот-репозитория,
+// объединяет генераторы всех компонентов, и добавляет в модель джобы
+// публикации образа и деплоя.
+func generateContent(info *analyzer.ProjectInfo, format string) (string, error) {
+	var p *pipeline.Pipeline
+	if len(info.Components) > 0 {
+		merged, err := buildComponents(info, format)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate pipeline: %w", err)
 		}
-	case "jenkins":
-		switch info.Language {
-		case "go":
-			pipelineContent = generateJenkinsGoPipeline(info)
-		case "python":
-			pipelineContent = generateJenkinsPythonPipeline(info)
-		case "java_gradle", "java_maven":
-			pipelineContent = generateJenkinsJavaPipeline(info)
-		case "javascript":
-			pipelineContent = generateJenkinsJavaScriptPipeline(info)
-		case "csharp":
-			pipelineContent = generateJenkinsCSharpPipeline(info)
-		case "ruby":
-			pipelineContent = generateJenkinsRubyPipeline(info)
-		case "rust":
-			pipelineContent = generateJenkinsRustPipeline(info)
-		case "cpp":
-			pipelineContent = generateJenkinsCppPipeline(info)
-		default:
-			return fmt.Errorf("unsupported language: %s", info.Language)
+		p = merged
+	} else {
+		g, err := lookupGenerator(info.Language, format)
+		if err != nil {
+			return "", err
 		}
-	default:
-		// GitHub Actions (по умолчанию)
-		switch info.Language {
-		case "go":
-			pipelineContent = generateGoPipelineActions(info)
-		case "python":
-			pipelineContent = generatePythonPipeline(info)
-		case "java_gradle", "java_maven":
-			pipelineContent = generateJavaPipeline(info)
-		case "javascript":
-			pipelineContent = generateJavaScriptPipeline(info)
-		case "csharp":
-			pipelineContent = generateCSharpPipeline(info)
-		case "ruby":
-			pipelineContent = generateRubyPipeline(info)
-		case "rust":
-			pipelineContent = generateRustPipeline(info)
-		case "cpp":
-			pipelineContent = generateCppPipeline(info)
-		case "swift":
-			pipelineContent = generateSwiftPipeline(info)
-		default:
-			return fmt.Errorf("unsupported language: %s", info.Language)
+		builder, ok := g.(PipelineBuilder)
+		if !ok {
+			content, err := g.Generate(info)
+			if err != nil {
+				return "", fmt.Errorf("failed to generate pipeline: %w", err)
+			}
+			return injectDeliveryJobs(content, info, format)
 		}
+		p = builder.Build(info)
+	}
+	if err := addDeliveryJobs(p, info, format); err != nil {
+		return "", err
 	}
+	content, err := pipeline.Render(p, format)
+	if err != nil || format != "tekton" {
+		return content, err
+	}
+	return addTektonImageBuild(content, info, imageCondition(p))
+}
 
-	if info.HasDockerfile {
-		pipelineContent = addDeployStage(pipelineContent, info, format)
+// addDeliveryJobs добавляет в модель публикацию образа и деплой.
+func addDeliveryJobs(p *pipeline.Pipeline, info *analyzer.ProjectInfo, format string) error {
+	if err := addImageJob(p, info, format); err != nil {
+		return err
 	}
-	fmt.Printf("%s, %s, %s, %s, %s, %s \n", info.Language, info.Version, info.Architecture, info.BuildTool, info.TestFramework, info.PackageManager)
-	return os.WriteFile(outputFile, []byte(pipelineContent), 0644)
+	return addDeployJob(p, info, format)
 }
-func addDeployStage(pipelineContent string, info *analyzer.ProjectInfo, format string) string {
+
+// injectDeliveryJobs встраивает джобы публикации образа и деплоя в готовый
+// текст генератора без модели: джобы сериализуются рендером формата
+// отдельно и вставляются в разобранный документ. В манифесты Tekton
+// добавляется задача сборки образа, генераторы остальных форматов
+// добавляют джобы самостоятельно.
+func injectDeliveryJobs(pipelineContent string, info *analyzer.ProjectInfo, format string) (string, error) {
+	p := &pipeline.Pipeline{Name: "deploy", Branches: []string{"main", "master"}}
+	if format == "tekton" {
+		return addTektonImageBuild(pipelineContent, info, imageCondition(p))
+	}
+	if format != "github" && format != "forgejo" && format != "gitlab" && format != "jenkins" {
+		return pipelineContent, nil
+	}
+	if err := addDeliveryJobs(p, info, format); err != nil {
+		return "", err
+	}
+	if len(p.Jobs) == 0 {
+		return pipelineContent, nil
+	}
+	rendered, err := pipeline.Render(p, format)
+	if err != nil {
+		return "", err
+	}
+
 	switch format {
-	case "gitlab":
-		return addGitLabDeployStage(pipelineContent, info)
 	case "jenkins":
-		return addJenkinsDeployStage(pipelineContent, info)
+		return addJenkinsStages(pipelineContent, rendered, p), nil
+	case "gitlab":
+		return addGitLabJobs(pipelineContent, rendered, p), nil
 	default:
-		return addGitHubDeployStage(pipelineContent, info)
+		return addWorkflowJobs(pipelineContent, rendered, p), nil
 	}
 }
 
-func addGitLabDeployStage(pipelineContent string, info *analyzer.ProjectInfo) string {
-	deployStage := `
-deploy:
-  stage: deploy
-  image: alpine:latest
-  before_script:
-    - apk add --no-cache openssh-client
-    - eval $(ssh-agent -s)
-    - echo "$SSH_PRIVATE_KEY" | ssh-add -
-    - mkdir -p ~/.ssh
-    - chmod 700 ~/.ssh
-  script:
-    - ssh -o StrictHostKeyChecking=no $DEPLOY_USER@$DEPLOY_SERVER "docker pull $CI_REGISTRY_IMAGE:latest"
-    - ssh -o StrictHostKeyChecking=no $DEPLOY_USER@$DEPLOY_SERVER "docker stop ${CI_PROJECT_NAME} || true"
-    - ssh -o StrictHostKeyChecking=no $DEPLOY_USER@$DEPLOY_SERVER "docker rm ${CI_PROJECT_NAME} || true"
-    - ssh -o StrictHostKeyChecking=no $DEPLOY_USER@$DEPLOY_SERVER "docker run -d --name ${CI_PROJECT_NAME} -p 8080:8080 $CI_REGISTRY_IMAGE:latest"
-  environment:
-    name: production
-    url: https://$DEPLOY_SERVER
-  only:
-    - main
-    - master
-  when: manual
-`
-
-	if strings.Contains(pipelineContent, "\ndeploy:") {
-		deployStart := strings.Index(pipelineContent, "\ndeploy:")
-		if deployStart != -1 {
-			nextStageStart := strings.Index(pipelineContent[deployStart+1:], "\n  ")
-			if nextStageStart == -1 {
-				nextStageStart = strings.Index(pipelineContent[deployStart+1:], "\n")
-			}
+// addGitLabJobs переносит джобы p из rendered в pipeline GitLab CI и
+// дополняет список stages. Джоб с тем же ID заменяется на месте: ссылки
+// needs на него остаются верными.
+func addGitLabJobs(pipelineContent, rendered string, p *pipeline.Pipeline) string {
+	docs, err := splitDocuments(pipelineContent)
+	if err != nil || len(docs) != 1 {
+		return pipelineContent
+	}
+	added, err := splitDocuments(rendered)
+	if err != nil || len(added) != 1 {
+		return pipelineContent
+	}
+	root := docs[0].Content[0]
+	var ids []string
+	var nodes []*yaml.Node
+	for _, job := range p.Jobs {
+		node := pipeline.MappingValue(added[0].Content[0], job.ID)
+		if node == nil {
+			return pipelineContent
+		}
+		ids = append(ids, job.ID)
+		nodes = append(nodes, node)
+	}
+	setMappingValues(root, ids, nodes)
+	if stages := pipeline.MappingValue(root, "stages"); stages != nil {
+		var names []string
+		for _, stage := range stages.Content {
+			names = append(names, stage.Value)
+		}
+		stages.Content = nil
+		for _, stage := range mergeStages(names, p.Stages) {
+			stages.Content = append(stages.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: stage})
+		}
+	}
+
+	content, err := joinDocuments(docs)
+	if err != nil {
+		return pipelineContent
+	}
+	return separateKeys(content, "")
+}
 
-			if nextStageStart != -1 {
-				endPos := deployStart + 1 + nextStageStart
-				return pipelineContent[:deployStart+1] + deployStage + pipelineContent[endPos:]
-			} else {
-				return pipelineContent[:deployStart+1] + deployStage
+// addWorkflowJobs переносит джобы p из rendered в workflow GitHub или
+// Forgejo либо заменяет ими существующие на месте. Первый джоб ждет сборку,
+// а без нее — тесты или последний джоб workflow.
+func addWorkflowJobs(pipelineContent, rendered string, p *pipeline.Pipeline) string {
+	docs, err := splitDocuments(pipelineContent)
+	if err != nil || len(docs) != 1 {
+		return pipelineContent
+	}
+	jobs := pipeline.MappingValue(docs[0].Content[0], "jobs")
+	if jobs == nil {
+		return pipelineContent
+	}
+	added, err := splitDocuments(rendered)
+	if err != nil || len(added) != 1 {
+		return pipelineContent
+	}
+	var ids []string
+	for _, job := range p.Jobs {
+		ids = append(ids, job.ID)
+	}
+	var nodes []*yaml.Node
+	for _, job := range p.Jobs {
+		node := pipeline.MappingValue(pipeline.MappingValue(added[0].Content[0], "jobs"), job.ID)
+		if node == nil {
+			return pipelineContent
+		}
+		if len(job.Needs) == 0 {
+			// needs ставится после runs-on, как в джобах рендера
+			if needs := deliveryNeeds(jobs, ids); needs != "" {
+				at := 0
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == "runs-on" {
+						at = i + 2
+					}
+				}
+				node.Content = append(node.Content[:at], append([]*yaml.Node{
+					{Kind: yaml.ScalarNode, Value: "needs"},
+					{Kind: yaml.ScalarNode, Value: needs},
+				}, node.Content[at:]...)...)
 			}
 		}
+		nodes = append(nodes, node)
 	}
+	setMappingValues(jobs, ids, nodes)
 
-	return pipelineContent + deployStage
+	content, err := joinDocuments(docs)
+	if err != nil {
+		return pipelineContent
+	}
+	return separateKeys(content, "jobs")
 }
 
-func addGitHubDeployStage(pipelineContent string, info *analyzer.ProjectInfo) string {
-	deployStage := `
-  deploy:
-    runs-on: ubuntu-latest
-    needs: build
-    if: github.ref == 'refs/heads/main' || github.ref == 'refs/heads/master'
-    environment: production
-    steps:
-    - name: Checkout code
-      uses: actions/checkout@v3
-    
-    - name: Deploy to server
-      uses: appleboy/ssh-action@master
-      with:
-        host: ${{ secrets.DEPLOY_HOST }}
-        username: ${{ secrets.DEPLOY_USER }}
-        key: ${{ secrets.DEPLOY_SSH_KEY }}
-        script: |
-          docker pull ${{ secrets.REGISTRY_URL }}/${{ github.repository }}:latest
-          docker stop ${{ github.event.repository.name }} || true
-          docker rm ${{ github.event.repository.name }} || true
-          docker run -d --name ${{ github.event.repository.name }} -p 8080:8080 ${{ secrets.REGISTRY_URL }}/${{ github.repository }}:latest
-`
-
-	if strings.Contains(pipelineContent, "\n  deploy:") {
-		deployStart := strings.Index(pipelineContent, "\n  deploy:")
-		if deployStart != -1 {
-			nextJobStart := strings.Index(pipelineContent[deployStart+1:], "\n  ")
-			if nextJobStart != -1 {
-				endPos := deployStart + 1 + nextJobStart
-				return pipelineContent[:deployStart+1] + deployStage + pipelineContent[endPos:]
-			} else {
-				return pipelineContent[:deployStart+1] + deployStage
+// deliveryNeeds выбирает джоб workflow, после которого запускаются
+// публикация образа и деплой.
+func deliveryNeeds(jobs *yaml.Node, ids []string) string {
+	for _, need := range []string{"build", "test"} {
+		if pipeline.MappingValue(jobs, need) != nil {
+			return need
+		}
+	}
+	last := ""
+	for i := 0; i+1 < len(jobs.Content); i += 2 {
+		if job := jobs.Content[i].Value; indexOf(ids, job) < 0 {
+			last = job
+		}
+	}
+	return last
+}
+
+// addJenkinsStages переносит стадии джобов p из rendered в Jenkinsfile. Существующие
+// стадии с теми же именами (стадия деплоя — с любым именем на Deploy)
+// удаляются, новые встают на место первой из них или в конец блока stages.
+func addJenkinsStages(pipelineContent, rendered string, p *pipeline.Pipeline) string {
+	root, err := parseGroovy(pipelineContent)
+	if err != nil {
+		return pipelineContent
+	}
+	stages := root.child("pipeline").child("stages")
+	if stages == nil {
+		return pipelineContent
+	}
+	addedRoot, err := parseGroovy(rendered)
+	if err != nil {
+		return pipelineContent
+	}
+	var names []string
+	for _, job := range p.Jobs {
+		names = append(names, job.Title())
+	}
+	// Служебные стадии рендера (Checkout) не переносятся
+	var added []*groovyNode
+	for _, c := range addedRoot.child("pipeline").child("stages").children {
+		if name, ok := c.stageName(); ok && indexOf(names, name) >= 0 {
+			added = append(added, c)
+		}
+	}
+	if len(added) == 0 {
+		return pipelineContent
+	}
+	replaced := func(name string) bool {
+		return indexOf(names, name) >= 0 || strings.HasPrefix(name, "Deploy") && indexOf(names, "Deploy") >= 0
+	}
+
+	indent := stages.indent() + "    "
+	for _, c := range stages.children {
+		if _, ok := c.stageName(); ok {
+			indent = c.indent()
+			break
+		}
+	}
+	var nodes []*groovyNode
+	for _, c := range added {
+		c.reindent(c.indent(), indent)
+		if len(nodes) > 0 {
+			nodes = append(nodes, &groovyNode{})
+		}
+		nodes = append(nodes, c)
+	}
+
+	at := -1
+	var children []*groovyNode
+	for _, c := range stages.children {
+		if name, ok := c.stageName(); ok && replaced(name) {
+			if at < 0 {
+				at = len(children)
 			}
+			continue
+		}
+		children = append(children, c)
+	}
+	if at < 0 {
+		if len(children) > 0 {
+			nodes = append([]*groovyNode{{}}, nodes...)
 		}
+		at = len(children)
 	}
+	children = append(children[:at], append(nodes, children[at:]...)...)
 
-	return pipelineContent + deployStage
+	// Пустые строки, которые разделяли удаленные стадии, схлопываются
+	stages.children = nil
+	for i, c := range children {
+		if i > 0 && isBlankGroovy(c) && isBlankGroovy(children[i-1]) {
+			continue
+		}
+		stages.children = append(stages.children, c)
+	}
+	return root.String()
 }
 
-func addJenkinsDeployStage(pipelineContent string, info *analyzer.ProjectInfo) string {
-	deployStage := `
-        stage('Deploy to Production') {
-            when {
-                branch 'main'
-            }
-            steps {
-                script {
-                    sshagent(['deploy-ssh-key']) {
-                        sh """
-                            ssh -o StrictHostKeyChecking=no ${DEPLOY_USER}@${DEPLOY_SERVER} "
-                                docker pull your-registry.com/your-project:latest
-                                docker stop your-project || true
-                                docker rm your-project || true  
-                                docker run -d --name your-project -p 8080:8080 your-registry.com/your-project:latest
-                            "
-                        """
-                    }
-                }
-            }
-        }
-`
-
-	if strings.Contains(pipelineContent, "stage('Deploy") {
-		deployStart := strings.Index(pipelineContent, "stage('Deploy")
-		if deployStart != -1 {
-			braceCount := 0
-			inStage := false
-			endPos := deployStart
-
-			for i := deployStart; i < len(pipelineContent); i++ {
-				if pipelineContent[i] == '{' {
-					braceCount++
-					inStage = true
-				} else if pipelineContent[i] == '}' {
-					braceCount--
-					if inStage && braceCount == 0 {
-						endPos = i + 1
-						break
-					}
-				}
-			}
+func isBlankGroovy(n *groovyNode) bool {
+	return !n.block && strings.TrimSpace(n.header) == ""
+}
 
-			if endPos > deployStart {
-				return pipelineContent[:deployStart] + deployStage + pipelineContent[endPos:]
+// kanikoArgs возвращает аргументы kaniko для стадии и аргументов сборки из
+// настроек. Значения из CI Tekton не передает: у PipelineRun их нет.
+func kanikoArgs(info *analyzer.ProjectInfo) string {
+	if info.Config == nil {
+		return ""
+	}
+	cfg := info.Config.Registry
+	var args []string
+	if cfg.Target != "" {
+		args = append(args, "--target="+cfg.Target)
+	}
+	var names []string
+	for name := range cfg.BuildArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args = append(args, fmt.Sprintf("--build-arg=%s=%s", name, cfg.BuildArgs[name]))
+	}
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString("\n        - " + strconv.Quote(arg))
+	}
+	return b.String()
+}
+
+// addTektonImageBuild добавляет в манифесты Task сборки образа kaniko и задачу
+// image-build, которая запускается после всех остальных задач Pipeline,
+// а при наличии деплоя — перед ним, с условием when, как у джоба
+// image-build других форматов. Образ публикуется в реестр registryFor
+// по параметру image, а ссылка с digest записывается в workspace, откуда
+// ее читает деплой.
+func addTektonImageBuild(pipelineContent string, info *analyzer.ProjectInfo, when pipeline.Condition) (string, error) {
+	if !info.HasDockerfile || !info.Config.StageEnabled("package") {
+		return pipelineContent, nil
+	}
+	registry, err := registryFor(info, "tekton")
+	if err != nil {
+		return "", err
+	}
+	docs, err := splitDocuments(pipelineContent)
+	if err != nil {
+		return pipelineContent, nil
+	}
+	pipelineIndex := -1
+	var spec, run *yaml.Node
+	name := ""
+	for i, doc := range docs {
+		root := doc.Content[0]
+		switch pipeline.MappingValue(root, "kind").Value {
+		case "Pipeline":
+			pipelineIndex = i
+			spec = pipeline.MappingValue(root, "spec")
+			name = pipeline.MappingValue(pipeline.MappingValue(root, "metadata"), "name").Value
+		case "PipelineRun":
+			run = pipeline.MappingValue(root, "spec")
+		}
+	}
+	tasks := pipeline.MappingValue(spec, "tasks")
+	if tasks == nil {
+		return pipelineContent, nil
+	}
+
+	// Сборка образа ждет задачи, от которых не зависит ни одна другая
+	var names []string
+	needed := map[string]bool{}
+	for _, task := range tasks.Content {
+		taskName := pipeline.MappingValue(task, "name").Value
+		if taskName == "image-build" {
+			return pipelineContent, nil
+		}
+		names = append(names, taskName)
+		if runAfter := pipeline.MappingValue(task, "runAfter"); runAfter != nil {
+			for _, dep := range runAfter.Content {
+				needed[dep.Value] = true
 			}
 		}
 	}
-	lastBraceIndex := strings.LastIndex(pipelineContent, "    }")
-	if lastBraceIndex != -1 {
-		before := pipelineContent[:lastBraceIndex]
-		after := pipelineContent[lastBraceIndex:]
-		return before + deployStage + after
+	var last []string
+	for _, taskName := range names {
+		if !needed[taskName] {
+			last = append(last, taskName)
+		}
+	}
+	// Деплой запускается после публикации образа
+	var deploy *yaml.Node
+	for _, task := range tasks.Content {
+		if pipeline.MappingValue(task, "name").Value == "deploy" {
+			deploy = task
+		}
+	}
+	if deploy != nil {
+		last = nil
+		if runAfter := pipeline.MappingValue(deploy, "runAfter"); runAfter != nil {
+			for _, dep := range runAfter.Content {
+				last = append(last, dep.Value)
+			}
+		}
+		setMappingValue(deploy, "runAfter", &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "image-build"},
+		}})
+	}
+
+	kanikoTask, err := parseNode(fmt.Sprintf(`apiVersion: tekton.dev/v1
+kind: Task
+metadata:
+  name: %s-image-build
+spec:
+  params:
+    - name: image
+      type: string
+  workspaces:
+    - name: source
+    - name: dockerconfig
+      optional: true
+      mountPath: /kaniko/.docker
+  steps:
+    - name: build-and-push
+      image: gcr.io/kaniko-project/executor:v1.23.2
+      args:
+        - --dockerfile=$(workspaces.source.path)/Dockerfile
+        - --context=dir://$(workspaces.source.path)
+        - --destination=$(params.image)
+        - --image-name-with-digest-file=$(workspaces.source.path)/%s%s
+`, name, imageRefFile, kanikoArgs(info)))
+	if err != nil {
+		return pipelineContent, nil
+	}
+	if len(registry.env) > 0 {
+		// Kubernetes подставляет $(NAME) в args из env контейнера
+		step := pipeline.MappingValue(pipeline.MappingValue(kanikoTask, "spec"), "steps").Content[0]
+		setMappingValue(step, "env", pipeline.TektonEnv(registry.env))
+	}
+	task, err := parseNode(fmt.Sprintf(`name: image-build
+taskRef:
+  name: %s-image-build
+runAfter: [%s]
+params:
+  - name: image
+    value: $(params.image)
+workspaces:
+  - name: source
+    workspace: source
+  - name: dockerconfig
+    workspace: dockerconfig
+`, name, strings.Join(last, ", ")))
+	if err != nil {
+		return pipelineContent, nil
+	}
+	if expressions := pipeline.TektonWhen(when); expressions != nil {
+		setMappingValues(task, []string{"when", "params"}, []*yaml.Node{expressions, pipeline.MappingValue(task, "params")})
+	}
+	tasks.Content = append(tasks.Content, task)
+	if params := pipeline.MappingValue(spec, "params"); params != nil {
+		if when.Tags && !hasParam(params, "tag") {
+			// Параметр tag объявляется рендером, только если на теги запускаются другие задачи
+			param, _ := parseNode("name: tag\ntype: string\ndefault: \"\"\n")
+			params.Content = append(params.Content, param)
+		}
+		param, _ := parseNode(fmt.Sprintf("name: image\ntype: string\ndefault: %q\n", tektonImage(registry.image)+":latest"))
+		params.Content = append(params.Content, param)
+	}
+	if workspaces := pipeline.MappingValue(spec, "workspaces"); workspaces != nil {
+		workspace, _ := parseNode("name: dockerconfig\noptional: true\n")
+		workspaces.Content = append(workspaces.Content, workspace)
+	}
+	if run != nil {
+		if workspaces := pipeline.MappingValue(run, "workspaces"); workspaces != nil {
+			workspace, _ := parseNode("name: dockerconfig\nsecret:\n  secretName: docker-config\n")
+			workspaces.Content = append(workspaces.Content, workspace)
+		}
 	}
 
-	return pipelineContent + deployStage
+	kaniko := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{kanikoTask}}
+	docs = append(docs[:pipelineIndex], append([]*yaml.Node{kaniko}, docs[pipelineIndex:]...)...)
+	content, err := joinDocuments(docs)
+	if err != nil {
+		return pipelineContent, nil
+	}
+	return content, nil
 }
+
+// hasParam сообщает, объявлен ли параметр в списке params Tekton.
+func hasParam(params *yaml.Node, name string) bool {
+	for _, param := range params.Content {
+		if pipeline.MappingValue(param, "name").Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+var shellVarPattern = regexp.MustCompile(`\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// tektonImage переводит переменные окружения в ссылке на образ из $NAME
+// в синтаксис Kubernetes $(NAME).
+func tektonImage(image string) string {
+	return shellVarPattern.ReplaceAllString(image, "$$($1)")
+}
+
+// imageName возвращает имя образа проекта для реестра.
+func imageName(info *analyzer.ProjectInfo) string {
+	if info.RepoName == "" {
+		return "app"
+	}
+	return strings.ToLower(info.RepoName)
+}
+
 func ProcessRepositoryList(listFile, branch, format string, maxConcurrent int) error {
 	file, err := os.Open(listFile)
 	if err != nil {
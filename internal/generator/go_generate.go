@@ -2,107 +2,99 @@ package generator
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateGoPipelineActions(info *analyzer.ProjectInfo) string {
-	var pipeline strings.Builder
-
-	pipeline.WriteString(`name: Go CI/CD Pipeline
-
-on:
-  push:
-    branches: [ main, master ]
-  pull_request:
-    branches: [ main, master ]
-
-env: 
-  GO_VERSION: `)
-
-	if info.Version != "" {
-		pipeline.WriteString(fmt.Sprintf(" '%s'", info.Version))
-	} else {
-		pipeline.WriteString(" '1.19'")
+func buildGoPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+	version := info.Version
+	if version == "" {
+		version = "1.21"
 	}
-	pipeline.WriteString(fmt.Sprintf(`
-  MAIN_PACKAGE_PATH: '%s'`, info.MainFilePath))
+	image := "golang:" + version
 
-	pipeline.WriteString(`
-   jobs:`)
+	p := &pipeline.Pipeline{
+		Name:     "Go CI/CD Pipeline",
+		Branches: []string{"main", "master"},
+		Stages:   []string{"test", "build", "deploy"},
+	}
 
 	// Job test (если есть тесты)
 	if info.HasTests {
-		pipeline.WriteString(`
-  test:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Set up Go
-      uses: actions/setup-go@v3
-      with:
-        go-version: ${{ env.GO_VERSION }}
-    - name: Download dependencies
-      run: go mod download
-    - name: Run tests
-      run: go test -v ./...`)
-
-		if strings.Contains(info.Architecture, "standard-go-layout") {
-			pipeline.WriteString(`    - name: Build all commands
-      run: go build ./cmd/...
-`)
-		} else {
-			pipeline.WriteString(`    - name: Build main package
-      run: go build ${{ env.MAIN_PACKAGE_PATH }}
-`)
-		}
-
+		test := p.AddJob(&pipeline.Job{ID: "test", Stage: "test", Image: image})
+		test.Steps = append(test.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("go", version),
+			pipeline.Run("Download dependencies", "go mod download"),
+		)
 		// Добавляем линтеры если нужно
 		if containsDependency(info.Dependencies, "web-framework") {
-			pipeline.WriteString(`    - name: Security scan
-      run: go vet ./...
-`)
+			test.Steps = append(test.Steps, pipeline.Run("Security scan", "go vet ./..."))
+		}
+		test.Steps = append(test.Steps,
+			pipeline.Run("Run tests", "go test -v -race -coverprofile=coverage.out ./..."),
+			pipeline.Run("Coverage summary", "go tool cover -func=coverage.out"),
+		)
+		if modules := moduleDirs(info.Modules); len(modules) > 0 {
+			test.Steps = append(test.Steps, pipeline.Run("Test nested modules", goModulesCommand(modules, "go test -race ./...")))
 		}
+		test.Steps = append(test.Steps, pipeline.Upload("coverage", "coverage.out"))
+		test.Coverage = `/total:\s+\(statements\)\s+(\d+\.\d+)%/`
 	}
 
 	// Job build
-	pipeline.WriteString(`
-    build:
-      runs-on: ubuntu-latest
-`)
-
-	// Добавляем зависимость только если есть тесты
+	build := p.AddJob(&pipeline.Job{ID: "build", Stage: "build", Image: image})
 	if info.HasTests {
-		pipeline.WriteString("    needs: test\n")
+		build.Needs = []string{"test"}
 	}
+	build.Steps = append(build.Steps,
+		pipeline.Checkout(),
+		pipeline.Setup("go", version),
+		pipeline.Run("Download dependencies", "go mod download"),
+	)
+	if strings.Contains(info.Architecture, "standard-go-layout") {
+		build.Steps = append(build.Steps, pipeline.Run("Build all commands", "go build ./cmd/..."))
+	}
+	if modules := moduleDirs(info.Modules); len(modules) > 0 {
+		build.Steps = append(build.Steps, pipeline.Run("Build nested modules", goModulesCommand(modules, "go build ./...")))
+	}
+	build.Steps = append(build.Steps,
+		pipeline.Run("Build main package", "mkdir -p bin\ngo build -o bin/app "+goMainPackage(info.MainFilePath)),
+		pipeline.Upload("go-binaries", "bin/"),
+	)
+	build.ExpireIn = "1 hour"
+
+	// Вложенные модули со своим go.mod собираются отдельно
+	addModuleJobs(p, info.Modules, image,
+		[]pipeline.Step{pipeline.Checkout(), pipeline.Setup("go", version), pipeline.Run("Download dependencies", "go mod download")},
+		func(string) moduleCommands {
+			return moduleCommands{test: "go test ./...", build: "go build ./...", inDir: true}
+		})
+
+	return p
+}
 
-	pipeline.WriteString(`    steps:
-    - uses: actions/checkout@v4
-    
-    - name: Set up Go
-      uses: actions/setup-go@v4
-      with:
-        go-version: ${{ env.GO_VERSION }}
-        
-    - name: Download dependencies
-      run: go mod download
-`)
-
-	pipeline.WriteString(`    - name: Build main package
-      run: |
-        mkdir -p bin
-        go build -o bin/app ${{ env.MAIN_PACKAGE_PATH }}
-`)
-
-	pipeline.WriteString(`    - name: Upload artifacts
-      uses: actions/upload-artifact@v4
-      with:
-        name: go-binaries
-        path: bin/
-`)
+// goModulesCommand выполняет команду в каталоге каждого вложенного модуля:
+// ./... корневого модуля не заходит в каталоги со своим go.mod.
+func goModulesCommand(modules []string, command string) string {
+	return fmt.Sprintf(`for module in %s; do
+  (cd "$module" && %s) || exit 1
+done`, strings.Join(modules, " "), command)
+}
 
-	return pipeline.String()
+// goMainPackage возвращает пакет, который нужно собрать, по пути к файлу с main.
+func goMainPackage(mainFilePath string) string {
+	if mainFilePath == "" {
+		return "."
+	}
+	dir := filepath.ToSlash(filepath.Dir(mainFilePath))
+	if dir == "." {
+		return "."
+	}
+	return "./" + dir
 }
 
 func containsDependency(deps []string, depType string) bool {
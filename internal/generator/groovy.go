@@ -0,0 +1,168 @@
+package generator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// groovyNode — строка Jenkinsfile или блок: строка header, которая открывает
+// фигурную скобку, вложенные узлы и строка footer с закрывающей скобкой.
+// Модели блоков достаточно, чтобы находить и заменять стадии, не разбирая
+// Groovy целиком.
+type groovyNode struct {
+	header   string
+	children []*groovyNode
+	footer   string
+	block    bool
+}
+
+var groovyStage = regexp.MustCompile(`^stage\(\s*['"](.*?)['"]\s*\)\s*\{$`)
+
+// parseGroovy разбирает Jenkinsfile в дерево блоков. Скобки внутри строк
+// и комментариев не учитываются.
+func parseGroovy(content string) (*groovyNode, error) {
+	root := &groovyNode{block: true}
+	stack := []*groovyNode{root}
+	var scanner groovyScanner
+	for i, line := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		current := stack[len(stack)-1]
+		switch depth := scanner.scan(line); {
+		case depth == 0:
+			current.children = append(current.children, &groovyNode{header: line})
+		case depth == 1:
+			block := &groovyNode{header: line, block: true}
+			current.children = append(current.children, block)
+			stack = append(stack, block)
+		case depth == -1 && len(stack) > 1:
+			current.footer = line
+			stack = stack[:len(stack)-1]
+		default:
+			return nil, fmt.Errorf("line %d: unbalanced braces", i+1)
+		}
+	}
+	if len(stack) != 1 || scanner.quote != "" || scanner.comment {
+		return nil, fmt.Errorf("unexpected end of Jenkinsfile")
+	}
+	return root, nil
+}
+
+// groovyScanner считает скобки построчно и помнит незакрытые многострочные
+// строки и комментарии.
+type groovyScanner struct {
+	quote   string // открытая многострочная строка: ''' или """
+	comment bool   // открытый комментарий /* */
+}
+
+// scan возвращает, на сколько строка меняет вложенность блоков.
+func (s *groovyScanner) scan(line string) int {
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		rest := line[i:]
+		switch {
+		case s.comment:
+			if strings.HasPrefix(rest, "*/") {
+				s.comment = false
+				i++
+			}
+		case s.quote != "":
+			if rest[0] == '\\' {
+				i++
+			} else if strings.HasPrefix(rest, s.quote) {
+				i += len(s.quote) - 1
+				s.quote = ""
+			}
+		case strings.HasPrefix(rest, "//"):
+			return depth
+		case strings.HasPrefix(rest, "/*"):
+			s.comment = true
+			i++
+		case strings.HasPrefix(rest, `"""`) || strings.HasPrefix(rest, "'''"):
+			s.quote = rest[:3]
+			i += 2
+		case rest[0] == '"' || rest[0] == '\'':
+			// Однострочная строка: закрывающая кавычка на той же строке
+			for i++; i < len(line) && line[i] != rest[0]; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+		case rest[0] == '{':
+			depth++
+		case rest[0] == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// String собирает Jenkinsfile обратно.
+func (n *groovyNode) String() string {
+	var lines []string
+	n.write(&lines, true)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func (n *groovyNode) write(lines *[]string, root bool) {
+	if !n.block {
+		*lines = append(*lines, n.header)
+		return
+	}
+	if !root {
+		*lines = append(*lines, n.header)
+	}
+	for _, child := range n.children {
+		child.write(lines, false)
+	}
+	if !root {
+		*lines = append(*lines, n.footer)
+	}
+}
+
+// child возвращает вложенный блок с заголовком name { или nil.
+func (n *groovyNode) child(name string) *groovyNode {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.children {
+		header := strings.TrimSpace(c.header)
+		if c.block && strings.HasPrefix(header, name) && strings.TrimSpace(strings.TrimPrefix(header, name)) == "{" {
+			return c
+		}
+	}
+	return nil
+}
+
+// stageName возвращает имя стадии, если узел — блок stage('...').
+func (n *groovyNode) stageName() (string, bool) {
+	if !n.block {
+		return "", false
+	}
+	m := groovyStage.FindStringSubmatch(strings.TrimSpace(n.header))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// indent возвращает отступ строки заголовка.
+func (n *groovyNode) indent() string {
+	return n.header[:len(n.header)-len(strings.TrimLeft(n.header, " \t"))]
+}
+
+// reindent заменяет отступ блока from на to во всех его строках.
+func (n *groovyNode) reindent(from, to string) {
+	shift := func(line string) string {
+		if strings.HasPrefix(line, from) {
+			return to + line[len(from):]
+		}
+		return line
+	}
+	n.header = shift(n.header)
+	if n.block {
+		n.footer = shift(n.footer)
+	}
+	for _, child := range n.children {
+		child.reindent(from, to)
+	}
+}
@@ -0,0 +1,402 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// imageRefFile — файл, в который джоб image-build записывает ссылку на
+// опубликованный образ с digest. Деплой читает ее из артефакта image-ref.
+const imageRefFile = "image-ref.txt"
+
+// ciVars — встроенные переменные CI-системы, от которых зависят теги образа.
+type ciVars struct {
+	sha      string // SHA коммита
+	tag      string // имя тега или ссылка refs/tags/..., в сборках веток пустая или ветка
+	artifact string // путь к артефакту image-ref у джоба деплоя, если он не в рабочем каталоге
+}
+
+var formatCIVars = map[string]ciVars{
+	"github":     {sha: "$GITHUB_SHA", tag: "$GITHUB_REF"},
+	"forgejo":    {sha: "$GITHUB_SHA", tag: "$GITHUB_REF"},
+	"gitlab":     {sha: "$CI_COMMIT_SHA", tag: "$CI_COMMIT_TAG"},
+	"jenkins":    {sha: "$GIT_COMMIT", tag: "$TAG_NAME"},
+	"azure":      {sha: "$BUILD_SOURCEVERSION", tag: "$BUILD_SOURCEBRANCH", artifact: "$PIPELINE_WORKSPACE/image-ref/" + imageRefFile},
+	"circleci":   {sha: "$CIRCLE_SHA1", tag: "$CIRCLE_TAG"},
+	"bitbucket":  {sha: "$BITBUCKET_COMMIT", tag: "$BITBUCKET_TAG"},
+	"woodpecker": {sha: "$CI_COMMIT_SHA", tag: "$CI_COMMIT_TAG"},
+	"drone":      {sha: "$DRONE_COMMIT_SHA", tag: "$DRONE_TAG"},
+	"codebuild":  {sha: "$CODEBUILD_RESOLVED_SOURCE_VERSION", tag: "$CODEBUILD_WEBHOOK_TRIGGER"},
+	"cloudbuild": {sha: "$COMMIT_SHA", tag: "$TAG_NAME"},
+}
+
+// defaultRegistries — реестр, в который по умолчанию публикует образ
+// каждая CI-система. Остальные форматы используют реестр из секрета
+// REGISTRY_URL.
+var defaultRegistries = map[string]string{
+	"github":     "ghcr",
+	"gitlab":     "gitlab",
+	"codebuild":  "ecr",
+	"cloudbuild": "gcr",
+}
+
+// imageRegistry описывает, куда публикуется образ и как войти в реестр.
+type imageRegistry struct {
+	image string         // ссылка на образ без тега, может содержать переменные окружения
+	login string         // команда входа в реестр, пустая, если CI-система входит сама
+	env   []pipeline.Var // переменные, на которые ссылается image
+	auth  []pipeline.Var // учетные данные для login
+}
+
+// registryLogin входит в реестр host с учетными данными из REGISTRY_USER
+// и REGISTRY_PASSWORD.
+func registryLogin(host string) string {
+	return fmt.Sprintf(`echo "$REGISTRY_PASSWORD" | docker login %s -u "$REGISTRY_USER" --password-stdin`, host)
+}
+
+var registryAuth = []pipeline.Var{
+	{Name: "REGISTRY_USER", Value: pipeline.SecretRef("REGISTRY_USER")},
+	{Name: "REGISTRY_PASSWORD", Value: pipeline.SecretRef("REGISTRY_PASSWORD")},
+}
+
+// registryFor выбирает реестр образа по настройкам проекта и формату.
+func registryFor(info *analyzer.ProjectInfo, format string) (imageRegistry, error) {
+	var cfg analyzer.RegistryConfig
+	if info.Config != nil {
+		cfg = info.Config.Registry
+	}
+	provider := cfg.Provider
+	if provider == "" && cfg.URL != "" {
+		provider = "custom"
+	}
+	if provider == "" {
+		provider = defaultRegistries[format]
+	}
+	name := cfg.Image
+	if name == "" {
+		name = imageName(info)
+	}
+
+	switch provider {
+	case "ghcr":
+		if format == "github" {
+			// Пакеты публикуются от имени владельца репозитория токеном workflow
+			return imageRegistry{
+				image: "ghcr.io/$GITHUB_REPOSITORY_OWNER/" + name,
+				login: registryLogin("ghcr.io"),
+				auth: []pipeline.Var{
+					{Name: "REGISTRY_USER", Value: "${{ github.actor }}"},
+					{Name: "REGISTRY_PASSWORD", Value: pipeline.SecretRef("GITHUB_TOKEN")},
+				},
+			}, nil
+		}
+		return userRegistry("ghcr.io", name), nil
+	case "gitlab":
+		if format == "gitlab" {
+			return imageRegistry{
+				image: "$CI_REGISTRY_IMAGE",
+				login: `echo "$CI_REGISTRY_PASSWORD" | docker login "$CI_REGISTRY" -u "$CI_REGISTRY_USER" --password-stdin`,
+			}, nil
+		}
+		return userRegistry("registry.gitlab.com", name), nil
+	case "dockerhub":
+		return userRegistry("docker.io", name), nil
+	case "ecr":
+		// Адрес репозитория ECR задается в переменной проекта CodeBuild
+		return imageRegistry{
+			image: "$REPOSITORY_URI",
+			login: `aws ecr get-login-password --region "$AWS_DEFAULT_REGION" | docker login --username AWS --password-stdin "${REPOSITORY_URI%%/*}"`,
+			env:   []pipeline.Var{{Name: "REPOSITORY_URI", Value: "ACCOUNT_ID.dkr.ecr.REGION.amazonaws.com/" + name}},
+		}, nil
+	case "gcr":
+		if format == "cloudbuild" {
+			// Cloud Build входит в реестр проекта сам
+			return imageRegistry{image: "gcr.io/$PROJECT_ID/" + name}, nil
+		}
+		return imageRegistry{
+			image: "gcr.io/$GCP_PROJECT_ID/" + name,
+			login: `echo "$GCP_SA_KEY" | docker login gcr.io -u _json_key --password-stdin`,
+			env:   []pipeline.Var{{Name: "GCP_PROJECT_ID", Value: pipeline.SecretRef("GCP_PROJECT_ID")}},
+			auth:  []pipeline.Var{{Name: "GCP_SA_KEY", Value: pipeline.SecretRef("GCP_SA_KEY")}},
+		}, nil
+	case "", "custom":
+		if cfg.URL != "" {
+			url := strings.TrimSuffix(cfg.URL, "/")
+			return imageRegistry{
+				image: url + "/" + name,
+				login: registryLogin(strings.SplitN(url, "/", 2)[0]),
+				auth:  registryAuth,
+			}, nil
+		}
+		return imageRegistry{
+			image: "$REGISTRY_URL/" + name,
+			login: registryLogin(`"${REGISTRY_URL%%/*}"`),
+			env:   []pipeline.Var{{Name: "REGISTRY_URL", Value: pipeline.SecretRef("REGISTRY_URL")}},
+			auth:  registryAuth,
+		}, nil
+	default:
+		return imageRegistry{}, fmt.Errorf("unknown registry %s (supported: ghcr, gitlab, dockerhub, ecr, gcr, custom)", provider)
+	}
+}
+
+// userRegistry — реестр, в котором образы лежат в пространстве имен
+// пользователя REGISTRY_USER.
+func userRegistry(host, name string) imageRegistry {
+	return imageRegistry{
+		image: host + "/$REGISTRY_USER/" + name,
+		login: registryLogin(host),
+		env:   registryAuth[:1],
+		auth:  registryAuth[1:],
+	}
+}
+
+// imageJob собирает образ из Dockerfile через buildx и публикует его с тегами
+// SHA коммита, версии (для тегов vX.Y.Z) и latest (для веток). Кеш слоев
+// хранится в образе latest. Ссылка на образ с digest сохраняется
+// в артефакт image-ref для деплоя. Скрипт сборки запускается с set -e:
+// Azure Pipelines и CodeBuild иначе проверяют только код последней
+// команды, и деплой получил бы ссылку без digest.
+func imageJob(info *analyzer.ProjectInfo, p *pipeline.Pipeline, format string) (*pipeline.Job, error) {
+	// Tekton собирает образ kaniko без Docker daemon
+	if !info.HasDockerfile || !info.Config.StageEnabled("package") || format == "tekton" {
+		return nil, nil
+	}
+	registry, err := registryFor(info, format)
+	if err != nil {
+		return nil, err
+	}
+	ci := formatCIVars[format]
+
+	image := "docker:27-cli"
+	if format == "cloudbuild" {
+		// Шаги Cloud Build запускаются через bash
+		image = "gcr.io/cloud-builders/docker"
+	}
+	job := &pipeline.Job{
+		ID:     "image-build",
+		Stage:  "package",
+		Image:  image,
+		Needs:  deployNeedsJobs(p),
+		Env:    append(append([]pipeline.Var{}, registry.env...), registry.auth...),
+		When:   imageCondition(p),
+		Docker: true,
+		Steps: []pipeline.Step{
+			pipeline.Checkout(),
+			pipeline.Action("Set up Docker Buildx", "docker/setup-buildx-action@v3", ""),
+		},
+	}
+	if format == "github" && strings.HasPrefix(registry.image, "ghcr.io/") {
+		job.Permissions = []pipeline.Var{{Name: "contents", Value: "read"}, {Name: "packages", Value: "write"}}
+	}
+	if registry.login != "" {
+		job.Steps = append(job.Steps, pipeline.Run("Log in to registry", registry.login))
+	}
+	options, secrets, err := buildOptions(info, ci)
+	if err != nil {
+		return nil, err
+	}
+	job.Env = append(job.Env, secretEnv(secrets...)...)
+	job.Steps = append(job.Steps,
+		pipeline.Run("Build and push image", fmt.Sprintf(`set -e
+IMAGE=$(echo "%s" | tr '[:upper:]' '[:lower:]')
+VERSION=$(echo "%s" | sed -nE 's#^(refs/tags/|tag/)?v?([0-9]+\.[0-9]+\.[0-9]+.*)$#\2#p')
+TAGS="--tag $IMAGE:%s"
+if [ -n "$VERSION" ]; then TAGS="$TAGS --tag $IMAGE:$VERSION"; else TAGS="$TAGS --tag $IMAGE:latest"; fi
+docker buildx build --push $TAGS%s \
+  --cache-from "$IMAGE:latest" --cache-to type=inline \
+  --metadata-file image-metadata.json .
+DIGEST=$(sed -nE 's/.*"containerimage.digest": *"([^"]+)".*/\1/p' image-metadata.json)
+if [ -z "$DIGEST" ]; then echo "Image digest not found in image-metadata.json" >&2; exit 1; fi
+echo "$IMAGE@$DIGEST" > %s`, registry.image, ci.tag, ci.sha, options, imageRefFile)),
+		pipeline.Upload("image-ref", imageRefFile),
+	)
+	return job, nil
+}
+
+// predefinedArgs задает BuildKit или прокси сам, передавать их не нужно.
+var predefinedArgs = map[string]bool{
+	"TARGETPLATFORM": true, "TARGETOS": true, "TARGETARCH": true, "TARGETVARIANT": true,
+	"BUILDPLATFORM": true, "BUILDOS": true, "BUILDARCH": true, "BUILDVARIANT": true,
+	"HTTP_PROXY": true, "HTTPS_PROXY": true, "FTP_PROXY": true, "NO_PROXY": true, "ALL_PROXY": true,
+	"http_proxy": true, "https_proxy": true, "ftp_proxy": true, "no_proxy": true, "all_proxy": true,
+}
+
+// metadataArgs — аргументы с метаданными сборки, значения которых берутся
+// из CI: SHA коммита, версия из тега и время сборки.
+var metadataArgs = map[string]string{
+	"COMMIT":      "revision",
+	"COMMIT_SHA":  "revision",
+	"GIT_COMMIT":  "revision",
+	"GIT_SHA":     "revision",
+	"REVISION":    "revision",
+	"VERSION":     "version",
+	"APP_VERSION": "version",
+	"BUILD_DATE":  "date",
+	"CREATED":     "date",
+}
+
+// buildOptions возвращает флаги --target и --build-arg для docker buildx
+// build и имена секретов, из которых берутся аргументы без значения.
+// Аргументы со значением по умолчанию передаются, только если значение
+// задано в настройках или берется из CI.
+func buildOptions(info *analyzer.ProjectInfo, ci ciVars) (string, []string, error) {
+	var cfg analyzer.RegistryConfig
+	if info.Config != nil {
+		cfg = info.Config.Registry
+	}
+	d := info.Dockerfile
+	if d == nil {
+		d = &analyzer.Dockerfile{}
+	}
+
+	var options []string
+	var secrets []string
+	if cfg.Target != "" {
+		if indexOf(d.Targets(), cfg.Target) < 0 {
+			return "", nil, fmt.Errorf("unknown Dockerfile target %s (stages: %s)", cfg.Target, strings.Join(d.Targets(), ", "))
+		}
+		options = append(options, "--target "+cfg.Target)
+	}
+	for _, arg := range d.Args {
+		if value, ok := cfg.BuildArgs[arg.Name]; ok {
+			options = append(options, fmt.Sprintf("--build-arg %s=%q", arg.Name, value))
+			continue
+		}
+		switch metadataArgs[arg.Name] {
+		case "revision":
+			options = append(options, fmt.Sprintf(`--build-arg %s="%s"`, arg.Name, ci.sha))
+		case "version":
+			fallback := arg.Default
+			if !arg.HasDefault {
+				fallback = ci.sha
+			}
+			options = append(options, fmt.Sprintf(`--build-arg %s="${VERSION:-%s}"`, arg.Name, fallback))
+		case "date":
+			options = append(options, fmt.Sprintf(`--build-arg %s="$(date -u +%%Y-%%m-%%dT%%H:%%M:%%SZ)"`, arg.Name))
+		default:
+			if !arg.HasDefault && !predefinedArgs[arg.Name] {
+				// Значение берется из переменной окружения с тем же именем
+				options = append(options, "--build-arg "+arg.Name)
+				secrets = append(secrets, arg.Name)
+			}
+		}
+	}
+	// Аргументы из настроек, которых нет в Dockerfile, передаются как есть:
+	// о неиспользованных аргументах предупредит сам docker
+	var extra []string
+	for name := range cfg.BuildArgs {
+		if !d.HasArg(name) {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		options = append(options, fmt.Sprintf("--build-arg %s=%q", name, cfg.BuildArgs[name]))
+	}
+
+	if len(options) == 0 {
+		return "", secrets, nil
+	}
+	return " \\\n  " + strings.Join(options, " \\\n  "), secrets, nil
+}
+
+// imageCondition — условие публикации образа: основные ветки pipeline и теги.
+func imageCondition(p *pipeline.Pipeline) pipeline.Condition {
+	branches := p.Branches
+	if len(branches) == 0 {
+		branches = []string{"main"}
+	}
+	return pipeline.Condition{Branches: branches, Tags: true}
+}
+
+// addImageJob добавляет в pipeline стадию package с джобом image-build
+// перед деплоем.
+func addImageJob(p *pipeline.Pipeline, info *analyzer.ProjectInfo, format string) error {
+	job, err := imageJob(info, p, format)
+	if err != nil || job == nil {
+		return err
+	}
+	if indexOf(p.Stages, job.Stage) < 0 {
+		if at := indexOf(p.Stages, "deploy"); at >= 0 {
+			p.Stages = append(p.Stages[:at], append([]string{job.Stage}, p.Stages[at:]...)...)
+		} else {
+			p.Stages = append(p.Stages, job.Stage)
+		}
+	}
+	p.RemoveJobs(func(existing *pipeline.Job) bool { return existing.ID == job.ID })
+	p.Jobs = append(p.Jobs, job)
+	return nil
+}
+
+// resolveImageRef добавляет в команды джоба деплоя, которые ссылаются на
+// $IMAGE_REF, чтение опубликованного образа из артефакта image-ref. Без
+// артефакта (CircleCI не передает файлы между джобами, Tekton собирает
+// образ сам) используется тег SHA коммита или latest. Деплой ждет джоб
+// image-build; если pipeline образ не собирает, он должен быть задан
+// в registry.image.
+func resolveImageRef(job *pipeline.Job, info *analyzer.ProjectInfo, p *pipeline.Pipeline, format string) error {
+	uses := false
+	for _, step := range job.Steps {
+		uses = uses || strings.Contains(step.Command, "$IMAGE_REF")
+	}
+	if !uses {
+		return nil
+	}
+	built := p.Job("image-build") != nil
+	if format == "tekton" {
+		// Задача сборки образа добавляется в манифесты после рендера
+		built = info.HasDockerfile && info.Config.StageEnabled("package")
+	}
+	if !built && (info.Config == nil || info.Config.Registry.Image == "") {
+		if !info.HasDockerfile {
+			return fmt.Errorf("deploys an image, but there is no Dockerfile to build it: add a Dockerfile or set registry.image")
+		}
+		return fmt.Errorf("deploys an image, but the package stage that builds it is disabled: enable it or set registry.image")
+	}
+	if p.Job("image-build") != nil && indexOf(job.Needs, "image-build") < 0 {
+		job.Needs = append(job.Needs, "image-build")
+	}
+	registry, err := registryFor(info, format)
+	if err != nil {
+		return err
+	}
+	ci := formatCIVars[format]
+	tag := ci.sha
+	if tag == "" {
+		tag = "latest"
+	}
+	files := imageRefFile
+	if ci.artifact != "" {
+		files += " " + ci.artifact
+	}
+	resolve := fmt.Sprintf(`IMAGE_REF=$(cat %s 2>/dev/null | head -n 1)
+[ -n "$IMAGE_REF" ] || IMAGE_REF=$(echo "%s:%s" | tr '[:upper:]' '[:lower:]')`, files, registry.image, tag)
+	for i, step := range job.Steps {
+		if strings.Contains(step.Command, "$IMAGE_REF") {
+			job.Steps[i].Command = resolve + "\n" + step.Command
+		}
+	}
+	for _, v := range registry.env {
+		if !hasVar(job.Env, v.Name) {
+			job.Env = append(job.Env, v)
+		}
+	}
+	if p.Job("image-build") != nil && (format == "github" || format == "forgejo") {
+		download := pipeline.Action("Download image reference", "actions/download-artifact@v4", "", pipeline.Var{Name: "name", Value: "image-ref"})
+		job.Steps = append([]pipeline.Step{download}, job.Steps...)
+	}
+	return nil
+}
+
+func hasVar(vars []pipeline.Var, name string) bool {
+	for _, v := range vars {
+		if v.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -5,322 +5,145 @@ import (
 	"strings"
 
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateJavaPipeline(info *analyzer.ProjectInfo) string {
-	var pipeline strings.Builder
-
+func buildJavaPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
 	// Умное определение билд-тула с проверкой структуры проекта
 	buildTool := detectBuildTool(info)
+	version := getDefaultJavaVersion(info)
+
+	p := &pipeline.Pipeline{
+		Name:     fmt.Sprintf("Java CI/CD Pipeline (%s)", strings.Title(buildTool)),
+		Branches: []string{"main", "master", "develop"},
+		Stages:   []string{"test", "build", "quality", "publish", "deploy"},
+		Env:      append([]pipeline.Var{{Name: "DEFAULT_JAVA_VERSION", Value: version}}, javaCacheEnv(buildTool)...),
+		Cache:    javaCache(buildTool),
+	}
 
-	pipeline.WriteString(fmt.Sprintf(`name: Java CI/CD Pipeline (%s)
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
-
-env:
-  DEFAULT_JAVA_VERSION: '%s'
-
-jobs:
-`, strings.Title(buildTool), getDefaultJavaVersion(info)))
-
-	// Job для тестов или проверки
+	previousJob := "verify"
 	if info.HasTests {
-		pipeline.WriteString(`  test:
-    runs-on: ubuntu-latest
-    strategy:
-      matrix:
-        java-version: [`)
+		previousJob = "test"
 
 		// Умный подбор версий Java
-		versions := getJavaTestVersions(info)
-		for i, v := range versions {
-			if i > 0 {
-				pipeline.WriteString(", ")
-			}
-			pipeline.WriteString(fmt.Sprintf("'%s'", v))
-		}
-
-		pipeline.WriteString(`]
-    steps:
-    - uses: actions/checkout@v4
-    
-    - name: Validate project structure
-      run: |
-        if [ ! -f "build.gradle" ] && [ ! -f "pom.xml" ] && [ ! -f "build.gradle.kts" ]; then
-          echo "❌ No build configuration found (build.gradle, pom.xml, build.gradle.kts)"
-          exit 1
-        fi
-        echo "✅ Build configuration found"
-    
-    - name: Set up JDK ${{ matrix.java-version }}
-      uses: actions/setup-java@v4
-      with:
-        java-version: ${{ matrix.java-version }}
-        distribution: 'temurin'
-        cache: '` + buildTool + `'
-`)
-
-		// Общие шаги для сборки
-		pipeline.WriteString(buildToolSetupSteps(buildTool, true))
-
-		// Запуск тестов
-		if buildTool == "gradle" {
-			pipeline.WriteString(`    - name: Run tests with Gradle
-      run: |
-        if [ -f "gradlew" ]; then
-          ./gradlew test --no-daemon
-        else
-          gradle test --no-daemon
-        fi
-`)
-		} else {
-			pipeline.WriteString(`    - name: Run tests with Maven
-      run: mvn test -B
-`)
-		}
+		test := p.AddJob(&pipeline.Job{
+			ID:     "test",
+			Stage:  "test",
+			Image:  javaImage(buildTool, pipeline.MatrixRef("java-version")),
+			Matrix: []pipeline.Axis{{Name: "java-version", Values: getJavaTestVersions(info)}},
+		})
+		test.Steps = append(test.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("java", pipeline.MatrixRef("java-version"), pipeline.Var{Name: "cache", Value: buildTool}),
+			pipeline.Run("Validate project structure", `if [ ! -f "build.gradle" ] && [ ! -f "pom.xml" ] && [ ! -f "build.gradle.kts" ]; then
+  echo "No build configuration found (build.gradle, pom.xml, build.gradle.kts)"
+  exit 1
+fi`),
+		)
+		test.Steps = append(test.Steps, javaToolSteps(buildTool)...)
+		test.Steps = append(test.Steps, pipeline.Run("Run tests", javaCommand(buildTool, "test", "mvn test -B")))
 
 		// Для многомодульных проектов - дополнительная проверка
 		if len(info.Modules) > 0 {
-			pipeline.WriteString(fmt.Sprintf(`    - name: Verify module structure
-      run: echo "Project has %d modules" && ls -la
-`, len(info.Modules)))
+			test.Steps = append(test.Steps, pipeline.Run("Verify module structure",
+				fmt.Sprintf(`echo "Project has %d modules" && ls -la`, len(info.Modules))))
 		}
 
 		// Отчет о покрытии
 		if hasCoverageTool(info.Dependencies) {
-			pipeline.WriteString(`    - name: Upload coverage reports
-      uses: codecov/codecov-action@v4
-      with:
-        file: ./build/reports/jacoco/test/jacocoTestReport.xml
-        flags: unittests
-        name: codecov-umbrella
-`)
+			report := "target/site/jacoco/jacoco.xml"
+			if buildTool == "gradle" {
+				report = "build/reports/jacoco/test/jacocoTestReport.xml"
+			}
+			test.Steps = append(test.Steps, pipeline.CoverageReport("jacoco", report))
 		}
 
 		// Сохранение результатов тестов
-		pipeline.WriteString(`    - name: Upload test results
-      uses: actions/upload-artifact@v4
-      if: always()
-      with:
-        name: test-results-${{ matrix.java-version }}
-        path: |
-          build/reports/tests/
-          target/surefire-reports/
-        retention-days: 7
-`)
-
+		test.Steps = append(test.Steps, pipeline.Upload("test-results-"+pipeline.MatrixRef("java-version"),
+			"build/reports/tests/", "target/surefire-reports/"))
+		test.ExpireIn = "7 days"
 	} else {
 		// Если тестов нет - простая проверка сборки
-		pipeline.WriteString(`  verify:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v4
-    
-    - name: Set up JDK
-      uses: actions/setup-java@v4
-      with:
-        java-version: ${{ env.DEFAULT_JAVA_VERSION }}
-        distribution: 'temurin'
-        cache: '` + buildTool + `'
-`)
-
-		pipeline.WriteString(buildToolSetupSteps(buildTool, false))
-
-		if buildTool == "gradle" {
-			pipeline.WriteString(`    - name: Verify Gradle build
-      run: |
-        if [ -f "gradlew" ]; then
-          ./gradlew assemble -x test --no-daemon
-        else
-          gradle assemble -x test --no-daemon
-        fi
-`)
-		} else {
-			pipeline.WriteString(`    - name: Verify Maven build
-      run: mvn compile -B -q
-`)
-		}
+		verify := p.AddJob(&pipeline.Job{ID: "verify", Stage: "test", Image: javaImage(buildTool, version)})
+		verify.Steps = append(verify.Steps, javaSetupSteps(buildTool, version)...)
+		verify.Steps = append(verify.Steps, pipeline.Run("Verify build",
+			javaCommand(buildTool, "assemble -x test", "mvn compile -B -q")))
 	}
 
 	// Job для сборки
-	previousJob := "test"
-	if !info.HasTests {
-		previousJob = "verify"
-	}
-
-	pipeline.WriteString(fmt.Sprintf(`  build:
-    runs-on: ubuntu-latest
-    needs: %s
-    steps:
-    - uses: actions/checkout@v4
-    
-    - name: Set up JDK
-      uses: actions/setup-java@v4
-      with:
-        java-version: ${{ env.DEFAULT_JAVA_VERSION }}
-        distribution: 'temurin'
-        cache: '`+buildTool+`'
-`, previousJob))
-
-	pipeline.WriteString(buildToolSetupSteps(buildTool, false))
-
-	// Логика сборки
+	build := p.AddJob(&pipeline.Job{
+		ID:       "build",
+		Stage:    "build",
+		Image:    javaImage(buildTool, version),
+		Needs:    []string{previousJob},
+		ExpireIn: "30 days",
+	})
+	build.Steps = append(build.Steps, javaSetupSteps(buildTool, version)...)
 	if buildTool == "gradle" {
-		pipeline.WriteString(`    - name: Build with Gradle
-      run: |
-        if [ -f "gradlew" ]; then
-          ./gradlew build -x test --no-daemon
-        else
-          gradle build -x test --no-daemon
-        fi
-        
-    - name: Verify artifacts existence
-      run: |
-        echo "Checking for artifacts in build/libs/:"
-        ls -la build/libs/ || echo "No artifacts found in build/libs/"
-        find . -name "*.jar" -o -name "*.war" | head -10 || echo "No JAR/WAR files found"
-        
-    - name: Upload JAR artifacts
-      uses: actions/upload-artifact@v4
-      with:
-        name: java-artifacts
-        path: |
-          build/libs/*.jar
-          build/libs/*.war
-        retention-days: 30
-`)
-
+		build.Steps = append(build.Steps,
+			pipeline.Run("Build with Gradle", javaCommand(buildTool, "build -x test", "")),
+			pipeline.Run("Verify artifacts existence", `ls -la build/libs/ || echo "No artifacts found in build/libs/"`),
+			pipeline.Upload("java-artifacts", "build/libs/*.jar", "build/libs/*.war"),
+		)
 		// Для Spring Boot приложений
 		if containsDependency(info.Dependencies, "spring-boot") {
-			pipeline.WriteString(`    - name: Build Spring Boot executable
-      run: |
-        if [ -f "gradlew" ]; then
-          ./gradlew bootJar --no-daemon
-        else
-          gradle bootJar --no-daemon
-        fi
-        
-    - name: Upload Spring Boot JAR
-      uses: actions/upload-artifact@v4
-      with:
-        name: spring-boot-app
-        path: build/libs/*-boot.jar
-        retention-days: 30
-`)
+			build.Steps = append(build.Steps,
+				pipeline.Run("Build Spring Boot executable", javaCommand(buildTool, "bootJar", "")),
+				pipeline.Upload("spring-boot-app", "build/libs/*-boot.jar"),
+			)
 		}
 	} else {
-		pipeline.WriteString(`    - name: Build with Maven
-      run: mvn package -DskipTests -B
-      
-    - name: Verify artifacts existence  
-      run: |
-        echo "Checking for artifacts in target/:"
-        ls -la target/ || echo "No artifacts found in target/"
-        find . -name "*.jar" -o -name "*.war" | head -10 || echo "No JAR/WAR files found"
-        
-    - name: Upload JAR artifacts
-      uses: actions/upload-artifact@v4
-      with:
-        name: java-artifacts  
-        path: |
-          target/*.jar
-          target/*.war
-        retention-days: 30
-`)
+		build.Steps = append(build.Steps,
+			pipeline.Run("Build with Maven", "mvn package -DskipTests -B"),
+			pipeline.Run("Verify artifacts existence", `ls -la target/ || echo "No artifacts found in target/"`),
+			pipeline.Upload("java-artifacts", "target/*.jar", "target/*.war"),
+		)
 	}
 
 	// Job для линтинга/проверки качества кода
 	if hasQualityTools(info.Dependencies) {
-		pipeline.WriteString(`  quality:
-    runs-on: ubuntu-latest
-    needs: ` + previousJob + `
-    steps:
-    - uses: actions/checkout@v4
-    
-    - name: Set up JDK
-      uses: actions/setup-java@v4
-      with:
-        java-version: ${{ env.DEFAULT_JAVA_VERSION }}
-        distribution: 'temurin'
-        cache: '` + buildTool + `'
-`)
-
-		pipeline.WriteString(buildToolSetupSteps(buildTool, false))
-
-		if buildTool == "gradle" {
-			pipeline.WriteString(`    - name: Run quality checks
-      run: |
-        if [ -f "gradlew" ]; then
-          ./gradlew checkstyleMain spotbugsMain pmdMain --no-daemon
-        else
-          gradle checkstyleMain spotbugsMain pmdMain --no-daemon
-        fi
-`)
-		} else {
-			pipeline.WriteString(`    - name: Run quality checks
-      run: mvn checkstyle:check pmd:check spotbugs:check -B
-`)
-		}
-
-		pipeline.WriteString(`    - name: Upload quality reports
-      uses: actions/upload-artifact@v4
-      with:
-        name: quality-reports
-        path: |
-          build/reports/
-          target/site/
-        retention-days: 7
-`)
+		quality := p.AddJob(&pipeline.Job{
+			ID:       "quality",
+			Stage:    "quality",
+			Image:    javaImage(buildTool, version),
+			Needs:    []string{previousJob},
+			ExpireIn: "7 days",
+		})
+		quality.Steps = append(quality.Steps, javaSetupSteps(buildTool, version)...)
+		quality.Steps = append(quality.Steps,
+			pipeline.Run("Run quality checks", javaCommand(buildTool,
+				"checkstyleMain spotbugsMain pmdMain", "mvn checkstyle:check pmd:check spotbugs:check -B")),
+			pipeline.Upload("quality-reports", "build/reports/", "target/site/"),
+		)
 	}
 
 	// Job для публикации
 	if hasPublishingConfig(info.Dependencies) {
-		pipeline.WriteString(`  publish:
-    runs-on: ubuntu-latest
-    needs: build
-    if: github.event_name == 'push' && contains(github.ref, 'refs/tags/')
-    steps:
-    - uses: actions/checkout@v4
-    
-    - name: Set up JDK
-      uses: actions/setup-java@v4
-      with:
-        java-version: ${{ env.DEFAULT_JAVA_VERSION }}
-        distribution: 'temurin'
-        cache: '` + buildTool + `'
-`)
-
-		pipeline.WriteString(buildToolSetupSteps(buildTool, false))
+		publish := p.AddJob(&pipeline.Job{
+			ID:    "publish",
+			Stage: "publish",
+			Image: javaImage(buildTool, version),
+			Needs: []string{"build"},
+			When:  pipeline.Condition{Tags: true},
+			Env: []pipeline.Var{
+				{Name: "MAVEN_USERNAME", Value: pipeline.SecretRef("MAVEN_USERNAME")},
+				{Name: "MAVEN_PASSWORD", Value: pipeline.SecretRef("MAVEN_PASSWORD")},
+			},
+		})
+		publish.Steps = append(publish.Steps, javaSetupSteps(buildTool, version)...)
+		publish.Steps = append(publish.Steps,
+			pipeline.Run("Publish to repository", javaCommand(buildTool, "publish -x test", "mvn deploy -DskipTests -B")))
+	}
 
-		pipeline.WriteString(`    - name: Validate publishing configuration
-      run: |
-        echo "Checking publishing config..."
-        if [ -f "gradlew" ]; then
-          ./gradlew tasks --all | grep publish || echo "No publish tasks found"
-        else
-          mvn help:describe -Dcmd=deploy || echo "No deploy goal found"
-        fi
-        
-    - name: Publish to repository
-      run: |
-        if [ -f "gradlew" ]; then
-          ./gradlew publish -x test --no-daemon
-        else
-          mvn deploy -DskipTests -B
-        fi
-      env:
-        GITHUB_TOKEN: ${{ secrets.GITHUB_TOKEN }}
-        # Дополнительные переменные для публикации
-        MAVEN_USERNAME: ${{ github.actor }}
-        MAVEN_PASSWORD: ${{ secrets.GITHUB_TOKEN }}
-`)
+	// Подпроекты Gradle запускаются своими задачами через корневой wrapper
+	if buildTool == "gradle" {
+		addModuleJobs(p, info.Modules, javaImage(buildTool, version), javaSetupSteps(buildTool, version), func(dir string) moduleCommands {
+			project := ":" + strings.ReplaceAll(dir, "/", ":")
+			return moduleCommands{test: javaCommand(buildTool, project+":test", ""), build: javaCommand(buildTool, project+":build -x test", "")}
+		})
 	}
 
-	return pipeline.String()
+	return p
 }
 
 // Вспомогательные функции
@@ -333,6 +156,9 @@ func detectBuildTool(info *analyzer.ProjectInfo) string {
 	if info.BuildTool == "gradle" {
 		return "gradle"
 	}
+	if info.Language == "java_maven" {
+		return "maven"
+	}
 
 	// По умолчанию Gradle
 	return "gradle"
@@ -373,34 +199,60 @@ func cleanJavaVersion(version string) string {
 	return version
 }
 
-func buildToolSetupSteps(buildTool string, needsTestSetup bool) string {
-	var steps strings.Builder
+// javaImage возвращает образ с JDK и билд-тулом для контейнерных форматов.
+func javaImage(buildTool, version string) string {
+	if buildTool == "gradle" {
+		return "gradle:jdk" + version
+	}
+	return "maven:3-eclipse-temurin-" + version
+}
 
+// javaCache кеширует локальный репозиторий зависимостей билд-тула.
+func javaCache(buildTool string) *pipeline.Cache {
 	if buildTool == "gradle" {
-		steps.WriteString(`    - name: Setup Gradle
-      uses: gradle/actions/setup-gradle@v4
-      
-    - name: Validate Gradle wrapper
-      run: |
-        if [ -f "gradlew" ]; then
-          chmod +x gradlew
-          echo "✅ Using Gradle wrapper"
-        else
-          echo "ℹ️  Using system Gradle"
-        fi
-`)
-	} else {
-		steps.WriteString(`    - name: Cache Maven dependencies
-      uses: actions/cache@v4
-      with:
-        path: ~/.m2/repository
-        key: maven-${{ hashFiles('**/pom.xml') }}
-        restore-keys: |
-          maven-
-`)
+		return &pipeline.Cache{Paths: []string{".gradle/caches/", ".gradle/wrapper/"}}
+	}
+	return &pipeline.Cache{Paths: []string{".m2/repository/"}}
+}
+
+// javaCacheEnv переносит локальный репозиторий в каталог проекта, чтобы его
+// мог закешировать раннер.
+func javaCacheEnv(buildTool string) []pipeline.Var {
+	if buildTool == "gradle" {
+		return []pipeline.Var{{Name: "GRADLE_USER_HOME", Value: ".gradle"}}
+	}
+	return []pipeline.Var{{Name: "MAVEN_OPTS", Value: "-Dmaven.repo.local=.m2/repository"}}
+}
+
+// javaSetupSteps — общие шаги подготовки: исходники, JDK и билд-тул.
+func javaSetupSteps(buildTool, version string) []pipeline.Step {
+	steps := []pipeline.Step{
+		pipeline.Checkout(),
+		pipeline.Setup("java", version, pipeline.Var{Name: "cache", Value: buildTool}),
 	}
+	return append(steps, javaToolSteps(buildTool)...)
+}
+
+func javaToolSteps(buildTool string) []pipeline.Step {
+	if buildTool != "gradle" {
+		return nil
+	}
+	return []pipeline.Step{
+		pipeline.Action("Setup Gradle", "gradle/actions/setup-gradle@v4", ""),
+		pipeline.Run("Validate Gradle wrapper", `if [ -f "gradlew" ]; then chmod +x gradlew; fi`),
+	}
+}
 
-	return steps.String()
+// javaCommand возвращает команду Gradle (через wrapper, если он есть) или Maven.
+func javaCommand(buildTool, gradleTasks, maven string) string {
+	if buildTool != "gradle" {
+		return maven
+	}
+	return fmt.Sprintf(`if [ -f "gradlew" ]; then
+  ./gradlew %[1]s --no-daemon
+else
+  gradle %[1]s --no-daemon
+fi`, gradleTasks)
 }
 
 func hasCoverageTool(deps []string) bool {
@@ -1,451 +1,178 @@
 package generator
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateJavaScriptPipeline(info *analyzer.ProjectInfo) string {
-	var pipeline strings.Builder
-
-	pipeline.WriteString(`name: JavaScript/Node.js CI/CD Pipeline
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
-
-jobs:
-`)
-
-	// Job для установки зависимостей и кеширования
-	pipeline.WriteString(`  install:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Node.js
-      uses: actions/setup-node@v3
-      with:
-        node-version: '`)
-
-	if info.Version != "" {
-		pipeline.WriteString(info.Version)
-	} else {
-		pipeline.WriteString("18")
+func buildJavaScriptPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+	version := info.Version
+	if version == "" {
+		version = "18"
 	}
-
-	pipeline.WriteString(`'
-        cache: '`)
-
-	// Определяем тип кеша в зависимости от инструмента сборки
-	switch info.BuildTool {
-	case "yarn":
-		pipeline.WriteString("yarn")
-	case "pnpm":
-		pipeline.WriteString("pnpm")
-	default:
-		pipeline.WriteString("npm")
-	}
-
-	pipeline.WriteString(`'
-`)
-
-	// Установка зависимостей в зависимости от инструмента
-	switch info.BuildTool {
-	case "yarn":
-		pipeline.WriteString(`    - name: Install dependencies with Yarn
-      run: yarn install --frozen-lockfile
-`)
-	case "pnpm":
-		pipeline.WriteString(`    - name: Install pnpm
-      uses: pnpm/action-setup@v2
-      with:
-        version: latest
-    - name: Install dependencies with pnpm
-      run: pnpm install --frozen-lockfile
-`)
-	default:
-		pipeline.WriteString(`    - name: Install dependencies with npm
-      run: npm ci
-`)
+	tool := jsPackageManager(info.BuildTool)
+	hasLint := containsDependency(info.Dependencies, "eslint") || containsDependency(info.Dependencies, "prettier")
+
+	p := &pipeline.Pipeline{
+		Name:     "JavaScript/Node.js CI/CD Pipeline",
+		Branches: []string{"main", "master", "develop"},
+		Stages:   []string{"lint", "test", "build", "deploy"},
+		Cache:    &pipeline.Cache{KeyFiles: []string{jsLockFile(tool)}, Paths: []string{"node_modules/"}},
 	}
 
 	// Job для линтинга
-	if containsDependency(info.Dependencies, "eslint") || containsDependency(info.Dependencies, "prettier") {
-		pipeline.WriteString(`  lint:
-    runs-on: ubuntu-latest
-    needs: install
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Node.js
-      uses: actions/setup-node@v3
-      with:
-        node-version: '`)
-
-		if info.Version != "" {
-			pipeline.WriteString(info.Version)
-		} else {
-			pipeline.WriteString("18")
-		}
-
-		pipeline.WriteString(`'
-        cache: '`)
-
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString("yarn")
-		case "pnpm":
-			pipeline.WriteString("pnpm")
-		default:
-			pipeline.WriteString("npm")
-		}
-
-		pipeline.WriteString(`'
-`)
-
-		// Установка зависимостей для линтинга
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString(`    - name: Install dependencies
-      run: yarn install --frozen-lockfile
-`)
-		case "pnpm":
-			pipeline.WriteString(`    - name: Install pnpm
-      uses: pnpm/action-setup@v2
-      with:
-        version: latest
-    - name: Install dependencies
-      run: pnpm install --frozen-lockfile
-`)
-		default:
-			pipeline.WriteString(`    - name: Install dependencies
-      run: npm ci
-`)
-		}
-
-		pipeline.WriteString(`    - name: Run ESLint
-      run: `)
-
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString("yarn lint")
-		case "pnpm":
-			pipeline.WriteString("pnpm lint")
-		default:
-			pipeline.WriteString("npm run lint")
-		}
-
-		pipeline.WriteString(`
-    - name: Run Prettier check
-      run: `)
-
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString("yarn format:check")
-		case "pnpm":
-			pipeline.WriteString("pnpm format:check")
-		default:
-			pipeline.WriteString("npm run format:check")
-		}
-
-		pipeline.WriteString(`
-`)
+	if hasLint {
+		lint := p.AddJob(&pipeline.Job{ID: "lint", Stage: "lint", Image: "node:" + version})
+		lint.Steps = append(lint.Steps, jsSetupSteps(tool, version)...)
+		lint.Steps = append(lint.Steps,
+			pipeline.Run("Run ESLint", jsScript(tool, "lint")),
+			pipeline.Run("Run Prettier check", jsScript(tool, "format:check")),
+		)
 	}
 
-	// Job для тестов
+	previousJob := "verify"
 	if info.HasTests {
-		pipeline.WriteString(`  test:
-    runs-on: ubuntu-latest
-    needs: install
-    strategy:
-      matrix:
-        node-version: [`)
+		previousJob = "test"
 
 		// Добавляем версии Node.js
+		versions := []string{"16", "18", "20"}
 		if info.Version != "" && info.Version != "18" {
-			pipeline.WriteString(fmt.Sprintf(" '%s', '16', '18' ", info.Version))
-		} else {
-			pipeline.WriteString(" '16', '18', '20' ")
-		}
-
-		pipeline.WriteString(`]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Node.js ${{ matrix.node-version }}
-      uses: actions/setup-node@v3
-      with:
-        node-version: ${{ matrix.node-version }}
-        cache: '`)
-
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString("yarn")
-		case "pnpm":
-			pipeline.WriteString("pnpm")
-		default:
-			pipeline.WriteString("npm")
-		}
-
-		pipeline.WriteString(`'
-`)
-
-		// Установка зависимостей для тестов
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString(`    - name: Install dependencies
-      run: yarn install --frozen-lockfile
-`)
-		case "pnpm":
-			pipeline.WriteString(`    - name: Install pnpm
-      uses: pnpm/action-setup@v2
-      with:
-        version: latest
-    - name: Install dependencies
-      run: pnpm install --frozen-lockfile
-`)
-		default:
-			pipeline.WriteString(`    - name: Install dependencies
-      run: npm ci
-`)
+			versions = []string{info.Version, "16", "18"}
 		}
 
-		// Запуск тестов в зависимости от фреймворка
-		pipeline.WriteString(`    - name: Run tests
-      run: `)
-
-		switch info.TestFramework {
-		case "jest":
-			switch info.BuildTool {
-			case "yarn":
-				pipeline.WriteString("yarn test")
-			case "pnpm":
-				pipeline.WriteString("pnpm test")
-			default:
-				pipeline.WriteString("npm test")
-			}
-		case "vitest":
-			switch info.BuildTool {
-			case "yarn":
-				pipeline.WriteString("yarn test:vitest")
-			case "pnpm":
-				pipeline.WriteString("pnpm test:vitest")
-			default:
-				pipeline.WriteString("npm run test:vitest")
-			}
-		case "mocha":
-			switch info.BuildTool {
-			case "yarn":
-				pipeline.WriteString("yarn test:mocha")
-			case "pnpm":
-				pipeline.WriteString("pnpm test:mocha")
-			default:
-				pipeline.WriteString("npm run test:mocha")
-			}
-		case "cypress":
-			switch info.BuildTool {
-			case "yarn":
-				pipeline.WriteString("yarn cypress run")
-			case "pnpm":
-				pipeline.WriteString("pnpm cypress run")
-			default:
-				pipeline.WriteString("npm run cypress:run")
-			}
-		case "playwright":
-			switch info.BuildTool {
-			case "yarn":
-				pipeline.WriteString("yarn playwright test")
-			case "pnpm":
-				pipeline.WriteString("pnpm playwright test")
-			default:
-				pipeline.WriteString("npm run test:playwright")
-			}
-		default:
-			switch info.BuildTool {
-			case "yarn":
-				pipeline.WriteString("yarn test")
-			case "pnpm":
-				pipeline.WriteString("pnpm test")
-			default:
-				pipeline.WriteString("npm test")
-			}
-		}
-
-		pipeline.WriteString(`
-`)
+		test := p.AddJob(&pipeline.Job{
+			ID:     "test",
+			Stage:  "test",
+			Image:  "node:" + pipeline.MatrixRef("node-version"),
+			Matrix: []pipeline.Axis{{Name: "node-version", Values: versions}},
+		})
+		test.Steps = append(test.Steps, jsSetupSteps(tool, pipeline.MatrixRef("node-version"))...)
+		test.Steps = append(test.Steps, pipeline.Run("Run tests", jsTestCommand(tool, info.TestFramework)))
 
 		// Добавляем отчет о покрытии для Jest
 		if info.TestFramework == "jest" {
-			pipeline.WriteString(`    - name: Upload coverage reports
-      uses: codecov/codecov-action@v3
-      with:
-        file: ./coverage/lcov.info
-        flags: unittests
-        name: codecov-umbrella
-`)
+			test.Steps = append(test.Steps, pipeline.CoverageReport("lcov", "coverage/lcov.info"))
 		}
-
 	} else {
 		// Если тестов нет - простая проверка
-		pipeline.WriteString(`  verify:
-    runs-on: ubuntu-latest
-    needs: install
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Node.js
-      uses: actions/setup-node@v3
-      with:
-        node-version: '`)
-
-		if info.Version != "" {
-			pipeline.WriteString(info.Version)
-		} else {
-			pipeline.WriteString("18")
-		}
-
-		pipeline.WriteString(`'
-        cache: '`)
-
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString("yarn")
-		case "pnpm":
-			pipeline.WriteString("pnpm")
-		default:
-			pipeline.WriteString("npm")
-		}
-
-		pipeline.WriteString(`'
-`)
-
-		switch info.BuildTool {
-		case "yarn":
-			pipeline.WriteString(`    - name: Install dependencies
-      run: yarn install --frozen-lockfile
-    - name: Verify build
-      run: yarn build
-`)
-		case "pnpm":
-			pipeline.WriteString(`    - name: Install pnpm
-      uses: pnpm/action-setup@v2
-      with:
-        version: latest
-    - name: Install dependencies
-      run: pnpm install --frozen-lockfile
-    - name: Verify build
-      run: pnpm build
-`)
-		default:
-			pipeline.WriteString(`    - name: Install dependencies
-      run: npm ci
-    - name: Verify build
-      run: npm run build
-`)
-		}
+		verify := p.AddJob(&pipeline.Job{ID: "verify", Stage: "test", Image: "node:" + version})
+		verify.Steps = append(verify.Steps, jsSetupSteps(tool, version)...)
+		verify.Steps = append(verify.Steps, pipeline.Run("Verify build", jsScript(tool, "build")))
 	}
 
 	// Job для сборки
-	previousJob := "test"
-	if !info.HasTests {
-		previousJob = "verify"
+	needs := []string{previousJob}
+	if hasLint {
+		needs = append(needs, "lint")
 	}
-	if containsDependency(info.Dependencies, "eslint") || containsDependency(info.Dependencies, "prettier") {
-		previousJob = "lint"
-	}
-
-	pipeline.WriteString(fmt.Sprintf(`  build:
-    runs-on: ubuntu-latest
-    needs: %s
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Node.js
-      uses: actions/setup-node@v3
-      with:
-        node-version: '`, previousJob))
+	build := p.AddJob(&pipeline.Job{ID: "build", Stage: "build", Image: "node:" + version, Needs: needs})
+	build.Steps = append(build.Steps, jsSetupSteps(tool, version)...)
+	build.Steps = append(build.Steps, pipeline.Run("Build application", jsScript(tool, "build")))
 
-	if info.Version != "" {
-		pipeline.WriteString(info.Version)
-	} else {
-		pipeline.WriteString("18")
+	// Определяем пути для артефактов в зависимости от типа приложения
+	artifacts := []string{"dist/", "build/"}
+	if containsDependency(info.Dependencies, "frontend-framework") {
+		artifacts = append(artifacts, ".next/", "out/")
+	} else if containsDependency(info.Dependencies, "backend-framework") {
+		artifacts = append(artifacts, "lib/")
 	}
+	build.Steps = append(build.Steps, pipeline.Upload("build-files", artifacts...))
 
-	pipeline.WriteString(`'
-        cache: '`)
+	// Пакеты workspaces: зависимости ставятся в корне, скрипты запускаются
+	// для одного пакета
+	addModuleJobs(p, info.Modules, "node:"+version, jsSetupSteps(tool, version), func(dir string) moduleCommands {
+		return moduleCommands{test: jsWorkspaceScript(tool, dir, "test"), build: jsWorkspaceScript(tool, dir, "build")}
+	})
 
-	switch info.BuildTool {
-	case "yarn":
-		pipeline.WriteString("yarn")
+	return p
+}
+
+// jsWorkspaceScript запускает скрипт одного пакета workspaces из корня репозитория.
+func jsWorkspaceScript(tool, dir, script string) string {
+	switch tool {
 	case "pnpm":
-		pipeline.WriteString("pnpm")
+		return "pnpm --filter ./" + dir + " run --if-present " + script
+	case "yarn":
+		return "yarn --cwd " + dir + " run " + script
 	default:
-		pipeline.WriteString("npm")
+		return "npm run " + script + " --workspace=" + dir + " --if-present"
 	}
+}
 
-	pipeline.WriteString(`'
-`)
+func jsPackageManager(buildTool string) string {
+	switch buildTool {
+	case "yarn", "pnpm":
+		return buildTool
+	default:
+		return "npm"
+	}
+}
 
-	// Установка зависимостей для сборки
-	switch info.BuildTool {
+func jsLockFile(tool string) string {
+	switch tool {
 	case "yarn":
-		pipeline.WriteString(`    - name: Install dependencies
-      run: yarn install --frozen-lockfile
-`)
+		return "yarn.lock"
 	case "pnpm":
-		pipeline.WriteString(`    - name: Install pnpm
-      uses: pnpm/action-setup@v2
-      with:
-        version: latest
-    - name: Install dependencies
-      run: pnpm install --frozen-lockfile
-`)
+		return "pnpm-lock.yaml"
 	default:
-		pipeline.WriteString(`    - name: Install dependencies
-      run: npm ci
-`)
+		return "package-lock.json"
 	}
+}
 
-	// Сборка в зависимости от инструмента сборки
-	pipeline.WriteString(`    - name: Build application
-      run: `)
-
-	switch info.BuildTool {
+// jsSetupSteps — исходники, Node.js и установка зависимостей выбранным менеджером пакетов.
+func jsSetupSteps(tool, version string) []pipeline.Step {
+	steps := []pipeline.Step{pipeline.Checkout()}
+	switch tool {
 	case "yarn":
-		pipeline.WriteString("yarn build")
+		steps = append(steps,
+			pipeline.Setup("node", version, pipeline.Var{Name: "cache", Value: "yarn"}),
+			pipeline.Run("Install dependencies", "corepack enable\nyarn install --frozen-lockfile"),
+		)
 	case "pnpm":
-		pipeline.WriteString("pnpm build")
+		// pnpm должен быть установлен до setup-node, иначе не заработает кеш
+		steps = append(steps,
+			pipeline.Action("Install pnpm", "pnpm/action-setup@v4", "", pipeline.Var{Name: "version", Value: "latest"}),
+			pipeline.Setup("node", version, pipeline.Var{Name: "cache", Value: "pnpm"}),
+			pipeline.Run("Install dependencies", "corepack enable\npnpm install --frozen-lockfile"),
+		)
 	default:
-		pipeline.WriteString("npm run build")
+		steps = append(steps,
+			pipeline.Setup("node", version, pipeline.Var{Name: "cache", Value: "npm"}),
+			pipeline.Run("Install dependencies", "npm ci"),
+		)
 	}
-
-	pipeline.WriteString(`
-    - name: Upload build artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: build-files
-        path: |`)
-
-	// Определяем пути для артефактов в зависимости от типа приложения
-	if containsDependency(info.Dependencies, "frontend-framework") {
-		pipeline.WriteString(`
-          dist/
-          build/
-          .next/
-          out/`)
-	} else if containsDependency(info.Dependencies, "backend-framework") {
-		pipeline.WriteString(`
-          dist/
-          build/
-          lib/`)
-	} else {
-		pipeline.WriteString(`
-          dist/
-          build/`)
+	return steps
+}
+
+// jsScript запускает скрипт из package.json.
+func jsScript(tool, script string) string {
+	if tool == "npm" {
+		if script == "test" {
+			return "npm test"
+		}
+		return "npm run " + script
 	}
-
-	pipeline.WriteString(`
-`)
-
-	return pipeline.String()
-}
\ No newline at end of file
+	return tool + " " + script
+}
+
+// jsTestCommand выбирает команду запуска тестов по фреймворку.
+func jsTestCommand(tool, framework string) string {
+	switch framework {
+	case "vitest":
+		return jsScript(tool, "test:vitest")
+	case "mocha":
+		return jsScript(tool, "test:mocha")
+	case "cypress":
+		if tool == "npm" {
+			return "npm run cypress:run"
+		}
+		return tool + " cypress run"
+	case "playwright":
+		if tool == "npm" {
+			return "npm run test:playwright"
+		}
+		return tool + " playwright test"
+	default:
+		return jsScript(tool, "test")
+	}
+}
@@ -0,0 +1,578 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+	"gopkg.in/yaml.v3"
+)
+
+// generatedMarker — комментарий над джобом, которым владеет генератор.
+// При слиянии (--merge) такие джобы обновляются, а джобы без пометки
+// считаются написанными вручную и остаются как есть.
+const generatedMarker = "# pipeline-gen: generated"
+
+// generatedHeader открывает сгенерированный файл с помеченными джобами.
+const generatedHeader = `# Generated by pipeline-gen. Jobs marked with "pipeline-gen: generated" are
+# updated by "pipeline-gen --merge", other jobs are kept as is.
+
+`
+
+// mergeFormats — форматы, конфигурации которых умеет объединять --merge.
+var mergeFormats = []string{"github", "forgejo", "gitlab"}
+
+// configPatterns — пути, по которым CI-системы ищут конфигурацию в репозитории.
+var configPatterns = map[string][]string{
+	"github":     {".github/workflows/*.yml", ".github/workflows/*.yaml"},
+	"forgejo":    {".forgejo/workflows/*.yml", ".forgejo/workflows/*.yaml", ".gitea/workflows/*.yml", ".gitea/workflows/*.yaml"},
+	"gitlab":     {".gitlab-ci.yml"},
+	"jenkins":    {"Jenkinsfile"},
+	"azure":      {"azure-pipelines.yml"},
+	"circleci":   {".circleci/config.yml"},
+	"bitbucket":  {"bitbucket-pipelines.yml"},
+	"woodpecker": {".woodpecker.yml", ".woodpecker/*.yml"},
+	"drone":      {".drone.yml"},
+	"codebuild":  {"buildspec.yml"},
+	"cloudbuild": {"cloudbuild.yaml"},
+}
+
+// gitlabDefaultStages — стадии GitLab CI, доступные без объявления stages.
+var gitlabDefaultStages = []string{"build", "test", "deploy"}
+
+// CanMerge сообщает, умеет ли --merge объединять конфигурации формата.
+func CanMerge(format string) bool {
+	for _, f := range mergeFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ExistingConfigs возвращает конфигурации формата format, которые уже есть
+// в локальном репозитории; с пустым format — конфигурации всех форматов.
+func ExistingConfigs(repoPath, format string) []string {
+	var configs []string
+	for f, patterns := range configPatterns {
+		if format != "" && f != format {
+			continue
+		}
+		for _, pattern := range patterns {
+			matches, _ := filepath.Glob(filepath.Join(repoPath, filepath.FromSlash(pattern)))
+			configs = append(configs, matches...)
+		}
+	}
+	sort.Strings(configs)
+	return configs
+}
+
+// MergeTarget выбирает в репозитории файл, с которым объединяется pipeline:
+// единственную конфигурацию формата или единственную из них с джобами
+// генератора. Пустая строка означает, что конфигурации формата нет.
+func MergeTarget(repoPath, format string) (string, error) {
+	configs := ExistingConfigs(repoPath, format)
+	switch len(configs) {
+	case 0:
+		return "", nil
+	case 1:
+		return configs[0], nil
+	}
+	var marked []string
+	for _, config := range configs {
+		content, err := os.ReadFile(config)
+		if err == nil && strings.Contains(string(content), generatedMarker) {
+			marked = append(marked, config)
+		}
+	}
+	if len(marked) == 1 {
+		return marked[0], nil
+	}
+	return "", fmt.Errorf("several %s configs found (%s), choose one with --output", format, strings.Join(configs, ", "))
+}
+
+// MergePipeline генерирует pipeline и объединяет его с существующим файлом
+// outputFile: джобы с пометкой генератора обновляются, новые добавляются,
+// написанные вручную остаются как есть. Перед записью confirm получает
+// unified diff, файл записывается, только если он вернул true.
+func MergePipeline(info *analyzer.ProjectInfo, outputFile, format string, confirm func(diff string) bool) (bool, error) {
+	if !CanMerge(format) {
+		return false, fmt.Errorf("merging %s configs is not supported, supported formats: %s", format, strings.Join(mergeFormats, ", "))
+	}
+	outputFile = outputPath(outputFile, format)
+	generated, err := buildPipeline(info, format)
+	if err != nil {
+		return false, err
+	}
+	existing, err := os.ReadFile(outputFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("failed to read %s: %w", outputFile, err)
+	}
+
+	merged, notes, err := mergeContent(string(existing), generated, format)
+	if err != nil {
+		return false, fmt.Errorf("failed to merge with %s: %w", outputFile, err)
+	}
+	for _, note := range notes {
+		fmt.Printf("⚠ %s\n", note)
+	}
+	if merged == string(existing) {
+		fmt.Printf("✓ %s is up to date\n", outputFile)
+		return false, nil
+	}
+	if !confirm(unifiedDiff(outputFile, string(existing), merged)) {
+		return false, nil
+	}
+	return true, writePipeline(outputFile, merged)
+}
+
+// markGeneratedJobs ставит пометку генератора над каждым джобом и
+// добавляет заголовок, который ее объясняет.
+func markGeneratedJobs(content, format string) (string, error) {
+	if !CanMerge(format) {
+		return content, nil
+	}
+	l, err := parseLayout(content, format)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(generatedHeader)
+	for _, seg := range l.segments {
+		if seg.kind == jobSegment && !seg.generated {
+			marker := strings.Repeat(" ", seg.indent) + generatedMarker
+			seg.lines = append(seg.lines[:seg.keyLine], append([]string{marker}, seg.lines[seg.keyLine:]...)...)
+			seg.generated = true
+		}
+	}
+	b.WriteString(l.String())
+	return b.String(), nil
+}
+
+type segmentKind int
+
+const (
+	preambleSegment segmentKind = iota // текст до первого ключа
+	topSegment                         // ключ верхнего уровня, не джоб
+	jobSegment
+)
+
+// segment — часть YAML-файла: ключ верхнего уровня или джоб вместе
+// с комментариями над ним. Пустые строки в конце хранятся отдельно
+// в tail, чтобы при замене части сохранялись отступы между джобами.
+type segment struct {
+	kind      segmentKind
+	key       string
+	value     *yaml.Node
+	lines     []string
+	tail      int
+	keyLine   int // индекс строки ключа в lines
+	indent    int
+	generated bool
+}
+
+// layout — разметка YAML-файла на части. Слияние работает со строками,
+// а не с узлами YAML, чтобы не менять форматирование и комментарии
+// в джобах, написанных вручную.
+type layout struct {
+	segments []*segment
+}
+
+// parseLayout размечает файл формата format на части.
+func parseLayout(content, format string) (*layout, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, err
+	}
+	lines := strings.Split(content, "\n")
+	if len(doc.Content) == 0 {
+		return &layout{segments: []*segment{newSegment(preambleSegment, lines)}}, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("YAML document is not a mapping")
+	}
+
+	starts := map[int]*segment{}
+	addKey := func(kind segmentKind, key, value *yaml.Node) {
+		start := key.Line - 1
+		for start > 0 && strings.HasPrefix(strings.TrimSpace(lines[start-1]), "#") {
+			start--
+		}
+		starts[start] = &segment{kind: kind, key: key.Value, value: value, keyLine: key.Line - 1 - start, indent: key.Column - 1}
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		kind := topSegment
+		if format == "gitlab" && pipeline.IsGitLabJob(key.Value) {
+			kind = jobSegment
+		}
+		addKey(kind, key, value)
+		if format != "gitlab" && key.Value == "jobs" && value.Kind == yaml.MappingNode {
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				addKey(jobSegment, value.Content[j], value.Content[j+1])
+			}
+		}
+	}
+
+	var bounds []int
+	for start := range starts {
+		bounds = append(bounds, start)
+	}
+	sort.Ints(bounds)
+	l := &layout{}
+	if bounds[0] > 0 {
+		l.segments = append(l.segments, newSegment(preambleSegment, lines[:bounds[0]]))
+	}
+	for i, start := range bounds {
+		end := len(lines)
+		if i+1 < len(bounds) {
+			end = bounds[i+1]
+		}
+		seg := starts[start]
+		full := newSegment(seg.kind, lines[start:end])
+		seg.lines, seg.tail = full.lines, full.tail
+		for _, line := range seg.lines[:seg.keyLine] {
+			if strings.TrimSpace(line) == generatedMarker {
+				seg.generated = true
+			}
+		}
+		l.segments = append(l.segments, seg)
+	}
+	return l, nil
+}
+
+// newSegment отделяет от строк пустые строки в конце.
+func newSegment(kind segmentKind, lines []string) *segment {
+	n := len(lines)
+	for n > 0 && strings.TrimSpace(lines[n-1]) == "" {
+		n--
+	}
+	return &segment{kind: kind, lines: append([]string{}, lines[:n]...), tail: len(lines) - n}
+}
+
+func (l *layout) String() string {
+	var lines []string
+	for _, seg := range l.segments {
+		lines = append(lines, seg.lines...)
+		for i := 0; i < seg.tail; i++ {
+			lines = append(lines, "")
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (l *layout) find(kind segmentKind, key string) *segment {
+	for _, seg := range l.segments {
+		if seg.kind == kind && seg.key == key {
+			return seg
+		}
+	}
+	return nil
+}
+
+// mergeContent объединяет существующий файл со сгенерированным. Вместе
+// с результатом возвращаются заметки о джобах, которые не обновлены.
+func mergeContent(existing, generated, format string) (string, []string, error) {
+	if strings.TrimSpace(existing) == "" {
+		return generated, nil, nil
+	}
+	old, err := parseLayout(existing, format)
+	if err != nil {
+		return "", nil, err
+	}
+	gen, err := parseLayout(generated, format)
+	if err != nil {
+		return "", nil, err
+	}
+
+	indent := -1
+	for _, seg := range old.segments {
+		if seg.kind == jobSegment {
+			indent = seg.indent
+			break
+		}
+	}
+	var notes []string
+	used := map[string]bool{}
+	var out []*segment
+	lastJob := -1
+	for _, seg := range old.segments {
+		if seg.kind != jobSegment {
+			out = append(out, seg)
+			continue
+		}
+		fresh := gen.find(jobSegment, seg.key)
+		switch {
+		case !seg.generated:
+			if fresh != nil {
+				notes = append(notes, fmt.Sprintf("job %s is written by hand and kept, the generated one is skipped", seg.key))
+				used[seg.key] = true
+			}
+			out = append(out, seg)
+		case fresh != nil:
+			used[seg.key] = true
+			out = append(out, reindent(fresh, indent, seg.tail))
+		default:
+			notes = append(notes, fmt.Sprintf("generated job %s is no longer generated and is removed", seg.key))
+			continue
+		}
+		lastJob = len(out) - 1
+	}
+
+	// Ключи верхнего уровня, которых нет в файле (например, env с переменными
+	// новых джобов), добавляются перед джобами
+	for _, seg := range gen.segments {
+		if seg.kind != topSegment || old.find(topSegment, seg.key) != nil {
+			continue
+		}
+		// workflow ограничил бы запуск джобов, которые уже есть в файле
+		if format == "gitlab" && (seg.key == "stages" || seg.key == "workflow") {
+			continue
+		}
+		at := len(out)
+		for i, s := range out {
+			if s.kind == jobSegment || s.kind == topSegment && s.key == "jobs" {
+				at = i
+				break
+			}
+		}
+		insert := *seg
+		insert.tail = 1
+		if seg.key == "jobs" {
+			insert.tail = 0
+		}
+		out = insertSegments(out, at, []*segment{&insert})
+	}
+	// Новые джобы добавляются после последнего джоба файла
+	var added []*segment
+	for _, seg := range gen.segments {
+		if seg.kind == jobSegment && !used[seg.key] {
+			added = append(added, reindent(seg, indent, 1))
+		}
+	}
+	at := lastJob + 1
+	if lastJob < 0 {
+		at = len(out)
+		for i, seg := range out {
+			if seg.kind == topSegment && seg.key == "jobs" {
+				at = i + 1
+			}
+		}
+	}
+	out = insertSegments(out, at, added)
+
+	if format == "gitlab" {
+		out = mergeGitLabStages(out, old, gen)
+	}
+
+	out[len(out)-1].tail = old.segments[len(old.segments)-1].tail
+	merged := (&layout{segments: out}).String()
+	var check yaml.Node
+	if err := yaml.Unmarshal([]byte(merged), &check); err != nil {
+		return "", nil, fmt.Errorf("merged config is not valid YAML: %w", err)
+	}
+	return merged, notes, nil
+}
+
+// insertSegments вставляет части в позицию at, отделяя их от соседних
+// джобов пустой строкой.
+func insertSegments(out []*segment, at int, added []*segment) []*segment {
+	if len(added) == 0 {
+		return out
+	}
+	if at > 0 {
+		prev := out[at-1]
+		added[len(added)-1].tail = max(prev.tail, 1)
+		if prev.kind == jobSegment && prev.tail == 0 {
+			prev.tail = 1
+		}
+	}
+	return append(out[:at], append(added, out[at:]...)...)
+}
+
+// reindent копирует часть со сдвигом под отступ джобов существующего
+// файла. Отрицательный indent оставляет отступ как есть.
+func reindent(seg *segment, indent, tail int) *segment {
+	c := *seg
+	c.tail = tail
+	if indent < 0 || indent == seg.indent {
+		return &c
+	}
+	c.lines = make([]string, len(seg.lines))
+	for i, line := range seg.lines {
+		switch {
+		case strings.TrimSpace(line) == "":
+			c.lines[i] = line
+		case indent > seg.indent:
+			c.lines[i] = strings.Repeat(" ", indent-seg.indent) + line
+		default:
+			c.lines[i] = strings.TrimPrefix(line, strings.Repeat(" ", seg.indent-indent))
+		}
+	}
+	c.indent = indent
+	return &c
+}
+
+// mergeGitLabStages дополняет стадии GitLab CI стадиями новых джобов. Без
+// объявления stages в файле учитываются стадии GitLab по умолчанию.
+func mergeGitLabStages(out []*segment, old, gen *layout) []*segment {
+	fresh := gen.find(topSegment, "stages")
+	if fresh == nil {
+		return out
+	}
+	current := old.find(topSegment, "stages")
+	if current == nil {
+		// Без объявления джобы файла используют стадии по умолчанию, а порядок
+		// задают новые стадии: их джобы связаны через needs
+		merged := mergeStages(nodeStrings(fresh.value), gitlabDefaultStages)
+		return insertStages(out, merged)
+	}
+	stages := nodeStrings(current.value)
+	merged := mergeStages(append([]string{}, stages...), nodeStrings(fresh.value))
+	if len(merged) == len(stages) {
+		return out
+	}
+	current.lines = append(current.lines[:current.keyLine], stagesLines(merged)...)
+	return out
+}
+
+// insertStages добавляет объявление stages в начало файла.
+func insertStages(out []*segment, stages []string) []*segment {
+	at := 0
+	if len(out) > 0 && out[0].kind == preambleSegment {
+		at = 1
+	}
+	seg := &segment{kind: topSegment, key: "stages", lines: stagesLines(stages), tail: 1}
+	return append(out[:at], append([]*segment{seg}, out[at:]...)...)
+}
+
+func stagesLines(stages []string) []string {
+	lines := []string{"stages:"}
+	for _, stage := range stages {
+		lines = append(lines, "  - "+stage)
+	}
+	return lines
+}
+
+// nodeStrings возвращает значения последовательности скаляров.
+func nodeStrings(n *yaml.Node) []string {
+	var values []string
+	if n != nil && n.Kind == yaml.SequenceNode {
+		for _, item := range n.Content {
+			values = append(values, item.Value)
+		}
+	}
+	return values
+}
+
+// unifiedDiff строит unified diff двух версий файла с тремя строками контекста.
+func unifiedDiff(name, before, after string) string {
+	a, b := diffLines(before), diffLines(after)
+	// lcs[i][j] — длина общей подпоследовательности a[i:] и b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type edit struct {
+		op   byte
+		line string
+		i, j int // номера строк в a и b перед правкой
+	}
+	var edits []edit
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			edits = append(edits, edit{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			edits = append(edits, edit{'-', a[i], i, j})
+			i++
+		default:
+			edits = append(edits, edit{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	const context = 3
+	var out strings.Builder
+	from, to := filepath.ToSlash(name), filepath.ToSlash(name)
+	if !filepath.IsAbs(name) {
+		from, to = "a/"+from, "b/"+to
+	}
+	if before == "" {
+		from = "/dev/null"
+	}
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", from, to)
+	for k := 0; k < len(edits); {
+		if edits[k].op == ' ' {
+			k++
+			continue
+		}
+		// Правки, между которыми меньше двух контекстов, идут одним блоком
+		start := max(k-context, 0)
+		end := k
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(edits) && edits[next].op == ' ' {
+				next++
+			}
+			if next == len(edits) || next-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = next
+		}
+		var aLen, bLen int
+		for _, e := range edits[start:end] {
+			if e.op != '+' {
+				aLen++
+			}
+			if e.op != '-' {
+				bLen++
+			}
+		}
+		aStart, bStart := edits[start].i, edits[start].j
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", e.op, e.line)
+		}
+		k = end
+	}
+	return out.String()
+}
+
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// moduleCommands — команды тестов и сборки одного модуля монорепозитория.
+type moduleCommands struct {
+	test, build string
+	inDir       bool // команды выполняются из каталога модуля, а не из корня
+}
+
+// addModuleJobs добавляет для каждого модуля монорепозитория пару джобов
+// test и build, которые запускаются только при изменении файлов модуля.
+// Корневые джобы остаются без фильтра и по-прежнему собирают все целиком.
+func addModuleJobs(p *pipeline.Pipeline, modules []string, image string, setup []pipeline.Step, commands func(dir string) moduleCommands) {
+	for _, dir := range moduleDirs(modules) {
+		cmd := commands(dir)
+		id := strings.ReplaceAll(dir, "/", "-")
+		when := pipeline.Condition{Changes: []string{dir + "/**"}}
+		workDir := ""
+		if cmd.inDir {
+			workDir = dir
+		}
+
+		var needs []string
+		if cmd.test != "" {
+			test := p.AddJob(&pipeline.Job{ID: id + "-test", Name: dir + ": Test", Stage: "test", Image: image, Dir: workDir, When: when})
+			test.Steps = append(append(test.Steps, setup...), pipeline.Run("Run tests", cmd.test))
+			needs = []string{test.ID}
+		}
+		build := p.AddJob(&pipeline.Job{ID: id + "-build", Name: dir + ": Build", Stage: "build", Image: image, Dir: workDir, Needs: needs, When: when})
+		build.Steps = append(append(build.Steps, setup...), pipeline.Run("Build", cmd.build))
+	}
+}
+
+// moduleDirs приводит модули из анализатора к каталогам относительно корня.
+// Шаблоны workspaces и корневой модуль пропускаются: их покрывают общие джобы.
+func moduleDirs(modules []string) []string {
+	seen := map[string]bool{}
+	var dirs []string
+	for _, m := range modules {
+		dir := strings.ReplaceAll(strings.Trim(m, ":"), ":", "/")
+		dir = strings.TrimSuffix(path.Clean(dir), "/")
+		if dir == "." || dir == "" || strings.ContainsAny(dir, "*?[") || strings.HasPrefix(dir, "..") {
+			continue
+		}
+		if moduleSkipped(dir) {
+			continue
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// moduleSkipped отбрасывает тестовые данные и вендоренные зависимости.
+func moduleSkipped(dir string) bool {
+	for _, part := range strings.Split(dir, "/") {
+		switch part {
+		case "testdata", "vendor", "node_modules":
+			return true
+		}
+	}
+	return false
+}
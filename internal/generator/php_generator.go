@@ -1,305 +1,158 @@
 package generator
 
 import (
-    "fmt"
-    "strings"
-
     "github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+    "github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generatePHPPipeline(info *analyzer.ProjectInfo) string {
-    var pipeline strings.Builder
-
-    buildTool := "composer"
-    if info.BuildTool != "composer" && info.BuildTool != "php" {
-        buildTool = info.BuildTool
+func buildPHPPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+    version := info.Version
+    if version == "" {
+        version = "8.1"
     }
-
-    pipeline.WriteString(fmt.Sprintf(`name: PHP CI/CD Pipeline (%s)
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
-
-env:
-  PHP_VERSION: '`, buildTool))
-
-    // Устанавливаем версию PHP
-    if info.Version != "" {
-        pipeline.WriteString(info.Version)
-    } else {
-        pipeline.WriteString("8.1")
+    composer := info.PackageManager == "composer"
+    install := ""
+    if composer {
+        install = phpComposerInstall
     }
+    isLaravel := containsDependency(info.Dependencies, "framework:laravel")
+    isSymfony := containsDependency(info.Dependencies, "framework:symfony")
 
-    pipeline.WriteString(`'
-
-jobs:
-`)
-
-    // Job для установки зависимостей
-    pipeline.WriteString(`  setup:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    
-    - name: Setup PHP
-      uses: shivammathur/setup-php@v2
-      with:
-        php-version: ${{ env.PHP_VERSION }}
-        extensions: mbstring, xml, ctype, iconv, intl, pdo_sqlite
-        coverage: none
-        
-    - name: Check PHP version
-      run: php -v
-`)
-
-    // Если есть Composer, устанавливаем зависимости
-    if info.PackageManager == "composer" {
-        pipeline.WriteString(`    - name: Validate composer.json and composer.lock
-      run: composer validate --strict
-      
-    - name: Cache Composer packages
-      id: composer-cache
-      uses: actions/cache@v3
-      with:
-        path: vendor
-        key: ${{ runner.os }}-php-${{ hashFiles('**/composer.lock') }}
-        restore-keys: |
-          ${{ runner.os }}-php-
-          
-    - name: Install dependencies
-      run: composer install --prefer-dist --no-progress --no-interaction
-`)
+    p := &pipeline.Pipeline{
+        Name:     "PHP CI/CD Pipeline",
+        Branches: []string{"main", "master", "develop"},
+        Stages:   []string{"lint", "test", "build", "deploy"},
     }
-
-    // Job для линтинга и статического анализа
-    pipeline.WriteString(`  lint:
-    runs-on: ubuntu-latest
-    needs: setup
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup PHP
-      uses: shivammathur/setup-php@v2
-      with:
-        php-version: ${{ env.PHP_VERSION }}
-        
-`)
-
-    if info.PackageManager == "composer" {
-        pipeline.WriteString(`    - name: Install dependencies
-      run: composer install --prefer-dist --no-progress --no-interaction
-      
-`)
+    if install != "" {
+        p.Env = []pipeline.Var{{Name: "COMPOSER_CACHE_DIR", Value: ".composer-cache"}}
+        p.Cache = &pipeline.Cache{KeyFiles: []string{"composer.lock"}, Paths: []string{"vendor/", ".composer-cache/"}}
     }
 
-    // Проверка синтаксиса
-    pipeline.WriteString(`    - name: Check PHP syntax
-      run: find . -name "*.php" -exec php -l {} \;
-`)
-
-    // Статический анализ если есть соответствующие зависимости
+    // Job для проверки синтаксиса и статического анализа
+    lint := p.AddJob(&pipeline.Job{ID: "lint", Stage: "lint", Image: phpImage(version)})
+    lint.Steps = append(lint.Steps, phpSetupSteps(version, "none", install)...)
+    lint.Steps = append(lint.Steps,
+        pipeline.Run("Check PHP syntax", `find . -name "*.php" -not -path "./vendor/*" -print0 | xargs -0 -n1 php -l`))
+    if install != "" {
+        lint.Steps = append(lint.Steps, pipeline.Run("Validate composer.json", "composer validate --no-check-publish"))
+    }
     if containsDependency(info.Dependencies, "quality:static-analysis") {
-        pipeline.WriteString(`    - name: Run PHPStan
-      run: composer require --dev phpstan/phpstan && vendor/bin/phpstan analyse
-`)
+        lint.Steps = append(lint.Steps, pipeline.Run("Run PHPStan", "vendor/bin/phpstan analyse --no-progress"))
     }
-
     if containsDependency(info.Dependencies, "quality:code-style") {
-        pipeline.WriteString(`    - name: Check code style with PHP_CodeSniffer
-      run: composer require --dev squizlabs/php_codesniffer && vendor/bin/phpcs
-`)
+        lint.Steps = append(lint.Steps, pipeline.Run("Check code style with PHP_CodeSniffer", "vendor/bin/phpcs"))
     }
 
-    // Job для тестов
+    previousJob := "lint"
     if info.HasTests {
-        pipeline.WriteString(`  test:
-    runs-on: ubuntu-latest
-    needs: setup
-    strategy:
-      matrix:
-        php-version: ['8.1', '8.2', '8.3']
-    steps:
-    - uses: actions/checkout@v3
-    
-    - name: Setup PHP
-      uses: shivammathur/setup-php@v2
-      with:
-        php-version: ${{ matrix.php-version }}
-        extensions: mbstring, xml, ctype, iconv, intl, pdo_sqlite
-        coverage: pcov
-        
-`)
+        previousJob = "test"
 
-        if info.PackageManager == "composer" {
-            pipeline.WriteString(`    - name: Install dependencies
-      run: composer install --prefer-dist --no-progress --no-interaction
-      
-`)
+        // Добавляем версии PHP
+        versions := []string{"8.1", "8.2", "8.3"}
+        if indexOf(versions, version) < 0 {
+            versions = append([]string{version}, versions...)
         }
 
-        // Запуск тестов в зависимости от фреймворка
-        switch info.TestFramework {
-        case "phpunit":
-            pipeline.WriteString(`    - name: Run PHPUnit tests
-      run: |
-        if [ -f vendor/bin/phpunit ]; then
-          vendor/bin/phpunit --coverage-clover=coverage.xml
-        elif [ -f phpunit ]; then
-          ./phpunit --coverage-clover=coverage.xml
-        else
-          phpunit --coverage-clover=coverage.xml
-        fi
-        
-    - name: Upload coverage to Codecov
-      uses: codecov/codecov-action@v3
-      with:
-        file: coverage.xml
-`)
-
-        case "pest":
-            pipeline.WriteString(`    - name: Run Pest tests
-      run: vendor/bin/pest --coverage-clover=coverage.xml
-      
-    - name: Upload coverage to Codecov
-      uses: codecov/codecov-action@v3
-      with:
-        file: coverage.xml
-`)
-
-        case "codeception":
-            pipeline.WriteString(`    - name: Run Codeception tests
-      run: vendor/bin/codecept run --coverage-xml
-`)
-
-        default:
-            pipeline.WriteString(`    - name: Run tests
-      run: |
-        if [ -f vendor/bin/phpunit ]; then
-          vendor/bin/phpunit
-        else
-          echo "No test runner found"
-        fi
-`)
+        test := p.AddJob(&pipeline.Job{
+            ID:     "test",
+            Stage:  "test",
+            Image:  phpImage(pipeline.MatrixRef("php-version")),
+            Needs:  []string{"lint"},
+            Matrix: []pipeline.Axis{{Name: "php-version", Values: versions}},
+        })
+        test.Steps = append(test.Steps, phpSetupSteps(pipeline.MatrixRef("php-version"), "pcov", install)...)
+
+        // Laravel-тестам нужен .env с ключом приложения
+        if isLaravel {
+            test.Steps = append(test.Steps, pipeline.Run("Prepare Laravel environment",
+                "cp .env.example .env\nphp artisan key:generate"))
         }
 
-        // Для Laravel проектов
-        if containsDependency(info.Dependencies, "framework:laravel") {
-            pipeline.WriteString(`    - name: Laravel environment setup
-      run: |
-        cp .env.example .env.test
-        php artisan key:generate --env=test
-        
-    - name: Run Laravel specific tests
-      run: php artisan test
-`)
+        // Запуск тестов в зависимости от фреймворка
+        switch {
+        case info.TestFramework == "pest":
+            test.Steps = append(test.Steps,
+                pipeline.Run("Run Pest tests", "vendor/bin/pest --coverage-clover=coverage.xml"),
+                pipeline.CoverageReport("clover", "coverage.xml"))
+        case info.TestFramework == "codeception":
+            test.Steps = append(test.Steps, pipeline.Run("Run Codeception tests", "vendor/bin/codecept run --coverage-xml"))
+        case info.TestFramework == "behat":
+            test.Steps = append(test.Steps, pipeline.Run("Run Behat scenarios", "vendor/bin/behat --no-interaction"))
+        case isLaravel:
+            test.Steps = append(test.Steps, pipeline.Run("Run tests", "php artisan test"))
+        default:
+            test.Steps = append(test.Steps,
+                pipeline.Run("Run PHPUnit tests", `if [ -f vendor/bin/phpunit ]; then
+  vendor/bin/phpunit --coverage-clover=coverage.xml
+else
+  phpunit --coverage-clover=coverage.xml
+fi`),
+                pipeline.CoverageReport("clover", "coverage.xml"))
         }
     }
 
-    // Job для сборки (если требуется)
-    previousJob := "setup"
-    if info.HasTests {
-        previousJob = "test"
-    }
-
-    pipeline.WriteString(fmt.Sprintf(`  build:
-    runs-on: ubuntu-latest
-    needs: %s
-    if: github.event_name == 'push' && (github.ref == 'refs/heads/main' || github.ref == 'refs/heads/master')
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup PHP
-      uses: shivammathur/setup-php@v2
-      with:
-        php-version: ${{ env.PHP_VERSION }}
-        
-`, previousJob))
-
-    if info.PackageManager == "composer" {
-        pipeline.WriteString(`    - name: Install dependencies (no dev)
-      run: composer install --prefer-dist --no-dev --no-progress --no-interaction --optimize-autoloader
-      
-    - name: Optimize autoloader
-      run: composer dump-autoload --no-dev --optimize
-`)
+    // Job для сборки: зависимости без dev-пакетов и оптимизированный автозагрузчик
+    build := p.AddJob(&pipeline.Job{ID: "build", Stage: "build", Image: phpImage(version), Needs: []string{previousJob}})
+    if composer {
+        install = phpComposerInstall + " --no-dev --optimize-autoloader"
     }
-
-    // Для Laravel проектов
-    if containsDependency(info.Dependencies, "framework:laravel") {
-        pipeline.WriteString(`    - name: Laravel optimization
-      run: |
-        php artisan config:cache
-        php artisan route:cache
-        php artisan view:cache
-        
-    - name: Generate build artifacts
-      run: |
-        mkdir -p build
-        cp -r app bootstrap config database public resources routes storage vendor build/
-        cp artisan composer.json composer.lock .env.example build/
-`)
-    } else {
-        pipeline.WriteString(`    - name: Create build package
-      run: |
-        mkdir -p build
-        cp -r src vendor build/
-        if [ -f composer.json ]; then cp composer.json composer.lock build/; fi
-`)
+    build.Steps = append(build.Steps, phpSetupSteps(version, "none", install)...)
+
+    switch {
+    case isLaravel:
+        build.Steps = append(build.Steps,
+            pipeline.Run("Laravel optimization", "php artisan config:cache\nphp artisan route:cache\nphp artisan view:cache"),
+            pipeline.Run("Create build package", `mkdir -p build
+cp -r app bootstrap config database public resources routes storage vendor build/
+cp artisan composer.json composer.lock build/`),
+        )
+    case isSymfony:
+        build.Steps = append(build.Steps,
+            pipeline.Run("Warm up cache", "APP_ENV=prod php bin/console cache:warmup"),
+            pipeline.Run("Create build package", `mkdir -p build
+cp -r bin config public src var vendor build/
+cp composer.json composer.lock build/`),
+        )
+    default:
+        build.Steps = append(build.Steps, pipeline.Run("Create build package", `mkdir -p build
+for dir in src public vendor; do
+  if [ -d "$dir" ]; then cp -r "$dir" build/; fi
+done
+if [ -f composer.json ]; then cp composer.json build/; fi`))
     }
-
-    pipeline.WriteString(`    - name: Upload build artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: php-build
-        path: build/
-`)
-
-    // Job для деплоя (опционально)
-    if containsDependency(info.Dependencies, "framework:laravel") {
-        pipeline.WriteString(`  deploy:
-    runs-on: ubuntu-latest
-    needs: build
-    if: github.event_name == 'push' && github.ref == 'refs/heads/main'
-    steps:
-    - name: Download build artifacts
-      uses: actions/download-artifact@v3
-      with:
-        name: php-build
-        
-    - name: Deploy to server
-      env:
-        DEPLOY_KEY: ${{ secrets.DEPLOY_KEY }}
-        SERVER_HOST: ${{ secrets.SERVER_HOST }}
-      run: |
-        echo "Deployment would happen here"
-        # rsync -avz -e "ssh -i $DEPLOY_KEY" build/ user@$SERVER_HOST:/var/www/html/
-`)
+    build.Steps = append(build.Steps, pipeline.Upload("php-build", "build/"))
+    build.ExpireIn = "1 week"
+
+    // Job для проверки зависимостей на уязвимости
+    if composer {
+        audit := p.AddJob(&pipeline.Job{ID: "security", Stage: "test", Image: phpImage(version), Needs: []string{"lint"}, AllowFailure: true})
+        audit.Steps = append(audit.Steps, phpSetupSteps(version, "none", phpComposerInstall)...)
+        audit.Steps = append(audit.Steps, pipeline.Run("Check for vulnerable dependencies", "composer audit"))
     }
 
-    // Job для безопасности
-    pipeline.WriteString(`  security:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup PHP
-      uses: shivammathur/setup-php@v2
-      with:
-        php-version: ${{ env.PHP_VERSION }}
-        
-`)
-
-    if info.PackageManager == "composer" {
-        pipeline.WriteString(`    - name: Install dependencies
-      run: composer install --prefer-dist --no-progress --no-interaction
-      
-    - name: Security check with Symfony Security Checker
-      run: composer require --dev enlightn/security-checker && vendor/bin/security-checker security:check
-      
-    - name: Check for vulnerable dependencies
-      run: composer audit
-`)
+    return p
+}
+
+const phpComposerInstall = "composer install --prefer-dist --no-progress --no-interaction"
+
+// phpImage — официальный образ PHP для GitLab и Jenkins.
+func phpImage(version string) string {
+    return "php:" + version + "-cli"
+}
+
+// phpSetupSteps — исходники, PHP с расширениями и установка зависимостей
+// командой install, если проект использует Composer. В официальных образах
+// PHP нет Composer, поэтому в GitLab и Jenkins он доустанавливается.
+func phpSetupSteps(version, coverage, install string) []pipeline.Step {
+    setup := pipeline.Setup("php", version,
+        pipeline.Var{Name: "extensions", Value: "mbstring, xml, ctype, iconv, intl, pdo_sqlite"},
+        pipeline.Var{Name: "coverage", Value: coverage},
+        pipeline.Var{Name: "tools", Value: "composer"},
+    )
+    if install == "" {
+        return []pipeline.Step{pipeline.Checkout(), setup}
     }
-
-    return pipeline.String()
-}
\ No newline at end of file
+    setup.Command = `apt-get update && apt-get install -y git unzip
+curl -sS https://getcomposer.org/installer | php -- --install-dir=/usr/local/bin --filename=composer`
+    return []pipeline.Step{pipeline.Checkout(), setup, pipeline.Run("Install dependencies", install)}
+}
@@ -1,195 +1,124 @@
 package generator
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generatePythonPipeline(info *analyzer.ProjectInfo) string {
-	var pipeline strings.Builder
-
-	pipeline.WriteString(`name: Python CI/CD Pipeline
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
+func buildPythonPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+	version := info.Version
+	if version == "" {
+		version = "3.9"
+	}
+	image := "python:" + version + "-slim"
+
+	p := &pipeline.Pipeline{
+		Name:     "Python CI/CD Pipeline",
+		Branches: []string{"main", "master", "develop"},
+		Stages:   []string{"lint", "test", "build", "deploy"},
+		Cache:    &pipeline.Cache{Paths: []string{".venv/", "venv/", ".cache/pip/"}},
+		Env:      []pipeline.Var{{Name: "PIP_CACHE_DIR", Value: ".cache/pip"}},
+	}
 
-jobs:
-`)
+	// Job для линтинга (если есть зависимости для линтинга)
+	if containsDependency(info.Dependencies, "web-framework") {
+		lint := p.AddJob(&pipeline.Job{ID: "lint", Stage: "lint", Image: image})
+		lint.Steps = append(lint.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("python", version),
+			pipeline.Run("Install linters", "pip install flake8 black mypy"),
+			pipeline.Run("Run linters", "flake8 .\nblack --check .\nmypy ."),
+		)
+	}
 
-	// Job для тестов или проверки
+	previousJob := "verify"
 	if info.HasTests {
-		pipeline.WriteString(`  test:
-    runs-on: ubuntu-latest
-    strategy:
-      matrix:
-        python-version: [`)
+		previousJob = "test"
 
 		// Добавляем версии Python
+		versions := []string{"3.8", "3.9", "3.10"}
 		if info.Version != "" && info.Version != "3.9" {
-			pipeline.WriteString(fmt.Sprintf(" '%s', '3.9' ", info.Version))
-		} else {
-			pipeline.WriteString(" '3.8', '3.9', '3.10' ")
-		}
-
-		pipeline.WriteString(`]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Set up Python ${{ matrix.python-version }}
-      uses: actions/setup-python@v3
-      with:
-        python-version: ${{ matrix.python-version }}
-    - name: Install dependencies
-`)
-
-		// Выбираем способ установки зависимостей
-		switch info.BuildTool {
-		case "poetry":
-			pipeline.WriteString(`      run: |
-        pip install poetry
-        poetry install
-`)
-		case "pipenv":
-			pipeline.WriteString(`      run: |
-        pip install pipenv
-        pipenv install --dev
-`)
-		default:
-			pipeline.WriteString(`      run: |
-        python -m pip install --upgrade pip
-        pip install -r requirements.txt
-`)
+			versions = []string{info.Version, "3.9"}
 		}
 
+		test := p.AddJob(&pipeline.Job{
+			ID:     "test",
+			Stage:  "test",
+			Image:  "python:" + pipeline.MatrixRef("python-version") + "-slim",
+			Matrix: []pipeline.Axis{{Name: "python-version", Values: versions}},
+		})
+		test.Steps = append(test.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("python", pipeline.MatrixRef("python-version")),
+			pipeline.Run("Install dependencies", pythonInstallCommand(info.BuildTool, true)),
+		)
 		// Добавляем запуск тестов
-		pipeline.WriteString(`    - name: Run tests
-`)
 		switch info.TestFramework {
 		case "pytest":
-			pipeline.WriteString(`      run: pytest
-`)
+			test.Steps = append(test.Steps,
+				pipeline.Run("Run tests", pythonRunPrefix(info.BuildTool)+"pytest --cov=. --cov-report=xml --cov-report=term"),
+				pipeline.CoverageReport("cobertura", "coverage.xml"),
+			)
+			test.Coverage = `/TOTAL.*\s+(\d+%)$/`
 		default:
-			pipeline.WriteString(`      run: python -m unittest discover
-`)
+			test.Steps = append(test.Steps,
+				pipeline.Run("Run tests", pythonRunPrefix(info.BuildTool)+"python -m unittest discover"))
 		}
-
 	} else {
 		// Если тестов нет - простая проверка
-		pipeline.WriteString(`  verify:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Set up Python
-      uses: actions/setup-python@v3
-      with:
-        python-version: '`)
-
-		if info.Version != "" {
-			pipeline.WriteString(info.Version)
-		} else {
-			pipeline.WriteString("3.9")
-		}
-
-		pipeline.WriteString(`'
-    - name: Install dependencies
-`)
+		verify := p.AddJob(&pipeline.Job{ID: "verify", Stage: "test", Image: image})
+		verify.Steps = append(verify.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("python", version),
+			pipeline.Run("Install dependencies", pythonInstallCommand(info.BuildTool, false)),
+			pipeline.Run("Verify imports", `python -c "import sys; print('Python path:', sys.path)"`),
+		)
+	}
 
+	if info.BuildTool == "poetry" || info.BuildTool == "setuptools" {
+		build := p.AddJob(&pipeline.Job{ID: "build", Stage: "build", Image: image, Needs: []string{previousJob}})
+		build.Steps = append(build.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("python", version),
+		)
 		switch info.BuildTool {
 		case "poetry":
-			pipeline.WriteString(`      run: |
-        pip install poetry
-        poetry install
-`)
-		case "pipenv":
-			pipeline.WriteString(`      run: |
-        pip install pipenv
-        pipenv install
-`)
-		default:
-			pipeline.WriteString(`      run: |
-        python -m pip install --upgrade pip
-        if [ -f requirements.txt ]; then pip install -r requirements.txt; fi
-`)
+			build.Steps = append(build.Steps, pipeline.Run("Build package", "pip install poetry\npoetry build"))
+		case "setuptools":
+			build.Steps = append(build.Steps, pipeline.Run("Build package", "pip install setuptools wheel\npython setup.py sdist bdist_wheel"))
 		}
-
-		pipeline.WriteString(`    - name: Verify imports
-      run: python -c "import sys; print('Python path:', sys.path)"
-`)
+		build.Steps = append(build.Steps, pipeline.Upload("python-package", "dist/"))
 	}
 
-	if info.BuildTool == "poetry" || info.BuildTool == "setuptools" {
-		previousJob := "test"
-		if !info.HasTests {
-			previousJob = "verify"
-		}
-
-		pipeline.WriteString(fmt.Sprintf(`  build:
-    runs-on: ubuntu-latest
-    needs: %s
-    steps:
-    - uses: actions/checkout@v3
-    - name: Set up Python
-      uses: actions/setup-python@v3
-      with:
-        python-version: '`, previousJob))
+	return p
+}
 
-		if info.Version != "" {
-			pipeline.WriteString(info.Version)
-		} else {
-			pipeline.WriteString("3.9")
+// pythonInstallCommand выбирает способ установки зависимостей
+func pythonInstallCommand(buildTool string, dev bool) string {
+	switch buildTool {
+	case "poetry":
+		return "pip install poetry\npoetry install"
+	case "pipenv":
+		if dev {
+			return "pip install pipenv\npipenv install --dev"
 		}
-
-		pipeline.WriteString(`'
-    - name: Build package
-`)
-
-		switch info.BuildTool {
-		case "poetry":
-			pipeline.WriteString(`      run: |
-        pip install poetry
-        poetry build
-`)
-		case "setuptools":
-			pipeline.WriteString(`      run: |
-        pip install setuptools wheel
-        python setup.py sdist bdist_wheel
-`)
-		default:
-			pipeline.WriteString(`      run: |
-        echo "No specific build step configured"
-`)
+		return "pip install pipenv\npipenv install"
+	default:
+		command := "python -m pip install --upgrade pip\nif [ -f requirements.txt ]; then pip install -r requirements.txt; fi"
+		if dev {
+			command += "\npip install pytest pytest-cov"
 		}
-
-		pipeline.WriteString(`    - name: Upload package
-      uses: actions/upload-artifact@v3
-      with:
-        name: python-package
-        path: dist/
-`)
+		return command
 	}
+}
 
-	// Job для линтинга (если есть зависимости для линтинга)
-	if containsDependency(info.Dependencies, "web-framework") {
-		pipeline.WriteString(`  lint:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Set up Python
-      uses: actions/setup-python@v3
-    - name: Install linters
-      run: |
-        pip install flake8 black mypy
-    - name: Run linters
-      run: |
-        flake8 .
-        black --check .
-        mypy .
-`)
+func pythonRunPrefix(buildTool string) string {
+	switch buildTool {
+	case "poetry":
+		return "poetry run "
+	case "pipenv":
+		return "pipenv run "
+	default:
+		return ""
 	}
-
-	return pipeline.String()
 }
@@ -0,0 +1,161 @@
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// Generator строит pipeline для одного языка в одном формате.
+type Generator interface {
+	Language() string
+	Format() string
+	Generate(info *analyzer.ProjectInfo) (string, error)
+}
+
+// GeneratorFunc превращает функцию в Generator для пары язык/формат.
+func GeneratorFunc(language, format string, generate func(*analyzer.ProjectInfo) (string, error)) Generator {
+	return funcGenerator{language: language, format: format, generate: generate}
+}
+
+type funcGenerator struct {
+	language string
+	format   string
+	generate func(*analyzer.ProjectInfo) (string, error)
+}
+
+func (g funcGenerator) Language() string { return g.language }
+func (g funcGenerator) Format() string   { return g.format }
+func (g funcGenerator) Generate(info *analyzer.ProjectInfo) (string, error) {
+	return g.generate(info)
+}
+
+// ModelGenerator возвращает Generator, который строит pipeline.Pipeline,
+// применяет к нему настройки проекта и сериализует рендером формата.
+// Такие генераторы можно объединять в один pipeline для полиглот-репозиториев.
+func ModelGenerator(language, format string, build func(*analyzer.ProjectInfo) *pipeline.Pipeline) Generator {
+	return modelGenerator{language: language, format: format, build: build}
+}
+
+// PipelineBuilder реализуют генераторы, которые отдают модель pipeline
+// до сериализации.
+type PipelineBuilder interface {
+	Build(info *analyzer.ProjectInfo) *pipeline.Pipeline
+}
+
+type modelGenerator struct {
+	language string
+	format   string
+	build    func(*analyzer.ProjectInfo) *pipeline.Pipeline
+}
+
+func (g modelGenerator) Language() string { return g.language }
+func (g modelGenerator) Format() string   { return g.format }
+func (g modelGenerator) Build(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+	p := g.build(info)
+	addServices(p, info)
+	applyConfig(p, info.Config)
+	return p
+}
+func (g modelGenerator) Generate(info *analyzer.ProjectInfo) (string, error) {
+	return pipeline.Render(g.Build(info), g.format)
+}
+
+type registryKey struct {
+	language string
+	format   string
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[registryKey]Generator{}
+)
+
+// Register добавляет генератор в реестр. Генератор, зарегистрированный позже
+// для той же пары язык/формат, заменяет предыдущий.
+func Register(g Generator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[registryKey{language: g.Language(), format: g.Format()}] = g
+}
+
+// Lookup ищет генератор для пары язык/формат.
+func Lookup(language, format string) (Generator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	g, ok := registry[registryKey{language: language, format: format}]
+	return g, ok
+}
+
+// Languages возвращает отсортированный список языков, для которых есть генераторы.
+func Languages() []string {
+	return registeredKeys(func(k registryKey) string { return k.language })
+}
+
+// Formats возвращает отсортированный список поддерживаемых форматов.
+func Formats() []string {
+	return registeredKeys(func(k registryKey) string { return k.format })
+}
+
+// FormatsFor возвращает форматы, доступные для языка.
+func FormatsFor(language string) []string {
+	var formats []string
+	for _, format := range Formats() {
+		if _, ok := Lookup(language, format); ok {
+			formats = append(formats, format)
+		}
+	}
+	return formats
+}
+
+func registeredKeys(field func(registryKey) string) []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	seen := map[string]bool{}
+	var values []string
+	for key := range registry {
+		value := field(key)
+		if !seen[value] {
+			seen[value] = true
+			values = append(values, value)
+		}
+	}
+	sort.Strings(values)
+	return values
+}
+
+// lookupGenerator возвращает генератор или ошибку, объясняющую, чего не хватает.
+func lookupGenerator(language, format string) (Generator, error) {
+	if g, ok := Lookup(language, format); ok {
+		return g, nil
+	}
+	if formats := FormatsFor(language); len(formats) > 0 {
+		return nil, fmt.Errorf("format %s is not supported for language %s (supported: %s)", format, language, strings.Join(formats, ", "))
+	}
+	return nil, fmt.Errorf("unsupported language: %s", language)
+}
+
+func init() {
+	builders := map[string]func(*analyzer.ProjectInfo) *pipeline.Pipeline{
+		"go":          buildGoPipeline,
+		"python":      buildPythonPipeline,
+		"java_gradle": buildJavaPipeline,
+		"java_maven":  buildJavaPipeline,
+		"javascript":  buildJavaScriptPipeline,
+		"csharp":      buildCSharpPipeline,
+		"ruby":        buildRubyPipeline,
+		"rust":        buildRustPipeline,
+		"cpp":         buildCppPipeline,
+		"php":         buildPHPPipeline,
+		"swift":       buildSwiftPipeline,
+	}
+	for language, build := range builders {
+		for _, format := range []string{"github", "gitlab", "jenkins", "azure", "circleci", "bitbucket", "woodpecker", "drone", "tekton", "forgejo", "codebuild", "cloudbuild"} {
+			Register(ModelGenerator(language, format, build))
+		}
+	}
+}
@@ -1,263 +1,113 @@
 package generator
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateRubyPipeline(info *analyzer.ProjectInfo) string {
-	var pipeline strings.Builder
-
-	pipeline.WriteString(`name: Ruby CI/CD Pipeline
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
-
-jobs:
-`)
-
-	// Job для установки зависимостей
-	pipeline.WriteString(`  install:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Ruby
-      uses: ruby/setup-ruby@v1
-      with:
-        ruby-version: '`)
-
-	if info.Version != "" {
-		pipeline.WriteString(info.Version)
-	} else {
-		pipeline.WriteString("2.7")
+func buildRubyPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+	version := info.Version
+	if version == "" {
+		version = "2.7"
 	}
-
-	pipeline.WriteString(`'
-        bundler-cache: true
-`)
-
-	// Установка зависимостей в зависимости от инструмента сборки
-	switch info.BuildTool {
-	case "bundler":
-		pipeline.WriteString(`    - name: Install dependencies
-      run: bundle install
-`)
-	case "rake":
-		pipeline.WriteString(`    - name: Install dependencies
-      run: |
-        gem install bundler
-        bundle install
-`)
-	default:
-		pipeline.WriteString(`    - name: Install dependencies
-      run: |
-        gem install bundler
-        bundle install
-`)
+	isRails := containsDependency(info.Dependencies, "web-framework:rails")
+	hasLint := containsDependency(info.Dependencies, "rubocop")
+
+	p := &pipeline.Pipeline{
+		Name:     "Ruby CI/CD Pipeline",
+		Branches: []string{"main", "master", "develop"},
+		Stages:   []string{"lint", "test", "build", "deploy"},
+		Env:      []pipeline.Var{{Name: "BUNDLE_PATH", Value: "vendor/bundle"}},
+		Cache:    &pipeline.Cache{KeyFiles: []string{"Gemfile.lock"}, Paths: []string{"vendor/bundle/"}},
 	}
 
 	// Job для линтинга (если есть RuboCop)
-	if containsDependency(info.Dependencies, "rubocop") {
-		pipeline.WriteString(`  lint:
-    runs-on: ubuntu-latest
-    needs: install
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Ruby
-      uses: ruby/setup-ruby@v1
-      with:
-        ruby-version: '`)
-
-		if info.Version != "" {
-			pipeline.WriteString(info.Version)
-		} else {
-			pipeline.WriteString("2.7")
-		}
-
-		pipeline.WriteString(`'
-        bundler-cache: true
-    - name: Install dependencies
-      run: bundle install
-    - name: Run RuboCop
-      run: bundle exec rubocop
-`)
+	if hasLint {
+		lint := p.AddJob(&pipeline.Job{ID: "lint", Stage: "lint", Image: "ruby:" + version})
+		lint.Steps = append(lint.Steps, rubySetupSteps(version)...)
+		lint.Steps = append(lint.Steps, pipeline.Run("Run RuboCop", "bundle exec rubocop"))
 	}
 
-	// Job для тестов
+	previousJob := "verify"
 	if info.HasTests {
-		pipeline.WriteString(`  test:
-    runs-on: ubuntu-latest
-    needs: install
-    strategy:
-      matrix:
-        ruby-version: [`)
+		previousJob = "test"
 
 		// Добавляем версии Ruby
+		versions := []string{"2.6", "2.7", "3.0"}
 		if info.Version != "" && info.Version != "2.7" {
-			pipeline.WriteString(fmt.Sprintf(" '%s', '2.6', '2.7', '3.0' ", info.Version))
-		} else {
-			pipeline.WriteString(" '2.6', '2.7', '3.0' ")
+			versions = []string{info.Version, "2.6", "2.7", "3.0"}
 		}
 
-		pipeline.WriteString(`]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Ruby ${{ matrix.ruby-version }}
-      uses: ruby/setup-ruby@v1
-      with:
-        ruby-version: ${{ matrix.ruby-version }}
-        bundler-cache: true
-    - name: Install dependencies
-      run: bundle install
-    - name: Run tests
-      run: `)
+		test := p.AddJob(&pipeline.Job{
+			ID:     "test",
+			Stage:  "test",
+			Image:  "ruby:" + pipeline.MatrixRef("ruby-version"),
+			Matrix: []pipeline.Axis{{Name: "ruby-version", Values: versions}},
+		})
+		test.Steps = append(test.Steps, rubySetupSteps(pipeline.MatrixRef("ruby-version"))...)
 
 		// Запуск тестов в зависимости от фреймворка
+		command := "bundle exec rake test"
 		switch info.TestFramework {
 		case "rspec":
-			pipeline.WriteString("bundle exec rspec")
-		case "minitest":
-			pipeline.WriteString("bundle exec rake test")
-		case "test-unit":
-			pipeline.WriteString("bundle exec rake test")
+			command = "bundle exec rspec"
 		case "cucumber":
-			pipeline.WriteString("bundle exec cucumber")
-		default:
-			pipeline.WriteString("bundle exec rake test")
+			command = "bundle exec cucumber"
 		}
-
-		pipeline.WriteString(`
-`)
+		test.Steps = append(test.Steps, pipeline.Run("Run tests", command))
 
 		// Добавляем отчет о покрытии для RSpec
 		if info.TestFramework == "rspec" {
-			pipeline.WriteString(`    - name: Upload coverage reports
-      uses: codecov/codecov-action@v3
-      with:
-        file: ./coverage/lcov.info
-        flags: unittests
-        name: codecov-umbrella
-`)
+			test.Steps = append(test.Steps, pipeline.CoverageReport("lcov", "coverage/lcov.info"))
 		}
-
 	} else {
 		// Если тестов нет - простая проверка
-		pipeline.WriteString(`  verify:
-    runs-on: ubuntu-latest
-    needs: install
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Ruby
-      uses: ruby/setup-ruby@v1
-      with:
-        ruby-version: '`)
-
-		if info.Version != "" {
-			pipeline.WriteString(info.Version)
-		} else {
-			pipeline.WriteString("2.7")
-		}
-
-		pipeline.WriteString(`'
-        bundler-cache: true
-    - name: Install dependencies
-      run: bundle install
-    - name: Verify syntax
-      run: |
-        find . -name "*.rb" -exec ruby -c {} \;
-`)
+		verify := p.AddJob(&pipeline.Job{ID: "verify", Stage: "test", Image: "ruby:" + version})
+		verify.Steps = append(verify.Steps, rubySetupSteps(version)...)
+		verify.Steps = append(verify.Steps, pipeline.Run("Verify syntax", `find . -name "*.rb" -not -path "./vendor/*" -exec ruby -c {} \;`))
 
 		// Проверка сборки для Rails приложений
-		if containsDependency(info.Dependencies, "web-framework:rails") {
-			pipeline.WriteString(`    - name: Verify Rails app
-      run: |
-        bundle exec rails db:create db:migrate
-        bundle exec rails assets:precompile
-`)
+		if isRails {
+			verify.Steps = append(verify.Steps, pipeline.Run("Verify Rails app",
+				"bundle exec rails db:create db:migrate\nbundle exec rails assets:precompile"))
 		}
-
-		pipeline.WriteString(`
-`)
 	}
 
 	// Job для сборки
-	previousJob := "test"
-	if !info.HasTests {
-		previousJob = "verify"
+	needs := []string{previousJob}
+	if hasLint {
+		needs = append(needs, "lint")
 	}
-	if containsDependency(info.Dependencies, "rubocop") {
-		previousJob = "lint"
-	}
-
-	pipeline.WriteString(fmt.Sprintf(`  build:
-    runs-on: ubuntu-latest
-    needs: %s
-    steps:
-    - uses: actions/checkout@v3
-    - name: Setup Ruby
-      uses: ruby/setup-ruby@v1
-      with:
-        ruby-version: '`, previousJob))
-
-	if info.Version != "" {
-		pipeline.WriteString(info.Version)
-	} else {
-		pipeline.WriteString("2.7")
-	}
-
-	pipeline.WriteString(`'
-        bundler-cache: true
-    - name: Install dependencies
-      run: bundle install
-`)
+	build := p.AddJob(&pipeline.Job{ID: "build", Stage: "build", Image: "ruby:" + version, Needs: needs})
+	build.Steps = append(build.Steps, rubySetupSteps(version)...)
 
 	// Сборка в зависимости от типа приложения
-	if containsDependency(info.Dependencies, "web-framework:rails") {
-		pipeline.WriteString(`    - name: Build Rails application
-      run: |
-        bundle exec rails assets:precompile
-        bundle exec rails db:create db:migrate
-`)
-	} else if containsDependency(info.Dependencies, "gem") {
-		pipeline.WriteString(`    - name: Build gem
-      run: |
-        gem build *.gemspec
-`)
-	} else {
-		pipeline.WriteString(`    - name: Build application
-      run: bundle exec rake build
-`)
+	switch {
+	case isRails:
+		build.Steps = append(build.Steps,
+			pipeline.Run("Build Rails application", "bundle exec rails assets:precompile"),
+			pipeline.Upload("build-files", "public/assets/"),
+		)
+	case containsDependency(info.Dependencies, "gem"):
+		build.Steps = append(build.Steps,
+			pipeline.Run("Build gem", "gem build *.gemspec"),
+			pipeline.Upload("build-files", "*.gem"),
+		)
+	default:
+		build.Steps = append(build.Steps,
+			pipeline.Run("Build application", "bundle exec rake build"),
+			pipeline.Upload("build-files", "pkg/"),
+		)
 	}
 
-	pipeline.WriteString(`    - name: Upload build artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: build-files
-        path: |`)
+	return p
+}
 
-	// Определяем пути для артефактов в зависимости от типа приложения
-	if containsDependency(info.Dependencies, "web-framework:rails") {
-		pipeline.WriteString(`
-          public/assets/
-          tmp/cache/
-          log/`)
-	} else if containsDependency(info.Dependencies, "gem") {
-		pipeline.WriteString(`
-          *.gem
-          pkg/`)
-	} else {
-		pipeline.WriteString(`
-          dist/
-          build/`)
+// rubySetupSteps — исходники, Ruby и установка гемов через Bundler.
+func rubySetupSteps(version string) []pipeline.Step {
+	return []pipeline.Step{
+		pipeline.Checkout(),
+		pipeline.Setup("ruby", version, pipeline.Var{Name: "bundler-cache", Value: "true"}),
+		pipeline.Run("Install dependencies", "bundle install"),
 	}
-
-	pipeline.WriteString(`
-`)
-
-	return pipeline.String()
-}
\ No newline at end of file
+}
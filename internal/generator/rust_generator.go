@@ -1,199 +1,119 @@
 package generator
 
 import (
-	"fmt"
-	"strings"
-
 	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateRustPipeline(info *analyzer.ProjectInfo) string {
-	var pipeline strings.Builder
-
-	pipeline.WriteString(`name: Rust CI/CD Pipeline
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
-
-jobs:
-`)
-
-	// Job для проверки кода (clippy) и форматирования (fmt)
-	pipeline.WriteString(`  check:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Rust
-      uses: actions-rust-lang/setup-rust-toolchain@v1
-      with:
-        toolchain: `)
+func buildRustPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
+	toolchain := info.Version
+	if toolchain == "" {
+		toolchain = "stable"
+	}
+	image := "rust:" + toolchain
+	if toolchain == "stable" {
+		image = "rust:latest"
+	}
 
-	if info.Version != "" && info.Version != "stable" {
-		pipeline.WriteString(fmt.Sprintf(" '%s' ", info.Version))
-	} else {
-		pipeline.WriteString(" 'stable' ")
+	p := &pipeline.Pipeline{
+		Name:     "Rust CI/CD Pipeline",
+		Branches: []string{"main", "master", "develop"},
+		Stages:   []string{"check", "test", "build", "publish", "deploy"},
+		Env:      []pipeline.Var{{Name: "CARGO_HOME", Value: ".cargo"}},
+		Cache:    &pipeline.Cache{KeyFiles: []string{"Cargo.lock"}, Paths: []string{".cargo/registry/", "target/"}},
 	}
 
-	pipeline.WriteString(`
-        components: clippy, rustfmt
-    - name: Check code format
-      run: cargo fmt -- --check
-    - name: Run clippy
-      run: cargo clippy -- -D warnings
-`)
+	// Job для проверки кода (clippy) и форматирования (fmt)
+	check := p.AddJob(&pipeline.Job{ID: "check", Stage: "check", Image: image})
+	check.Steps = append(check.Steps,
+		pipeline.Checkout(),
+		pipeline.Setup("rust", toolchain, pipeline.Var{Name: "components", Value: "clippy, rustfmt"}),
+		pipeline.Run("Install components", "rustup component add clippy rustfmt"),
+		pipeline.Run("Check code format", "cargo fmt -- --check"),
+		pipeline.Run("Run clippy", "cargo clippy -- -D warnings"),
+	)
 
 	// Job для тестов
+	previousJob := "check"
 	if info.HasTests {
-		pipeline.WriteString(`  test:
-    runs-on: ubuntu-latest
-    strategy:
-      matrix:
-        os: [ubuntu-latest, windows-latest, macos-latest]
-        rust: [`)
-
-		// Добавляем версии Rust
-		if info.Version != "" && info.Version != "stable" {
-			pipeline.WriteString(fmt.Sprintf(" '%s', 'stable', 'nightly' ", info.Version))
-		} else {
-			pipeline.WriteString(" 'stable', 'nightly', '1.70.0' ")
-		}
-
-		pipeline.WriteString(`]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Rust ${{ matrix.rust }}
-      uses: actions-rust-lang/setup-rust-toolchain@v1
-      with:
-        toolchain: ${{ matrix.rust }}
-    - name: Run tests
-      run: cargo test
-    - name: Run tests with coverage
-      run: cargo tarpaulin --out Xml
-      if: matrix.os == 'ubuntu-latest' && matrix.rust == 'stable'
-    - name: Upload coverage to Codecov
-      uses: codecov/codecov-action@v3
-      if: matrix.os == 'ubuntu-latest' && matrix.rust == 'stable'
-      with:
-        files: cobertura.xml
-`)
-
+		previousJob = "test"
+		command := "cargo test"
 		// Для workspace проектов
 		if len(info.Modules) > 1 {
-			pipeline.WriteString(`    - name: Run tests for all workspace members
-      run: cargo test --workspace
-`)
+			command = "cargo test --workspace"
 		}
+		test := p.AddJob(&pipeline.Job{ID: "test", Stage: "test", Image: image, Needs: []string{"check"}})
+		test.Steps = append(test.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("rust", toolchain),
+			pipeline.Run("Run tests", command),
+			pipeline.Run("Run tests with coverage", "cargo install cargo-tarpaulin\ncargo tarpaulin --out Xml"),
+			pipeline.CoverageReport("cobertura", "cobertura.xml"),
+		)
 	}
 
 	// Job для сборки
-	previousJob := "test"
-	if !info.HasTests {
-		previousJob = "check"
-	}
-
-	pipeline.WriteString(fmt.Sprintf(`  build:
-    runs-on: ubuntu-latest
-    needs: %s
-    strategy:
-      matrix:
-        target: [x86_64-unknown-linux-gnu, x86_64-pc-windows-msvc, x86_64-apple-darwin]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Rust
-      uses: actions-rust-lang/setup-rust-toolchain@v1
-      with:
-        toolchain: `, previousJob))
-
-	if info.Version != "" {
-		pipeline.WriteString(fmt.Sprintf(" '%s' ", info.Version))
-	} else {
-		pipeline.WriteString(" 'stable' ")
-	}
-
-	pipeline.WriteString(fmt.Sprintf(`
-        target: ${{ matrix.target }}
-    - name: Build release
-      run: cargo build --release --target ${{ matrix.target }}
-    - name: Upload artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: rust-${{ matrix.target }}
-        path: target/${{ matrix.target }}/release/
-`))
+	build := p.AddJob(&pipeline.Job{ID: "build", Stage: "build", Image: image, Needs: []string{previousJob}})
+	build.Steps = append(build.Steps,
+		pipeline.Checkout(),
+		pipeline.Setup("rust", toolchain),
+		pipeline.Run("Build release", "cargo build --release"),
+		pipeline.Upload("rust-release", "target/release/"),
+	)
 
 	// Job для проверки безопасности (audit)
-	pipeline.WriteString(`  security:
-    runs-on: ubuntu-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Rust
-      uses: actions-rust-lang/setup-rust-toolchain@v1
-      with:
-        toolchain: stable
-    - name: Install cargo-audit
-      run: cargo install cargo-audit
-    - name: Audit dependencies
-      run: cargo audit
-`)
+	security := p.AddJob(&pipeline.Job{ID: "security", Stage: "check", Image: image})
+	security.Steps = append(security.Steps,
+		pipeline.Checkout(),
+		pipeline.Setup("rust", "stable"),
+		pipeline.Run("Install cargo-audit", "cargo install cargo-audit"),
+		pipeline.Run("Audit dependencies", "cargo audit"),
+	)
 
 	// Job для бенчмарков (если есть)
-	if containsDependency(info.Dependencies, "bench") || detectRustBenchmarks(info) {
-		pipeline.WriteString(`  benchmark:
-    runs-on: ubuntu-latest
-    needs: build
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Rust
-      uses: actions-rust-lang/setup-rust-toolchain@v1
-      with:
-        toolchain: stable
-    - name: Run benchmarks
-      run: cargo bench
-`)
+	if detectRustBenchmarks(info) {
+		benchmark := p.AddJob(&pipeline.Job{ID: "benchmark", Stage: "build", Image: image, Needs: []string{"build"}})
+		benchmark.Steps = append(benchmark.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("rust", "stable"),
+			pipeline.Run("Run benchmarks", "cargo bench"),
+		)
 	}
 
 	// Job для публикации в crates.io (если это библиотека)
 	if containsDependency(info.Dependencies, "type:library") {
-		pipeline.WriteString(`  publish:
-    runs-on: ubuntu-latest
-    needs: [check, test, security]
-    if: github.event_name == 'push' && contains(github.ref, 'refs/tags/')
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Rust
-      uses: actions-rust-lang/setup-rust-toolchain@v1
-      with:
-        toolchain: stable
-    - name: Publish to crates.io
-      run: cargo publish
-      env:
-        CARGO_REGISTRY_TOKEN: ${{ secrets.CARGO_REGISTRY_TOKEN }}
-`)
+		publish := p.AddJob(&pipeline.Job{
+			ID:    "publish",
+			Stage: "publish",
+			Image: image,
+			Needs: []string{previousJob, "security"},
+			When:  pipeline.Condition{Tags: true},
+			Env:   []pipeline.Var{{Name: "CARGO_REGISTRY_TOKEN", Value: pipeline.SecretRef("CARGO_REGISTRY_TOKEN")}},
+		})
+		publish.Steps = append(publish.Steps,
+			pipeline.Checkout(),
+			pipeline.Setup("rust", "stable"),
+			pipeline.Run("Publish to crates.io", "cargo publish"),
+		)
 	}
 
 	// Job для сборки документации
-	pipeline.WriteString(`  docs:
-    runs-on: ubuntu-latest
-    needs: build
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install Rust
-      uses: actions-rust-lang/setup-rust-toolchain@v1
-      with:
-        toolchain: stable
-    - name: Build documentation
-      run: cargo doc --no-deps
-    - name: Upload documentation
-      uses: actions/upload-artifact@v3
-      with:
-        name: rust-docs
-        path: target/doc/
-`)
-
-	return pipeline.String()
+	docs := p.AddJob(&pipeline.Job{ID: "docs", Stage: "build", Image: image, Needs: []string{"build"}})
+	docs.Steps = append(docs.Steps,
+		pipeline.Checkout(),
+		pipeline.Setup("rust", "stable"),
+		pipeline.Run("Build documentation", "cargo doc --no-deps"),
+		pipeline.Upload("rust-docs", "target/doc/"),
+	)
+
+	// Крейты workspace проверяются отдельно при изменении их файлов
+	addModuleJobs(p, info.Modules, image,
+		[]pipeline.Step{pipeline.Checkout(), pipeline.Setup("rust", toolchain)},
+		func(string) moduleCommands {
+			return moduleCommands{test: "cargo test", build: "cargo build --release", inDir: true}
+		})
+
+	return p
 }
 
 // Вспомогательная функция для обнаружения бенчмарков
@@ -0,0 +1,65 @@
+package generator
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// dependencyServices — сервисы, без которых не проходят тесты проекта
+// с такой зависимостью.
+var dependencyServices = map[string]pipeline.Service{
+	"database:redis":   {Name: "redis", Image: "redis:7-alpine", Port: 6379},
+	"database:mongodb": {Name: "mongo", Image: "mongo:7", Port: 27017},
+	"database:mongoid": {Name: "mongo", Image: "mongo:7", Port: 27017},
+}
+
+// serviceEnv — переменные, без которых не стартуют официальные образы баз данных.
+var serviceEnv = map[string][]pipeline.Var{
+	"postgres": {{Name: "POSTGRES_PASSWORD", Value: "postgres"}},
+	"mysql":    {{Name: "MYSQL_ROOT_PASSWORD", Value: "root"}},
+	"mariadb":  {{Name: "MARIADB_ROOT_PASSWORD", Value: "root"}},
+}
+
+// addServices подключает к джобам стадии test сервисы, найденные по
+// зависимостям проекта и перечисленные в секции services файла .pipeline-gen.yaml.
+func addServices(p *pipeline.Pipeline, info *analyzer.ProjectInfo) {
+	var services []pipeline.Service
+	seen := map[string]bool{}
+	add := func(service pipeline.Service) {
+		if !seen[service.Name] {
+			seen[service.Name] = true
+			services = append(services, service)
+		}
+	}
+
+	// Сервисы из конфигурации важнее найденных по зависимостям
+	if info.Config != nil {
+		var names []string
+		for name := range info.Config.Services {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			image := info.Config.Services[name]
+			base := strings.SplitN(image[strings.LastIndex(image, "/")+1:], ":", 2)[0]
+			add(pipeline.Service{Name: name, Image: image, Port: pipeline.ServicePort(image), Env: serviceEnv[base]})
+		}
+	}
+	for _, dep := range info.Dependencies {
+		if service, ok := dependencyServices[dep]; ok {
+			add(service)
+		}
+	}
+	if len(services) == 0 {
+		return
+	}
+	for _, job := range p.Jobs {
+		// Контейнеры сервисов доступны только на Linux-раннерах
+		if job.Stage == "test" && (job.OS == "" || job.OS == "linux") {
+			job.Services = append(job.Services, services...)
+		}
+	}
+}
@@ -5,220 +5,161 @@ import (
     "strings"
 
     "github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+    "github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
 )
 
-func generateSwiftPipeline(info *analyzer.ProjectInfo) string {
-    var pipeline strings.Builder
-
+func buildSwiftPipeline(info *analyzer.ProjectInfo) *pipeline.Pipeline {
     buildTool := "spm" // Swift Package Manager по умолчанию
     if info.BuildTool == "xcodebuild" {
         buildTool = "xcodebuild"
     }
 
-    pipeline.WriteString(fmt.Sprintf(`name: Swift CI/CD Pipeline (%s)
-
-on:
-  push:
-    branches: [ main, master, develop ]
-  pull_request:
-    branches: [ main, master, develop ]
+    version := info.Version
+    if version == "" {
+        version = "5.7"
+    }
 
-jobs:
-`, buildTool))
+    p := &pipeline.Pipeline{
+        Name:     fmt.Sprintf("Swift CI/CD Pipeline (%s)", buildTool),
+        Branches: []string{"main", "master", "develop"},
+        Stages:   []string{"lint", "test", "build", "publish", "deploy"},
+    }
 
-    // Job для тестов
-    if info.HasTests {
-        pipeline.WriteString(`  test:
-    runs-on: macos-latest
-    strategy:
-      matrix:
-        xcode: ['15.0', '14.3']
-        swift-version: [`)
-
-        // Добавляем версии Swift
-        if info.Version != "" && info.Version != "5.7" {
-            pipeline.WriteString(fmt.Sprintf(" '%s', '5.7', '5.8' ", info.Version))
+    // Серверные пакеты SwiftPM собираются в официальном Linux-образе swift,
+    // проекты Xcode — только на macOS-раннерах и агентах
+    macOS := buildTool == "xcodebuild" || hasXcodeProject(info.Structure)
+    newJob := func(job *pipeline.Job) *pipeline.Job {
+        if macOS {
+            job.OS = "macos"
         } else {
-            pipeline.WriteString(" '5.7', '5.8', '5.9' ")
+            job.Image = "swift:" + version
         }
+        return p.AddJob(job)
+    }
+    var setup pipeline.Step
+    if macOS {
+        setup = pipeline.Run("Select Xcode", "sudo xcode-select -s /Applications/Xcode_15.0.app")
+    } else {
+        setup = pipeline.Setup("swift", version)
+    }
 
-        pipeline.WriteString(`]
-    steps:
-    - uses: actions/checkout@v3
-    - name: Select Xcode ${{ matrix.xcode }}
-      run: sudo xcode-select -s /Applications/Xcode_${{ matrix.xcode }}.app
-`)
+    // Job для тестов
+    previousJob := "verify"
+    if info.HasTests {
+        previousJob = "test"
+        test := newJob(&pipeline.Job{ID: "test", Stage: "test"})
+        test.Steps = append(test.Steps, pipeline.Checkout(), setup)
 
         if buildTool == "spm" {
-            pipeline.WriteString(`    - name: Resolve dependencies
-      run: swift package resolve
-      
-    - name: Build with SwiftPM
-      run: swift build
-      
-    - name: Run tests with SwiftPM
-      run: swift test
-`)
-
-            // Для проектов с Vapor
-            if containsDependency(info.Dependencies, "vapor") {
-                pipeline.WriteString(`    - name: Run Vapor tests
-      run: swift test --enable-test-discovery
-`)
-            }
-
+            test.Steps = append(test.Steps,
+                pipeline.Run("Resolve dependencies", "swift package resolve"),
+                pipeline.Run("Build with SwiftPM", "swift build"),
+                pipeline.Run("Run tests with SwiftPM", "swift test --enable-code-coverage"),
+            )
         } else {
             // Для Xcode проектов
-            pipeline.WriteString(`    - name: Build with xcodebuild
-      run: xcodebuild -scheme MyApp -destination 'platform=iOS Simulator,name=iPhone 14' build
-      
-    - name: Run tests with xcodebuild
-      run: xcodebuild test -scheme MyApp -destination 'platform=iOS Simulator,name=iPhone 14'
-`)
+            test.Steps = append(test.Steps,
+                pipeline.Run("Build with xcodebuild", "xcodebuild -scheme MyApp -destination 'platform=iOS Simulator,name=iPhone 14' build"),
+                pipeline.Run("Run tests with xcodebuild", "xcodebuild test -scheme MyApp -destination 'platform=iOS Simulator,name=iPhone 14'"),
+            )
         }
 
         // Добавляем отчет о покрытии если есть соответствующие зависимости
-        if containsDependency(info.Dependencies, "coverage") {
-            pipeline.WriteString(`    - name: Generate code coverage
-      run: xcrun llvm-cov export -format="lcov" .build/debug/MyAppPackageTests.xctest/Contents/MacOS/MyAppPackageTests -instr-profile .build/debug/codecov/default.profdata > lcov.info
-      
-    - name: Upload coverage to Codecov
-      uses: codecov/codecov-action@v3
-      with:
-        file: lcov.info
-`)
+        if buildTool == "spm" && containsDependency(info.Dependencies, "coverage") {
+            export := `llvm-cov export -format="lcov" $(swift build --show-bin-path)/*PackageTests.xctest -instr-profile .build/debug/codecov/default.profdata > lcov.info`
+            if macOS {
+                export = `xcrun llvm-cov export -format="lcov" $(swift build --show-bin-path)/*.xctest/Contents/MacOS/*PackageTests -instr-profile .build/debug/codecov/default.profdata > lcov.info`
+            }
+            test.Steps = append(test.Steps,
+                pipeline.Run("Generate code coverage", export),
+                pipeline.CoverageReport("lcov", "lcov.info"),
+            )
         }
-
     } else {
         // Если тестов нет - простая проверка сборки
-        pipeline.WriteString(`  verify:
-    runs-on: macos-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Select Xcode
-      run: sudo xcode-select -s /Applications/Xcode_15.0.app
-`)
-
+        verify := newJob(&pipeline.Job{ID: "verify", Stage: "test"})
+        verify.Steps = append(verify.Steps, pipeline.Checkout(), setup)
         if buildTool == "spm" {
-            pipeline.WriteString(`    - name: Verify Swift package
-      run: swift package resolve && swift build
-`)
+            verify.Steps = append(verify.Steps, pipeline.Run("Verify Swift package", "swift package resolve && swift build"))
         } else {
-            pipeline.WriteString(`    - name: Verify Xcode project
-      run: xcodebuild -scheme MyApp -destination 'generic/platform=iOS' build
-`)
+            verify.Steps = append(verify.Steps, pipeline.Run("Verify Xcode project", "xcodebuild -scheme MyApp -destination 'generic/platform=iOS' build"))
         }
     }
 
     // Job для сборки
-    previousJob := "test"
-    if !info.HasTests {
-        previousJob = "verify"
-    }
-
-    pipeline.WriteString(fmt.Sprintf(`  build:
-    runs-on: macos-latest
-    needs: %s
-    steps:
-    - uses: actions/checkout@v3
-    - name: Select Xcode 15.0
-      run: sudo xcode-select -s /Applications/Xcode_15.0.app
-`, previousJob))
-
+    build := newJob(&pipeline.Job{ID: "build", Stage: "build", Needs: []string{previousJob}})
+    build.Steps = append(build.Steps, pipeline.Checkout(), setup)
     if buildTool == "spm" {
-        pipeline.WriteString(`    - name: Build release with SwiftPM
-      run: swift build -c release
-      
-    - name: Create artifacts directory
-      run: mkdir -p artifacts
-      
-    - name: Archive build products
-      run: |
-        cp -R .build/release artifacts/
-        find .build/release -name "*.dylib" -o -name "*.a" | xargs -I {} cp {} artifacts/
-      
-    - name: Upload artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: swift-artifacts
-        path: artifacts/
-`)
-
-        // Для исполняемых продуктов
-        pipeline.WriteString(`    - name: Detect executable products
-      run: |
-        EXECUTABLES=$(swift package describe --type json | grep '"type":"executable"' | wc -l)
-        echo "EXECUTABLES=$EXECUTABLES" >> $GITHUB_ENV
-        
-    - name: Package executables
-      if: env.EXECUTABLES != '0'
-      run: |
-        swift package archive-source
-        mv *.zip artifacts/ 2>/dev/null || true
-`)
-
+        build.Steps = append(build.Steps,
+            pipeline.Run("Build release with SwiftPM", "swift build -c release"),
+            pipeline.Run("Archive build products", "mkdir -p artifacts\ncp -R .build/release artifacts/"),
+            pipeline.Upload("swift-artifacts", "artifacts/"),
+        )
     } else {
-        pipeline.WriteString(`    - name: Archive with xcodebuild
-      run: xcodebuild -scheme MyApp -configuration Release -archivePath ./build/MyApp.xcarchive archive
-      
-    - name: Export IPA
-      run: xcodebuild -exportArchive -archivePath ./build/MyApp.xcarchive -exportOptionsPlist ExportOptions.plist -exportPath ./build
-      
-    - name: Upload artifacts
-      uses: actions/upload-artifact@v3
-      with:
-        name: xcode-artifacts
-        path: build/
-`)
+        build.Steps = append(build.Steps,
+            pipeline.Run("Archive with xcodebuild", "xcodebuild -scheme MyApp -configuration Release -archivePath ./build/MyApp.xcarchive archive"),
+            pipeline.Run("Export IPA", "xcodebuild -exportArchive -archivePath ./build/MyApp.xcarchive -exportOptionsPlist ExportOptions.plist -exportPath ./build"),
+            pipeline.Upload("xcode-artifacts", "build/"),
+        )
     }
 
     // Job для линтинга (SwiftLint)
     if containsDependency(info.Dependencies, "swiftlint") || fileExistsInStructure(info.Structure, ".swiftlint.yml") {
-        pipeline.WriteString(`  lint:
-    runs-on: macos-latest
-    steps:
-    - uses: actions/checkout@v3
-    - name: Install SwiftLint
-      run: brew install swiftlint
-    - name: Run SwiftLint
-      run: swiftlint
-`)
+        if macOS {
+            lint := p.AddJob(&pipeline.Job{ID: "lint", Stage: "lint", OS: "macos"})
+            lint.Steps = append(lint.Steps,
+                pipeline.Checkout(),
+                pipeline.Run("Install SwiftLint", "brew install swiftlint"),
+                pipeline.Run("Run SwiftLint", "swiftlint"),
+            )
+        } else {
+            // На Linux SwiftLint берется из официального образа проекта
+            lint := p.AddJob(&pipeline.Job{ID: "lint", Stage: "lint", Image: "ghcr.io/realm/swiftlint:latest"})
+            lint.Steps = append(lint.Steps,
+                pipeline.Checkout(),
+                pipeline.Action("Run SwiftLint", "norio-nomura/action-swiftlint@3.2.1", "swiftlint"),
+            )
+        }
     }
 
     // Job для документации (Swift-DocC)
     if containsDependency(info.Dependencies, "documentation") {
-        pipeline.WriteString(`  documentation:
-    runs-on: macos-latest
-    needs: build
-    steps:
-    - uses: actions/checkout@v3
-    - name: Generate documentation
-      run: swift package generate-documentation
-    - name: Upload documentation
-      uses: actions/upload-artifact@v3
-      with:
-        name: documentation
-        path: .build/documentation/
-`)
+        docs := newJob(&pipeline.Job{ID: "documentation", Stage: "build", Needs: []string{"build"}})
+        docs.Steps = append(docs.Steps,
+            pipeline.Checkout(),
+            setup,
+            pipeline.Run("Generate documentation", "swift package generate-documentation"),
+            pipeline.Upload("documentation", ".build/documentation/"),
+        )
     }
 
     // Job для публикации в Swift Package Index
     if containsDependency(info.Dependencies, "spi") || strings.Contains(info.RepositoryURL, "github.com") {
-        pipeline.WriteString(`  publish-spi:
-    runs-on: macos-latest
-    needs: build
-    if: github.event_name == 'push' && contains(github.ref, 'refs/tags/')
-    steps:
-    - uses: actions/checkout@v3
-    - name: Validate for Swift Package Index
-      run: |
-        swift package diagnose-api-breaking-changes $(git describe --abbrev=0 --tags)
-      env:
-        SPI_TOKEN: ${{ secrets.SPI_TOKEN }}
-`)
+        publish := newJob(&pipeline.Job{
+            ID:    "publish-spi",
+            Stage: "publish",
+            Needs: []string{"build"},
+            When:  pipeline.Condition{Tags: true},
+            Env:   []pipeline.Var{{Name: "SPI_TOKEN", Value: pipeline.SecretRef("SPI_TOKEN")}},
+        })
+        publish.Steps = append(publish.Steps,
+            pipeline.Checkout(),
+            setup,
+            pipeline.Run("Validate for Swift Package Index", "swift package diagnose-api-breaking-changes $(git describe --abbrev=0 --tags)"),
+        )
     }
 
-    return pipeline.String()
+    return p
+}
+
+// hasXcodeProject проверяет, есть ли в репозитории проект или workspace Xcode.
+func hasXcodeProject(structure []string) bool {
+    for _, file := range structure {
+        if strings.Contains(file, ".xcodeproj/") || strings.Contains(file, ".xcworkspace/") {
+            return true
+        }
+    }
+    return false
 }
 
 // Вспомогательная функция для проверки наличия файла в структуре
@@ -3,6 +3,7 @@ package git
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -19,11 +20,14 @@ type RemoteRepoInfo struct {
 	DefaultBranch string
 	FileTree      map[string]string // путь -> содержимое файла
 	Structure     []string          // список файлов и директорий
+
+	tree *object.Tree // дерево коммита для чтения остальных файлов по запросу
 }
 
-// AnalyzeRemoteRepo анализирует удаленный репозиторий в памяти
+// AnalyzeRemoteRepo анализирует удаленный репозиторий в памяти. Ход
+// клонирования выводится в stderr, чтобы не смешиваться с результатом в stdout.
 func AnalyzeRemoteRepo(repoURL, branch string) (*RemoteRepoInfo, error) {
-	fmt.Printf("Cloning repository: %s (branch: %s)\n", repoURL, branch)
+	fmt.Fprintf(os.Stderr, "Cloning repository: %s (branch: %s)\n", repoURL, branch)
 
 	info := &RemoteRepoInfo{
 		URL:       repoURL,
@@ -44,7 +48,7 @@ func AnalyzeRemoteRepo(repoURL, branch string) (*RemoteRepoInfo, error) {
 	})
 
 	if err != nil {
-		fmt.Printf("Error cloning with branch %s: %v\n", branch, err)
+		fmt.Fprintf(os.Stderr, "Error cloning with branch %s: %v\n", branch, err)
 
 		// Если ошибка связана с веткой, пробуем основные ветки по порядку
 		if strings.Contains(err.Error(), "couldn't find remote ref") {
@@ -60,7 +64,7 @@ func AnalyzeRemoteRepo(repoURL, branch string) (*RemoteRepoInfo, error) {
 	}
 
 	info.DefaultBranch = ref.Name().Short()
-	fmt.Printf("Successfully cloned repository, branch: %s\n", info.DefaultBranch)
+	fmt.Fprintf(os.Stderr, "Successfully cloned repository, branch: %s\n", info.DefaultBranch)
 
 	// Получаем дерево файлов
 	commit, err := repo.CommitObject(ref.Hash())
@@ -79,18 +83,18 @@ func AnalyzeRemoteRepo(repoURL, branch string) (*RemoteRepoInfo, error) {
 		return nil, fmt.Errorf("error building file tree: %v", err)
 	}
 
-	fmt.Printf("Repository analyzed successfully. Found %d files\n", len(info.Structure))
+	fmt.Fprintf(os.Stderr, "Repository analyzed successfully. Found %d files\n", len(info.Structure))
 	return info, nil
 }
 
 func tryDefaultBranches(repoURL string) (*RemoteRepoInfo, error) {
-	fmt.Println("Trying default branches...")
+	fmt.Fprintln(os.Stderr, "Trying default branches...")
 
 	// Пробуем основные ветки по порядку
 	branches := []string{"main", "master", "develop"}
 
 	for _, branch := range branches {
-		fmt.Printf("Trying branch: %s\n", branch)
+		fmt.Fprintf(os.Stderr, "Trying branch: %s\n", branch)
 
 		// Создаем контекст с таймаутом для каждой попытки
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -104,7 +108,7 @@ func tryDefaultBranches(repoURL string) (*RemoteRepoInfo, error) {
 		cancel() // Освобождаем контекст сразу после использования
 
 		if err == nil {
-			fmt.Printf("Successfully cloned with branch: %s\n", branch)
+			fmt.Fprintf(os.Stderr, "Successfully cloned with branch: %s\n", branch)
 
 			info := &RemoteRepoInfo{
 				URL:       repoURL,
@@ -138,7 +142,7 @@ func tryDefaultBranches(repoURL string) (*RemoteRepoInfo, error) {
 			return info, nil
 		}
 
-		fmt.Printf("Failed with branch %s: %v\n", branch, err)
+		fmt.Fprintf(os.Stderr, "Failed with branch %s: %v\n", branch, err)
 	}
 
 	return nil, fmt.Errorf("could not find repository on any default branch (main, master, develop)")
@@ -146,6 +150,7 @@ func tryDefaultBranches(repoURL string) (*RemoteRepoInfo, error) {
 
 // Упрощенная версия buildFileTree для большей надежности
 func buildFileTreeSimple(tree *object.Tree, info *RemoteRepoInfo) error {
+	info.tree = tree
 	return tree.Files().ForEach(func(f *object.File) error {
 		info.Structure = append(info.Structure, f.Name)
 
@@ -182,10 +187,25 @@ func isConfigFile(filename string) bool {
 	return false
 }
 
-// GetFileContent возвращает содержимое файла из репозитория
+// GetFileContent возвращает содержимое файла из репозитория. Файлы, не
+// прочитанные при клонировании, загружаются из дерева коммита.
 func (r *RemoteRepoInfo) GetFileContent(path string) (string, bool) {
-	content, exists := r.FileTree[path]
-	return content, exists
+	if content, exists := r.FileTree[path]; exists {
+		return content, true
+	}
+	if r.tree == nil {
+		return "", false
+	}
+	file, err := r.tree.File(path)
+	if err != nil {
+		return "", false
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return "", false
+	}
+	r.FileTree[path] = content
+	return content, true
 }
 
 // HasFile проверяет наличие файла в репозитории
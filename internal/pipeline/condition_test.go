@@ -0,0 +1,104 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+)
+
+// conditionPipeline — pipeline с тестами и джобом image, который
+// запускается по условию.
+func conditionPipeline(when Condition) *Pipeline {
+	return &Pipeline{
+		Name:     "app",
+		Branches: []string{"main"},
+		Stages:   []string{"test", "publish"},
+		Jobs: []*Job{
+			{ID: "test", Stage: "test", Image: "golang:1.22", Steps: []Step{Checkout(), Run("Test", "go test ./...")}},
+			{ID: "image", Stage: "publish", Image: "docker:27", Needs: []string{"test"}, When: when,
+				Steps: []Step{Checkout(), Run("Build", "docker build .")}},
+		},
+	}
+}
+
+func TestRenderBranchesOrTags(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    []string
+		notWant []string
+	}{
+		{format: "gitlab", want: []string{`- if: $CI_COMMIT_BRANCH == "main" || $CI_COMMIT_TAG`}},
+		{format: "github", want: []string{`if: github.ref == 'refs/heads/main' || startsWith(github.ref, 'refs/tags/')`}},
+		{format: "forgejo", want: []string{`if: github.ref == 'refs/heads/main' || startsWith(github.ref, 'refs/tags/')`}},
+		{format: "jenkins", want: []string{"anyOf {\n                    branch 'main'\n                    buildingTag()"}},
+		{format: "azure", want: []string{"or(eq(variables['Build.SourceBranch'], 'refs/heads/main'), startsWith(variables['Build.SourceBranch'], 'refs/tags/'))"}},
+		{
+			format:  "circleci",
+			want:    []string{"branches:\n              only:\n                - main\n            tags:\n              only: /.*/"},
+			notWant: []string{"ignore: /.*/"},
+		},
+		{format: "bitbucket", want: []string{"main:\n      - step: *test\n      - step: *image", "'*':\n      - step: *test\n      - step: *image"}},
+		{
+			format:  "woodpecker",
+			want:    []string{"when:\n      - event: [push]\n        branch: [main]\n      - event: tag"},
+			notWant: []string{"event: tag\n      branch"},
+		},
+		{
+			format:  "drone",
+			want:    []string{"when:\n      ref:\n        - refs/heads/main\n        - refs/tags/**"},
+			notWant: []string{"event: [tag]"},
+		},
+		{
+			format:  "tekton",
+			want:    []string{`- cel: '''$(params.revision)'' in [''main''] || ''$(params.tag)'' != '''''`},
+			notWant: []string{"operator: in"},
+		},
+		{format: "codebuild", want: []string{`{ [ "${CODEBUILD_WEBHOOK_HEAD_REF#refs/heads/}" = "main" ] || case "$CODEBUILD_WEBHOOK_TRIGGER" in tag/*) true ;; *) false ;; esac; }`}},
+		{format: "cloudbuild", want: []string{`if ! { [ "$BRANCH_NAME" = "main" ] || [ -n "$TAG_NAME" ]; }`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, err := Render(conditionPipeline(Condition{Branches: []string{"main"}, Tags: true}), tt.format)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output does not contain %q:\n%s", want, out)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out, notWant) {
+					t.Errorf("output contains %q:\n%s", notWant, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderSingleCondition(t *testing.T) {
+	tests := []struct {
+		format string
+		when   Condition
+		want   string
+	}{
+		{format: "circleci", when: Condition{Tags: true}, want: "branches:\n              ignore: /.*/"},
+		{format: "circleci", when: Condition{Branches: []string{"main"}}, want: "branches:\n              only:\n                - main"},
+		{format: "woodpecker", when: Condition{Tags: true}, want: "when:\n      event: tag"},
+		{format: "woodpecker", when: Condition{Branches: []string{"main"}}, want: "when:\n      event: [push]\n      branch: [main]"},
+		{format: "drone", when: Condition{Tags: true}, want: "when:\n      event: [tag]"},
+		{format: "drone", when: Condition{Branches: []string{"main"}}, want: "when:\n      event: [push]\n      branch: [main]"},
+		{format: "tekton", when: Condition{Tags: true}, want: "- input: $(params.tag)\n          operator: notin"},
+		{format: "tekton", when: Condition{Branches: []string{"main"}}, want: "- input: $(params.revision)\n          operator: in\n          values: [main]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			out, err := Render(conditionPipeline(tt.when), tt.format)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("output does not contain %q:\n%s", tt.want, out)
+			}
+		})
+	}
+}
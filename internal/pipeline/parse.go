@@ -0,0 +1,380 @@
+package pipeline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Issue — конструкция исходной конфигурации, которую нельзя перенести
+// в модель. Конвертер сообщает о ней, а не пропускает молча.
+type Issue struct {
+	Path    string // место в исходном файле, например jobs.build.steps[2]
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Path + ": " + i.Message
+}
+
+// issues накапливает проблемы разбора.
+type issues []Issue
+
+func (l *issues) add(path, format string, args ...any) {
+	*l = append(*l, Issue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// ParseFormats — форматы, конфигурацию которых умеет разбирать Parse.
+var ParseFormats = []string{"github", "forgejo", "gitlab"}
+
+// Parse разбирает существующую конфигурацию CI формата format в модель —
+// операция, обратная Render. Вместе с pipeline возвращаются конструкции,
+// которые не удалось перенести.
+func Parse(content []byte, format string) (*Pipeline, []Issue, error) {
+	var doc yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, nil, fmt.Errorf("failed to parse %s config: %w", format, err)
+	}
+	// Заголовок spec с входными параметрами GitLab CI идет отдельным
+	// документом перед конфигурацией
+	if format == "gitlab" && len(doc.Content) > 0 && isSpecHeader(resolve(doc.Content[0])) {
+		doc = yaml.Node{}
+		if err := dec.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+			return nil, nil, fmt.Errorf("failed to parse %s config: %w", format, err)
+		}
+	}
+	if len(doc.Content) == 0 || resolve(doc.Content[0]).Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("%s config is empty or is not a mapping", format)
+	}
+	root := resolve(doc.Content[0])
+	switch format {
+	case "github", "forgejo":
+		p, found := parseGitHub(root)
+		return p, found, nil
+	case "gitlab":
+		p, found := parseGitLab(root)
+		return p, found, nil
+	default:
+		return nil, nil, fmt.Errorf("parsing %s configs is not supported", format)
+	}
+}
+
+func isSpecHeader(root *yaml.Node) bool {
+	return root.Kind == yaml.MappingNode && len(root.Content) == 2 && root.Content[0].Value == "spec"
+}
+
+// SourceFormat определяет формат конфигурации CI по пути файла.
+func SourceFormat(file string) (string, bool) {
+	file = path.Clean(strings.ReplaceAll(file, "\\", "/"))
+	base := path.Base(file)
+	dir := path.Dir(file)
+	yml := strings.HasSuffix(base, ".yml") || strings.HasSuffix(base, ".yaml")
+	switch {
+	case base == ".gitlab-ci.yml":
+		return "gitlab", true
+	case yml && strings.HasSuffix(dir, ".github/workflows"):
+		return "github", true
+	case yml && (strings.HasSuffix(dir, ".forgejo/workflows") || strings.HasSuffix(dir, ".gitea/workflows")):
+		return "forgejo", true
+	case base == "Jenkinsfile":
+		return "jenkins", true
+	case base == ".travis.yml":
+		return "travis", true
+	}
+	return "", false
+}
+
+// toolImages — официальные образы тулчейнов. По ним джоб без контейнера
+// получает образ из шага установки, а джоб с образом — шаг установки.
+var toolImages = map[string]string{
+	"go":     "golang",
+	"node":   "node",
+	"python": "python",
+	"java":   "eclipse-temurin",
+	"ruby":   "ruby",
+	"rust":   "rust",
+	"php":    "php",
+	"dotnet": "mcr.microsoft.com/dotnet/sdk",
+	"swift":  "swift",
+}
+
+// toolImage возвращает образ тулчейна для шага установки.
+func toolImage(step Step) (string, bool) {
+	image, ok := toolImages[step.Tool]
+	if !ok {
+		return "", false
+	}
+	version := strings.TrimSuffix(step.Version, ".x")
+	if version == "" {
+		version = "latest"
+	}
+	return image + ":" + version, true
+}
+
+// imageTool определяет тулчейн и его версию по официальному образу:
+// golang:1.22-alpine — go 1.22.
+func imageTool(image string) (string, string, bool) {
+	name, tag, _ := strings.Cut(image, ":")
+	for tool, toolImage := range toolImages {
+		if name != toolImage && name != "library/"+toolImage && name != "docker.io/library/"+toolImage {
+			continue
+		}
+		if !strings.HasPrefix(tag, "${{") {
+			tag, _, _ = strings.Cut(tag, "-")
+		}
+		if tag == "latest" {
+			tag = ""
+		}
+		return tool, tag, true
+	}
+	return "", "", false
+}
+
+// stageNames — стадии, к которым относятся джобы с такими словами в ID.
+var stageNames = []struct {
+	stage string
+	words []string
+}{
+	{"lint", []string{"lint", "format", "style", "check"}},
+	{"test", []string{"test", "spec"}},
+	{"build", []string{"build", "compile", "package"}},
+	{"security", []string{"security", "audit", "scan"}},
+	{"deploy", []string{"deploy", "release", "publish"}},
+}
+
+// assignStages раскладывает джобы без стадий по уровням графа Needs: джобы
+// одного уровня независимы и попадают в одну стадию. Стадия называется
+// по назначению джобов, если оно у всех одно.
+func assignStages(p *Pipeline) {
+	depth := map[string]int{}
+	var level func(job *Job, seen map[string]bool) int
+	level = func(job *Job, seen map[string]bool) int {
+		if d, ok := depth[job.ID]; ok {
+			return d
+		}
+		if seen[job.ID] {
+			return 0
+		}
+		seen[job.ID] = true
+		d := 0
+		for _, need := range job.Needs {
+			if other := p.Job(need); other != nil {
+				if l := level(other, seen) + 1; l > d {
+					d = l
+				}
+			}
+		}
+		depth[job.ID] = d
+		return d
+	}
+	levels := 0
+	for _, job := range p.Jobs {
+		if l := level(job, map[string]bool{}) + 1; l > levels {
+			levels = l
+		}
+	}
+
+	names := make([]string, levels)
+	for i := range names {
+		kind := ""
+		for _, job := range p.Jobs {
+			if depth[job.ID] != i {
+				continue
+			}
+			jobKind := stageKind(job.ID)
+			if kind == "" {
+				kind = jobKind
+			} else if kind != jobKind {
+				kind = "-"
+			}
+		}
+		name := kind
+		if name == "-" || contains(names[:i], name) {
+			name = fmt.Sprintf("stage-%d", i+1)
+		}
+		names[i] = name
+	}
+	p.Stages = names
+	for _, job := range p.Jobs {
+		job.Stage = names[depth[job.ID]]
+	}
+}
+
+// stageKind возвращает стадию по словам в ID джоба или сам ID.
+func stageKind(id string) string {
+	lower := strings.ToLower(id)
+	for _, s := range stageNames {
+		for _, word := range s.words {
+			if strings.Contains(lower, word) {
+				return s.stage
+			}
+		}
+	}
+	return id
+}
+
+// inferImages дает джобам без образа образ тулчейна из первого шага
+// установки, а джобам с официальным образом — шаг установки: так джоб
+// работает и в контейнерных форматах, и на раннерах GitHub.
+func inferImages(p *Pipeline) {
+	for _, job := range p.Jobs {
+		setup := -1
+		for i, step := range job.Steps {
+			if step.Kind == KindSetup {
+				setup = i
+				break
+			}
+		}
+		if job.Image == "" && setup >= 0 {
+			if image, ok := toolImage(job.Steps[setup]); ok {
+				job.Image = image
+			}
+			continue
+		}
+		if job.Image == "" || setup >= 0 {
+			continue
+		}
+		tool, version, ok := imageTool(job.Image)
+		if !ok {
+			continue
+		}
+		at := 0
+		if len(job.Steps) > 0 && job.Steps[0].Kind == KindCheckout {
+			at = 1
+		}
+		job.Steps = append(job.Steps[:at], append([]Step{Setup(tool, version)}, job.Steps[at:]...)...)
+	}
+}
+
+// entry — пара ключ/значение mapping-узла.
+type entry struct {
+	key   string
+	value *yaml.Node
+}
+
+// resolve раскрывает алиас YAML.
+func resolve(n *yaml.Node) *yaml.Node {
+	for n != nil && n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+	return n
+}
+
+// entries возвращает ключи mapping-узла по порядку с учетом слияния "<<":
+// собственные ключи переопределяют ключи шаблона.
+func entries(n *yaml.Node) []entry {
+	n = resolve(n)
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	var merged, own []entry
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i].Value, resolve(n.Content[i+1])
+		if key != "<<" {
+			own = append(own, entry{key, value})
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			for _, e := range entries(source) {
+				if lookup(merged, e.key) == nil {
+					merged = append(merged, e)
+				}
+			}
+		}
+	}
+	return overlay(merged, own)
+}
+
+// overlay накладывает ключи own на base.
+func overlay(base, own []entry) []entry {
+	result := append([]entry{}, base...)
+	for _, e := range own {
+		replaced := false
+		for i := range result {
+			if result[i].key == e.key {
+				result[i] = e
+				replaced = true
+			}
+		}
+		if !replaced {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func lookup(m []entry, key string) *yaml.Node {
+	for _, e := range m {
+		if e.key == key {
+			return e.value
+		}
+	}
+	return nil
+}
+
+// scalar возвращает значение скалярного узла или пустую строку.
+func scalar(n *yaml.Node) string {
+	n = resolve(n)
+	if n == nil || n.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return n.Value
+}
+
+// scalars возвращает значения скаляра или последовательности скаляров.
+// Вложенные последовательности (их дают якоря в GitLab) разворачиваются.
+func scalars(n *yaml.Node) []string {
+	n = resolve(n)
+	if n == nil {
+		return nil
+	}
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return nil
+		}
+		return []string{n.Value}
+	case yaml.SequenceNode:
+		var values []string
+		for _, item := range n.Content {
+			values = append(values, scalars(item)...)
+		}
+		return values
+	}
+	return nil
+}
+
+func isTrue(n *yaml.Node) bool {
+	return scalar(n) == "true"
+}
+
+// vars превращает mapping в переменные. Значение-mapping берется из ключа
+// value, как в переменных GitLab с описанием.
+func vars(n *yaml.Node) []Var {
+	var result []Var
+	for _, e := range entries(n) {
+		value := scalar(e.value)
+		if e.value.Kind == yaml.MappingNode {
+			value = scalar(lookup(entries(e.value), "value"))
+		}
+		result = append(result, Var{Name: e.key, Value: value})
+	}
+	return result
+}
+
+// replaceVarRefs заменяет ссылки $NAME и ${NAME} в тексте.
+func replaceVarRefs(s, name, replacement string) string {
+	re := regexp.MustCompile(`\$(\{` + regexp.QuoteMeta(name) + `\}|` + regexp.QuoteMeta(name) + `\b)`)
+	return re.ReplaceAllLiteralString(s, replacement)
+}
@@ -0,0 +1,500 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	githubMatrixExpr = regexp.MustCompile(`\$\{\{\s*matrix\.([\w-]+)\s*\}\}`)
+	githubSecretExpr = regexp.MustCompile(`\$\{\{\s*secrets\.(\w+)\s*\}\}`)
+	githubEnvExpr    = regexp.MustCompile(`\$\{\{\s*env\.(\w+)\s*\}\}`)
+	githubExpr       = regexp.MustCompile(`\$\{\{.*?\}\}`)
+	githubBranchCond = regexp.MustCompile(`^github\.ref\s*==\s*'refs/heads/([^']+)'$`)
+	githubTagCond    = regexp.MustCompile(`^startsWith\(\s*github\.ref\s*,\s*'refs/tags/?'\s*\)$`)
+	githubManualCond = regexp.MustCompile(`^github\.event_name\s*==\s*'workflow_dispatch'$`)
+	githubChangeCond = regexp.MustCompile(`^needs\.([\w-]+)\.outputs\.([\w-]+)\s*==\s*'true'$`)
+	githubHashFiles  = regexp.MustCompile(`hashFiles\(([^)]*)\)`)
+)
+
+// githubParser разбирает workflow GitHub Actions (и совместимый с ним
+// Forgejo Actions).
+type githubParser struct {
+	issues
+	filters  map[string]map[string][]string // джоб paths-filter -> фильтр -> шаблоны путей
+	dispatch bool                           // workflow запускается вручную через workflow_dispatch
+}
+
+func parseGitHub(root *yaml.Node) (*Pipeline, []Issue) {
+	g := &githubParser{filters: map[string]map[string][]string{}}
+	p := &Pipeline{}
+	top := entries(root)
+	for _, e := range top {
+		switch e.key {
+		case "name":
+			p.Name = scalar(e.value)
+		case "on":
+			p.Branches = g.triggers(e.value)
+		case "env":
+			p.Env = g.env("env", e.value, nil)
+		case "jobs":
+		default:
+			g.add(e.key, "workflow key is not supported")
+		}
+	}
+
+	jobs := entries(lookup(top, "jobs"))
+	// Джобы dorny/paths-filter превращаются в фильтры путей остальных джобов
+	for _, e := range jobs {
+		if filters := g.pathsFilter(e.value); filters != nil {
+			g.filters[e.key] = filters
+		}
+	}
+	for _, e := range jobs {
+		if _, ok := g.filters[e.key]; ok {
+			continue
+		}
+		p.Jobs = append(p.Jobs, g.job(e.key, e.value))
+	}
+	if g.dispatch && !p.hasManualJobs() {
+		g.add("on.workflow_dispatch", "manual runs are converted only for jobs that check github.event_name")
+	}
+	assignStages(p)
+	inferImages(p)
+	return p, g.issues
+}
+
+// triggers возвращает ветки событий push и pull_request. Событие
+// workflow_dispatch запускает ручные джобы.
+func (g *githubParser) triggers(n *yaml.Node) []string {
+	var branches []string
+	if n.Kind != yaml.MappingNode {
+		for _, event := range scalars(n) {
+			if event == "workflow_dispatch" {
+				g.dispatch = true
+			} else if event != "push" && event != "pull_request" {
+				g.add("on."+event, "trigger is not supported")
+			}
+		}
+		return nil
+	}
+	for _, e := range entries(n) {
+		if e.key == "workflow_dispatch" {
+			g.dispatch = true
+			if len(entries(e.value)) > 0 {
+				g.add("on.workflow_dispatch", "inputs of manual runs are not supported")
+			}
+			continue
+		}
+		if e.key != "push" && e.key != "pull_request" {
+			g.add("on."+e.key, "trigger is not supported")
+			continue
+		}
+		for _, f := range entries(e.value) {
+			switch f.key {
+			case "branches":
+				for _, branch := range scalars(f.value) {
+					if !contains(branches, branch) {
+						branches = append(branches, branch)
+					}
+				}
+			case "tags":
+				// Джобы для тегов отмечаются своим условием
+			default:
+				g.add("on."+e.key+"."+f.key, "trigger filter is not supported")
+			}
+		}
+	}
+	return branches
+}
+
+// pathsFilter возвращает фильтры джоба, который только вызывает
+// dorny/paths-filter, как джоб changes у RenderGitHub.
+func (g *githubParser) pathsFilter(n *yaml.Node) map[string][]string {
+	steps := resolve(lookup(entries(n), "steps"))
+	if steps == nil {
+		return nil
+	}
+	for _, step := range steps.Content {
+		s := entries(step)
+		if !strings.HasPrefix(scalar(lookup(s, "uses")), "dorny/paths-filter@") {
+			continue
+		}
+		var spec yaml.Node
+		if err := yaml.Unmarshal([]byte(scalar(lookup(entries(lookup(s, "with")), "filters"))), &spec); err != nil || len(spec.Content) == 0 {
+			return nil
+		}
+		filters := map[string][]string{}
+		for _, f := range entries(spec.Content[0]) {
+			filters[f.key] = scalars(f.value)
+		}
+		return filters
+	}
+	return nil
+}
+
+func (g *githubParser) job(id string, n *yaml.Node) *Job {
+	job := &Job{ID: id}
+	path := "jobs." + id
+	m := entries(n)
+
+	// Рабочий каталог нужен раньше шагов
+	if defaults := lookup(m, "defaults"); defaults != nil {
+		for _, e := range entries(defaults) {
+			if e.key != "run" {
+				g.add(path+".defaults."+e.key, "not supported")
+				continue
+			}
+			for _, r := range entries(e.value) {
+				if r.key == "working-directory" {
+					job.Dir = scalar(r.value)
+				} else {
+					g.add(path+".defaults.run."+r.key, "not supported")
+				}
+			}
+		}
+	}
+
+	for _, e := range m {
+		at := path + "." + e.key
+		switch e.key {
+		case "name":
+			job.Name = g.expr(at, scalar(e.value), job)
+		case "runs-on":
+			job.OS = g.runner(at, scalars(e.value))
+		case "needs":
+			for _, need := range scalars(e.value) {
+				if _, ok := g.filters[need]; !ok {
+					job.Needs = append(job.Needs, need)
+				}
+			}
+		case "if":
+			job.When = g.condition(at, scalar(e.value))
+		case "container":
+			job.Image = scalar(e.value)
+			if e.value.Kind == yaml.MappingNode {
+				c := entries(e.value)
+				job.Image = scalar(lookup(c, "image"))
+				for _, key := range c {
+					if key.key != "image" {
+						g.add(at+"."+key.key, "not supported")
+					}
+				}
+			}
+			job.Image = githubMatrixExpr.ReplaceAllString(job.Image, "${{ matrix.$1 }}")
+		case "services":
+			job.Services = g.services(at, e.value)
+		case "strategy":
+			job.Matrix = g.matrix(at, e.value)
+		case "env":
+			job.Env = append(job.Env, g.env(at, e.value, job)...)
+		case "environment":
+			job.Environment = scalar(e.value)
+			if e.value.Kind == yaml.MappingNode {
+				env := entries(e.value)
+				job.Environment = scalar(lookup(env, "name"))
+				if lookup(env, "url") != nil {
+					g.add(at+".url", "environment URL is not supported")
+				}
+			}
+		case "continue-on-error":
+			if value := scalar(e.value); value == "true" {
+				job.AllowFailure = true
+			} else if value != "false" {
+				g.add(at, "expression %q is not supported", value)
+			}
+		case "steps":
+			for i, step := range e.value.Content {
+				if s, ok := g.step(fmt.Sprintf("%s[%d]", at, i), step, job); ok {
+					job.Steps = append(job.Steps, s)
+				}
+			}
+		case "defaults":
+		default:
+			g.add(at, "job key is not supported")
+		}
+	}
+	return job
+}
+
+// runner определяет ОС по меткам runs-on.
+func (g *githubParser) runner(path string, labels []string) string {
+	for _, label := range labels {
+		switch {
+		case strings.HasPrefix(label, "${{"):
+			g.add(path, "runner expression %q is not supported, using linux", label)
+		case strings.HasPrefix(label, "macos"):
+			return "macos"
+		case strings.HasPrefix(label, "windows"):
+			return "windows"
+		}
+	}
+	return ""
+}
+
+// condition переводит условие if джоба. Поддерживаются проверки ветки,
+// тега, ручного запуска и фильтров путей в форме, которую порождает
+// RenderGitHub.
+func (g *githubParser) condition(path, expr string) Condition {
+	var c Condition
+	expr = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(expr), "${{"), "}}"))
+	for _, and := range strings.Split(expr, "&&") {
+		and = strings.TrimSpace(and)
+		if m := githubChangeCond.FindStringSubmatch(and); m != nil {
+			if filters, ok := g.filters[m[1]]; ok {
+				c.Changes = filters[m[2]]
+				continue
+			}
+		}
+		if githubManualCond.MatchString(and) {
+			c.Manual = true
+			continue
+		}
+		if strings.HasPrefix(and, "(") && strings.HasSuffix(and, ")") {
+			and = and[1 : len(and)-1]
+		}
+		for _, or := range strings.Split(and, "||") {
+			or = strings.TrimSpace(or)
+			if m := githubBranchCond.FindStringSubmatch(or); m != nil {
+				c.Branches = append(c.Branches, m[1])
+			} else if githubTagCond.MatchString(or) {
+				c.Tags = true
+			} else {
+				g.add(path, "condition %q is not supported, the job runs unconditionally", or)
+			}
+		}
+	}
+	return c
+}
+
+func (g *githubParser) services(path string, n *yaml.Node) []Service {
+	var services []Service
+	for _, e := range entries(n) {
+		service := Service{Name: e.key}
+		for _, s := range entries(e.value) {
+			switch s.key {
+			case "image":
+				service.Image = scalar(s.value)
+			case "env":
+				service.Env = vars(s.value)
+			case "ports":
+				for _, port := range scalars(s.value) {
+					host, _, _ := strings.Cut(port, ":")
+					if number, err := strconv.Atoi(host); err == nil && service.Port == 0 {
+						service.Port = number
+					}
+				}
+			default:
+				g.add(path+"."+e.key+"."+s.key, "service key is not supported")
+			}
+		}
+		services = append(services, service)
+	}
+	return services
+}
+
+func (g *githubParser) matrix(path string, n *yaml.Node) []Axis {
+	var axes []Axis
+	for _, e := range entries(n) {
+		if e.key != "matrix" {
+			g.add(path+"."+e.key, "strategy key is not supported")
+			continue
+		}
+		for _, a := range entries(e.value) {
+			if a.value.Kind != yaml.SequenceNode {
+				g.add(path+".matrix."+a.key, "matrix %s is not supported", a.key)
+				continue
+			}
+			axes = append(axes, Axis{Name: a.key, Values: scalars(a.value)})
+		}
+	}
+	return axes
+}
+
+// env переводит переменные; значения, целиком состоящие из ссылки на
+// секрет, становятся SecretRef.
+func (g *githubParser) env(path string, n *yaml.Node, job *Job) []Var {
+	var result []Var
+	for _, v := range vars(n) {
+		if m := githubSecretExpr.FindStringSubmatch(v.Value); m != nil && m[0] == strings.TrimSpace(v.Value) {
+			result = append(result, Var{Name: v.Name, Value: SecretRef(m[1])})
+			continue
+		}
+		result = append(result, Var{Name: v.Name, Value: g.expr(path+"."+v.Name, v.Value, job)})
+	}
+	return result
+}
+
+// expr переводит выражения ${{ }} в тексте: ссылки на матрицу остаются
+// MatrixRef, на env — переменными shell, секреты подключаются к джобу
+// переменными окружения. Прочие выражения остаются как есть.
+func (g *githubParser) expr(path, s string, job *Job) string {
+	s = githubMatrixExpr.ReplaceAllString(s, "${{ matrix.$1 }}")
+	s = githubEnvExpr.ReplaceAllString(s, "$$$1")
+	if job != nil {
+		s = githubSecretExpr.ReplaceAllStringFunc(s, func(ref string) string {
+			name := githubSecretExpr.FindStringSubmatch(ref)[1]
+			found := false
+			for _, v := range job.Env {
+				found = found || v.Name == name
+			}
+			if !found {
+				job.Env = append(job.Env, Var{Name: name, Value: SecretRef(name)})
+			}
+			return "$" + name
+		})
+	}
+	var kept []string
+	for _, e := range githubExpr.FindAllString(s, -1) {
+		if !githubMatrixExpr.MatchString(e) && !contains(kept, e) {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) > 0 {
+		g.add(path, "expressions %s are kept as is", strings.Join(kept, ", "))
+	}
+	return s
+}
+
+func (g *githubParser) step(path string, n *yaml.Node, job *Job) (Step, bool) {
+	m := entries(n)
+	name := scalar(lookup(m, "name"))
+	for _, e := range m {
+		switch e.key {
+		case "name", "uses", "run", "with", "id":
+		case "env":
+			for _, v := range g.env(path+".env", e.value, job) {
+				found := false
+				for _, existing := range job.Env {
+					if existing.Name == v.Name {
+						found = true
+						if existing.Value != v.Value {
+							g.add(path+".env."+v.Name, "conflicts with the job variable, step value is dropped")
+						}
+					}
+				}
+				if !found {
+					job.Env = append(job.Env, v)
+				}
+			}
+		default:
+			g.add(path+"."+e.key, "step key is not supported")
+		}
+	}
+
+	if run := lookup(m, "run"); run != nil {
+		return Run(name, g.expr(path+".run", scalar(run), job)), true
+	}
+
+	uses := scalar(lookup(m, "uses"))
+	action, _, _ := strings.Cut(uses, "@")
+	action = strings.TrimPrefix(strings.TrimPrefix(action, "https://github.com/"), "https://code.forgejo.org/")
+	// Выражения во входах переводятся только для шагов, которые их сохраняют
+	with := vars(lookup(m, "with"))
+	translate := func(values []Var) []Var {
+		var result []Var
+		for _, v := range values {
+			result = append(result, Var{Name: v.Name, Value: g.expr(path+".with."+v.Name, v.Value, job)})
+		}
+		return result
+	}
+	option := func(key string) string {
+		for _, v := range with {
+			if v.Name == key {
+				return v.Value
+			}
+		}
+		return ""
+	}
+
+	switch action {
+	case "actions/checkout":
+		return Checkout(), true
+	case "actions/cache":
+		job.Cache = &Cache{Paths: strings.Fields(option("path"))}
+		for _, args := range githubHashFiles.FindAllStringSubmatch(option("key"), -1) {
+			for _, arg := range strings.Split(args[1], ",") {
+				file := strings.TrimPrefix(strings.Trim(strings.TrimSpace(arg), `'"`), "**/")
+				job.Cache.KeyFiles = append(job.Cache.KeyFiles, file)
+			}
+		}
+		return Step{}, false
+	case "actions/upload-artifact":
+		artifact := option("name")
+		if artifact == "" {
+			artifact = "artifact"
+		}
+		step := Upload(artifact, strings.Fields(option("path"))...)
+		if name != "" {
+			step.Name = name
+		}
+		return step, true
+	case "codecov/codecov-action":
+		files := option("files")
+		if files == "" {
+			files = option("file")
+		}
+		var step Step
+		for _, file := range strings.Split(files, ",") {
+			if file = strings.TrimSpace(file); file == "" {
+				continue
+			}
+			if step.Kind != KindCoverage {
+				step = CoverageReport(coverageFormat(file), file)
+			} else {
+				step.Paths = append(step.Paths, file)
+			}
+		}
+		if step.Kind != KindCoverage {
+			g.add(path, "%s without files is not converted, the report path is unknown", uses)
+			return Step{}, false
+		}
+		return step, true
+	}
+
+	for tool, setup := range githubSetupActions {
+		if action != strings.SplitN(setup[0], "@", 2)[0] {
+			continue
+		}
+		var options []Var
+		for _, v := range with {
+			// Пути lock-файлов в подкаталоге рендер подставляет сам
+			skip := job.Dir != "" && (v.Name == "cache-dependency-path" || v.Name == "working-directory")
+			if v.Name != setup[1] && !skip {
+				options = append(options, v)
+			}
+		}
+		step := Setup(tool, g.expr(path+".with."+setup[1], option(setup[1]), job), translate(options)...)
+		if name != "" {
+			step.Name = name
+		}
+		return step, true
+	}
+
+	if uses == "" {
+		g.add(path, "step has neither run nor uses")
+		return Step{}, false
+	}
+	g.add(path, "action %s is kept only for GitHub-compatible formats", uses)
+	return Action(name, uses, "", translate(with)...), true
+}
+
+// coverageFormat угадывает формат отчета о покрытии по имени файла.
+func coverageFormat(file string) string {
+	lower := strings.ToLower(file)
+	switch {
+	case strings.Contains(lower, "jacoco"):
+		return "jacoco"
+	case strings.Contains(lower, "clover"):
+		return "clover"
+	case strings.HasSuffix(lower, ".info") || strings.Contains(lower, "lcov"):
+		return "lcov"
+	case strings.HasSuffix(lower, ".xml"):
+		return "cobertura"
+	}
+	return ""
+}
@@ -0,0 +1,554 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	gitlabBranchCond = regexp.MustCompile(`^\$(?:CI_COMMIT_BRANCH|CI_COMMIT_REF_NAME)\s*==\s*["']([^"']+)["']$`)
+	gitlabTagCond    = regexp.MustCompile(`^\$CI_COMMIT_TAG(?:\s*!=\s*null)?$`)
+	gitlabVarRef     = regexp.MustCompile(`^\$\{?(\w+)\}?$`)
+	gitlabPredefined = regexp.MustCompile(`\$\{?((?:CI|GITLAB)_\w+)`)
+	gitlabMergeCond  = regexp.MustCompile(`^\$CI_PIPELINE_SOURCE\s*==\s*["']merge_request_event["']`)
+)
+
+// GitLabKeywords — ключи верхнего уровня .gitlab-ci.yml, которые не являются
+// джобами. spec — заголовок с входными параметрами в отдельном документе.
+var GitLabKeywords = []string{"stages", "variables", "cache", "default", "image", "services", "before_script", "after_script", "workflow", "include", "spec"}
+
+// IsGitLabJob сообщает, является ли ключ верхнего уровня .gitlab-ci.yml
+// джобом: не ключевым словом и не скрытым шаблоном.
+func IsGitLabJob(key string) bool {
+	return !contains(GitLabKeywords, key) && !strings.HasPrefix(key, ".")
+}
+
+// gitlabParser разбирает .gitlab-ci.yml.
+type gitlabParser struct {
+	issues
+	templates map[string]*yaml.Node // скрытые джобы .name для extends
+	declared  map[string]bool       // переменные, объявленные в файле
+	needs     map[string]bool       // джобы с явным needs
+}
+
+func parseGitLab(root *yaml.Node) (*Pipeline, []Issue) {
+	g := &gitlabParser{templates: map[string]*yaml.Node{}, declared: map[string]bool{}, needs: map[string]bool{}}
+	p := &Pipeline{Name: "CI"}
+	top := entries(root)
+	for _, e := range top {
+		if strings.HasPrefix(e.key, ".") {
+			g.templates[e.key] = e.value
+		}
+		declared := lookup(entries(e.value), "variables")
+		if e.key == "variables" {
+			declared = e.value
+		}
+		for _, v := range vars(declared) {
+			g.declared[v.Name] = true
+		}
+	}
+
+	// Ключи default и устаревшие глобальные ключи действуют на все джобы
+	var defaults []entry
+	for _, e := range top {
+		switch e.key {
+		case "stages":
+			p.Stages = scalars(e.value)
+		case "variables":
+			p.Env = g.vars(e.value)
+		case "cache":
+			p.Cache = g.cache(e.key, e.value)
+		case "image", "services", "before_script", "after_script":
+			defaults = overlay(defaults, []entry{e})
+		case "default":
+			for _, d := range entries(e.value) {
+				switch d.key {
+				case "cache":
+					p.Cache = g.cache("default.cache", d.value)
+				case "image", "services", "before_script", "after_script", "tags":
+					defaults = overlay(defaults, []entry{d})
+				default:
+					g.add("default."+d.key, "default key is not supported")
+				}
+			}
+		case "workflow":
+			p.Branches = g.workflow(e.value)
+		case "include":
+			g.add(e.key, "included files are not converted")
+		}
+	}
+
+	for _, e := range top {
+		if !IsGitLabJob(e.key) {
+			continue
+		}
+		if e.value.Kind != yaml.MappingNode {
+			g.add(e.key, "unknown top-level key")
+			continue
+		}
+		m := overlay(defaults, g.extend(e.key, e.value, 0))
+		if lookup(m, "trigger") != nil {
+			g.add(e.key, "trigger jobs are not supported")
+			continue
+		}
+		p.Jobs = append(p.Jobs, g.job(e.key, m))
+	}
+
+	if len(p.Stages) == 0 {
+		for _, stage := range []string{"build", "test", "deploy"} {
+			for _, job := range p.Jobs {
+				if job.Stage == stage && !contains(p.Stages, stage) {
+					p.Stages = append(p.Stages, stage)
+				}
+			}
+		}
+	}
+	stageNeeds(p, g.needs)
+	inferImages(p)
+	return p, g.issues
+}
+
+// stageNeeds задает джобам без явного needs зависимость от джобов
+// предыдущей стадии: GitLab запускает стадии по очереди, а форматы
+// с графом джобов без needs запустили бы все джобы сразу.
+func stageNeeds(p *Pipeline, explicit map[string]bool) {
+	var previous []string
+	for _, stage := range p.stageOrder() {
+		var current []string
+		for _, job := range p.Jobs {
+			if job.Stage != stage {
+				continue
+			}
+			current = append(current, job.ID)
+			if !explicit[job.ID] {
+				job.Needs = append([]string{}, previous...)
+			}
+		}
+		if len(current) > 0 {
+			previous = current
+		}
+	}
+}
+
+// extend раскрывает extends: ключи шаблонов сливаются с ключами джоба,
+// вложенные mapping объединяются, списки заменяются целиком.
+func (g *gitlabParser) extend(path string, n *yaml.Node, depth int) []entry {
+	own := entries(n)
+	extends := scalars(lookup(own, "extends"))
+	if len(extends) == 0 {
+		return own
+	}
+	var base []entry
+	for _, name := range extends {
+		template, ok := g.templates[name]
+		if !ok || depth > 10 {
+			g.add(path+".extends", "template %s is not found", name)
+			continue
+		}
+		base = mergeEntries(base, g.extend(name, template, depth+1))
+	}
+	var rest []entry
+	for _, e := range own {
+		if e.key != "extends" {
+			rest = append(rest, e)
+		}
+	}
+	return mergeEntries(base, rest)
+}
+
+// mergeEntries накладывает own на base, объединяя вложенные mapping.
+func mergeEntries(base, own []entry) []entry {
+	result := append([]entry{}, base...)
+	for _, e := range own {
+		merged := false
+		for i := range result {
+			if result[i].key != e.key {
+				continue
+			}
+			merged = true
+			if result[i].value.Kind == yaml.MappingNode && e.value.Kind == yaml.MappingNode {
+				m := &yaml.Node{Kind: yaml.MappingNode}
+				for _, inner := range mergeEntries(entries(result[i].value), entries(e.value)) {
+					m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: inner.key}, inner.value)
+				}
+				result[i].value = m
+			} else {
+				result[i].value = e.value
+			}
+		}
+		if !merged {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (g *gitlabParser) job(id string, m []entry) *Job {
+	job := &Job{ID: id, Stage: "test"}
+	var before, script, after []string
+	var publish []Step
+	for _, e := range m {
+		at := id + "." + e.key
+		switch e.key {
+		case "stage":
+			job.Stage = scalar(e.value)
+		case "image":
+			job.Image = g.image(at, e.value)
+		case "services":
+			job.Services = g.services(at, e.value)
+		case "variables":
+			job.Env = g.vars(e.value)
+		case "before_script":
+			before = scalars(e.value)
+		case "script":
+			script = scalars(e.value)
+		case "after_script":
+			after = scalars(e.value)
+			g.add(at, "after_script is appended to the script and runs only if it succeeds")
+		case "needs":
+			g.needs[id] = true
+			for _, need := range e.value.Content {
+				if name := scalar(need); name != "" {
+					job.Needs = append(job.Needs, name)
+				} else if name := scalar(lookup(entries(need), "job")); name != "" {
+					job.Needs = append(job.Needs, name)
+				}
+			}
+		case "rules":
+			job.When = g.rules(at, e.value, job.When)
+		case "only":
+			job.When = g.only(at, e.value, job.When)
+		case "when":
+			switch value := scalar(e.value); value {
+			case "manual":
+				job.When.Manual = true
+			case "on_success":
+			default:
+				g.add(at, "when: %s is not supported", value)
+			}
+		case "allow_failure":
+			job.AllowFailure = isTrue(e.value) || e.value.Kind == yaml.MappingNode
+			if e.value.Kind == yaml.MappingNode {
+				g.add(at, "exit codes are not supported, any failure is allowed")
+			}
+		case "artifacts":
+			publish = g.artifacts(at, e.value, job)
+		case "cache":
+			job.Cache = g.cache(at, e.value)
+		case "coverage":
+			job.Coverage = scalar(e.value)
+		case "environment":
+			job.Environment = scalar(e.value)
+			for _, env := range entries(e.value) {
+				if env.key == "name" {
+					job.Environment = scalar(env.value)
+				} else {
+					g.add(at+"."+env.key, "environment key is not supported")
+				}
+			}
+		case "parallel":
+			job.Matrix = g.parallel(at, e.value)
+		case "tags":
+			for _, tag := range scalars(e.value) {
+				if tag == "macos" || tag == "windows" {
+					job.OS = tag
+				} else {
+					g.add(at, "runner tag %s is not supported", tag)
+				}
+			}
+		default:
+			g.add(at, "job key is not supported")
+		}
+	}
+
+	commands := append(append(append([]string{}, before...), script...), after...)
+	if len(commands) > 0 && strings.HasPrefix(commands[0], "cd ") && !strings.ContainsAny(commands[0], "&;|\n") {
+		// Переход в каталог модуля, как его пишет RenderGitLab
+		job.Dir = strings.TrimSpace(strings.TrimPrefix(commands[0], "cd "))
+		commands = commands[1:]
+	}
+	job.Steps = []Step{Checkout()}
+	if len(commands) > 1 && commands[0] == "apt-get update" && strings.HasPrefix(commands[1], "apt-get install -y ") {
+		job.Steps = append(job.Steps, Packages(strings.Fields(strings.TrimPrefix(commands[1], "apt-get install -y "))...))
+		commands = commands[2:]
+	}
+	// Скрипт GitLab выполняется в одной оболочке, поэтому остается одним
+	// шагом: переменные и ssh-agent доступны следующим командам
+	if len(commands) > 0 {
+		job.Steps = append(job.Steps, Run("", strings.Join(commands, "\n")))
+	}
+	var predefined []string
+	for _, ref := range gitlabPredefined.FindAllStringSubmatch(strings.Join(commands, "\n"), -1) {
+		if !contains(predefined, ref[1]) {
+			predefined = append(predefined, ref[1])
+		}
+	}
+	job.Steps = append(job.Steps, publish...)
+	if len(predefined) > 0 {
+		g.add(id+".script", "predefined GitLab variables %s are not set by other formats", strings.Join(predefined, ", "))
+	}
+
+	// Переменные матрицы становятся ссылками MatrixRef
+	for _, axis := range job.Matrix {
+		ref := MatrixRef(axis.Name)
+		name := envName(axis.Name)
+		job.Image = replaceVarRefs(job.Image, name, ref)
+		for i := range job.Steps {
+			job.Steps[i].Command = replaceVarRefs(job.Steps[i].Command, name, ref)
+		}
+		for i := range job.Env {
+			job.Env[i].Value = replaceVarRefs(job.Env[i].Value, name, ref)
+		}
+	}
+	return job
+}
+
+// vars переводит переменные. Значение $NAME, где NAME не объявлена в файле,
+// — ссылка на переменную проекта, то есть на секрет.
+func (g *gitlabParser) vars(n *yaml.Node) []Var {
+	result := vars(n)
+	for i, v := range result {
+		if m := gitlabVarRef.FindStringSubmatch(v.Value); m != nil && !g.declared[m[1]] && !gitlabPredefined.MatchString(v.Value) {
+			result[i].Value = SecretRef(m[1])
+		}
+	}
+	return result
+}
+
+func (g *gitlabParser) image(path string, n *yaml.Node) string {
+	if n.Kind != yaml.MappingNode {
+		return scalar(n)
+	}
+	image := ""
+	for _, e := range entries(n) {
+		if e.key == "name" {
+			image = scalar(e.value)
+		} else {
+			g.add(path+"."+e.key, "image key is not supported")
+		}
+	}
+	return image
+}
+
+func (g *gitlabParser) services(path string, n *yaml.Node) []Service {
+	var services []Service
+	for i, item := range n.Content {
+		at := fmt.Sprintf("%s[%d]", path, i)
+		item = resolve(item)
+		var service Service
+		if item.Kind == yaml.MappingNode {
+			for _, e := range entries(item) {
+				switch e.key {
+				case "name":
+					service.Image = scalar(e.value)
+				case "alias":
+					service.Name = strings.Split(scalar(e.value), ",")[0]
+				case "variables":
+					service.Env = vars(e.value)
+				default:
+					g.add(at+"."+e.key, "service key is not supported")
+				}
+			}
+		} else {
+			service.Image = scalar(item)
+		}
+		if service.Name == "" {
+			// Имя хоста по умолчанию — имя образа без тега и реестра
+			service.Name = strings.SplitN(service.Image[strings.LastIndex(service.Image, "/")+1:], ":", 2)[0]
+		}
+		service.Port = ServicePort(service.Image)
+		services = append(services, service)
+	}
+	return services
+}
+
+// workflow возвращает ветки из правил workflow. Правила для merge request
+// и тегов, как их пишет RenderGitLab, следуют из веток и джобов для тегов.
+func (g *gitlabParser) workflow(n *yaml.Node) []string {
+	var branches []string
+	for _, e := range entries(n) {
+		if e.key != "rules" {
+			g.add("workflow."+e.key, "workflow key is not supported")
+			continue
+		}
+		for i, rule := range e.value.Content {
+			at := fmt.Sprintf("workflow.rules[%d]", i)
+			cond := scalar(lookup(entries(rule), "if"))
+			if len(entries(rule)) == 1 && (gitlabMergeCond.MatchString(cond) || gitlabTagCond.MatchString(cond)) {
+				continue
+			}
+			c := g.rule(at, rule, Condition{})
+			branches = append(branches, c.Branches...)
+			if c.Tags || c.Manual || len(c.Changes) > 0 {
+				g.add(at, "only branch rules are supported for the whole pipeline")
+			}
+		}
+	}
+	return branches
+}
+
+// rules переводит правила в условие. Правила объединяются: джоб запускается,
+// если подходит любое из них.
+func (g *gitlabParser) rules(path string, n *yaml.Node, c Condition) Condition {
+	for i, rule := range n.Content {
+		c = g.rule(fmt.Sprintf("%s[%d]", path, i), rule, c)
+	}
+	return c
+}
+
+// rule добавляет к условию одно правило.
+func (g *gitlabParser) rule(at string, rule *yaml.Node, c Condition) Condition {
+	for _, e := range entries(rule) {
+		switch e.key {
+		case "if":
+			for _, or := range strings.Split(scalar(e.value), "||") {
+				or = strings.TrimSpace(or)
+				if m := gitlabBranchCond.FindStringSubmatch(or); m != nil {
+					c.Branches = append(c.Branches, m[1])
+				} else if gitlabTagCond.MatchString(or) {
+					c.Tags = true
+				} else {
+					g.add(at+".if", "condition %q is not supported", or)
+				}
+			}
+		case "changes":
+			changes := e.value
+			if changes.Kind == yaml.MappingNode {
+				changes = lookup(entries(changes), "paths")
+			}
+			c.Changes = append(c.Changes, scalars(changes)...)
+		case "when":
+			switch value := scalar(e.value); value {
+			case "manual":
+				c.Manual = true
+			case "on_success", "always":
+			default:
+				g.add(at+".when", "when: %s is not supported", value)
+			}
+		default:
+			g.add(at+"."+e.key, "rule key is not supported")
+		}
+	}
+	return c
+}
+
+// only переводит устаревший фильтр only.
+func (g *gitlabParser) only(path string, n *yaml.Node, c Condition) Condition {
+	refs := n
+	if n.Kind == yaml.MappingNode {
+		refs = nil
+		for _, e := range entries(n) {
+			switch e.key {
+			case "refs":
+				refs = e.value
+			case "changes":
+				c.Changes = append(c.Changes, scalars(e.value)...)
+			default:
+				g.add(path+"."+e.key, "only key is not supported")
+			}
+		}
+	}
+	for _, ref := range scalars(refs) {
+		switch {
+		case ref == "tags":
+			c.Tags = true
+		case ref == "branches" || ref == "merge_requests" || ref == "pushes" || ref == "web":
+		case strings.HasPrefix(ref, "/"):
+			g.add(path, "ref pattern %s is not supported", ref)
+		default:
+			c.Branches = append(c.Branches, ref)
+		}
+	}
+	return c
+}
+
+// artifacts возвращает шаги публикации артефактов и отчета о покрытии.
+func (g *gitlabParser) artifacts(path string, n *yaml.Node, job *Job) []Step {
+	a := entries(n)
+	name := scalar(lookup(a, "name"))
+	if name == "" {
+		name = job.ID
+	}
+	var report Step
+	for _, e := range a {
+		switch e.key {
+		case "name", "paths":
+		case "expire_in":
+			job.ExpireIn = scalar(e.value)
+		case "reports":
+			for _, r := range entries(e.value) {
+				if r.key != "coverage_report" {
+					g.add(path+".reports."+r.key, "report is not supported")
+					continue
+				}
+				coverage := entries(r.value)
+				report = CoverageReport(scalar(lookup(coverage, "coverage_format")), scalar(lookup(coverage, "path")))
+			}
+		default:
+			g.add(path+"."+e.key, "artifacts key is not supported")
+		}
+	}
+	var paths []string
+	for _, p := range scalars(lookup(a, "paths")) {
+		if report.Kind != KindCoverage || p != report.Paths[0] {
+			paths = append(paths, p)
+		}
+	}
+	var steps []Step
+	if len(paths) > 0 {
+		steps = append(steps, Upload(name, paths...))
+	}
+	if report.Kind == KindCoverage {
+		steps = append(steps, report)
+	}
+	return steps
+}
+
+func (g *gitlabParser) cache(path string, n *yaml.Node) *Cache {
+	if n.Kind == yaml.SequenceNode {
+		if len(n.Content) > 1 {
+			g.add(path, "only the first of several caches is converted")
+		}
+		if len(n.Content) == 0 {
+			return nil
+		}
+		n = resolve(n.Content[0])
+	}
+	c := &Cache{}
+	for _, e := range entries(n) {
+		switch e.key {
+		case "paths":
+			c.Paths = scalars(e.value)
+		case "key":
+			if e.value.Kind == yaml.MappingNode {
+				c.KeyFiles = scalars(lookup(entries(e.value), "files"))
+			} else {
+				g.add(path+".key", "cache key %q is not supported, the key is derived from lock files", scalar(e.value))
+			}
+		default:
+			g.add(path+"."+e.key, "cache key is not supported")
+		}
+	}
+	return c
+}
+
+// parallel переводит parallel:matrix. Имена осей приводятся к нижнему
+// регистру, ссылки на них в командах — к MatrixRef.
+func (g *gitlabParser) parallel(path string, n *yaml.Node) []Axis {
+	matrix := lookup(entries(n), "matrix")
+	if matrix == nil || len(matrix.Content) == 0 {
+		g.add(path, "parallel without matrix is not supported")
+		return nil
+	}
+	if len(matrix.Content) > 1 {
+		g.add(path+".matrix", "only the first matrix entry is converted")
+	}
+	var axes []Axis
+	for _, e := range entries(matrix.Content[0]) {
+		axes = append(axes, Axis{Name: strings.ToLower(e.key), Values: scalars(e.value)})
+	}
+	return axes
+}
@@ -0,0 +1,257 @@
+package pipeline
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// roundTripPipeline — pipeline с порядком джобов, условиями по веткам
+// и тегам и ручным деплоем.
+func roundTripPipeline() *Pipeline {
+	return &Pipeline{
+		Name:     "CI",
+		Branches: []string{"main", "develop"},
+		Stages:   []string{"build", "test", "deploy"},
+		Jobs: []*Job{
+			{ID: "build", Stage: "build", Image: "golang:1.22", Steps: []Step{Checkout(), Run("", "go build ./...")}},
+			{ID: "test", Stage: "test", Image: "golang:1.22", Needs: []string{"build"}, Steps: []Step{Checkout(), Run("", "go test ./...")}},
+			{ID: "release", Stage: "deploy", Image: "golang:1.22", Needs: []string{"test"}, When: Condition{Branches: []string{"main"}, Tags: true},
+				Steps: []Step{Checkout(), Run("", "./release.sh")}},
+			{ID: "deploy", Stage: "deploy", Image: "alpine:3.20", Needs: []string{"test"}, When: Condition{Branches: []string{"main"}, Manual: true},
+				Steps: []Step{Checkout(), Run("", "./deploy.sh")}},
+		},
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	for _, format := range ParseFormats {
+		t.Run(format, func(t *testing.T) {
+			want := roundTripPipeline()
+			out, err := Render(want, format)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			got, issues, err := Parse([]byte(out), format)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if len(issues) > 0 {
+				t.Errorf("unexpected issues: %v\n%s", issues, out)
+			}
+			if !reflect.DeepEqual(got.Branches, want.Branches) {
+				t.Errorf("branches = %v, want %v\n%s", got.Branches, want.Branches, out)
+			}
+			if len(got.Jobs) != len(want.Jobs) {
+				t.Fatalf("got %d jobs, want %d\n%s", len(got.Jobs), len(want.Jobs), out)
+			}
+			for i, job := range want.Jobs {
+				g := got.Jobs[i]
+				if g.ID != job.ID {
+					t.Errorf("job %d = %s, want %s", i, g.ID, job.ID)
+					continue
+				}
+				if !reflect.DeepEqual(g.Needs, job.Needs) && len(g.Needs)+len(job.Needs) > 0 {
+					t.Errorf("%s needs = %v, want %v", job.ID, g.Needs, job.Needs)
+				}
+				if !reflect.DeepEqual(g.When, job.When) {
+					t.Errorf("%s when = %+v, want %+v", job.ID, g.When, job.When)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGitLab(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		needs  map[string][]string
+		when   map[string]Condition
+		issues []string
+	}{
+		{
+			name: "needs from stage order",
+			config: `stages: [build, test, deploy]
+build:
+  stage: build
+  script: [make]
+lint:
+  stage: test
+  needs: []
+  script: [make lint]
+test:
+  stage: test
+  script: [make test]
+deploy:
+  stage: deploy
+  script: [make deploy]
+`,
+			needs: map[string][]string{"build": {}, "lint": {}, "test": {"build"}, "deploy": {"lint", "test"}},
+		},
+		{
+			name: "stage without jobs is skipped",
+			config: `stages: [build, test, deploy]
+build:
+  stage: build
+  script: [make]
+deploy:
+  stage: deploy
+  needs: [build]
+  script: [make deploy]
+report:
+  stage: .post
+  script: [make report]
+`,
+			needs: map[string][]string{"build": {}, "deploy": {"build"}, "report": {"deploy"}},
+		},
+		{
+			name: "manual and tag rules",
+			config: `deploy:
+  script: [make deploy]
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main" || $CI_COMMIT_TAG
+      when: manual
+publish:
+  script: [make publish]
+  only: [tags]
+`,
+			when: map[string]Condition{
+				"deploy":  {Branches: []string{"main"}, Tags: true, Manual: true},
+				"publish": {Tags: true},
+			},
+		},
+		{
+			name: "workflow with unsupported rule",
+			config: `workflow:
+  rules:
+    - if: $CI_COMMIT_BRANCH == "main"
+    - if: $CI_PIPELINE_SOURCE == "merge_request_event"
+    - if: $CI_PIPELINE_SOURCE == "schedule"
+test:
+  script: [make test]
+`,
+			issues: []string{`workflow.rules[2].if: condition "$CI_PIPELINE_SOURCE == \"schedule\"" is not supported`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, issues, err := Parse([]byte(tt.config), "gitlab")
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			for id, want := range tt.needs {
+				job := p.Job(id)
+				if job == nil {
+					t.Errorf("job %s is missing", id)
+					continue
+				}
+				if len(job.Needs) != len(want) || len(want) > 0 && !reflect.DeepEqual(job.Needs, want) {
+					t.Errorf("%s needs = %v, want %v", id, job.Needs, want)
+				}
+			}
+			for id, want := range tt.when {
+				if job := p.Job(id); job == nil {
+					t.Errorf("job %s is missing", id)
+				} else if !reflect.DeepEqual(job.When, want) {
+					t.Errorf("%s when = %+v, want %+v", id, job.When, want)
+				}
+			}
+			assertIssues(t, issues, tt.issues)
+		})
+	}
+}
+
+func TestParseGitHub(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		branches []string
+		when     map[string]Condition
+		steps    map[string]int
+		issues   []string
+	}{
+		{
+			name: "manual job",
+			config: `on:
+  push:
+    branches: [main]
+  workflow_dispatch:
+jobs:
+  deploy:
+    runs-on: ubuntu-latest
+    if: github.ref == 'refs/heads/main' && github.event_name == 'workflow_dispatch'
+    steps:
+      - run: ./deploy.sh
+`,
+			branches: []string{"main"},
+			when:     map[string]Condition{"deploy": {Branches: []string{"main"}, Manual: true}},
+		},
+		{
+			name: "manual runs without manual jobs",
+			config: `on: [push, workflow_dispatch]
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make test
+`,
+			issues: []string{"on.workflow_dispatch: manual runs are converted only for jobs that check github.event_name"},
+		},
+		{
+			name: "codecov without files",
+			config: `on: push
+jobs:
+  test:
+    runs-on: ubuntu-latest
+    steps:
+      - run: make test
+      - uses: codecov/codecov-action@v4
+      - uses: codecov/codecov-action@v4
+        with:
+          files: coverage.xml
+`,
+			steps: map[string]int{"test": 2},
+			issues: []string{
+				"jobs.test.steps[1]: codecov/codecov-action@v4 without files is not converted, the report path is unknown",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, issues, err := Parse([]byte(tt.config), "github")
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(p.Branches, tt.branches) {
+				t.Errorf("branches = %v, want %v", p.Branches, tt.branches)
+			}
+			for id, want := range tt.when {
+				if job := p.Job(id); job == nil {
+					t.Errorf("job %s is missing", id)
+				} else if !reflect.DeepEqual(job.When, want) {
+					t.Errorf("%s when = %+v, want %+v", id, job.When, want)
+				}
+			}
+			for id, want := range tt.steps {
+				if job := p.Job(id); job == nil {
+					t.Errorf("job %s is missing", id)
+				} else if len(job.Steps) != want {
+					t.Errorf("%s has %d steps, want %d", id, len(job.Steps), want)
+				}
+			}
+			assertIssues(t, issues, tt.issues)
+		})
+	}
+}
+
+func assertIssues(t *testing.T, got []Issue, want []string) {
+	t.Helper()
+	var messages []string
+	for _, issue := range got {
+		messages = append(messages, issue.String())
+	}
+	if !reflect.DeepEqual(messages, want) && len(messages)+len(want) > 0 {
+		t.Errorf("issues:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,371 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pipeline описывает CI/CD pipeline независимо от формата вывода.
+// Языковые генераторы заполняют его один раз, а рендеры сериализуют
+// результат в GitHub Actions, Forgejo Actions, GitLab CI, Jenkinsfile,
+// Azure Pipelines, CircleCI, Bitbucket Pipelines, Woodpecker, Drone,
+// манифесты Tekton, AWS CodeBuild или Google Cloud Build.
+type Pipeline struct {
+	Name     string
+	Branches []string // ветки, для которых запускается pipeline
+	Env      []Var
+	Stages   []string
+	Cache    *Cache // общий кеш зависимостей для всех джобов
+	Jobs     []*Job
+}
+
+// Var — пара имя/значение. Используется вместо map, чтобы сохранить порядок.
+type Var struct {
+	Name  string
+	Value string
+}
+
+// Job — единица работы: джоб GitHub/GitLab или stage в Jenkins.
+type Job struct {
+	ID           string
+	Name         string // человекочитаемое имя, по умолчанию строится из ID
+	Stage        string
+	OS           string // linux (по умолчанию), macos или windows
+	Image        string // контейнерный образ с тулчейном
+	Dir          string // рабочий каталог команд относительно корня репозитория
+	Needs        []string
+	Matrix       []Axis
+	Env          []Var
+	Services     []Service
+	Steps        []Step
+	Cache        *Cache
+	ExpireIn     string // срок хранения артефактов
+	Coverage     string // регулярное выражение для процента покрытия в логе
+	When         Condition
+	Environment  string
+	AllowFailure bool
+	Docker       bool  // джобу нужен Docker daemon, например для сборки образов
+	Permissions  []Var // права GITHUB_TOKEN в GitHub Actions
+}
+
+// Service — контейнер, который работает рядом с джобом: база данных, кеш,
+// брокер сообщений.
+type Service struct {
+	Name  string // имя хоста, по которому джоб обращается к сервису
+	Image string
+	Port  int // порт, который публикуется на хост для джобов вне контейнера
+	Env   []Var
+}
+
+// servicePorts — стандартные порты официальных образов.
+var servicePorts = map[string]int{
+	"postgres": 5432,
+	"mysql":    3306,
+	"mariadb":  3306,
+	"redis":    6379,
+	"mongo":    27017,
+	"rabbitmq": 5672,
+}
+
+// ServicePort возвращает стандартный порт официального образа сервиса
+// или 0, если он неизвестен.
+func ServicePort(image string) int {
+	return servicePorts[strings.SplitN(image[strings.LastIndex(image, "/")+1:], ":", 2)[0]]
+}
+
+// Axis — одно измерение матрицы сборки.
+type Axis struct {
+	Name   string
+	Values []string
+}
+
+// Condition ограничивает запуск джоба.
+type Condition struct {
+	Branches []string // только для перечисленных веток
+	Tags     bool     // только для тегов
+	Manual   bool     // запуск вручную
+	Changes  []string // только при изменении файлов по этим шаблонам путей
+}
+
+// IsZero сообщает, что джоб запускается всегда.
+func (c Condition) IsZero() bool {
+	return len(c.Branches) == 0 && !c.Tags && !c.Manual && len(c.Changes) == 0
+}
+
+// Cache описывает кешируемые пути и файлы, от которых зависит ключ кеша.
+type Cache struct {
+	KeyFiles []string
+	Paths    []string
+}
+
+// StepKind определяет, как рендер должен интерпретировать шаг.
+type StepKind int
+
+const (
+	KindRun      StepKind = iota // shell-команды
+	KindCheckout                 // получение исходников
+	KindSetup                    // установка тулчейна Tool нужной версии
+	KindPackages                 // установка системных пакетов
+	KindUpload                   // публикация артефактов
+	KindCoverage                 // публикация отчета о покрытии
+	KindAction                   // GitHub Action; остальные форматы выполняют Command
+)
+
+// Step — шаг джоба.
+type Step struct {
+	Kind     StepKind
+	Name     string
+	Command  string // shell-команды, допускается многострочный текст; у KindSetup — доустановка в образе
+	Tool     string // go, node, python, java, dotnet, ruby, rust, swift, php
+	Version  string
+	Options  []Var    // дополнительные параметры установки или Action
+	Uses     string   // ссылка на GitHub Action
+	Paths    []string // артефакты, пакеты или отчет о покрытии
+	Artifact string   // имя набора артефактов
+	Format   string   // формат отчета о покрытии: cobertura, jacoco, lcov
+}
+
+// Run создает шаг с shell-командами.
+func Run(name, command string) Step {
+	return Step{Kind: KindRun, Name: name, Command: command}
+}
+
+// Checkout создает шаг получения исходников.
+func Checkout() Step {
+	return Step{Kind: KindCheckout, Name: "Checkout code"}
+}
+
+// Setup создает шаг установки тулчейна.
+func Setup(tool, version string, options ...Var) Step {
+	return Step{Kind: KindSetup, Name: "Set up " + toolTitle(tool), Tool: tool, Version: version, Options: options}
+}
+
+// Packages создает шаг установки системных пакетов (apt).
+func Packages(packages ...string) Step {
+	return Step{Kind: KindPackages, Name: "Install system packages", Paths: packages}
+}
+
+// Upload создает шаг публикации артефактов.
+func Upload(artifact string, paths ...string) Step {
+	return Step{Kind: KindUpload, Name: "Upload " + artifact, Artifact: artifact, Paths: paths}
+}
+
+// CoverageReport создает шаг публикации отчета о покрытии.
+func CoverageReport(format, path string) Step {
+	return Step{Kind: KindCoverage, Name: "Upload coverage report", Format: format, Paths: []string{path}}
+}
+
+// Action создает шаг, который в GitHub Actions использует готовый Action,
+// а в остальных форматах выполняет command (если она задана).
+func Action(name, uses, command string, with ...Var) Step {
+	return Step{Kind: KindAction, Name: name, Uses: uses, Command: command, Options: with}
+}
+
+// MatrixRef возвращает ссылку на значение оси матрицы. Рендеры заменяют ее
+// на синтаксис своего формата.
+func MatrixRef(axis string) string {
+	return fmt.Sprintf("${{ matrix.%s }}", axis)
+}
+
+// SecretRef возвращает ссылку на секрет CI-системы. В GitHub это secrets.NAME,
+// в GitLab — одноименная переменная проекта, в Jenkins — credentials.
+func SecretRef(name string) string {
+	return fmt.Sprintf("${{ secrets.%s }}", name)
+}
+
+// secretName возвращает имя секрета, если значение целиком является SecretRef.
+func secretName(value string) (string, bool) {
+	if !strings.HasPrefix(value, "${{ secrets.") || !strings.HasSuffix(value, " }}") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(value, "${{ secrets."), " }}"), true
+}
+
+// Job возвращает джоб по ID или nil.
+func (p *Pipeline) Job(id string) *Job {
+	for _, job := range p.Jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// AddJob добавляет джоб в pipeline и возвращает его для дальнейшей настройки.
+func (p *Pipeline) AddJob(job *Job) *Job {
+	p.Jobs = append(p.Jobs, job)
+	return job
+}
+
+// Title возвращает имя джоба для форматов, где оно обязательно.
+func (j *Job) Title() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	return titleize(j.ID)
+}
+
+// Render сериализует pipeline в указанный формат.
+func Render(p *Pipeline, format string) (string, error) {
+	switch format {
+	case "gitlab":
+		return RenderGitLab(p)
+	case "jenkins":
+		return RenderJenkins(p), nil
+	case "github":
+		return RenderGitHub(p)
+	case "azure":
+		return RenderAzure(p)
+	case "circleci":
+		return RenderCircleCI(p)
+	case "bitbucket":
+		return RenderBitbucket(p)
+	case "woodpecker":
+		return RenderWoodpecker(p)
+	case "drone":
+		return RenderDrone(p)
+	case "tekton":
+		return RenderTekton(p)
+	case "forgejo":
+		return RenderForgejo(p)
+	case "codebuild":
+		return RenderCodeBuild(p)
+	case "cloudbuild":
+		return RenderCloudBuild(p)
+	default:
+		return "", fmt.Errorf("unsupported format: %s", format)
+	}
+}
+
+// envName превращает имя оси матрицы в имя переменной окружения.
+func envName(name string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// replaceMatrixRefs заменяет ссылки MatrixRef с помощью функции форматирования.
+func replaceMatrixRefs(s string, axes []Axis, ref func(axis string) string) string {
+	for _, axis := range axes {
+		s = strings.ReplaceAll(s, MatrixRef(axis.Name), ref(axis.Name))
+	}
+	return s
+}
+
+// matrixLegs перемножает оси матрицы и возвращает все комбинации значений.
+func matrixLegs(axes []Axis) [][]Var {
+	legs := [][]Var{nil}
+	for _, axis := range axes {
+		var next [][]Var
+		for _, leg := range legs {
+			for _, value := range axis.Values {
+				next = append(next, append(append([]Var{}, leg...), Var{Name: axis.Name, Value: value}))
+			}
+		}
+		legs = next
+	}
+	return legs
+}
+
+func titleize(id string) string {
+	words := strings.FieldsFunc(id, func(r rune) bool {
+		return r == '-' || r == '_' || r == ' '
+	})
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func toolTitle(tool string) string {
+	switch tool {
+	case "go":
+		return "Go"
+	case "node":
+		return "Node.js"
+	case "python":
+		return "Python"
+	case "java":
+		return "JDK"
+	case "dotnet":
+		return ".NET"
+	case "ruby":
+		return "Ruby"
+	case "rust":
+		return "Rust"
+	case "swift":
+		return "Swift"
+	case "php":
+		return "PHP"
+	default:
+		return tool
+	}
+}
+
+// RemoveJobs удаляет джобы, для которых drop возвращает true, вместе со
+// ссылками на них в Needs остальных джобов.
+func (p *Pipeline) RemoveJobs(drop func(*Job) bool) {
+	removed := map[string]bool{}
+	jobs := p.Jobs[:0]
+	for _, job := range p.Jobs {
+		if drop(job) {
+			removed[job.ID] = true
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	p.Jobs = jobs
+	for _, job := range p.Jobs {
+		needs := job.Needs[:0]
+		for _, need := range job.Needs {
+			if !removed[need] {
+				needs = append(needs, need)
+			}
+		}
+		job.Needs = needs
+	}
+}
+
+// changeFilter — именованный набор шаблонов путей.
+type changeFilter struct {
+	key   string
+	paths []string
+}
+
+// changeFilters собирает уникальные наборы шаблонов Condition.Changes и дает
+// им имена, пригодные для переменных CI-системы.
+func changeFilters(p *Pipeline) []changeFilter {
+	var filters []changeFilter
+	used := map[string]bool{}
+	for _, job := range p.Jobs {
+		if len(job.When.Changes) == 0 || filterKey(filters, job.When.Changes) != "" {
+			continue
+		}
+		base := identifier(strings.TrimSuffix(job.When.Changes[0], "/**"))
+		key := base
+		for i := 2; used[key]; i++ {
+			key = fmt.Sprintf("%s_%d", base, i)
+		}
+		used[key] = true
+		filters = append(filters, changeFilter{key: key, paths: job.When.Changes})
+	}
+	return filters
+}
+
+func filterKey(filters []changeFilter, paths []string) string {
+	for _, f := range filters {
+		if len(paths) > 0 && strings.Join(f.paths, "\n") == strings.Join(paths, "\n") {
+			return f.key
+		}
+	}
+	return ""
+}
+
+// identifier заменяет в строке все, кроме латиницы и цифр, на "_": так
+// называются переменные, стадии и джобы в большинстве CI-систем.
+func identifier(s string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s), "_")
+}
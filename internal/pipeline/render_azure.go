@@ -0,0 +1,502 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// azureSetupTasks сопоставляет тулчейн с задачей установки Azure Pipelines и
+// именем входа версии. Для Go это GoTool (в интерфейсе — «Use Go»).
+var azureSetupTasks = map[string][2]string{
+	"go":     {"GoTool@0", "version"},
+	"node":   {"NodeTool@0", "versionSpec"},
+	"python": {"UsePythonVersion@0", "versionSpec"},
+	"java":   {"JavaToolInstaller@0", "versionSpec"},
+	"dotnet": {"UseDotNet@2", "version"},
+	"ruby":   {"UseRubyVersion@0", "versionSpec"},
+}
+
+// azureSetupScripts переключают версии тулчейнов, для которых нет задачи
+// установки, но которые предустановлены на агентах Microsoft.
+var azureSetupScripts = map[string]string{
+	"rust": "rustup toolchain install %[1]s && rustup default %[1]s",
+	"php":  "sudo update-alternatives --set php /usr/bin/php%[1]s",
+}
+
+// azureDotNetCommands выполняются задачей DotNetCoreCLI вместо script.
+var azureDotNetCommands = map[string]bool{"restore": true, "build": true, "test": true}
+
+const (
+	azureChangesStage = "changes"
+	azureChangesJob   = "detect"
+)
+
+// RenderAzure сериализует pipeline в azure-pipelines.yml. Стадии pipeline
+// становятся стадиями Azure и выполняются по порядку, поэтому Needs между
+// джобами разных стадий выполняются автоматически.
+func RenderAzure(p *Pipeline) (string, error) {
+	root := newMap()
+	trigger := newMap()
+	setKey(trigger, "branches", azureInclude(p.Branches))
+	if p.hasTagJobs() {
+		setKey(trigger, "tags", azureInclude([]string{"*"}))
+	}
+	setKey(root, "trigger", trigger)
+	pr := newMap()
+	setKey(pr, "branches", azureInclude(p.Branches))
+	setKey(root, "pr", pr)
+
+	if vars := azureVars(p.Env); len(vars) > 0 {
+		setKey(root, "variables", varsMap(vars))
+	}
+
+	stages := seq()
+	filters := changeFilters(p)
+	if len(filters) > 0 {
+		stages.Content = append(stages.Content, azureChangesStageNode(filters))
+	}
+	prev := ""
+	for _, stage := range p.stageOrder() {
+		var jobs []*Job
+		for _, job := range p.Jobs {
+			if job.Stage == stage {
+				jobs = append(jobs, job)
+			}
+		}
+		if len(jobs) == 0 {
+			continue
+		}
+		m := newMap()
+		setStr(m, "stage", identifier(stage))
+		setStr(m, "displayName", titleize(stage))
+		// Условия по изменениям читают выходы стадии changes, а по умолчанию
+		// стадия зависит только от предыдущей
+		if prev != "" && azureHasChanges(jobs) {
+			setKey(m, "dependsOn", flowSeq([]string{prev, azureChangesStage}))
+		}
+		list := seq()
+		for _, job := range jobs {
+			list.Content = append(list.Content, azureJobs(p, job, jobs, filters)...)
+		}
+		setKey(m, "jobs", list)
+		stages.Content = append(stages.Content, m)
+		prev = identifier(stage)
+	}
+	setKey(root, "stages", stages)
+
+	return encodeYAML(root, "")
+}
+
+// azureJobs возвращает джоб и, для ручного запуска, предшествующий ему
+// безагентный джоб подтверждения.
+func azureJobs(p *Pipeline, job *Job, stageJobs []*Job, filters []changeFilter) []*yaml.Node {
+	id := identifier(job.ID)
+	cond := azureCondition(job.When, filterKey(filters, job.When.Changes))
+	var needs []string
+	for _, need := range job.Needs {
+		for _, other := range stageJobs {
+			if other.ID == need {
+				needs = append(needs, identifier(need))
+			}
+		}
+	}
+
+	var nodes []*yaml.Node
+	if job.When.Manual {
+		approval := newMap()
+		setStr(approval, "job", id+"_approval")
+		setStr(approval, "displayName", "Approve "+job.Title())
+		if len(needs) > 0 {
+			setKey(approval, "dependsOn", flowSeq(needs))
+		}
+		if cond != "" {
+			setStr(approval, "condition", cond)
+		}
+		setStr(approval, "pool", "server")
+		task := newMap()
+		setStr(task, "task", "ManualValidation@0")
+		setStr(task, "timeoutInMinutes", "1440")
+		inputs := newMap()
+		setStr(inputs, "instructions", "Proceed?")
+		setStr(inputs, "onTimeout", "reject")
+		setKey(task, "inputs", inputs)
+		setKey(approval, "steps", seq(task))
+		nodes = append(nodes, approval)
+		needs = append(needs, id+"_approval")
+	}
+
+	m := newMap()
+	if job.Environment != "" {
+		setStr(m, "deployment", id)
+	} else {
+		setStr(m, "job", id)
+	}
+	setStr(m, "displayName", job.Title())
+	if len(needs) > 0 {
+		setKey(m, "dependsOn", flowSeq(needs))
+	}
+	if cond != "" {
+		setStr(m, "condition", cond)
+	}
+	if job.AllowFailure {
+		setKey(m, "continueOnError", boolean(true))
+	}
+	pool := newMap()
+	setStr(pool, "vmImage", azureVMImage(job.OS))
+	setKey(m, "pool", pool)
+	if job.Environment != "" {
+		setStr(m, "environment", job.Environment)
+	}
+	if len(job.Matrix) > 0 {
+		strategy := newMap()
+		setKey(strategy, "matrix", azureMatrix(job.Matrix))
+		setKey(m, "strategy", strategy)
+	}
+	if vars := azureVars(job.Env); len(vars) > 0 {
+		setKey(m, "variables", varsMap(vars))
+	}
+
+	steps := azureSteps(p, job)
+	if job.Environment != "" {
+		// Деплойный джоб описывает шаги внутри стратегии выката
+		deploy := newMap()
+		setKey(deploy, "steps", steps)
+		runOnce := newMap()
+		setKey(runOnce, "deploy", deploy)
+		strategy := newMap()
+		setKey(strategy, "runOnce", runOnce)
+		setKey(m, "strategy", strategy)
+	} else {
+		setKey(m, "steps", steps)
+	}
+	return append(nodes, m)
+}
+
+func azureSteps(p *Pipeline, job *Job) *yaml.Node {
+	cache := job.Cache
+	if cache == nil {
+		cache = p.Cache
+	}
+	secrets := azureSecrets(append(append([]Var{}, p.Env...), job.Env...))
+
+	steps := seq()
+	var archive []string
+	cacheAdded := cache == nil
+	for i, step := range job.Steps {
+		steps.Content = append(steps.Content, azureStep(step, job, secrets)...)
+		// Отчеты, которые Azure не показывает, сохраняются как артефакт
+		if step.Kind == KindCoverage && step.Format != "cobertura" && step.Format != "jacoco" {
+			archive = append(archive, step.Paths...)
+		}
+		// Кеш восстанавливаем сразу после установки тулчейна (или checkout).
+		next := i + 1
+		if !cacheAdded && (next == len(job.Steps) || (job.Steps[next].Kind != KindSetup && job.Steps[next].Kind != KindCheckout)) {
+			steps.Content = append(steps.Content, azureCacheSteps(job, cache)...)
+			cacheAdded = true
+		}
+	}
+	if len(archive) > 0 {
+		steps.Content = append(steps.Content, azurePublish(job, "coverage", archive)...)
+	}
+	if len(steps.Content) == 0 {
+		steps.Content = append(steps.Content, azureScript(fmt.Sprintf("echo %q", job.Title()), "", job, nil))
+	}
+	return steps
+}
+
+func azureStep(step Step, job *Job, secrets []Var) []*yaml.Node {
+	switch step.Kind {
+	case KindCheckout:
+		m := newMap()
+		setStr(m, "checkout", "self")
+		return []*yaml.Node{m}
+	case KindSetup:
+		version := azureExpand(step.Version, job.Matrix)
+		if task, ok := azureSetupTasks[step.Tool]; ok {
+			m := newMap()
+			setStr(m, "task", task[0])
+			setStr(m, "displayName", step.Name)
+			inputs := newMap()
+			if step.Tool == "dotnet" {
+				setStr(inputs, "packageType", "sdk")
+				if !strings.Contains(version, "$(") && strings.Count(version, ".") < 2 {
+					version += ".x"
+				}
+			}
+			setStr(inputs, task[1], version)
+			if step.Tool == "java" {
+				setStr(inputs, "jdkArchitectureOption", "x64")
+				setStr(inputs, "jdkSourceOption", "PreInstalled")
+			}
+			setKey(m, "inputs", inputs)
+			return []*yaml.Node{m}
+		}
+		if script, ok := azureSetupScripts[step.Tool]; ok && version != "" {
+			return []*yaml.Node{azureScript(fmt.Sprintf(script, version), step.Name, job, nil)}
+		}
+		return nil
+	case KindPackages:
+		return []*yaml.Node{azureScript("sudo apt-get update\nsudo apt-get install -y "+strings.Join(step.Paths, " "), step.Name, job, nil)}
+	case KindUpload:
+		return azurePublish(job, step.Artifact, step.Paths)
+	case KindCoverage:
+		if step.Format != "cobertura" && step.Format != "jacoco" {
+			return nil
+		}
+		m := newMap()
+		setStr(m, "task", "PublishCodeCoverageResults@2")
+		setStr(m, "displayName", step.Name)
+		inputs := newMap()
+		setStr(inputs, "summaryFileLocation", strings.Join(step.Paths, "\n"))
+		setKey(m, "inputs", inputs)
+		setStr(m, "condition", "succeededOrFailed()")
+		return []*yaml.Node{m}
+	default:
+		if step.Command == "" {
+			return nil
+		}
+		command := azureExpand(step.Command, job.Matrix)
+		if node := azureDotNet(command, step.Name, job, secrets); node != nil {
+			return []*yaml.Node{node}
+		}
+		return []*yaml.Node{azureScript(command, step.Name, job, secrets)}
+	}
+}
+
+func azureScript(command, name string, job *Job, secrets []Var) *yaml.Node {
+	m := newMap()
+	setStr(m, "script", command)
+	if name != "" {
+		setStr(m, "displayName", name)
+	}
+	if job.Dir != "" {
+		setStr(m, "workingDirectory", job.Dir)
+	}
+	if len(secrets) > 0 {
+		setKey(m, "env", varsMap(secrets))
+	}
+	return m
+}
+
+// azureDotNet превращает простую команду dotnet restore/build/test в задачу
+// DotNetCoreCLI. Команды с конвейерами и подстановками остаются скриптами.
+func azureDotNet(command, name string, job *Job, secrets []Var) *yaml.Node {
+	fields := strings.Fields(command)
+	if len(fields) < 2 || fields[0] != "dotnet" || !azureDotNetCommands[fields[1]] || strings.ContainsAny(command, "\n|;&$<>") {
+		return nil
+	}
+	m := newMap()
+	setStr(m, "task", "DotNetCoreCLI@2")
+	setStr(m, "displayName", name)
+	inputs := newMap()
+	setStr(inputs, "command", fields[1])
+	if len(fields) > 2 {
+		setStr(inputs, "arguments", strings.Join(fields[2:], " "))
+	}
+	if job.Dir != "" {
+		setStr(inputs, "workingDirectory", job.Dir)
+	}
+	setKey(m, "inputs", inputs)
+	if len(secrets) > 0 {
+		setKey(m, "env", varsMap(secrets))
+	}
+	return m
+}
+
+// azurePublish копирует файлы в каталог артефакта и публикует его. Джобы
+// с матрицей добавляют к имени артефакта имя своей ветки матрицы.
+func azurePublish(job *Job, artifact string, paths []string) []*yaml.Node {
+	if len(job.Matrix) > 0 {
+		artifact += "-$(System.JobName)"
+	}
+	target := "$(Build.ArtifactStagingDirectory)/" + artifact
+
+	copyFiles := newMap()
+	setStr(copyFiles, "task", "CopyFiles@2")
+	setStr(copyFiles, "displayName", "Collect "+artifact)
+	inputs := newMap()
+	setStr(inputs, "contents", strings.Join(azureArtifactPatterns(paths), "\n"))
+	setStr(inputs, "targetFolder", target)
+	setKey(copyFiles, "inputs", inputs)
+
+	publish := newMap()
+	setStr(publish, "task", "PublishPipelineArtifact@1")
+	setStr(publish, "displayName", "Publish "+artifact)
+	inputs = newMap()
+	setStr(inputs, "targetPath", target)
+	setStr(inputs, "artifact", artifact)
+	setKey(publish, "inputs", inputs)
+	return []*yaml.Node{copyFiles, publish}
+}
+
+// azureArtifactPatterns превращает пути каталогов в шаблоны CopyFiles.
+func azureArtifactPatterns(paths []string) []string {
+	patterns := make([]string, len(paths))
+	for i, path := range paths {
+		if strings.HasSuffix(path, "/") {
+			path += "**"
+		}
+		patterns[i] = path
+	}
+	return patterns
+}
+
+// azureCacheSteps восстанавливает кеш задачей Cache: она принимает один путь,
+// поэтому на каждый каталог приходится отдельный шаг.
+func azureCacheSteps(job *Job, cache *Cache) []*yaml.Node {
+	var steps []*yaml.Node
+	for _, path := range cache.Paths {
+		restore := fmt.Sprintf("%s | %q | \"$(Agent.OS)\"", identifier(job.ID), strings.TrimSuffix(path, "/"))
+		key := restore
+		for _, file := range cache.KeyFiles {
+			key += " | **/" + file
+		}
+		m := newMap()
+		setStr(m, "task", "Cache@2")
+		setStr(m, "displayName", "Cache "+path)
+		inputs := newMap()
+		setStr(inputs, "key", key)
+		if len(cache.KeyFiles) > 0 {
+			setStr(inputs, "restoreKeys", restore)
+		}
+		setStr(inputs, "path", strings.TrimSuffix(path, "/"))
+		setKey(m, "inputs", inputs)
+		steps = append(steps, m)
+	}
+	return steps
+}
+
+// azureChangesStageNode — служебная стадия, которая выставляет выходные
+// переменные для модулей, файлы которых изменились в последнем коммите.
+func azureChangesStageNode(filters []changeFilter) *yaml.Node {
+	var script strings.Builder
+	script.WriteString("changed=$(git diff --name-only HEAD~1 HEAD 2>/dev/null || git ls-files)\n")
+	script.WriteString("for file in $changed; do\n")
+	for _, f := range filters {
+		patterns := make([]string, len(f.paths))
+		for i, path := range f.paths {
+			patterns[i] = strings.ReplaceAll(path, "**", "*")
+		}
+		fmt.Fprintf(&script, "  case \"$file\" in %s) echo \"##vso[task.setvariable variable=%s;isOutput=true]true\" ;; esac\n",
+			strings.Join(patterns, "|"), f.key)
+	}
+	script.WriteString("done\n")
+
+	checkout := newMap()
+	setStr(checkout, "checkout", "self")
+	setKey(checkout, "fetchDepth", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: "2"})
+	detect := newMap()
+	setStr(detect, "script", script.String())
+	setStr(detect, "name", "filter")
+	setStr(detect, "displayName", "Detect changed modules")
+
+	job := newMap()
+	setStr(job, "job", azureChangesJob)
+	pool := newMap()
+	setStr(pool, "vmImage", "ubuntu-latest")
+	setKey(job, "pool", pool)
+	setKey(job, "steps", seq(checkout, detect))
+
+	m := newMap()
+	setStr(m, "stage", azureChangesStage)
+	setStr(m, "displayName", "Detect Changes")
+	setKey(m, "jobs", seq(job))
+	return m
+}
+
+func azureHasChanges(jobs []*Job) bool {
+	for _, job := range jobs {
+		if len(job.When.Changes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func azureCondition(c Condition, filterKey string) string {
+	var refs []string
+	for _, branch := range c.Branches {
+		refs = append(refs, fmt.Sprintf("eq(variables['Build.SourceBranch'], 'refs/heads/%s')", branch))
+	}
+	if c.Tags {
+		refs = append(refs, "startsWith(variables['Build.SourceBranch'], 'refs/tags/')")
+	}
+	var parts []string
+	switch len(refs) {
+	case 0:
+	case 1:
+		parts = append(parts, refs[0])
+	default:
+		parts = append(parts, "or("+strings.Join(refs, ", ")+")")
+	}
+	if filterKey != "" {
+		parts = append(parts, fmt.Sprintf("eq(stageDependencies.%s.%s.outputs['filter.%s'], 'true')", azureChangesStage, azureChangesJob, filterKey))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "and(succeeded(), " + strings.Join(parts, ", ") + ")"
+}
+
+// azureMatrix разворачивает оси в список комбинаций: Azure не умеет
+// перемножать оси сам.
+func azureMatrix(axes []Axis) *yaml.Node {
+	m := newMap()
+	for _, leg := range matrixLegs(axes) {
+		var name []string
+		vars := newMap()
+		for _, v := range leg {
+			name = append(name, v.Name+"_"+v.Value)
+			setStr(vars, envName(v.Name), v.Value)
+		}
+		setKey(m, identifier(strings.Join(name, "_")), vars)
+	}
+	return m
+}
+
+func azureInclude(values []string) *yaml.Node {
+	m := newMap()
+	setKey(m, "include", flowSeq(values))
+	return m
+}
+
+// azureVars оставляет обычные переменные: секреты Azure не подставляет в
+// variables, они передаются шагам через env.
+func azureVars(vars []Var) []Var {
+	var result []Var
+	for _, v := range vars {
+		if _, ok := secretName(v.Value); !ok {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+func azureSecrets(vars []Var) []Var {
+	var result []Var
+	for _, v := range vars {
+		if name, ok := secretName(v.Value); ok {
+			result = append(result, Var{Name: v.Name, Value: "$(" + name + ")"})
+		}
+	}
+	return result
+}
+
+func azureVMImage(os string) string {
+	switch os {
+	case "macos":
+		return "macOS-latest"
+	case "windows":
+		return "windows-latest"
+	default:
+		return "ubuntu-latest"
+	}
+}
+
+func azureExpand(s string, axes []Axis) string {
+	return replaceMatrixRefs(s, axes, func(axis string) string {
+		return "$(" + envName(axis) + ")"
+	})
+}
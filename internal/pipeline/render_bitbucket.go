@@ -0,0 +1,334 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// bitbucketImage используется шагами, для которых генератор не задал образ.
+const bitbucketImage = "atlassian/default-image:4"
+
+// RenderBitbucket сериализует pipeline в bitbucket-pipelines.yml. Шаги
+// описываются один раз в definitions и подключаются якорями в секции
+// default, branches, pull-requests и tags. Джобы одной стадии выполняются
+// параллельно, матрица разворачивается в отдельные шаги.
+func RenderBitbucket(p *Pipeline) (string, error) {
+	root := newMap()
+	setStr(root, "image", bitbucketImage)
+
+	caches := &bitbucketCaches{}
+	defs := seq()
+	steps := map[*Job][]*yaml.Node{}
+	for _, job := range p.Jobs {
+		for _, step := range bitbucketSteps(p, job, caches) {
+			entry := newMap()
+			setKey(entry, "step", step)
+			defs.Content = append(defs.Content, entry)
+			steps[job] = append(steps[job], step)
+		}
+	}
+	definitions := newMap()
+	if len(caches.names) > 0 {
+		setKey(definitions, "caches", caches.node())
+	}
+	setKey(definitions, "steps", defs)
+	setKey(root, "definitions", definitions)
+
+	always := func(job *Job) bool {
+		return len(job.When.Branches) == 0 && !job.When.Tags
+	}
+	pipelines := newMap()
+	setKey(pipelines, "default", bitbucketFlow(p, steps, always))
+	if branches := bitbucketBranches(p, steps, always); len(branches.Content) > 0 {
+		setKey(pipelines, "branches", branches)
+	}
+	pr := newMap()
+	setKey(pr, "**", bitbucketFlow(p, steps, always))
+	setKey(pipelines, "pull-requests", pr)
+	if p.hasTagJobs() {
+		tags := newMap()
+		setKey(tags, "*", bitbucketFlow(p, steps, func(job *Job) bool {
+			return always(job) || job.When.Tags
+		}))
+		setKey(pipelines, "tags", tags)
+	}
+	setKey(root, "pipelines", pipelines)
+
+	return encodeYAML(root, "")
+}
+
+// bitbucketBranches строит секцию branches: ветки с одинаковым набором шагов
+// объединяются в один шаблон {a,b}. Если ни один джоб не ограничен ветками,
+// секция описывает main и master, чтобы к ним можно было добавить деплой.
+func bitbucketBranches(p *Pipeline, steps map[*Job][]*yaml.Node, always func(*Job) bool) *yaml.Node {
+	var names []string
+	for _, job := range p.Jobs {
+		for _, branch := range job.When.Branches {
+			if !contains(names, branch) {
+				names = append(names, branch)
+			}
+		}
+	}
+	if len(names) == 0 {
+		for _, branch := range []string{"main", "master"} {
+			if len(p.Branches) == 0 || contains(p.Branches, branch) {
+				names = append(names, branch)
+			}
+		}
+	}
+
+	// Группируем ветки по списку джобов, сохраняя порядок первой ветки группы
+	var keys []string
+	groups := map[string][]string{}
+	for _, branch := range names {
+		var ids []string
+		for _, job := range p.Jobs {
+			if always(job) || contains(job.When.Branches, branch) {
+				ids = append(ids, job.ID)
+			}
+		}
+		key := strings.Join(ids, ",")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], branch)
+	}
+
+	m := newMap()
+	for _, key := range keys {
+		group := groups[key]
+		pattern := group[0]
+		if len(group) > 1 {
+			pattern = "{" + strings.Join(group, ",") + "}"
+		}
+		setKey(m, pattern, bitbucketFlow(p, steps, func(job *Job) bool {
+			return always(job) || contains(job.When.Branches, group[0])
+		}))
+	}
+	return m
+}
+
+// bitbucketFlow перечисляет шаги отобранных джобов по стадиям. Несколько
+// шагов одной стадии объединяются в блок parallel.
+func bitbucketFlow(p *Pipeline, steps map[*Job][]*yaml.Node, include func(*Job) bool) *yaml.Node {
+	flow := seq()
+	for _, stage := range p.stageOrder() {
+		var refs []*yaml.Node
+		for _, job := range p.Jobs {
+			if job.Stage != stage || !include(job) {
+				continue
+			}
+			for _, step := range steps[job] {
+				ref := newMap()
+				setKey(ref, "step", &yaml.Node{Kind: yaml.AliasNode, Value: step.Anchor, Alias: step})
+				refs = append(refs, ref)
+			}
+		}
+		switch {
+		case len(refs) == 1:
+			flow.Content = append(flow.Content, refs[0])
+		case len(refs) > 1:
+			parallel := newMap()
+			setKey(parallel, "parallel", seq(refs...))
+			flow.Content = append(flow.Content, parallel)
+		}
+	}
+	return flow
+}
+
+// bitbucketSteps описывает джоб шагом Bitbucket, а джоб с матрицей — шагом
+// на каждую комбинацию значений: матриц в Bitbucket нет.
+func bitbucketSteps(p *Pipeline, job *Job, caches *bitbucketCaches) []*yaml.Node {
+	if len(job.Matrix) == 0 {
+		return []*yaml.Node{bitbucketStep(p, job, identifier(job.ID), job.Title(), nil, caches)}
+	}
+	var steps []*yaml.Node
+	for _, leg := range matrixLegs(job.Matrix) {
+		anchor := job.ID
+		values := make([]string, len(leg))
+		for i, v := range leg {
+			anchor += "_" + v.Value
+			values[i] = v.Value
+		}
+		title := fmt.Sprintf("%s (%s)", job.Title(), strings.Join(values, ", "))
+		steps = append(steps, bitbucketStep(p, job, identifier(anchor), title, leg, caches))
+	}
+	return steps
+}
+
+func bitbucketStep(p *Pipeline, job *Job, anchor, title string, leg []Var, caches *bitbucketCaches) *yaml.Node {
+	expand := func(s string) string {
+		for _, v := range leg {
+			s = strings.ReplaceAll(s, MatrixRef(v.Name), v.Value)
+		}
+		return s
+	}
+
+	m := newMap()
+	m.Anchor = anchor
+	setStr(m, "name", title)
+	if job.OS != "" && job.OS != "linux" {
+		// Не-Linux шаги выполняются только на собственных раннерах
+		setKey(m, "runs-on", strSeq([]string{"self.hosted", job.OS}))
+	} else if job.Image != "" {
+		setStr(m, "image", expand(job.Image))
+	}
+	if job.Environment != "" {
+		setStr(m, "deployment", job.Environment)
+	}
+	if job.When.Manual {
+		setStr(m, "trigger", "manual")
+	}
+	if job.Docker {
+		setKey(m, "services", strSeq([]string{"docker"}))
+	}
+
+	cache := job.Cache
+	if cache == nil {
+		cache = p.Cache
+	}
+	if cache != nil {
+		var names []string
+		for _, path := range cache.Paths {
+			names = append(names, caches.add(path, cache.KeyFiles))
+		}
+		setKey(m, "caches", strSeq(names))
+	}
+
+	if len(job.When.Changes) > 0 {
+		include := newMap()
+		setKey(include, "includePaths", strSeq(job.When.Changes))
+		condition := newMap()
+		setKey(condition, "changesets", include)
+		setKey(m, "condition", condition)
+	}
+
+	// Переменных уровня шага в Bitbucket нет, они экспортируются в скрипте.
+	// Секреты задаются в настройках репозитория и доступны под своим именем.
+	var script []string
+	for _, v := range append(append([]Var{}, p.Env...), job.Env...) {
+		if name, ok := secretName(v.Value); ok {
+			if name != v.Name {
+				script = append(script, fmt.Sprintf("export %s=$%s", v.Name, name))
+			}
+			continue
+		}
+		script = append(script, fmt.Sprintf("export %s=%q", v.Name, expand(v.Value)))
+	}
+	if job.Dir != "" {
+		script = append(script, "cd "+job.Dir)
+	}
+	wrote := false
+	var artifacts []string
+	for _, step := range job.Steps {
+		command := ""
+		switch step.Kind {
+		case KindCheckout:
+			// Репозиторий клонирует сам Bitbucket.
+		case KindSetup:
+			// Тулчейн предоставляет образ шага, Command доустанавливает то,
+			// чего в образе нет.
+			command = step.Command
+		case KindPackages:
+			command = "apt-get update && apt-get install -y " + strings.Join(step.Paths, " ")
+		case KindUpload, KindCoverage:
+			artifacts = append(artifacts, step.Paths...)
+		default:
+			command = step.Command
+		}
+		if command == "" {
+			continue
+		}
+		command = expand(command)
+		if job.AllowFailure {
+			// Шагов, которым разрешено падать, в Bitbucket нет
+			command = fmt.Sprintf("{\n%s\n} || echo %q", strings.TrimRight(command, "\n"), step.Name+" failed, continuing")
+		}
+		script = append(script, command)
+		wrote = true
+	}
+	if !wrote {
+		script = append(script, fmt.Sprintf("echo %q", title))
+	}
+	setKey(m, "script", strSeq(script))
+	if len(artifacts) > 0 {
+		setKey(m, "artifacts", strSeq(bitbucketArtifactPatterns(artifacts)))
+	}
+	return m
+}
+
+// bitbucketArtifactPatterns превращает пути каталогов в glob-шаблоны артефактов.
+func bitbucketArtifactPatterns(paths []string) []string {
+	var patterns []string
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/") {
+			path += "**"
+		}
+		if !contains(patterns, path) {
+			patterns = append(patterns, path)
+		}
+	}
+	return patterns
+}
+
+// bitbucketCaches собирает пользовательские кеши: в Bitbucket у кеша один
+// каталог, поэтому каждый путь получает собственное имя.
+type bitbucketCaches struct {
+	names []string
+	paths map[string]string
+	keys  map[string][]string
+}
+
+func (c *bitbucketCaches) add(path string, keyFiles []string) string {
+	if c.paths == nil {
+		c.paths = map[string]string{}
+		c.keys = map[string][]string{}
+	}
+	path = strings.TrimSuffix(path, "/")
+	base := strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '-'
+	}, path), "-")
+	if base == "" {
+		base = "cache"
+	}
+	name := base
+	for i := 2; ; i++ {
+		existing, ok := c.paths[name]
+		if !ok {
+			break
+		}
+		if existing == path && strings.Join(c.keys[name], ",") == strings.Join(keyFiles, ",") {
+			return name
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	c.names = append(c.names, name)
+	c.paths[name] = path
+	c.keys[name] = keyFiles
+	return name
+}
+
+func (c *bitbucketCaches) node() *yaml.Node {
+	names := append([]string{}, c.names...)
+	sort.Strings(names)
+	m := newMap()
+	for _, name := range names {
+		if len(c.keys[name]) == 0 {
+			setStr(m, name, c.paths[name])
+			continue
+		}
+		files := newMap()
+		setKey(files, "files", strSeq(c.keys[name]))
+		cache := newMap()
+		setKey(cache, "key", files)
+		setStr(cache, "path", c.paths[name])
+		setKey(m, name, cache)
+	}
+	return m
+}
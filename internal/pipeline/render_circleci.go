@@ -0,0 +1,425 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// circleciImages — convenience-образы CircleCI по тулчейну, %s — версия.
+var circleciImages = map[string]string{
+	"go":     "cimg/go:%s",
+	"node":   "cimg/node:%s",
+	"python": "cimg/python:%s",
+	"java":   "cimg/openjdk:%s",
+	"ruby":   "cimg/ruby:%s",
+	"php":    "cimg/php:%s",
+	"rust":   "cimg/rust:%s",
+}
+
+// circleciOrbs — используемые орбы и их версии.
+var circleciOrbs = map[string]string{
+	"node":    "circleci/node@5.2",
+	"go":      "circleci/go@1.11",
+	"ruby":    "circleci/ruby@2.1",
+	"codecov": "codecov/codecov@4.1",
+}
+
+// circleciOrbCommands заменяют типовые команды установки зависимостей
+// командами орбов, которые сами кешируют результат. Ключ — команда шага,
+// значение — орб, команда орба и признак поддержки параметра app-dir.
+var circleciOrbCommands = map[string]struct {
+	orb, command string
+	appDir       bool
+}{
+	"npm ci":          {"node", "node/install-packages", true},
+	"go mod download": {"go", "go/mod-download-cached", false},
+	"bundle install":  {"ruby", "ruby/install-deps", true},
+}
+
+const circleciWorkflow = "ci"
+
+// RenderCircleCI сериализует pipeline в .circleci/config.yml. Порядок стадий
+// и Needs превращаются в requires одного workflow.
+func RenderCircleCI(p *Pipeline) (string, error) {
+	root := newMap()
+	setKey(root, "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: "2.1"})
+
+	used := map[string]bool{}
+	jobs := newMap()
+	for _, job := range p.Jobs {
+		setKey(jobs, job.ID, circleciJob(p, job, used))
+	}
+
+	if len(used) > 0 {
+		orbs := newMap()
+		var names []string
+		for name := range used {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			setStr(orbs, name, circleciOrbs[name])
+		}
+		setKey(root, "orbs", orbs)
+	}
+	setKey(root, "jobs", jobs)
+
+	workflow := newMap()
+	setKey(workflow, "jobs", circleciWorkflowJobs(p))
+	workflows := newMap()
+	setKey(workflows, circleciWorkflow, workflow)
+	setKey(root, "workflows", workflows)
+
+	return encodeYAML(root, "jobs")
+}
+
+func circleciJob(p *Pipeline, job *Job, used map[string]bool) *yaml.Node {
+	m := newMap()
+	if len(job.Matrix) > 0 {
+		params := newMap()
+		for _, axis := range job.Matrix {
+			param := newMap()
+			setStr(param, "type", "string")
+			setKey(params, axis.Name, param)
+		}
+		setKey(m, "parameters", params)
+	}
+
+	image, fromSetup := circleciImage(job)
+	if job.OS == "macos" {
+		macos := newMap()
+		setStr(macos, "xcode", "15.0.0")
+		setKey(m, "macos", macos)
+		setStr(m, "resource_class", "macos.m1.medium.gen1")
+	} else {
+		docker := newMap()
+		setStr(docker, "image", image)
+		setKey(m, "docker", seq(docker))
+	}
+
+	// Переменные уровня pipeline в CircleCI задаются в каждом джобе
+	env := append(append([]Var{}, p.Env...), job.Env...)
+	var plain, secrets []Var
+	for _, v := range env {
+		if name, ok := secretName(v.Value); ok {
+			// Секреты приходят из настроек проекта или контекста под своим именем
+			if name != v.Name {
+				secrets = append(secrets, Var{Name: v.Name, Value: name})
+			}
+			continue
+		}
+		plain = append(plain, Var{Name: v.Name, Value: circleciExpand(v.Value, job.Matrix)})
+	}
+	if len(plain) > 0 {
+		setKey(m, "environment", varsMap(plain))
+	}
+
+	cache := job.Cache
+	if cache == nil {
+		cache = p.Cache
+	}
+	steps := seq()
+	cacheAdded := cache == nil
+	for i, step := range job.Steps {
+		steps.Content = append(steps.Content, circleciStep(step, job, fromSetup, used)...)
+		if step.Kind == KindCheckout {
+			if job.Docker {
+				// Docker daemon работает в отдельной удаленной среде
+				steps.Content = append(steps.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "setup_remote_docker"})
+			}
+			steps.Content = append(steps.Content, circleciSecretSteps(secrets)...)
+			if len(job.When.Changes) > 0 {
+				steps.Content = append(steps.Content, circleciChangesStep(job.When.Changes))
+			}
+		}
+		// Кеш восстанавливаем сразу после установки тулчейна (или checkout).
+		next := i + 1
+		if !cacheAdded && (next == len(job.Steps) || (job.Steps[next].Kind != KindSetup && job.Steps[next].Kind != KindCheckout)) {
+			restore := newMap()
+			setKey(restore, "keys", strSeq([]string{circleciCacheKey(job, cache), "v1-" + job.ID + "-"}))
+			step := newMap()
+			setKey(step, "restore_cache", restore)
+			steps.Content = append(steps.Content, step)
+			cacheAdded = true
+		}
+	}
+	if cache != nil {
+		save := newMap()
+		setStr(save, "key", circleciCacheKey(job, cache))
+		setKey(save, "paths", strSeq(cache.Paths))
+		step := newMap()
+		setKey(step, "save_cache", save)
+		steps.Content = append(steps.Content, step)
+	}
+	if len(steps.Content) == 0 {
+		steps.Content = append(steps.Content, circleciRun(job.Title(), fmt.Sprintf("echo %q", job.Title()), job))
+	}
+	setKey(m, "steps", steps)
+	return m
+}
+
+// circleciImage выбирает convenience-образ по шагу установки тулчейна. Если
+// подходящего нет, используется образ джоба. Второй результат сообщает,
+// что образ выбран по тулчейну и доустановка не нужна.
+func circleciImage(job *Job) (string, bool) {
+	for _, step := range job.Steps {
+		if step.Kind != KindSetup {
+			continue
+		}
+		image, ok := circleciImages[step.Tool]
+		version := circleciExpand(step.Version, job.Matrix)
+		switch {
+		case !ok:
+		case step.Tool == "node" && !circleciMinor(step.Version, job.Matrix):
+			// У cimg/node нет тегов по мажорной версии, Node.js ставит орб
+			return "cimg/base:stable", true
+		case step.Tool == "java" && !circleciMinor(step.Version, job.Matrix):
+			return fmt.Sprintf(image, version+".0"), true
+		case step.Tool == "rust" && (version == "stable" || version == ""):
+		default:
+			return fmt.Sprintf(image, version), true
+		}
+	}
+	if job.Image != "" {
+		return circleciExpand(job.Image, job.Matrix), false
+	}
+	return "cimg/base:stable", false
+}
+
+func circleciStep(step Step, job *Job, fromSetup bool, used map[string]bool) []*yaml.Node {
+	switch step.Kind {
+	case KindCheckout:
+		return []*yaml.Node{str("checkout")}
+	case KindSetup:
+		if step.Tool == "node" && fromSetup && !circleciMinor(step.Version, job.Matrix) {
+			used["node"] = true
+			install := newMap()
+			setStr(install, "node-version", circleciExpand(step.Version, job.Matrix))
+			m := newMap()
+			setKey(m, "node/install", install)
+			return []*yaml.Node{m}
+		}
+		if !fromSetup && step.Command != "" {
+			return []*yaml.Node{circleciRun(step.Name, step.Command, job)}
+		}
+		return nil
+	case KindPackages:
+		return []*yaml.Node{circleciRun(step.Name, "sudo apt-get update\nsudo apt-get install -y "+strings.Join(step.Paths, " "), job)}
+	case KindUpload:
+		var nodes []*yaml.Node
+		for _, path := range step.Paths {
+			store := newMap()
+			setStr(store, "path", path)
+			setStr(store, "destination", step.Artifact+"/"+strings.TrimSuffix(path, "/"))
+			m := newMap()
+			setKey(m, "store_artifacts", store)
+			nodes = append(nodes, m)
+		}
+		return nodes
+	case KindCoverage:
+		used["codecov"] = true
+		upload := newMap()
+		setStr(upload, "files", strings.Join(step.Paths, ","))
+		m := newMap()
+		setKey(m, "codecov/upload", upload)
+		return []*yaml.Node{m}
+	default:
+		if step.Command == "" {
+			return nil
+		}
+		if orb, ok := circleciOrbCommands[step.Command]; ok && (job.Dir == "" || orb.appDir) {
+			used[orb.orb] = true
+			if job.Dir == "" {
+				return []*yaml.Node{str(orb.command)}
+			}
+			params := newMap()
+			setStr(params, "app-dir", job.Dir)
+			m := newMap()
+			setKey(m, orb.command, params)
+			return []*yaml.Node{m}
+		}
+		return []*yaml.Node{circleciRun(step.Name, step.Command, job)}
+	}
+}
+
+func circleciRun(name, command string, job *Job) *yaml.Node {
+	command = circleciExpand(command, job.Matrix)
+	if job.AllowFailure {
+		// У CircleCI нет джобов, которым разрешено падать: ошибка шага
+		// только выводится в лог
+		command = fmt.Sprintf("{\n%s\n} || echo %q", strings.TrimRight(command, "\n"), name+" failed, continuing")
+	}
+	run := newMap()
+	if name != "" {
+		setStr(run, "name", name)
+	}
+	setStr(run, "command", command)
+	if job.Dir != "" {
+		setStr(run, "working_directory", job.Dir)
+	}
+	m := newMap()
+	setKey(m, "run", run)
+	return m
+}
+
+// circleciSecretSteps передает секреты под другими именами через BASH_ENV:
+// в environment нельзя сослаться на переменную проекта.
+func circleciSecretSteps(secrets []Var) []*yaml.Node {
+	if len(secrets) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, v := range secrets {
+		lines = append(lines, fmt.Sprintf(`echo "export %s=\"\$%s\"" >> "$BASH_ENV"`, v.Name, v.Value))
+	}
+	run := newMap()
+	setStr(run, "name", "Export secrets")
+	setStr(run, "command", strings.Join(lines, "\n"))
+	m := newMap()
+	setKey(m, "run", run)
+	return []*yaml.Node{m}
+}
+
+// circleciChangesStep останавливает джоб без ошибки, если в последнем
+// коммите нет изменений по шаблонам: фильтров путей в workflow у CircleCI нет.
+func circleciChangesStep(paths []string) *yaml.Node {
+	patterns := make([]string, len(paths))
+	for i, path := range paths {
+		patterns[i] = strings.ReplaceAll(path, "**", "*")
+	}
+	command := fmt.Sprintf(`changed=$(git diff --name-only HEAD~1 HEAD 2>/dev/null || git ls-files)
+for file in $changed; do
+  case "$file" in %s) exit 0 ;; esac
+done
+circleci-agent step halt`, strings.Join(patterns, "|"))
+	run := newMap()
+	setStr(run, "name", "Skip unless "+strings.Join(paths, ", ")+" changed")
+	setStr(run, "command", command)
+	m := newMap()
+	setKey(m, "run", run)
+	return m
+}
+
+func circleciCacheKey(job *Job, cache *Cache) string {
+	key := "v1-" + job.ID + "-"
+	if len(cache.KeyFiles) == 0 {
+		return key + "{{ .Branch }}"
+	}
+	sums := make([]string, len(cache.KeyFiles))
+	for i, file := range cache.KeyFiles {
+		sums[i] = fmt.Sprintf("{{ checksum %q }}", file)
+	}
+	return key + strings.Join(sums, "-")
+}
+
+// circleciWorkflowJobs перечисляет джобы workflow с requires, фильтрами
+// веток и тегов, матрицами и джобами подтверждения для ручного запуска.
+func circleciWorkflowJobs(p *Pipeline) *yaml.Node {
+	list := seq()
+	tags := p.hasTagJobs()
+	for _, job := range p.Jobs {
+		requires := append([]string{}, job.Needs...)
+		filters := circleciFilters(job.When, tags)
+		if job.When.Manual {
+			hold := newMap()
+			setStr(hold, "type", "approval")
+			if len(requires) > 0 {
+				setKey(hold, "requires", strSeq(requires))
+			}
+			if filters != nil {
+				setKey(hold, "filters", filters)
+			}
+			entry := newMap()
+			setKey(entry, "hold-"+job.ID, hold)
+			list.Content = append(list.Content, entry)
+			requires = append(requires, "hold-"+job.ID)
+		}
+
+		m := newMap()
+		if len(requires) > 0 {
+			setKey(m, "requires", strSeq(requires))
+		}
+		if filters != nil {
+			setKey(m, "filters", filters)
+		}
+		if job.Environment != "" {
+			setKey(m, "context", strSeq([]string{job.Environment}))
+		}
+		if len(job.Matrix) > 0 {
+			params := newMap()
+			for _, axis := range job.Matrix {
+				setKey(params, axis.Name, flowSeq(axis.Values))
+			}
+			matrix := newMap()
+			setKey(matrix, "parameters", params)
+			setKey(m, "matrix", matrix)
+		}
+		if len(m.Content) == 0 {
+			list.Content = append(list.Content, str(job.ID))
+			continue
+		}
+		entry := newMap()
+		setKey(entry, job.ID, m)
+		list.Content = append(list.Content, entry)
+	}
+	return list
+}
+
+// circleciFilters строит фильтры веток и тегов. Джобы на тегах CircleCI
+// запускает, только если теги разрешены у всей цепочки requires, поэтому
+// при наличии таких джобов теги разрешаются всем джобам без ограничения веток.
+// Ветки и теги условия объединяются через ИЛИ: джоб с ветками и тегами
+// запускается и на этих ветках, и на любом теге.
+func circleciFilters(c Condition, tags bool) *yaml.Node {
+	filters := newMap()
+	switch {
+	case c.Tags:
+		branches := newMap()
+		if len(c.Branches) > 0 {
+			setKey(branches, "only", strSeq(c.Branches))
+		} else {
+			setStr(branches, "ignore", "/.*/")
+		}
+		setKey(filters, "branches", branches)
+		only := newMap()
+		setStr(only, "only", "/.*/")
+		setKey(filters, "tags", only)
+	case len(c.Branches) > 0:
+		branches := newMap()
+		setKey(branches, "only", strSeq(c.Branches))
+		setKey(filters, "branches", branches)
+	case tags:
+		only := newMap()
+		setStr(only, "only", "/.*/")
+		setKey(filters, "tags", only)
+	default:
+		return nil
+	}
+	return filters
+}
+
+// circleciMinor сообщает, что версия (или все значения оси матрицы, на
+// которую она ссылается) указана вместе с минорной.
+func circleciMinor(version string, axes []Axis) bool {
+	values := []string{version}
+	for _, axis := range axes {
+		if version == MatrixRef(axis.Name) {
+			values = axis.Values
+		}
+	}
+	for _, v := range values {
+		if !strings.Contains(v, ".") {
+			return false
+		}
+	}
+	return true
+}
+
+func circleciExpand(s string, axes []Axis) string {
+	return replaceMatrixRefs(s, axes, func(axis string) string {
+		return "<< parameters." + axis + " >>"
+	})
+}
@@ -0,0 +1,202 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RenderCloudBuild сериализует pipeline в cloudbuild.yaml Google Cloud Build.
+// Каждый джоб (или комбинация его матрицы) становится шагом в образе
+// джоба, порядок стадий и Needs — waitFor. Условий запуска у шагов Cloud
+// Build нет, поэтому условия джобов проверяются в скрипте по встроенным
+// подстановкам $BRANCH_NAME и $TAG_NAME. Фильтр по путям задается
+// в настройках триггера.
+func RenderCloudBuild(p *Pipeline) (string, error) {
+	root := newMap()
+
+	steps, names := containerSteps(p)
+	list := seq()
+	var secrets []string
+	var artifacts []string
+	manual := false
+	for _, step := range steps {
+		job := step.job
+		node := newMap()
+		setStr(node, "id", step.name)
+		image := containerImage(step)
+		setStr(node, "name", image)
+		setStr(node, "entrypoint", cloudbuildEntrypoint(image))
+		if job.Dir != "" {
+			setStr(node, "dir", job.Dir)
+		}
+
+		var env, secretEnv, exports []string
+		for _, v := range append(append([]Var{}, p.Env...), job.Env...) {
+			if name, ok := secretName(v.Value); ok {
+				// Секреты подставляются в переменные с именем секрета
+				if !contains(secretEnv, name) {
+					secretEnv = append(secretEnv, name)
+				}
+				if !contains(secrets, name) {
+					secrets = append(secrets, name)
+				}
+				if name != v.Name {
+					exports = append(exports, fmt.Sprintf(`export %s="$$%s"`, v.Name, name))
+				}
+				continue
+			}
+			env = append(env, v.Name+"="+cloudbuildEscape(step.expand(v.Value)))
+		}
+		if len(env) > 0 {
+			setKey(node, "env", strSeq(env))
+		}
+		if len(secretEnv) > 0 {
+			setKey(node, "secretEnv", strSeq(secretEnv))
+		}
+
+		commands := exports
+		for _, command := range containerCommands(step, false) {
+			// set -e задан для всего скрипта шага
+			commands = append(commands, cloudbuildEscape(strings.TrimPrefix(command, "set -e\n")))
+		}
+		if job.Dir != "" {
+			// Каталог задан полем dir
+			commands = append(commands[:len(exports)], commands[len(exports)+1:]...)
+		}
+		script := "set -e\n" + strings.Join(commands, "\n")
+		if guard := cloudbuildGuard(job.When); guard != "" {
+			script = guard + "\n" + script
+		}
+		setKey(node, "args", strSeq([]string{"-c", script}))
+
+		// "-" запускает шаг сразу, без waitFor шаг ждет все предыдущие
+		deps := containerDeps(p, job, names)
+		if len(deps) == 0 {
+			deps = []string{"-"}
+		}
+		setKey(node, "waitFor", flowSeq(deps))
+		if job.AllowFailure {
+			setKey(node, "allowFailure", boolean(true))
+		}
+		list.Content = append(list.Content, node)
+
+		manual = manual || job.When.Manual
+		for _, s := range job.Steps {
+			if s.Kind == KindUpload || s.Kind == KindCoverage {
+				for _, path := range s.Paths {
+					if strings.HasSuffix(path, "/") {
+						path += "**"
+					}
+					if !contains(artifacts, path) {
+						artifacts = append(artifacts, path)
+					}
+				}
+			}
+		}
+	}
+	setKey(root, "steps", list)
+
+	if manual {
+		substitutions := newMap()
+		setStr(substitutions, "_RUN_MANUAL", "false")
+		setKey(root, "substitutions", substitutions)
+	}
+	if len(secrets) > 0 {
+		manager := seq()
+		for _, name := range secrets {
+			secret := newMap()
+			setStr(secret, "versionName", fmt.Sprintf("projects/$PROJECT_ID/secrets/%s/versions/latest", name))
+			setStr(secret, "env", name)
+			manager.Content = append(manager.Content, secret)
+		}
+		available := newMap()
+		setKey(available, "secretManager", manager)
+		setKey(root, "availableSecrets", available)
+	}
+	if len(artifacts) > 0 {
+		objects := newMap()
+		setStr(objects, "location", "gs://${PROJECT_ID}_cloudbuild/artifacts/$BUILD_ID")
+		setKey(objects, "paths", strSeq(artifacts))
+		m := newMap()
+		setKey(m, "objects", objects)
+		setKey(root, "artifacts", m)
+	}
+
+	return encodeYAML(root, "steps")
+}
+
+// cloudbuildEntrypoint выбирает shell шага: в образах alpine нет bash.
+func cloudbuildEntrypoint(image string) string {
+	ref := image[strings.LastIndex(image, "/")+1:]
+	name, tag, _ := strings.Cut(ref, ":")
+	if name == "alpine" || name == "busybox" || strings.Contains(tag, "alpine") {
+		return "sh"
+	}
+	return "bash"
+}
+
+// cloudbuildGuard завершает шаг без ошибки, если сборка не подходит под
+// условие джоба. Ручные джобы выполняются при подстановке _RUN_MANUAL=true.
+func cloudbuildGuard(c Condition) string {
+	var conds []string
+	for _, branch := range c.Branches {
+		conds = append(conds, fmt.Sprintf(`[ "$BRANCH_NAME" = %q ]`, branch))
+	}
+	if c.Tags {
+		conds = append(conds, `[ -n "$TAG_NAME" ]`)
+	}
+	cond := strings.Join(conds, " || ")
+	if c.Manual {
+		manual := `[ "$_RUN_MANUAL" = "true" ]`
+		if cond != "" {
+			cond = fmt.Sprintf("{ %s; } && %s", cond, manual)
+		} else {
+			cond = manual
+		}
+	}
+	if cond == "" {
+		return ""
+	}
+	return fmt.Sprintf(`if ! { %s; }; then echo "Skipped by condition"; exit 0; fi`, cond)
+}
+
+// cloudbuildEscape экранирует переменные shell: Cloud Build сам
+// подставляет $NAME, а $$ передает в шаг как есть. Встроенные подстановки
+// и пользовательские _NAME оставляются для Cloud Build.
+func cloudbuildEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			continue
+		}
+		rest := s[i+1:]
+		if cloudbuildSubstitution(rest) {
+			b.WriteByte('$')
+			continue
+		}
+		b.WriteString("$$")
+	}
+	return b.String()
+}
+
+// cloudbuildSubstitutions — встроенные подстановки Cloud Build.
+var cloudbuildSubstitutions = []string{
+	"PROJECT_ID", "PROJECT_NUMBER", "BUILD_ID", "LOCATION", "TRIGGER_NAME",
+	"COMMIT_SHA", "SHORT_SHA", "REVISION_ID", "REPO_NAME", "REPO_FULL_NAME",
+	"BRANCH_NAME", "TAG_NAME", "REF_NAME", "TRIGGER_BUILD_CONFIG_PATH",
+	"SERVICE_ACCOUNT_EMAIL", "SERVICE_ACCOUNT",
+}
+
+func cloudbuildSubstitution(rest string) bool {
+	rest = strings.TrimPrefix(rest, "{")
+	end := 0
+	for end < len(rest) && (rest[end] == '_' || rest[end] >= 'A' && rest[end] <= 'Z' || rest[end] >= '0' && rest[end] <= '9') {
+		end++
+	}
+	name := rest[:end]
+	if strings.HasPrefix(name, "_") && len(name) > 1 {
+		return true
+	}
+	return contains(cloudbuildSubstitutions, name)
+}
@@ -0,0 +1,22 @@
+package pipeline
+
+import "testing"
+
+func TestCloudBuildEntrypoint(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "alpine:3.20", want: "sh"},
+		{image: "golang:1.22-alpine", want: "sh"},
+		{image: "docker.io/library/busybox", want: "sh"},
+		{image: "alpine/k8s:1.30.2", want: "bash"},
+		{image: "golang:1.22", want: "bash"},
+		{image: "gcr.io/cloud-builders/docker", want: "bash"},
+	}
+	for _, tt := range tests {
+		if got := cloudbuildEntrypoint(tt.image); got != tt.want {
+			t.Errorf("cloudbuildEntrypoint(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
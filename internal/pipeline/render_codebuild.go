@@ -0,0 +1,346 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// codebuildRuntimes сопоставляет тулчейн с именем в runtime-versions.
+var codebuildRuntimes = map[string]string{
+	"go":     "golang",
+	"node":   "nodejs",
+	"python": "python",
+	"java":   "java",
+	"ruby":   "ruby",
+	"php":    "php",
+	"dotnet": "dotnet",
+}
+
+// codebuildCoverageFormats — форматы отчетов покрытия CodeBuild.
+var codebuildCoverageFormats = map[string]string{
+	"cobertura": "COBERTURAXML",
+	"jacoco":    "JACOCOXML",
+	"clover":    "CLOVERXML",
+	"lcov":      "LCOVINFO",
+}
+
+// codebuildSecret — секрет AWS Secrets Manager, ключи которого совпадают
+// с именами секретов pipeline.
+const codebuildSecret = "pipeline-secrets"
+
+// RenderCodeBuild сериализует pipeline в buildspec.yml AWS CodeBuild.
+// Сборка CodeBuild — одно окружение, поэтому джобы выполняются по очереди:
+// стадии до test и build попадают в фазу pre_build, test и build — в build,
+// остальные — в post_build и пропускаются, если сборка упала. Матрица не поддерживается: используется версия
+// из runtime-versions.
+func RenderCodeBuild(p *Pipeline) (string, error) {
+	root := newMap()
+	setKey(root, "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: "0.2"})
+
+	runtimes := codebuildRuntimeVersions(p)
+	var plain, secrets []Var
+	for _, v := range p.Env {
+		if name, ok := secretName(v.Value); ok {
+			secrets = append(secrets, Var{Name: v.Name, Value: codebuildSecret + ":" + name})
+			continue
+		}
+		plain = append(plain, v)
+	}
+	for _, job := range p.Jobs {
+		for _, v := range job.Env {
+			if name, ok := secretName(v.Value); ok {
+				secrets = append(secrets, Var{Name: v.Name, Value: codebuildSecret + ":" + name})
+			} else if !hasOption(plain, v.Name) {
+				// Окружение у всех джобов общее
+				plain = append(plain, v)
+			}
+		}
+	}
+	if len(plain) > 0 || len(secrets) > 0 {
+		env := newMap()
+		if len(plain) > 0 {
+			setKey(env, "variables", varsMap(plain))
+		}
+		if len(secrets) > 0 {
+			setKey(env, "secrets-manager", varsMap(secrets))
+		}
+		setKey(root, "env", env)
+	}
+
+	// Установка тулчейнов и системных пакетов общая для всех джобов
+	var install []string
+	seen := map[string]bool{}
+	addInstall := func(command string) {
+		if command != "" && !seen[command] {
+			seen[command] = true
+			install = append(install, command)
+		}
+	}
+	for _, job := range p.Jobs {
+		for _, step := range job.Steps {
+			switch step.Kind {
+			case KindSetup:
+				// Rust в образах CodeBuild нет, его ставит rustup
+				if step.Tool == "rust" {
+					addInstall(fmt.Sprintf(`curl https://sh.rustup.rs -sSf | sh -s -- -y --default-toolchain %s
+. "$HOME/.cargo/env"`, codebuildVersion(step, job, runtimes)))
+				}
+				addInstall(codebuildExpand(step.Command, job, runtimes))
+			case KindPackages:
+				addInstall("apt-get update && apt-get install -y " + strings.Join(step.Paths, " "))
+			}
+		}
+	}
+
+	phases := newMap()
+	installPhase := newMap()
+	if len(runtimes) > 0 {
+		versions := newMap()
+		for _, v := range runtimes {
+			setStr(versions, v.Name, v.Value)
+		}
+		setKey(installPhase, "runtime-versions", versions)
+	}
+	if len(install) > 0 {
+		setKey(installPhase, "commands", strSeq(install))
+	}
+	if len(installPhase.Content) > 0 {
+		setKey(phases, "install", installPhase)
+	}
+
+	var artifacts []string
+	var reports []Step
+	done := map[string]bool{}
+	phaseCommands := map[string][]string{}
+	for _, job := range p.Jobs {
+		phase := codebuildPhase(p, job.Stage)
+		phaseCommands[phase] = append(phaseCommands[phase], codebuildJob(job, runtimes, done, phase == "post_build")...)
+		for _, step := range job.Steps {
+			switch step.Kind {
+			case KindUpload:
+				artifacts = append(artifacts, step.Paths...)
+			case KindCoverage:
+				if _, ok := codebuildCoverageFormats[step.Format]; ok {
+					reports = append(reports, step)
+				} else {
+					artifacts = append(artifacts, step.Paths...)
+				}
+			}
+		}
+	}
+	for _, phase := range []string{"pre_build", "build", "post_build"} {
+		if commands := phaseCommands[phase]; len(commands) > 0 {
+			m := newMap()
+			setKey(m, "commands", strSeq(commands))
+			setKey(phases, phase, m)
+		}
+	}
+	setKey(root, "phases", phases)
+
+	if len(reports) > 0 {
+		m := newMap()
+		for i, report := range reports {
+			r := newMap()
+			setKey(r, "files", strSeq(report.Paths))
+			setStr(r, "file-format", codebuildCoverageFormats[report.Format])
+			name := "coverage"
+			if i > 0 {
+				name = fmt.Sprintf("coverage-%d", i+1)
+			}
+			setKey(m, name, r)
+		}
+		setKey(root, "reports", m)
+	}
+	if len(artifacts) > 0 {
+		m := newMap()
+		setKey(m, "files", strSeq(codebuildGlobs(artifacts)))
+		setKey(root, "artifacts", m)
+	}
+	if paths := codebuildCachePaths(p); len(paths) > 0 {
+		m := newMap()
+		setKey(m, "paths", strSeq(paths))
+		setKey(root, "cache", m)
+	}
+
+	return encodeYAML(root, "")
+}
+
+// codebuildJob возвращает команды джоба. Команда, которая уже выполнялась
+// в том же каталоге (например, установка зависимостей), повторно не
+// добавляется. Условия джоба проверяются в shell по переменным CodeBuild.
+// Фаза post_build выполняется и после упавшей фазы build, поэтому ее джобы
+// (упаковка, деплой) запускаются, только если сборка еще успешна.
+func codebuildJob(job *Job, runtimes []Var, done map[string]bool, postBuild bool) []string {
+	var commands []string
+	for _, step := range job.Steps {
+		if step.Kind != KindRun && step.Kind != KindAction {
+			continue
+		}
+		command := codebuildExpand(step.Command, job, runtimes)
+		key := job.Dir + "\x00" + command
+		if command == "" || done[key] {
+			continue
+		}
+		done[key] = true
+		if job.AllowFailure {
+			command = fmt.Sprintf("{\n%s\n} || echo %q", strings.TrimRight(command, "\n"), step.Name+" failed, continuing")
+		}
+		commands = append(commands, command)
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	if job.Dir != "" {
+		commands = append([]string{fmt.Sprintf(`cd "$CODEBUILD_SRC_DIR/%s"`, job.Dir)}, commands...)
+		commands = append(commands, `cd "$CODEBUILD_SRC_DIR"`)
+	}
+	commands = append([]string{fmt.Sprintf("echo %q", "== "+job.Title())}, commands...)
+
+	var conds []string
+	for _, branch := range job.When.Branches {
+		conds = append(conds, fmt.Sprintf(`[ "${CODEBUILD_WEBHOOK_HEAD_REF#refs/heads/}" = %q ]`, branch))
+	}
+	if job.When.Tags {
+		conds = append(conds, `case "$CODEBUILD_WEBHOOK_TRIGGER" in tag/*) true ;; *) false ;; esac`)
+	}
+	cond := strings.Join(conds, " || ")
+	if job.When.Manual {
+		// Ручные джобы запускаются, если сборка стартовала с RUN_MANUAL=true
+		manual := `[ "$RUN_MANUAL" = "true" ]`
+		if cond != "" {
+			cond = fmt.Sprintf("{ %s; } && %s", cond, manual)
+		} else {
+			cond = manual
+		}
+	}
+	if postBuild {
+		succeeding := `[ "$CODEBUILD_BUILD_SUCCEEDING" = "1" ]`
+		if cond != "" {
+			cond = fmt.Sprintf("%s && { %s; }", succeeding, cond)
+		} else {
+			cond = succeeding
+		}
+	}
+	if cond == "" {
+		return commands
+	}
+	body := strings.Join(commands, "\n")
+	body = "  " + strings.ReplaceAll(strings.TrimRight(body, "\n"), "\n", "\n  ")
+	return []string{fmt.Sprintf("if %s; then\n%s\nfi", cond, body)}
+}
+
+// codebuildPhase выбирает фазу buildspec для стадии.
+func codebuildPhase(p *Pipeline, stage string) string {
+	if stage == "test" || stage == "build" {
+		return "build"
+	}
+	for _, s := range p.stageOrder() {
+		if s == stage {
+			return "pre_build"
+		}
+		if s == "test" || s == "build" {
+			return "post_build"
+		}
+	}
+	return "post_build"
+}
+
+// codebuildRuntimeVersions берет версии тулчейнов из шагов установки.
+// Для матричных джобов используется версия из джобов без матрицы, то есть
+// обнаруженная версия проекта.
+func codebuildRuntimeVersions(p *Pipeline) []Var {
+	var runtimes []Var
+	found := map[string]bool{}
+	for pass := 0; pass < 2; pass++ {
+		for _, job := range p.Jobs {
+			if (len(job.Matrix) > 0) != (pass == 1) {
+				continue
+			}
+			for _, step := range job.Steps {
+				name, ok := codebuildRuntimes[step.Tool]
+				if step.Kind != KindSetup || !ok {
+					continue
+				}
+				if found[name] {
+					continue
+				}
+				version := step.Version
+				for _, axis := range job.Matrix {
+					if version == MatrixRef(axis.Name) {
+						version = axis.Values[0]
+					}
+				}
+				if version == "" {
+					continue
+				}
+				if name == "java" {
+					version = "corretto" + strings.SplitN(version, ".", 2)[0]
+				}
+				found[name] = true
+				runtimes = append(runtimes, Var{Name: name, Value: version})
+			}
+		}
+	}
+	return runtimes
+}
+
+// codebuildVersion возвращает версию тулчейна шага с подставленной матрицей.
+func codebuildVersion(step Step, job *Job, runtimes []Var) string {
+	version := codebuildExpand(step.Version, job, runtimes)
+	if version == "" {
+		return "stable"
+	}
+	return version
+}
+
+// codebuildExpand подставляет вместо ссылок на матрицу значение, которое
+// соответствует runtime-versions, а без него — первое значение оси.
+func codebuildExpand(s string, job *Job, runtimes []Var) string {
+	for _, axis := range job.Matrix {
+		value := axis.Values[0]
+		for _, step := range job.Steps {
+			name, ok := codebuildRuntimes[step.Tool]
+			if step.Kind != KindSetup || !ok || step.Version != MatrixRef(axis.Name) {
+				continue
+			}
+			for _, v := range runtimes {
+				if v.Name == name && contains(axis.Values, strings.TrimPrefix(v.Value, "corretto")) {
+					value = strings.TrimPrefix(v.Value, "corretto")
+				}
+			}
+		}
+		s = strings.ReplaceAll(s, MatrixRef(axis.Name), value)
+	}
+	return s
+}
+
+// codebuildCachePaths собирает кешируемые каталоги всех джобов в шаблоны CodeBuild.
+func codebuildCachePaths(p *Pipeline) []string {
+	var paths []string
+	add := func(c *Cache) {
+		if c != nil {
+			paths = append(paths, c.Paths...)
+		}
+	}
+	add(p.Cache)
+	for _, job := range p.Jobs {
+		add(job.Cache)
+	}
+	return codebuildGlobs(paths)
+}
+
+// codebuildGlobs превращает каталоги в шаблоны dir/**/* без повторов.
+func codebuildGlobs(paths []string) []string {
+	var globs []string
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/") {
+			path += "**/*"
+		}
+		if !contains(globs, path) {
+			globs = append(globs, path)
+		}
+	}
+	return globs
+}
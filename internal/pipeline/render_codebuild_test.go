@@ -0,0 +1,34 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeBuildPostBuildRunsOnSuccess(t *testing.T) {
+	p := &Pipeline{
+		Name:   "app",
+		Stages: []string{"test", "deploy"},
+		Jobs: []*Job{
+			{ID: "test", Stage: "test", Steps: []Step{Run("Test", "go test ./...")}},
+			{ID: "deploy", Stage: "deploy", Steps: []Step{Run("Deploy", "./deploy.sh")}},
+			{ID: "release", Stage: "deploy", When: Condition{Tags: true}, Steps: []Step{Run("Release", "./release.sh")}},
+		},
+	}
+	out, err := RenderCodeBuild(p)
+	if err != nil {
+		t.Fatalf("RenderCodeBuild: %v", err)
+	}
+	build, post, _ := strings.Cut(out, "post_build:")
+	if strings.Contains(build, "CODEBUILD_BUILD_SUCCEEDING") {
+		t.Errorf("build phase is guarded by CODEBUILD_BUILD_SUCCEEDING:\n%s", out)
+	}
+	for _, want := range []string{
+		"if [ \"$CODEBUILD_BUILD_SUCCEEDING\" = \"1\" ]; then\n          echo \"== Deploy\"",
+		`if [ "$CODEBUILD_BUILD_SUCCEEDING" = "1" ] && { case "$CODEBUILD_WEBHOOK_TRIGGER" in tag/*) true ;; *) false ;; esac; }; then`,
+	} {
+		if !strings.Contains(post, want) {
+			t.Errorf("post_build does not contain %q:\n%s", want, out)
+		}
+	}
+}
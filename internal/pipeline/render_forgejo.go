@@ -0,0 +1,91 @@
+package pipeline
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// forgejoActionsURL — зеркало Actions, которые поддерживает Forgejo.
+const forgejoActionsURL = "https://code.forgejo.org/"
+
+// forgejoMirrored — Actions из зеркала code.forgejo.org. Версия указана, если
+// зеркало отстает от GitHub: Forgejo не поддерживает протокол artifact v4.
+var forgejoMirrored = map[string]string{
+	"actions/checkout":          "",
+	"actions/cache":             "",
+	"actions/setup-go":          "",
+	"actions/setup-node":        "",
+	"actions/setup-python":      "",
+	"actions/setup-java":        "",
+	"actions/setup-dotnet":      "",
+	"actions/upload-artifact":   "v3",
+	"actions/download-artifact": "v3",
+}
+
+// RenderForgejo сериализует pipeline в workflow Forgejo (и Gitea) Actions.
+// Workflow строится так же, как для GitHub, затем меняются метки раннеров
+// и ссылки на Actions: Forgejo не знает маркетплейса GitHub и принимает
+// только полные URL.
+func RenderForgejo(p *Pipeline) (string, error) {
+	root := githubWorkflow(p)
+	if jobs := MappingValue(root, "jobs"); jobs != nil {
+		for i := 1; i < len(jobs.Content); i += 2 {
+			forgejoJob(jobs.Content[i])
+		}
+	}
+	return encodeYAML(root, "jobs")
+}
+
+func forgejoJob(job *yaml.Node) {
+	if runsOn := MappingValue(job, "runs-on"); runsOn != nil {
+		runsOn.Value = forgejoRunner(runsOn.Value)
+	}
+	steps := MappingValue(job, "steps")
+	if steps == nil {
+		return
+	}
+	for _, step := range steps.Content {
+		if uses := MappingValue(step, "uses"); uses != nil {
+			uses.Value = forgejoAction(uses.Value)
+		}
+		// Раннер docker выполняет шаги от root в образе без sudo
+		if run := MappingValue(step, "run"); run != nil {
+			lines := strings.Split(run.Value, "\n")
+			for i, line := range lines {
+				lines[i] = strings.TrimPrefix(line, "sudo ")
+			}
+			run.Value = strings.Join(lines, "\n")
+		}
+	}
+}
+
+// forgejoRunner заменяет метки GitHub-hosted раннеров: Linux-джобы идут на
+// раннер с меткой docker, для остальных ОС нужен собственный раннер.
+func forgejoRunner(label string) string {
+	switch label {
+	case "ubuntu-latest":
+		return "docker"
+	case "macos-latest":
+		return "macos"
+	case "windows-latest":
+		return "windows"
+	}
+	return label
+}
+
+// forgejoAction превращает ссылку owner/repo@version в полный URL: Actions из
+// зеркала Forgejo берутся с code.forgejo.org, остальные — с github.com.
+func forgejoAction(uses string) string {
+	if strings.HasPrefix(uses, "./") || strings.HasPrefix(uses, "docker://") || strings.Contains(uses, "://") {
+		return uses
+	}
+	name, version, _ := strings.Cut(uses, "@")
+	if pinned, ok := forgejoMirrored[name]; ok {
+		if pinned != "" {
+			version = pinned
+		}
+		return forgejoActionsURL + name + "@" + version
+	}
+	return "https://github.com/" + uses
+}
@@ -0,0 +1,349 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// githubSetupActions сопоставляет тулчейн с Action установки и именем входа версии.
+var githubSetupActions = map[string][2]string{
+	"go":     {"actions/setup-go@v5", "go-version"},
+	"node":   {"actions/setup-node@v4", "node-version"},
+	"python": {"actions/setup-python@v5", "python-version"},
+	"java":   {"actions/setup-java@v4", "java-version"},
+	"dotnet": {"actions/setup-dotnet@v4", "dotnet-version"},
+	"ruby":   {"ruby/setup-ruby@v1", "ruby-version"},
+	"rust":   {"actions-rust-lang/setup-rust-toolchain@v1", "toolchain"},
+	"php":    {"shivammathur/setup-php@v2", "php-version"},
+	"swift":  {"swift-actions/setup-swift@v2", "swift-version"},
+}
+
+// RenderGitHub сериализует pipeline в workflow GitHub Actions.
+func RenderGitHub(p *Pipeline) (string, error) {
+	return encodeYAML(githubWorkflow(p), "jobs")
+}
+
+// githubWorkflow строит дерево workflow. Его же используют форматы,
+// совместимые с GitHub Actions.
+func githubWorkflow(p *Pipeline) *yaml.Node {
+	root := newMap()
+	setStr(root, "name", p.Name)
+
+	trigger := newMap()
+	// Без списка веток workflow запускается для всех веток
+	push := newMap()
+	if len(p.Branches) > 0 {
+		setKey(push, "branches", flowSeq(p.Branches))
+	}
+	if p.hasTagJobs() {
+		if len(p.Branches) == 0 {
+			// Фильтр tags без branches отключил бы запуск для веток
+			setKey(push, "branches", flowSeq([]string{"**"}))
+		}
+		setKey(push, "tags", flowSeq([]string{"*"}))
+	}
+	setKey(trigger, "push", push)
+	pr := newMap()
+	if len(p.Branches) > 0 {
+		setKey(pr, "branches", flowSeq(p.Branches))
+	}
+	setKey(trigger, "pull_request", pr)
+	if p.hasManualJobs() {
+		// Ручные джобы выполняются только при запуске workflow вручную
+		setKey(trigger, "workflow_dispatch", newMap())
+	}
+	setKey(root, "on", trigger)
+
+	if len(p.Env) > 0 {
+		setKey(root, "env", varsMap(p.Env))
+	}
+
+	jobs := newMap()
+	filters := changeFilters(p)
+	if len(filters) > 0 {
+		setKey(jobs, githubChangesJob, githubChangesJobNode(filters))
+	}
+	for _, job := range p.Jobs {
+		setKey(jobs, job.ID, githubJob(job, filterKey(filters, job.When.Changes)))
+	}
+	setKey(root, "jobs", jobs)
+	return root
+}
+
+// githubJob сериализует джоб. filterKey — имя фильтра путей в джобе changes,
+// если джоб запускается только при изменении файлов модуля.
+func githubJob(job *Job, filterKey string) *yaml.Node {
+	m := newMap()
+	if job.Name != "" {
+		setStr(m, "name", job.Name)
+	}
+	setStr(m, "runs-on", githubRunner(job.OS))
+	needs := job.Needs
+	cond := githubCondition(job.When)
+	if filterKey != "" {
+		needs = append([]string{githubChangesJob}, needs...)
+		changed := fmt.Sprintf("needs.%s.outputs.%s == 'true'", githubChangesJob, filterKey)
+		if cond != "" {
+			changed = fmt.Sprintf("(%s) && %s", cond, changed)
+		}
+		cond = changed
+	}
+	switch len(needs) {
+	case 0:
+	case 1:
+		setStr(m, "needs", needs[0])
+	default:
+		setKey(m, "needs", flowSeq(needs))
+	}
+	if cond != "" {
+		setStr(m, "if", cond)
+	}
+	if job.Environment != "" {
+		setStr(m, "environment", job.Environment)
+	}
+	if len(job.Permissions) > 0 {
+		setKey(m, "permissions", varsMap(job.Permissions))
+	}
+	if job.AllowFailure {
+		setKey(m, "continue-on-error", boolean(true))
+	}
+	if len(job.Matrix) > 0 {
+		matrix := newMap()
+		for _, axis := range job.Matrix {
+			setKey(matrix, axis.Name, flowSeq(axis.Values))
+		}
+		strategy := newMap()
+		setKey(strategy, "matrix", matrix)
+		setKey(m, "strategy", strategy)
+	}
+	if len(job.Env) > 0 {
+		setKey(m, "env", varsMap(job.Env))
+	}
+	if len(job.Services) > 0 {
+		setKey(m, "services", githubServices(job.Services))
+	}
+	if job.Dir != "" {
+		run := newMap()
+		setStr(run, "working-directory", job.Dir)
+		defaults := newMap()
+		setKey(defaults, "run", run)
+		setKey(m, "defaults", defaults)
+	}
+
+	steps := seq()
+	cacheAdded := job.Cache == nil
+	for i, step := range job.Steps {
+		if node := githubStep(step, job.Dir); node != nil {
+			steps.Content = append(steps.Content, node)
+		}
+		// Кеш восстанавливаем сразу после установки тулчейна (или checkout).
+		next := i + 1
+		if !cacheAdded && (next == len(job.Steps) || (job.Steps[next].Kind != KindSetup && job.Steps[next].Kind != KindCheckout)) {
+			steps.Content = append(steps.Content, githubCacheStep(job))
+			cacheAdded = true
+		}
+	}
+	setKey(m, "steps", steps)
+	return m
+}
+
+func githubStep(step Step, dir string) *yaml.Node {
+	m := newMap()
+	switch step.Kind {
+	case KindCheckout:
+		setStr(m, "uses", "actions/checkout@v4")
+	case KindSetup:
+		action, ok := githubSetupActions[step.Tool]
+		if !ok {
+			return nil
+		}
+		setStr(m, "name", step.Name)
+		setStr(m, "uses", action[0])
+		with := newMap()
+		if step.Version != "" {
+			setStr(with, action[1], step.Version)
+		}
+		if step.Tool == "java" && !hasOption(step.Options, "distribution") {
+			setStr(with, "distribution", "temurin")
+		}
+		for _, opt := range append(step.Options, githubDirOptions(step, dir)...) {
+			setStr(with, opt.Name, opt.Value)
+		}
+		if len(with.Content) > 0 {
+			setKey(m, "with", with)
+		}
+	case KindPackages:
+		setStr(m, "name", step.Name)
+		setStr(m, "run", "sudo apt-get update\nsudo apt-get install -y "+strings.Join(step.Paths, " "))
+	case KindUpload:
+		setStr(m, "name", step.Name)
+		setStr(m, "uses", "actions/upload-artifact@v4")
+		with := newMap()
+		setStr(with, "name", step.Artifact)
+		setStr(with, "path", strings.Join(step.Paths, "\n"))
+		setKey(m, "with", with)
+	case KindCoverage:
+		setStr(m, "name", step.Name)
+		setStr(m, "uses", "codecov/codecov-action@v4")
+		with := newMap()
+		setStr(with, "files", strings.Join(step.Paths, ","))
+		setKey(m, "with", with)
+	case KindAction:
+		setStr(m, "name", step.Name)
+		setStr(m, "uses", step.Uses)
+		if len(step.Options) > 0 {
+			setKey(m, "with", varsMap(step.Options))
+		}
+	default:
+		if step.Name != "" {
+			setStr(m, "name", step.Name)
+		}
+		setStr(m, "run", step.Command)
+	}
+	return m
+}
+
+// githubDirOptions подсказывает Action установки, где искать lock-файлы,
+// если команды джоба выполняются в подкаталоге.
+func githubDirOptions(step Step, dir string) []Var {
+	if dir == "" {
+		return nil
+	}
+	switch step.Tool {
+	case "node":
+		locks := map[string]string{"npm": "package-lock.json", "yarn": "yarn.lock", "pnpm": "pnpm-lock.yaml"}
+		for _, opt := range step.Options {
+			if lock, ok := locks[opt.Value]; ok && opt.Name == "cache" {
+				return []Var{{Name: "cache-dependency-path", Value: dir + "/" + lock}}
+			}
+		}
+	case "ruby":
+		return []Var{{Name: "working-directory", Value: dir}}
+	}
+	return nil
+}
+
+func githubCacheStep(job *Job) *yaml.Node {
+	m := newMap()
+	setStr(m, "name", "Cache dependencies")
+	setStr(m, "uses", "actions/cache@v4")
+	with := newMap()
+	setStr(with, "path", strings.Join(job.Cache.Paths, "\n"))
+	hash := make([]string, len(job.Cache.KeyFiles))
+	for i, file := range job.Cache.KeyFiles {
+		hash[i] = fmt.Sprintf("'**/%s'", file)
+	}
+	setStr(with, "key", fmt.Sprintf("${{ runner.os }}-%s-${{ hashFiles(%s) }}", job.ID, strings.Join(hash, ", ")))
+	setKey(m, "with", with)
+	return m
+}
+
+// githubChangesJob — служебный джоб, который определяет измененные модули.
+// GitHub умеет фильтровать по путям только весь workflow, поэтому фильтры
+// отдельных джобов вычисляются через dorny/paths-filter.
+const githubChangesJob = "changes"
+
+func githubChangesJobNode(filters []changeFilter) *yaml.Node {
+	m := newMap()
+	setStr(m, "runs-on", "ubuntu-latest")
+	outputs := newMap()
+	var spec strings.Builder
+	for _, f := range filters {
+		setStr(outputs, f.key, fmt.Sprintf("${{ steps.filter.outputs.%s }}", f.key))
+		spec.WriteString(f.key + ":\n")
+		for _, path := range f.paths {
+			fmt.Fprintf(&spec, "  - '%s'\n", path)
+		}
+	}
+	setKey(m, "outputs", outputs)
+
+	checkout := newMap()
+	setStr(checkout, "uses", "actions/checkout@v4")
+	filter := newMap()
+	setStr(filter, "uses", "dorny/paths-filter@v3")
+	setStr(filter, "id", "filter")
+	with := newMap()
+	setStr(with, "filters", spec.String())
+	setKey(filter, "with", with)
+	setKey(m, "steps", seq(checkout, filter))
+	return m
+}
+
+func githubRunner(os string) string {
+	switch os {
+	case "macos":
+		return "macos-latest"
+	case "windows":
+		return "windows-latest"
+	default:
+		return "ubuntu-latest"
+	}
+}
+
+// githubCondition строит выражение if джоба. Ручные джобы запускаются
+// только при запуске workflow через workflow_dispatch.
+func githubCondition(c Condition) string {
+	var parts []string
+	for _, branch := range c.Branches {
+		parts = append(parts, fmt.Sprintf("github.ref == 'refs/heads/%s'", branch))
+	}
+	if c.Tags {
+		parts = append(parts, "startsWith(github.ref, 'refs/tags/')")
+	}
+	cond := strings.Join(parts, " || ")
+	if c.Manual {
+		manual := "github.event_name == 'workflow_dispatch'"
+		if cond != "" {
+			return fmt.Sprintf("(%s) && %s", cond, manual)
+		}
+		return manual
+	}
+	return cond
+}
+
+func (p *Pipeline) hasTagJobs() bool {
+	for _, job := range p.Jobs {
+		if job.When.Tags {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pipeline) hasManualJobs() bool {
+	for _, job := range p.Jobs {
+		if job.When.Manual {
+			return true
+		}
+	}
+	return false
+}
+
+func hasOption(options []Var, name string) bool {
+	for _, opt := range options {
+		if opt.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// githubServices публикует порты сервисов на хост: джоб выполняется
+// на раннере, а не в контейнере, и обращается к ним через localhost.
+func githubServices(services []Service) *yaml.Node {
+	m := newMap()
+	for _, service := range services {
+		svc := newMap()
+		setStr(svc, "image", service.Image)
+		if len(service.Env) > 0 {
+			setKey(svc, "env", varsMap(service.Env))
+		}
+		if service.Port != 0 {
+			setKey(svc, "ports", strSeq([]string{fmt.Sprintf("%d:%d", service.Port, service.Port)}))
+		}
+		setKey(m, service.Name, svc)
+	}
+	return m
+}
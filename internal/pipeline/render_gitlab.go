@@ -0,0 +1,244 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// gitlabMergeRequest — условие pipeline для merge request.
+const gitlabMergeRequest = `$CI_PIPELINE_SOURCE == "merge_request_event"`
+
+// RenderGitLab сериализует pipeline в .gitlab-ci.yml.
+func RenderGitLab(p *Pipeline) (string, error) {
+	root := newMap()
+	if workflow := gitlabWorkflow(p); workflow != nil {
+		setKey(root, "workflow", workflow)
+	}
+	setKey(root, "stages", strSeq(p.Stages))
+	if len(p.Env) > 0 {
+		setKey(root, "variables", varsMap(p.Env))
+	}
+	if p.Cache != nil {
+		setKey(root, "cache", gitlabCache(p.Cache))
+	}
+	for _, job := range p.Jobs {
+		setKey(root, job.ID, gitlabJob(job))
+	}
+	return encodeYAML(root, "")
+}
+
+func gitlabJob(job *Job) *yaml.Node {
+	m := newMap()
+	setStr(m, "stage", job.Stage)
+	if job.Image != "" {
+		setStr(m, "image", gitlabExpand(job.Image, job.Matrix))
+	}
+	if job.OS != "" && job.OS != "linux" {
+		setKey(m, "tags", strSeq([]string{job.OS}))
+	}
+	if len(job.Needs) > 0 {
+		setKey(m, "needs", strSeq(job.Needs))
+	}
+	if len(job.Matrix) > 0 {
+		entry := newMap()
+		for _, axis := range job.Matrix {
+			setKey(entry, envName(axis.Name), flowSeq(axis.Values))
+		}
+		parallel := newMap()
+		setKey(parallel, "matrix", seq(entry))
+		setKey(m, "parallel", parallel)
+	}
+	env := job.Env
+	if job.Docker {
+		env = append([]Var{{Name: "DOCKER_TLS_CERTDIR", Value: "/certs"}}, env...)
+	}
+	if vars := gitlabVars(env); len(vars) > 0 {
+		setKey(m, "variables", varsMap(vars))
+	}
+	services := job.Services
+	if job.Docker {
+		// Клиент Docker находит daemon сервиса dind по сертификатам
+		// в DOCKER_TLS_CERTDIR, раннеру нужен привилегированный режим
+		services = append([]Service{{Name: "docker", Image: "docker:27-dind"}}, services...)
+	}
+	if len(services) > 0 {
+		list := seq()
+		for _, service := range services {
+			svc := newMap()
+			setStr(svc, "name", service.Image)
+			setStr(svc, "alias", service.Name)
+			if len(service.Env) > 0 {
+				setKey(svc, "variables", varsMap(service.Env))
+			}
+			list.Content = append(list.Content, svc)
+		}
+		setKey(m, "services", list)
+	}
+
+	script := seq()
+	var artifacts, reports []string
+	var reportFormat string
+	for _, step := range job.Steps {
+		switch step.Kind {
+		case KindCheckout:
+			// Исходники получает раннер.
+		case KindSetup:
+			// Тулчейн предоставляет образ джоба, Command доустанавливает то,
+			// чего в образе нет.
+			if step.Command != "" {
+				script.Content = append(script.Content, str(gitlabExpand(step.Command, job.Matrix)))
+			}
+		case KindPackages:
+			script.Content = append(script.Content,
+				str("apt-get update"),
+				str("apt-get install -y "+strings.Join(step.Paths, " ")))
+		case KindUpload:
+			artifacts = append(artifacts, step.Paths...)
+		case KindCoverage:
+			if step.Format == "cobertura" || step.Format == "jacoco" {
+				reports = append(reports, step.Paths...)
+				reportFormat = step.Format
+			}
+			artifacts = append(artifacts, step.Paths...)
+		default:
+			if step.Command != "" {
+				script.Content = append(script.Content, str(gitlabExpand(step.Command, job.Matrix)))
+			}
+		}
+	}
+	if len(script.Content) == 0 {
+		script.Content = append(script.Content, str(fmt.Sprintf("echo %q", job.Title())))
+	}
+	if job.Dir != "" {
+		script.Content = append([]*yaml.Node{str("cd " + job.Dir)}, script.Content...)
+	}
+	setKey(m, "script", script)
+
+	if job.Cache != nil {
+		setKey(m, "cache", gitlabCache(job.Cache))
+	}
+	if len(artifacts) > 0 {
+		a := newMap()
+		setKey(a, "paths", strSeq(artifacts))
+		if len(reports) > 0 {
+			report := newMap()
+			setStr(report, "coverage_format", reportFormat)
+			setStr(report, "path", reports[0])
+			r := newMap()
+			setKey(r, "coverage_report", report)
+			setKey(a, "reports", r)
+		}
+		expire := job.ExpireIn
+		if expire == "" {
+			expire = "1 week"
+		}
+		setStr(a, "expire_in", expire)
+		setKey(m, "artifacts", a)
+	}
+	if job.Coverage != "" {
+		setStr(m, "coverage", job.Coverage)
+	}
+	if job.Environment != "" {
+		setStr(m, "environment", job.Environment)
+	}
+	if job.AllowFailure {
+		setKey(m, "allow_failure", boolean(true))
+	}
+	if rules := gitlabRules(job.When); rules != nil {
+		setKey(m, "rules", rules)
+	}
+	return m
+}
+
+// gitlabVars пропускает переменные-секреты: в GitLab они задаются в настройках
+// проекта и доступны джобу под тем же именем.
+func gitlabVars(vars []Var) []Var {
+	var result []Var
+	for _, v := range vars {
+		if name, ok := secretName(v.Value); ok {
+			if name != v.Name {
+				result = append(result, Var{Name: v.Name, Value: "$" + name})
+			}
+			continue
+		}
+		result = append(result, v)
+	}
+	return result
+}
+
+func gitlabCache(c *Cache) *yaml.Node {
+	m := newMap()
+	if len(c.KeyFiles) > 0 {
+		key := newMap()
+		setKey(key, "files", strSeq(c.KeyFiles))
+		setKey(m, "key", key)
+	}
+	setKey(m, "paths", strSeq(c.Paths))
+	return m
+}
+
+// gitlabWorkflow ограничивает запуск pipeline ветками, как push
+// и pull_request в GitHub Actions: pipeline запускается для веток, для
+// merge request в них и для тегов, если есть джобы для тегов.
+func gitlabWorkflow(p *Pipeline) *yaml.Node {
+	if len(p.Branches) == 0 {
+		return nil
+	}
+	var branches, targets []string
+	for _, branch := range p.Branches {
+		branches = append(branches, fmt.Sprintf("$CI_COMMIT_BRANCH == %q", branch))
+		targets = append(targets, fmt.Sprintf("$CI_MERGE_REQUEST_TARGET_BRANCH_NAME == %q", branch))
+	}
+	rules := seq()
+	addRule := func(cond string) {
+		rule := newMap()
+		setStr(rule, "if", cond)
+		rules.Content = append(rules.Content, rule)
+	}
+	addRule(strings.Join(branches, " || "))
+	merge := gitlabMergeRequest
+	if len(targets) > 1 {
+		merge += " && (" + strings.Join(targets, " || ") + ")"
+	} else {
+		merge += " && " + targets[0]
+	}
+	addRule(merge)
+	if p.hasTagJobs() {
+		addRule("$CI_COMMIT_TAG")
+	}
+	m := newMap()
+	setKey(m, "rules", rules)
+	return m
+}
+
+func gitlabRules(c Condition) *yaml.Node {
+	if c.IsZero() {
+		return nil
+	}
+	var conds []string
+	for _, branch := range c.Branches {
+		conds = append(conds, fmt.Sprintf("$CI_COMMIT_BRANCH == %q", branch))
+	}
+	if c.Tags {
+		conds = append(conds, "$CI_COMMIT_TAG")
+	}
+	rule := newMap()
+	if len(conds) > 0 {
+		setStr(rule, "if", strings.Join(conds, " || "))
+	}
+	if len(c.Changes) > 0 {
+		setKey(rule, "changes", strSeq(c.Changes))
+	}
+	if c.Manual {
+		setStr(rule, "when", "manual")
+	}
+	return seq(rule)
+}
+
+func gitlabExpand(s string, axes []Axis) string {
+	return replaceMatrixRefs(s, axes, func(axis string) string {
+		return "$" + envName(axis)
+	})
+}
@@ -0,0 +1,362 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+)
+
+// groovyWriter накапливает Jenkinsfile с отступами по 4 пробела.
+type groovyWriter struct {
+	b      strings.Builder
+	indent int
+}
+
+func (w *groovyWriter) line(format string, args ...any) {
+	if format == "" {
+		w.b.WriteString("\n")
+		return
+	}
+	w.b.WriteString(strings.Repeat("    ", w.indent))
+	fmt.Fprintf(&w.b, format, args...)
+	w.b.WriteString("\n")
+}
+
+func (w *groovyWriter) open(format string, args ...any) {
+	w.line(format+" {", args...)
+	w.indent++
+}
+
+func (w *groovyWriter) close() {
+	w.indent--
+	w.line("}")
+}
+
+// RenderJenkins сериализует pipeline в декларативный Jenkinsfile.
+// Джобы одной стадии выполняются параллельно.
+func RenderJenkins(p *Pipeline) string {
+	w := &groovyWriter{}
+	w.open("pipeline")
+	w.line("agent any")
+
+	if len(p.Env) > 0 {
+		w.line("")
+		w.open("environment")
+		for _, v := range p.Env {
+			w.line("%s = %s", v.Name, groovyString(v.Value))
+		}
+		w.close()
+	}
+
+	w.line("")
+	w.open("stages")
+	w.open("stage('Checkout')")
+	w.open("steps")
+	w.line("checkout scm")
+	w.close()
+	w.close()
+
+	for _, group := range p.jobsByStage() {
+		w.line("")
+		if len(group) == 1 {
+			jenkinsStage(w, group[0])
+			continue
+		}
+		w.open("stage(%s)", groovyString(titleize(group[0].Stage)))
+		w.open("parallel")
+		for i, job := range group {
+			if i > 0 {
+				w.line("")
+			}
+			jenkinsStage(w, job)
+		}
+		w.close()
+		w.close()
+	}
+	w.close()
+
+	w.line("")
+	w.open("post")
+	w.open("always")
+	w.line("cleanWs()")
+	w.close()
+	w.open("success")
+	w.line("echo 'Pipeline completed successfully!'")
+	w.close()
+	w.open("failure")
+	w.line("echo 'Pipeline failed!'")
+	w.close()
+	w.close()
+	w.close()
+
+	return w.b.String()
+}
+
+func jenkinsStage(w *groovyWriter, job *Job) {
+	w.open("stage(%s)", groovyString(job.Title()))
+	if !job.When.IsZero() {
+		jenkinsWhen(w, job.When)
+	}
+	if len(job.Matrix) > 0 {
+		w.open("matrix")
+		jenkinsAgent(w, job)
+		w.open("axes")
+		for _, axis := range job.Matrix {
+			w.open("axis")
+			w.line("name %s", groovyString(envName(axis.Name)))
+			values := make([]string, len(axis.Values))
+			for i, v := range axis.Values {
+				values[i] = groovyString(v)
+			}
+			w.line("values %s", strings.Join(values, ", "))
+			w.close()
+		}
+		w.close()
+		w.open("stages")
+		w.open("stage(%s)", groovyString(job.Title()))
+		jenkinsBody(w, job)
+		w.close()
+		w.close()
+		w.close()
+	} else {
+		jenkinsAgent(w, job)
+		jenkinsBody(w, job)
+	}
+	w.close()
+}
+
+func jenkinsAgent(w *groovyWriter, job *Job) {
+	switch {
+	case job.OS != "" && job.OS != "linux":
+		w.open("agent")
+		w.line("label %s", groovyString(job.OS))
+		w.close()
+	case job.Image != "":
+		w.open("agent")
+		w.open("docker")
+		w.line("image %s", groovyString(jenkinsExpand(job.Image, job.Matrix)))
+		if job.Docker {
+			w.line("args %s", groovyString("-v /var/run/docker.sock:/var/run/docker.sock"))
+		}
+		w.line("reuseNode true")
+		w.close()
+		w.close()
+	}
+}
+
+func jenkinsWhen(w *groovyWriter, c Condition) {
+	w.open("when")
+	var conds []string
+	for _, branch := range c.Branches {
+		conds = append(conds, "branch "+groovyString(branch))
+	}
+	if c.Tags {
+		conds = append(conds, "buildingTag()")
+	}
+	var changes []string
+	for _, pattern := range c.Changes {
+		changes = append(changes, "changeset "+groovyString(pattern))
+	}
+	// Ветки и изменения проверяются вместе: джоб модуля запускается только
+	// на нужной ветке и только при изменении файлов модуля.
+	if len(conds) > 0 && len(changes) > 0 {
+		w.open("allOf")
+		jenkinsAnyOf(w, conds)
+		jenkinsAnyOf(w, changes)
+		w.close()
+	} else {
+		jenkinsAnyOf(w, append(conds, changes...))
+	}
+	if c.Manual {
+		w.line("beforeInput true")
+	}
+	w.close()
+	if c.Manual {
+		w.open("input")
+		w.line("message 'Proceed?'")
+		w.close()
+	}
+}
+
+// jenkinsAnyOf выводит одно условие как есть, а несколько — внутри anyOf.
+func jenkinsAnyOf(w *groovyWriter, conds []string) {
+	if len(conds) > 1 {
+		w.open("anyOf")
+		for _, cond := range conds {
+			w.line("%s", cond)
+		}
+		w.close()
+	} else if len(conds) == 1 {
+		w.line("%s", conds[0])
+	}
+}
+
+func jenkinsBody(w *groovyWriter, job *Job) {
+	if len(job.Env) > 0 {
+		w.open("environment")
+		for _, v := range job.Env {
+			if name, ok := secretName(v.Value); ok {
+				w.line("%s = credentials(%s)", v.Name, groovyString(name))
+				continue
+			}
+			w.line("%s = %s", v.Name, groovyString(jenkinsExpand(v.Value, job.Matrix)))
+		}
+		w.close()
+	}
+	w.open("steps")
+	if job.Dir != "" {
+		w.open("dir(%s)", groovyString(job.Dir))
+	}
+	wrote := false
+	var archive []string
+	var reports []Step
+	for _, step := range job.Steps {
+		switch step.Kind {
+		case KindCheckout:
+			// Исходники получены на стадии Checkout.
+		case KindSetup:
+			// Тулчейн предоставляет агент, Command доустанавливает то, чего нет в образе.
+			if step.Command != "" {
+				jenkinsSh(w, step.Command, job.Matrix)
+				wrote = true
+			}
+		case KindPackages:
+			jenkinsSh(w, "apt-get update && apt-get install -y "+strings.Join(step.Paths, " "), job.Matrix)
+			wrote = true
+		case KindUpload:
+			archive = append(archive, step.Paths...)
+		case KindCoverage:
+			if step.Format == "cobertura" || step.Format == "jacoco" {
+				reports = append(reports, step)
+			} else {
+				archive = append(archive, step.Paths...)
+			}
+		default:
+			if step.Command != "" {
+				jenkinsSh(w, step.Command, job.Matrix)
+				wrote = true
+			}
+		}
+	}
+	if !wrote {
+		w.line("echo %s", groovyString(job.Title()))
+	}
+	if job.Dir != "" {
+		w.close()
+	}
+	w.close()
+
+	if len(archive) == 0 && len(reports) == 0 {
+		return
+	}
+	w.open("post")
+	w.open("always")
+	for _, report := range reports {
+		w.line("recordCoverage(tools: [[parser: %s, pattern: %s]])",
+			groovyString(strings.ToUpper(report.Format)), groovyString(strings.Join(report.Paths, ",")))
+	}
+	if len(archive) > 0 {
+		w.line("archiveArtifacts artifacts: %s, allowEmptyArchive: true, fingerprint: true",
+			groovyString(jenkinsArtifactPattern(archive)))
+	}
+	w.close()
+	w.close()
+}
+
+func jenkinsSh(w *groovyWriter, command string, axes []Axis) {
+	command = jenkinsExpand(command, axes)
+	if !strings.Contains(command, "\n") {
+		w.line("sh %s", groovyLiteral(command))
+		return
+	}
+	w.line("sh '''")
+	for _, line := range strings.Split(strings.TrimRight(command, "\n"), "\n") {
+		w.line("    %s", escapeGroovy(line))
+	}
+	w.line("'''")
+}
+
+// jenkinsArtifactPattern превращает пути каталогов в Ant-шаблоны archiveArtifacts.
+func jenkinsArtifactPattern(paths []string) string {
+	patterns := make([]string, len(paths))
+	for i, path := range paths {
+		if strings.HasSuffix(path, "/") {
+			path += "**"
+		}
+		patterns[i] = path
+	}
+	return strings.Join(patterns, ", ")
+}
+
+func jenkinsExpand(s string, axes []Axis) string {
+	return replaceMatrixRefs(s, axes, func(axis string) string {
+		return "${" + envName(axis) + "}"
+	})
+}
+
+// groovyString возвращает строковый литерал Groovy. Строки со ссылками ${...}
+// оформляются в двойных кавычках, чтобы Jenkins подставил переменные.
+func groovyString(s string) string {
+	if strings.Contains(s, "${") {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+	return groovyLiteral(s)
+}
+
+// groovyLiteral возвращает литерал без интерполяции: переменные окружения
+// в shell-командах подставляет sh, а не Groovy.
+func groovyLiteral(s string) string {
+	return "'" + strings.ReplaceAll(escapeGroovy(s), "'", `\'`) + "'"
+}
+
+func escapeGroovy(s string) string {
+	return strings.ReplaceAll(s, `\`, `\\`)
+}
+
+// jobsByStage группирует джобы по стадиям в порядке p.Stages. Джобы с матрицей
+// всегда получают собственную группу: Jenkins не допускает matrix внутри parallel.
+func (p *Pipeline) jobsByStage() [][]*Job {
+	var groups [][]*Job
+	for _, stage := range p.stageOrder() {
+		var group []*Job
+		for _, job := range p.Jobs {
+			if job.Stage != stage {
+				continue
+			}
+			if len(job.Matrix) > 0 {
+				groups = append(groups, []*Job{job})
+				continue
+			}
+			group = append(group, job)
+		}
+		if len(group) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// stageOrder возвращает стадии p.Stages без повторов и следом стадии джобов,
+// которых нет в списке.
+func (p *Pipeline) stageOrder() []string {
+	var order []string
+	for _, stage := range p.Stages {
+		if !contains(order, stage) {
+			order = append(order, stage)
+		}
+	}
+	for _, job := range p.Jobs {
+		if !contains(order, job.Stage) {
+			order = append(order, job.Stage)
+		}
+	}
+	return order
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
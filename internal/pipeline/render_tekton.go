@@ -0,0 +1,380 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	tektonAPIVersion = "tekton.dev/v1"
+	tektonWorkspace  = "source"
+	tektonFetchTask  = "fetch-source"
+	tektonSecret     = "pipeline-secrets" // Kubernetes Secret с секретами pipeline
+)
+
+// RenderTekton сериализует pipeline в манифесты Tekton: Task на каждый джоб,
+// Pipeline, который клонирует репозиторий задачей git-clone из Tekton Hub
+// и запускает задачи в порядке стадий, и пример PipelineRun. Документы
+// разделяются "---".
+func RenderTekton(p *Pipeline) (string, error) {
+	name := dnsName(p.Name)
+	if name == "" {
+		name = "pipeline"
+	}
+	var docs []*yaml.Node
+	for _, job := range p.Jobs {
+		docs = append(docs, tektonTask(p, name, job))
+	}
+	docs = append(docs, tektonPipeline(p, name), tektonPipelineRun(p, name))
+	return encodeDocuments(docs)
+}
+
+func tektonManifest(kind, name string) (*yaml.Node, *yaml.Node) {
+	m := newMap()
+	setStr(m, "apiVersion", tektonAPIVersion)
+	setStr(m, "kind", kind)
+	metadata := newMap()
+	setStr(metadata, "name", name)
+	setKey(m, "metadata", metadata)
+	spec := newMap()
+	setKey(m, "spec", spec)
+	return m, spec
+}
+
+func tektonTask(p *Pipeline, prefix string, job *Job) *yaml.Node {
+	m, spec := tektonManifest("Task", prefix+"-"+dnsName(job.ID))
+	if len(job.Matrix) > 0 {
+		params := seq()
+		for _, axis := range job.Matrix {
+			param := newMap()
+			setStr(param, "name", axis.Name)
+			setStr(param, "type", "string")
+			params.Content = append(params.Content, param)
+		}
+		setKey(spec, "params", params)
+	}
+	workspace := newMap()
+	setStr(workspace, "name", tektonWorkspace)
+	setKey(spec, "workspaces", seq(workspace))
+
+	workDir := "$(workspaces." + tektonWorkspace + ".path)"
+	if job.Dir != "" {
+		workDir += "/" + job.Dir
+	}
+	image := job.Image
+	if image == "" {
+		image = woodpeckerImage
+	}
+	image = tektonExpand(image, job.Matrix)
+	env := tektonEnv(append(append([]Var{}, p.Env...), job.Env...), job.Matrix)
+
+	// Все шаги модели выполняются одним скриптом: у каждого шага Task свой
+	// контейнер, и установленное в одном шаге не дожило бы до следующего.
+	// Между задачами Pipeline общим остается только workspace.
+	var commands []string
+	for _, step := range job.Steps {
+		command := ""
+		switch step.Kind {
+		case KindCheckout, KindUpload, KindCoverage:
+		case KindPackages:
+			command = "apt-get update && apt-get install -y " + strings.Join(step.Paths, " ")
+		default:
+			command = step.Command
+		}
+		if command != "" {
+			commands = append(commands, strings.TrimPrefix(command, "set -e\n"))
+		}
+	}
+	if len(commands) == 0 {
+		commands = append(commands, fmt.Sprintf("echo %q", job.Title()))
+	}
+	steps := seq(tektonStep("run", image, workDir, strings.Join(commands, "\n"), env, job))
+	setKey(spec, "steps", steps)
+
+	if len(job.Services) > 0 {
+		sidecars := seq()
+		for _, service := range job.Services {
+			sidecar := newMap()
+			setStr(sidecar, "name", dnsName(service.Name))
+			setStr(sidecar, "image", service.Image)
+			if len(service.Env) > 0 {
+				setKey(sidecar, "env", tektonEnv(service.Env, nil))
+			}
+			sidecars.Content = append(sidecars.Content, sidecar)
+		}
+		setKey(spec, "sidecars", sidecars)
+	}
+	return m
+}
+
+func tektonStep(name, image, workDir, command string, env *yaml.Node, job *Job) *yaml.Node {
+	step := newMap()
+	setStr(step, "name", name)
+	setStr(step, "image", image)
+	setStr(step, "workingDir", workDir)
+	if len(env.Content) > 0 {
+		setKey(step, "env", env)
+	}
+	if job.AllowFailure {
+		setStr(step, "onError", "continue")
+	}
+	setStr(step, "script", "#!/bin/sh\nset -e\n"+tektonExpand(command, job.Matrix))
+	return step
+}
+
+// TektonEnv превращает переменные в env контейнера задачи Tekton, которую
+// генератор добавляет к манифестам рендера.
+func TektonEnv(vars []Var) *yaml.Node {
+	return tektonEnv(vars, nil)
+}
+
+// tektonEnv превращает переменные в env контейнера. Секреты читаются
+// из Kubernetes Secret tektonSecret по имени переменной.
+func tektonEnv(vars []Var, axes []Axis) *yaml.Node {
+	env := seq()
+	for _, v := range vars {
+		item := newMap()
+		setStr(item, "name", v.Name)
+		if name, ok := secretName(v.Value); ok {
+			ref := newMap()
+			setStr(ref, "name", tektonSecret)
+			setStr(ref, "key", name)
+			from := newMap()
+			setKey(from, "secretKeyRef", ref)
+			setKey(item, "valueFrom", from)
+		} else {
+			setStr(item, "value", tektonExpand(v.Value, axes))
+		}
+		env.Content = append(env.Content, item)
+	}
+	return env
+}
+
+func tektonPipeline(p *Pipeline, name string) *yaml.Node {
+	m, spec := tektonManifest("Pipeline", name)
+	params := seq(tektonParam("repo-url", "", false), tektonParam("revision", "main", true))
+	if p.hasTagJobs() {
+		params.Content = append(params.Content, tektonParam("tag", "", true))
+	}
+	if tektonHasManual(p) {
+		params.Content = append(params.Content, tektonParam("manual", "false", true))
+	}
+	setKey(spec, "params", params)
+	workspace := newMap()
+	setStr(workspace, "name", tektonWorkspace)
+	setKey(spec, "workspaces", seq(workspace))
+
+	tasks := seq(tektonFetch())
+	for _, job := range p.Jobs {
+		task := newMap()
+		setStr(task, "name", dnsName(job.ID))
+		ref := newMap()
+		setStr(ref, "name", name+"-"+dnsName(job.ID))
+		setKey(task, "taskRef", ref)
+		setKey(task, "runAfter", flowSeq(tektonRunAfter(p, job)))
+		if when := tektonWhen(job.When); when != nil {
+			setKey(task, "when", when)
+		}
+		if len(job.Matrix) > 0 {
+			matrixParams := seq()
+			for _, axis := range job.Matrix {
+				param := newMap()
+				setStr(param, "name", axis.Name)
+				setKey(param, "value", flowSeq(axis.Values))
+				matrixParams.Content = append(matrixParams.Content, param)
+			}
+			matrix := newMap()
+			setKey(matrix, "params", matrixParams)
+			setKey(task, "matrix", matrix)
+		}
+		setKey(task, "workspaces", seq(tektonWorkspaceBinding(tektonWorkspace)))
+		tasks.Content = append(tasks.Content, task)
+	}
+	setKey(spec, "tasks", tasks)
+	return m
+}
+
+// tektonFetch клонирует репозиторий в общий workspace.
+func tektonFetch() *yaml.Node {
+	task := newMap()
+	setStr(task, "name", tektonFetchTask)
+	ref := newMap()
+	setStr(ref, "resolver", "hub")
+	setKey(ref, "params", tektonNameValues([]Var{{Name: "kind", Value: "task"}, {Name: "name", Value: "git-clone"}, {Name: "version", Value: "0.9"}}))
+	setKey(task, "taskRef", ref)
+	setKey(task, "params", tektonNameValues([]Var{{Name: "url", Value: "$(params.repo-url)"}, {Name: "revision", Value: "$(params.revision)"}}))
+	setKey(task, "workspaces", seq(tektonWorkspaceBinding("output")))
+	return task
+}
+
+// tektonNameValues — список параметров в форме name/value.
+func tektonNameValues(vars []Var) *yaml.Node {
+	params := seq()
+	for _, v := range vars {
+		param := newMap()
+		setStr(param, "name", v.Name)
+		setStr(param, "value", v.Value)
+		params.Content = append(params.Content, param)
+	}
+	return params
+}
+
+func tektonParam(name, defaultValue string, hasDefault bool) *yaml.Node {
+	param := newMap()
+	setStr(param, "name", name)
+	setStr(param, "type", "string")
+	if hasDefault {
+		setStr(param, "default", defaultValue)
+	}
+	return param
+}
+
+// tektonWorkspaceBinding подключает общий workspace pipeline к workspace задачи.
+func tektonWorkspaceBinding(name string) *yaml.Node {
+	binding := newMap()
+	setStr(binding, "name", name)
+	setStr(binding, "workspace", tektonWorkspace)
+	return binding
+}
+
+// tektonRunAfter возвращает задачи, после которых запускается джоб: его Needs,
+// а без них — джобы предыдущей стадии или клонирование репозитория.
+func tektonRunAfter(p *Pipeline, job *Job) []string {
+	needs := job.Needs
+	if len(needs) == 0 {
+		for _, stage := range p.stageOrder() {
+			if stage == job.Stage {
+				break
+			}
+			var stageJobs []string
+			for _, other := range p.Jobs {
+				if other.Stage == stage {
+					stageJobs = append(stageJobs, other.ID)
+				}
+			}
+			if len(stageJobs) > 0 {
+				needs = stageJobs
+			}
+		}
+	}
+	if len(needs) == 0 {
+		return []string{tektonFetchTask}
+	}
+	names := make([]string, len(needs))
+	for i, need := range needs {
+		names[i] = dnsName(need)
+	}
+	return names
+}
+
+// TektonWhen переводит условия в when-выражения задачи Tekton, которую
+// генератор добавляет к манифестам рендера.
+func TektonWhen(c Condition) *yaml.Node {
+	return tektonWhen(c)
+}
+
+// tektonWhen переводит условия в when-выражения по параметрам запуска.
+// Фильтра по изменениям путей у Tekton нет: его задают в Tekton Triggers.
+// When-выражения объединяются через И, поэтому ветки вместе с тегами
+// проверяются одним CEL-выражением (нужен флаг enable-cel-in-whenexpression).
+func tektonWhen(c Condition) *yaml.Node {
+	when := seq()
+	add := func(input, operator string, values []string) {
+		expr := newMap()
+		setStr(expr, "input", input)
+		setStr(expr, "operator", operator)
+		setKey(expr, "values", flowSeq(values))
+		when.Content = append(when.Content, expr)
+	}
+	switch {
+	case len(c.Branches) > 0 && c.Tags:
+		branches := make([]string, len(c.Branches))
+		for i, branch := range c.Branches {
+			branches[i] = "'" + branch + "'"
+		}
+		expr := newMap()
+		setStr(expr, "cel", fmt.Sprintf("'$(params.revision)' in [%s] || '$(params.tag)' != ''", strings.Join(branches, ", ")))
+		when.Content = append(when.Content, expr)
+	case len(c.Branches) > 0:
+		add("$(params.revision)", "in", c.Branches)
+	case c.Tags:
+		add("$(params.tag)", "notin", []string{""})
+	}
+	if c.Manual {
+		add("$(params.manual)", "in", []string{"true"})
+	}
+	if len(when.Content) == 0 {
+		return nil
+	}
+	return when
+}
+
+func tektonHasManual(p *Pipeline) bool {
+	for _, job := range p.Jobs {
+		if job.When.Manual {
+			return true
+		}
+	}
+	return false
+}
+
+// tektonPipelineRun — пример запуска с временным томом для workspace.
+func tektonPipelineRun(p *Pipeline, name string) *yaml.Node {
+	m := newMap()
+	setStr(m, "apiVersion", tektonAPIVersion)
+	setStr(m, "kind", "PipelineRun")
+	metadata := newMap()
+	setStr(metadata, "generateName", name+"-run-")
+	setKey(m, "metadata", metadata)
+	spec := newMap()
+	ref := newMap()
+	setStr(ref, "name", name)
+	setKey(spec, "pipelineRef", ref)
+
+	revision := "main"
+	if len(p.Branches) > 0 {
+		revision = p.Branches[0]
+	}
+	setKey(spec, "params", tektonNameValues([]Var{{Name: "repo-url", Value: "https://example.com/org/repo.git"}, {Name: "revision", Value: revision}}))
+
+	storage := newMap()
+	setStr(storage, "storage", "1Gi")
+	resources := newMap()
+	setKey(resources, "requests", storage)
+	claim := newMap()
+	setKey(claim, "accessModes", strSeq([]string{"ReadWriteOnce"}))
+	setKey(claim, "resources", resources)
+	template := newMap()
+	setKey(template, "spec", claim)
+	workspace := newMap()
+	setStr(workspace, "name", tektonWorkspace)
+	setKey(workspace, "volumeClaimTemplate", template)
+	setKey(spec, "workspaces", seq(workspace))
+	setKey(m, "spec", spec)
+	return m
+}
+
+func tektonExpand(s string, axes []Axis) string {
+	return replaceMatrixRefs(s, axes, func(axis string) string {
+		return "$(params." + axis + ")"
+	})
+}
+
+// dnsName приводит строку к имени ресурса Kubernetes: строчные буквы,
+// цифры и дефисы.
+func dnsName(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,77 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestTektonStepsShareContainer(t *testing.T) {
+	p := &Pipeline{
+		Name:   "app",
+		Stages: []string{"test", "deploy"},
+		Jobs: []*Job{
+			{ID: "test", Stage: "test", Image: "python:3.12", Steps: []Step{
+				Checkout(),
+				Run("Install linters", "pip install flake8"),
+				Run("Install dependencies", "pip install -r requirements.txt pytest"),
+				Run("Lint", "flake8 ."),
+				Run("Test", "pytest"),
+			}},
+			{ID: "deploy", Stage: "deploy", Image: "alpine:3.20", Needs: []string{"test"}, Steps: []Step{
+				Run("Set up SSH", "apk add --no-cache openssh-client\nmkdir -p ~/.ssh"),
+				Run("Deploy", `ssh "$DEPLOY_USER@$DEPLOY_HOST" ./deploy.sh`),
+			}},
+		},
+	}
+	out, err := Render(p, "tekton")
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	scripts := map[string][]string{}
+	dec := yaml.NewDecoder(strings.NewReader(out))
+	for {
+		var doc struct {
+			Kind     string
+			Metadata struct{ Name string }
+			Spec     struct {
+				Steps []struct{ Script string }
+			}
+		}
+		if dec.Decode(&doc) != nil {
+			break
+		}
+		if doc.Kind != "Task" {
+			continue
+		}
+		for _, step := range doc.Spec.Steps {
+			scripts[doc.Metadata.Name] = append(scripts[doc.Metadata.Name], step.Script)
+		}
+	}
+	tests := []struct {
+		task    string
+		install string
+		use     string
+	}{
+		{task: "app-test", install: "pip install flake8", use: "flake8 ."},
+		{task: "app-test", install: "pip install -r requirements.txt pytest", use: "pytest"},
+		{task: "app-deploy", install: "apk add --no-cache openssh-client", use: "ssh "},
+	}
+	for _, tt := range tests {
+		steps, ok := scripts[tt.task]
+		if !ok {
+			t.Fatalf("task %s is missing:\n%s", tt.task, out)
+		}
+		found := false
+		for _, script := range steps {
+			i, j := strings.Index(script, tt.install), strings.LastIndex(script, tt.use)
+			if i >= 0 && j > i {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("%s: %q and a later %q are not in the same step:\n%s", tt.task, tt.install, tt.use, strings.Join(steps, "\n---\n"))
+		}
+	}
+}
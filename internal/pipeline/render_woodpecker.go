@@ -0,0 +1,379 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// woodpeckerImage используется шагами, для которых генератор не задал образ.
+const woodpeckerImage = "ubuntu:22.04"
+
+// dockerSocket — сокет Docker хоста для джобов с Job.Docker.
+const dockerSocket = "/var/run/docker.sock"
+
+// containerStep — шаг Woodpecker или Drone: джоб модели или одна комбинация
+// его матрицы.
+type containerStep struct {
+	name string
+	job  *Job
+	leg  []Var
+}
+
+func (s containerStep) expand(value string) string {
+	for _, v := range s.leg {
+		value = strings.ReplaceAll(value, MatrixRef(v.Name), v.Value)
+	}
+	return value
+}
+
+// RenderWoodpecker сериализует pipeline в .woodpecker.yml. Каждый джоб
+// становится шагом в контейнере, порядок стадий и Needs — depends_on,
+// условия джобов — фильтры when по веткам, событиям и путям.
+func RenderWoodpecker(p *Pipeline) (string, error) {
+	root := newMap()
+
+	when := newMap()
+	setKey(when, "event", flowSeq([]string{"push", "pull_request", "manual"}))
+	if len(p.Branches) > 0 {
+		setKey(when, "branch", flowSeq(p.Branches))
+	}
+	whens := seq(when)
+	if p.hasTagJobs() {
+		tag := newMap()
+		setStr(tag, "event", "tag")
+		whens.Content = append(whens.Content, tag)
+	}
+	setKey(root, "when", whens)
+
+	steps, names := containerSteps(p)
+	m := newMap()
+	for _, step := range steps {
+		node := newMap()
+		setStr(node, "image", containerImage(step))
+		if env := containerEnv(p, step); len(env.Content) > 0 {
+			setKey(node, "environment", env)
+		}
+		setKey(node, "commands", strSeq(containerCommands(step, false)))
+		if step.job.Docker {
+			// Сокет Docker хоста монтируется только в доверенных репозиториях
+			setKey(node, "volumes", strSeq([]string{dockerSocket + ":" + dockerSocket}))
+		}
+		// depends_on: [] запускает шаг сразу, без него шаги идут по очереди
+		setKey(node, "depends_on", flowSeq(containerDeps(p, step.job, names)))
+		if cond := woodpeckerWhen(step.job.When); cond != nil {
+			setKey(node, "when", cond)
+		}
+		if step.job.AllowFailure {
+			setStr(node, "failure", "ignore")
+		}
+		setKey(m, step.name, node)
+	}
+	setKey(root, "steps", m)
+
+	if services := containerServices(p); len(services) > 0 {
+		m := newMap()
+		for _, service := range services {
+			node := newMap()
+			setStr(node, "image", service.Image)
+			if len(service.Env) > 0 {
+				setKey(node, "environment", varsMap(service.Env))
+			}
+			setKey(m, service.Name, node)
+		}
+		setKey(root, "services", m)
+	}
+
+	return encodeYAML(root, "steps")
+}
+
+// RenderDrone сериализует pipeline в .drone.yml. Структура совпадает с
+// Woodpecker, но шаги и сервисы задаются списками, ручной запуск — событием
+// promote, а фильтра по путям у Drone нет и его заменяет проверка в скрипте.
+func RenderDrone(p *Pipeline) (string, error) {
+	root := newMap()
+	setStr(root, "kind", "pipeline")
+	setStr(root, "type", "docker")
+	name := p.Name
+	if name == "" {
+		name = "default"
+	}
+	setStr(root, "name", name)
+
+	var refs []string
+	for _, branch := range p.Branches {
+		refs = append(refs, "refs/heads/"+branch)
+	}
+	if len(refs) > 0 {
+		refs = append(refs, "refs/pull/**")
+		if p.hasTagJobs() {
+			refs = append(refs, "refs/tags/**")
+		}
+		include := newMap()
+		setKey(include, "include", strSeq(refs))
+		trigger := newMap()
+		setKey(trigger, "ref", include)
+		setKey(root, "trigger", trigger)
+	}
+
+	steps, names := containerSteps(p)
+	list := seq()
+	docker := false
+	for _, step := range steps {
+		node := newMap()
+		setStr(node, "name", step.name)
+		setStr(node, "image", containerImage(step))
+		if env := containerEnv(p, step); len(env.Content) > 0 {
+			setKey(node, "environment", env)
+		}
+		setKey(node, "commands", strSeq(containerCommands(step, true)))
+		if step.job.Docker {
+			volume := newMap()
+			setStr(volume, "name", "docker")
+			setStr(volume, "path", dockerSocket)
+			setKey(node, "volumes", seq(volume))
+			docker = true
+		}
+		if deps := containerDeps(p, step.job, names); len(deps) > 0 {
+			setKey(node, "depends_on", flowSeq(deps))
+		}
+		if cond := droneWhen(step.job.When); cond != nil {
+			setKey(node, "when", cond)
+		}
+		if step.job.AllowFailure {
+			setStr(node, "failure", "ignore")
+		}
+		list.Content = append(list.Content, node)
+	}
+	setKey(root, "steps", list)
+
+	if services := containerServices(p); len(services) > 0 {
+		list := seq()
+		for _, service := range services {
+			node := newMap()
+			setStr(node, "name", service.Name)
+			setStr(node, "image", service.Image)
+			if len(service.Env) > 0 {
+				setKey(node, "environment", varsMap(service.Env))
+			}
+			list.Content = append(list.Content, node)
+		}
+		setKey(root, "services", list)
+	}
+	if docker {
+		// Сокет Docker хоста подключается к шагам томом
+		host := newMap()
+		setStr(host, "path", dockerSocket)
+		volume := newMap()
+		setStr(volume, "name", "docker")
+		setKey(volume, "host", host)
+		setKey(root, "volumes", seq(volume))
+	}
+
+	return encodeYAML(root, "")
+}
+
+// containerSteps разворачивает джобы с матрицей в отдельные шаги и
+// возвращает имена шагов каждого джоба для depends_on.
+func containerSteps(p *Pipeline) ([]containerStep, map[string][]string) {
+	var steps []containerStep
+	names := map[string][]string{}
+	for _, job := range p.Jobs {
+		if len(job.Matrix) == 0 {
+			steps = append(steps, containerStep{name: job.ID, job: job})
+			names[job.ID] = append(names[job.ID], job.ID)
+			continue
+		}
+		for _, leg := range matrixLegs(job.Matrix) {
+			name := job.ID
+			for _, v := range leg {
+				name += "-" + v.Value
+			}
+			steps = append(steps, containerStep{name: name, job: job, leg: leg})
+			names[job.ID] = append(names[job.ID], name)
+		}
+	}
+	return steps, names
+}
+
+func containerImage(step containerStep) string {
+	if step.job.Image == "" {
+		return woodpeckerImage
+	}
+	return step.expand(step.job.Image)
+}
+
+// containerEnv объединяет переменные pipeline и джоба. Секреты берутся
+// из хранилища CI через from_secret.
+func containerEnv(p *Pipeline, step containerStep) *yaml.Node {
+	m := newMap()
+	for _, v := range append(append([]Var{}, p.Env...), step.job.Env...) {
+		if name, ok := secretName(v.Value); ok {
+			secret := newMap()
+			setStr(secret, "from_secret", strings.ToLower(name))
+			setKey(m, v.Name, secret)
+			continue
+		}
+		setStr(m, v.Name, step.expand(v.Value))
+	}
+	return m
+}
+
+// containerCommands собирает команды шага. Артефакты не публикуются:
+// следующие шаги видят их в общем workspace.
+func containerCommands(step containerStep, drone bool) []string {
+	job := step.job
+	var commands []string
+	if drone && len(job.When.Changes) > 0 {
+		commands = append(commands, droneChangesGuard(job.When.Changes))
+	}
+	if job.Dir != "" {
+		commands = append(commands, "cd "+job.Dir)
+	}
+	wrote := false
+	for _, s := range job.Steps {
+		command := ""
+		switch s.Kind {
+		case KindCheckout, KindUpload, KindCoverage:
+			// Исходники клонирует CI, артефакты остаются в workspace.
+		case KindSetup:
+			// Тулчейн предоставляет образ шага, Command доустанавливает то,
+			// чего в образе нет.
+			command = s.Command
+		case KindPackages:
+			command = "apt-get update && apt-get install -y " + strings.Join(s.Paths, " ")
+		default:
+			command = s.Command
+		}
+		if command != "" {
+			commands = append(commands, step.expand(command))
+			wrote = true
+		}
+	}
+	if !wrote {
+		commands = append(commands, fmt.Sprintf("echo %q", job.Title()))
+	}
+	return commands
+}
+
+// containerDeps возвращает шаги, после которых запускается джоб: его Needs,
+// а без них — джобы ближайшей предыдущей стадии. Джобы, которые могут быть
+// пропущены по другому условию, в зависимости не попадают.
+func containerDeps(p *Pipeline, job *Job, names map[string][]string) []string {
+	deps := []string{}
+	needs := job.Needs
+	if len(needs) == 0 {
+		for _, stage := range p.stageOrder() {
+			if stage == job.Stage {
+				break
+			}
+			var stageJobs []string
+			for _, other := range p.Jobs {
+				if other.Stage == stage && (other.When.IsZero() || fmt.Sprint(other.When) == fmt.Sprint(job.When)) {
+					stageJobs = append(stageJobs, other.ID)
+				}
+			}
+			if len(stageJobs) > 0 {
+				needs = stageJobs
+			}
+		}
+	}
+	for _, need := range needs {
+		deps = append(deps, names[need]...)
+	}
+	return deps
+}
+
+// containerServices собирает сервисы всех джобов: в Woodpecker и Drone они
+// общие для pipeline.
+func containerServices(p *Pipeline) []Service {
+	var services []Service
+	seen := map[string]bool{}
+	for _, job := range p.Jobs {
+		for _, service := range job.Services {
+			if !seen[service.Name] {
+				seen[service.Name] = true
+				services = append(services, service)
+			}
+		}
+	}
+	return services
+}
+
+// woodpeckerWhen строит условие шага. Условие с ветками и тегами
+// становится списком: Woodpecker запускает шаг, если выполнен любой
+// из элементов, а внутри элемента фильтры объединяются через И.
+func woodpeckerWhen(c Condition) *yaml.Node {
+	if c.IsZero() {
+		return nil
+	}
+	if c.Tags && len(c.Branches) > 0 {
+		push := woodpeckerWhen(Condition{Branches: c.Branches, Changes: c.Changes})
+		return seq(push, woodpeckerWhen(Condition{Tags: true, Changes: c.Changes}))
+	}
+	m := newMap()
+	switch {
+	case c.Tags:
+		setStr(m, "event", "tag")
+	case c.Manual:
+		setStr(m, "event", "manual")
+	case len(c.Branches) > 0:
+		// branch совпадает и с целевой веткой pull request
+		setKey(m, "event", flowSeq([]string{"push"}))
+	}
+	if len(c.Branches) > 0 {
+		setKey(m, "branch", flowSeq(c.Branches))
+	}
+	if len(c.Changes) > 0 {
+		include := newMap()
+		setKey(include, "include", strSeq(c.Changes))
+		setKey(m, "path", include)
+	}
+	return m
+}
+
+// droneWhen строит условие шага. Фильтры when у Drone объединяются через
+// И, поэтому ветки вместе с тегами задаются одним фильтром по ref.
+func droneWhen(c Condition) *yaml.Node {
+	if len(c.Branches) == 0 && !c.Tags && !c.Manual {
+		return nil
+	}
+	m := newMap()
+	if c.Tags && len(c.Branches) > 0 {
+		var refs []string
+		for _, branch := range c.Branches {
+			refs = append(refs, "refs/heads/"+branch)
+		}
+		setKey(m, "ref", strSeq(append(refs, "refs/tags/**")))
+		return m
+	}
+	switch {
+	case c.Tags:
+		setKey(m, "event", flowSeq([]string{"tag"}))
+	case c.Manual:
+		setKey(m, "event", flowSeq([]string{"promote"}))
+	case len(c.Branches) > 0:
+		// branch совпадает и с целевой веткой pull request
+		setKey(m, "event", flowSeq([]string{"push"}))
+	}
+	if len(c.Branches) > 0 {
+		setKey(m, "branch", flowSeq(c.Branches))
+	}
+	return m
+}
+
+// droneChangesGuard завершает шаг без ошибки, если в последнем коммите нет
+// изменений по шаблонам: фильтра по путям у Drone нет.
+func droneChangesGuard(paths []string) string {
+	patterns := make([]string, len(paths))
+	for i, path := range paths {
+		patterns[i] = strings.ReplaceAll(path, "**", "*")
+	}
+	return fmt.Sprintf(`changed=$(git diff --name-only HEAD~1 HEAD 2>/dev/null || git ls-files)
+match=false
+for file in $changed; do
+  case "$file" in %s) match=true ;; esac
+done
+if [ "$match" = false ]; then echo "No changes in %s, skipping"; exit 0; fi`, strings.Join(patterns, "|"), strings.Join(paths, ", "))
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"bytes"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Вспомогательные функции для построения YAML-дерева с сохранением порядка ключей.
+
+func newMap() *yaml.Node {
+	return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+}
+
+func setKey(m *yaml.Node, key string, value *yaml.Node) {
+	m.Content = append(m.Content, str(key), value)
+}
+
+func setStr(m *yaml.Node, key, value string) {
+	setKey(m, key, str(value))
+}
+
+func str(value string) *yaml.Node {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+	if strings.Contains(value, "\n") {
+		node.Style = yaml.LiteralStyle
+		if !strings.HasSuffix(value, "\n") {
+			node.Value += "\n"
+		}
+	}
+	return node
+}
+
+func boolean(value bool) *yaml.Node {
+	v := "false"
+	if value {
+		v = "true"
+	}
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: v}
+}
+
+func seq(items ...*yaml.Node) *yaml.Node {
+	return &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: items}
+}
+
+func strSeq(values []string) *yaml.Node {
+	node := seq()
+	for _, v := range values {
+		node.Content = append(node.Content, str(v))
+	}
+	return node
+}
+
+func flowSeq(values []string) *yaml.Node {
+	node := strSeq(values)
+	node.Style = yaml.FlowStyle
+	return node
+}
+
+func varsMap(vars []Var) *yaml.Node {
+	m := newMap()
+	for _, v := range vars {
+		setStr(m, v.Name, v.Value)
+	}
+	return m
+}
+
+// MappingValue возвращает значение ключа в mapping-узле или nil.
+func MappingValue(m *yaml.Node, key string) *yaml.Node {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// encodeYAML сериализует дерево и отделяет пустой строкой элементы верхнего
+// уровня (и джобы внутри секции jobsKey, если она указана).
+func encodeYAML(root *yaml.Node, jobsKey string) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		return "", err
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	section := ""
+	prev := ""
+	for i, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+		topLevel := isKeyAtIndent(line, 0)
+		if topLevel {
+			section = strings.SplitN(line, ":", 2)[0]
+		}
+		job := jobsKey != "" && section == jobsKey && isKeyAtIndent(line, 2) && prev != jobsKey+":"
+		if i > 0 && (topLevel || job) {
+			out.WriteString("\n")
+		}
+		out.WriteString(line)
+		out.WriteString("\n")
+		prev = line
+	}
+	return out.String(), nil
+}
+
+// encodeDocuments сериализует несколько YAML-документов в один файл,
+// разделяя их "---".
+func encodeDocuments(docs []*yaml.Node) (string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for _, doc := range docs {
+		if err := enc.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{doc}}); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func isKeyAtIndent(line string, indent int) bool {
+	if len(line) <= indent || strings.TrimLeft(line[:indent], " ") != "" {
+		return false
+	}
+	c := line[indent]
+	return c != ' ' && c != '-'
+}
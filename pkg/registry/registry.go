@@ -0,0 +1,86 @@
+// Package registry позволяет программам, встраивающим pipeline-gen,
+// регистрировать собственные анализаторы языков и генераторы pipeline
+// до вызова cmd.Execute.
+package registry
+
+import (
+	"github.com/immxrtalbeast/pipeline-gen/internal/analyzer"
+	"github.com/immxrtalbeast/pipeline-gen/internal/generator"
+	"github.com/immxrtalbeast/pipeline-gen/internal/pipeline"
+)
+
+// ProjectInfo — результат анализа репозитория.
+type ProjectInfo = analyzer.ProjectInfo
+
+// Generator строит pipeline для одного языка в одном формате.
+type Generator = generator.Generator
+
+// Типы модели pipeline для генераторов, использующих встроенные рендеры.
+type (
+	Pipeline  = pipeline.Pipeline
+	Job       = pipeline.Job
+	Step      = pipeline.Step
+	Var       = pipeline.Var
+	Axis      = pipeline.Axis
+	Cache     = pipeline.Cache
+	Condition = pipeline.Condition
+)
+
+// Register добавляет генератор в реестр. Встроенный генератор для той же
+// пары язык/формат заменяется.
+func Register(g Generator) {
+	generator.Register(g)
+}
+
+// GeneratorFunc превращает функцию в Generator для пары язык/формат.
+func GeneratorFunc(language, format string, generate func(*ProjectInfo) (string, error)) Generator {
+	return generator.GeneratorFunc(language, format, generate)
+}
+
+// ModelGenerator возвращает Generator, который сериализует построенный
+// Pipeline встроенным рендером формата.
+func ModelGenerator(language, format string, build func(*ProjectInfo) *Pipeline) Generator {
+	return generator.ModelGenerator(language, format, build)
+}
+
+// Languages возвращает языки, для которых зарегистрированы генераторы.
+func Languages() []string {
+	return generator.Languages()
+}
+
+// Formats возвращает зарегистрированные форматы.
+func Formats() []string {
+	return generator.Formats()
+}
+
+// DeployTarget строит джоб деплоя, который рендерится во всех форматах.
+type DeployTarget = generator.DeployTarget
+
+// RegisterDeployTarget добавляет цель деплоя, доступную через --deploy и
+// deploy.target в .pipeline-gen.yaml. Встроенная цель с тем же именем
+// заменяется.
+func RegisterDeployTarget(t DeployTarget) {
+	generator.RegisterDeployTarget(t)
+}
+
+// DeployTargetFunc превращает функцию в DeployTarget.
+func DeployTargetFunc(name string, job func(*ProjectInfo, *Pipeline) *Job) DeployTarget {
+	return generator.DeployTargetFunc(name, job)
+}
+
+// LanguageAnalyzer заполняет ProjectInfo для одного языка по дереву файлов.
+type LanguageAnalyzer = analyzer.LanguageAnalyzer
+
+// Repo — дерево файлов анализируемого репозитория.
+type Repo = analyzer.Repo
+
+// RegisterAnalyzer добавляет анализатор языка. Встроенный анализатор
+// того же языка заменяется.
+func RegisterAnalyzer(a LanguageAnalyzer) {
+	analyzer.RegisterAnalyzer(a)
+}
+
+// AnalyzerFunc превращает функцию в LanguageAnalyzer для языка.
+func AnalyzerFunc(language string, analyze func(*Repo, *ProjectInfo) error) LanguageAnalyzer {
+	return analyzer.AnalyzerFunc(language, analyze)
+}